@@ -0,0 +1,83 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package apiError
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+//v2Key the context key set on the /cr/v2 requests
+const v2Key contextKey = 0
+
+//IsV2 returns true if the request is served by a /cr/v2 endpoint
+func IsV2(req *http.Request) bool {
+	v2, _ := req.Context().Value(v2Key).(bool)
+	return v2
+}
+
+//errorWriter holds back the plain text error responses to convert them into json errors
+type errorWriter struct {
+	http.ResponseWriter
+	status    int
+	intercept bool
+	body      bytes.Buffer
+}
+
+func (w *errorWriter) WriteHeader(status int) {
+	contentType := w.Header().Get("Content-Type")
+	if status >= http.StatusBadRequest && (contentType == "" || strings.HasPrefix(contentType, "text/plain")) {
+		w.status = status
+		w.intercept = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.intercept {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+//Flush allows the streamed responses
+func (w *errorWriter) Flush() {
+	if w.intercept {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+//Handler marks the request as a /cr/v2 request, the handler errors written with HTTPError are json errors
+//and the remaining plain text errors are converted into json errors with the same status.
+func Handler(handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ew := &errorWriter{ResponseWriter: w}
+		handler.ServeHTTP(ew, req.WithContext(context.WithValue(req.Context(), v2Key, true)))
+		if ew.intercept {
+			Write(w, req, New(ew.status, "", strings.TrimSpace(ew.body.String())))
+		}
+	})
+}
@@ -0,0 +1,164 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package apiError
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
+)
+
+//Error codes returned in the json error
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeExtensionNotFound   = "extension_not_found"
+	CodeStateNotFound       = "state_not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeConflict            = "conflict"
+	CodePreconditionFailed  = "precondition_failed"
+	CodeInternalServerError = "internal_server_error"
+	CodeServiceUnavailable  = "service_unavailable"
+)
+
+//Error the json error returned by the /cr/v2 endpoints
+type Error struct {
+	//Code a stable identifier of the error, ie: extension_not_found
+	Code string `json:"code"`
+	//Message the error message in english
+	Message string `json:"message"`
+	//MessageLocalized the error message translated in the language of the request
+	MessageLocalized string `json:"message_localized,omitempty"`
+	//Details additional information, ie: the extension_name and the request_id
+	Details map[string]interface{} `json:"details,omitempty"`
+	//Status the http status code
+	Status int `json:"-"`
+}
+
+//codes the error code of each status
+var codes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+	http.StatusConflict:            CodeConflict,
+	http.StatusPreconditionFailed:  CodePreconditionFailed,
+	http.StatusInternalServerError: CodeInternalServerError,
+	http.StatusServiceUnavailable:  CodeServiceUnavailable,
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+//New creates an error, the code is derived from the status if empty
+func New(status int, code string, message string) *Error {
+	if code == "" {
+		code = statusCode(status)
+	}
+	return &Error{
+		Code:    code,
+		Message: message,
+		Status:  status,
+	}
+}
+
+//NotFound creates a 404 error with the code, ie: CodeExtensionNotFound
+func NotFound(code string, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+//Conflict creates a 409 error
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+//BadRequest creates a 400 error
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+//FromError returns the error as *Error: an *Error is returned as is,
+//a missing extension-name is a bad request and any other error gets the status.
+func FromError(err error, status int) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	if err == global.ErrExtensionNameNotFound {
+		return BadRequest(err.Error())
+	}
+	return New(status, "", err.Error())
+}
+
+//HTTPError writes the error of a handler.
+//A /cr/v1 request gets the plain text error with the v1Status, kept for compatibility,
+//a /cr/v2 request gets the json error with the status and the code of the *Error or the v1Status for other errors.
+func HTTPError(w http.ResponseWriter, req *http.Request, err error, v1Status int) {
+	if !IsV2(req) {
+		http.Error(w, err.Error(), v1Status)
+		return
+	}
+	Write(w, req, FromError(err, v1Status))
+}
+
+//statusCode returns the code of a status, ie: 406 gives not_acceptable
+func statusCode(status int) string {
+	if code, ok := codes[status]; ok {
+		return code
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternalServerError
+	}
+	return strings.Replace(strings.ToLower(text), " ", "_", -1)
+}
+
+//Localize sets the translated message, the translation of the code followed by the message
+func (e *Error) Localize(langs []string) {
+	title := strings.Replace(e.Code, "_", " ", -1)
+	translation, err := i18nUtils.Translate("error."+e.Code, title, langs)
+	if err != nil || translation == "" {
+		translation = title
+	}
+	e.MessageLocalized = translation + ": " + e.Message
+}
+
+//Write writes the error as json with its status
+func Write(w http.ResponseWriter, req *http.Request, e *Error) {
+	e.Localize(i18nUtils.GetLangs(req))
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	if extensionName := req.URL.Query().Get("extension-name"); extensionName != "" {
+		e.Details["extension_name"] = extensionName
+	}
+	if requestID := logger.GetRequestID(req); requestID != "" {
+		e.Details["request_id"] = requestID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(e)
+}
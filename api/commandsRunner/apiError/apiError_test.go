@@ -0,0 +1,123 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package apiError
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err            error
+		status         int
+		expectedStatus int
+		expectedCode   string
+	}{
+		{NotFound(CodeExtensionNotFound, "Extension name: ext1 not found"), http.StatusInternalServerError, http.StatusNotFound, CodeExtensionNotFound},
+		{NotFound(CodeStateNotFound, "State: task1 not found!"), http.StatusInternalServerError, http.StatusNotFound, CodeStateNotFound},
+		{Conflict("Token t1 already exists"), http.StatusInternalServerError, http.StatusConflict, CodeConflict},
+		{BadRequest("Token name missing"), http.StatusInternalServerError, http.StatusBadRequest, CodeBadRequest},
+		{global.ErrExtensionNameNotFound, http.StatusInternalServerError, http.StatusBadRequest, CodeBadRequest},
+		{errors.New("Property name missing"), http.StatusInternalServerError, http.StatusInternalServerError, CodeInternalServerError},
+		{errors.New("Invalid config"), http.StatusNotAcceptable, http.StatusNotAcceptable, "not_acceptable"},
+	}
+	for _, test := range tests {
+		e := FromError(test.err, test.status)
+		if e.Status != test.expectedStatus || e.Code != test.expectedCode {
+			t.Errorf("%d %s: expected %d %s, got %d %s", test.status, test.err.Error(), test.expectedStatus, test.expectedCode, e.Status, e.Code)
+		}
+		if e.Message != test.err.Error() {
+			t.Error("Expected message '" + test.err.Error() + "' got '" + e.Message + "'")
+		}
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	handlerFunc := func(w http.ResponseWriter, req *http.Request) {
+		HTTPError(w, req, NotFound(CodeExtensionNotFound, "Extension name: ext1 not found"), http.StatusInternalServerError)
+	}
+	//v1 keeps the plain text error and its status
+	req := httptest.NewRequest("GET", "/cr/v1/states?extension-name=ext1", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlerFunc).ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+	if rr.Body.String() != "Extension name: ext1 not found\n" {
+		t.Error("Unexpected v1 error: " + rr.Body.String())
+	}
+	//v2 gets the status and the code of the error
+	req = httptest.NewRequest("GET", "/cr/v2/states?extension-name=ext1", nil)
+	rr = httptest.NewRecorder()
+	Handler(handlerFunc).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	var e Error
+	err := json.Unmarshal(rr.Body.Bytes(), &e)
+	if err != nil {
+		t.Fatal(err.Error() + ": " + rr.Body.String())
+	}
+	if e.Code != CodeExtensionNotFound || e.Details["extension_name"] != "ext1" {
+		t.Error("Unexpected v2 error: " + rr.Body.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	handler := Handler(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("fail") == "true" {
+			http.Error(w, "stateManager not found for ext1", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	//Error converted into json
+	req := httptest.NewRequest("GET", "/cr/v2/states?extension-name=ext1&fail=true", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Error("Expected application/json, got:" + rr.Header().Get("Content-Type"))
+	}
+	var e Error
+	err := json.Unmarshal(rr.Body.Bytes(), &e)
+	if err != nil {
+		t.Fatal(err.Error() + ": " + rr.Body.String())
+	}
+	if e.Code != CodeBadRequest || e.Message != "stateManager not found for ext1" {
+		t.Error("Unexpected error: " + rr.Body.String())
+	}
+	if e.MessageLocalized == "" || e.Details["extension_name"] != "ext1" {
+		t.Error("Expected a localized message and the extension_name detail: " + rr.Body.String())
+	}
+	//Success unchanged
+	req = httptest.NewRequest("GET", "/cr/v2/states?extension-name=ext1", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "ok" {
+		t.Errorf("Expected 200 ok, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,83 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+)
+
+//HandleAudit handles audit rest api requests
+func (l *Log) HandleAudit(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleAudit")
+	switch req.Method {
+	case "GET":
+		l.getAuditEndpoint(w, req)
+	default:
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+	}
+}
+
+/*
+Retrieve the audit records
+from and to are RFC3339 dates, endpoint is a prefix, limit returns the most recent records.
+URL: /cr/v1/audit?[caller=<caller>][&method=<method>][&endpoint=<endpoint>][&extension-name=<extension>][&status=<code>][&from=<date>][&to=<date>][&limit=<n>]
+Method: GET
+*/
+func (l *Log) getAuditEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... getAuditEndpoint")
+	m, _ := url.ParseQuery(req.URL.RawQuery)
+	var filter Filter
+	var err error
+	filter.Caller = m.Get("caller")
+	filter.Method = m.Get("method")
+	filter.Endpoint = m.Get("endpoint")
+	filter.ExtensionName = m.Get("extension-name")
+	if statusFound := m.Get("status"); statusFound != "" {
+		filter.Status, err = strconv.Atoi(statusFound)
+	}
+	if limitFound := m.Get("limit"); limitFound != "" && err == nil {
+		filter.Limit, err = strconv.Atoi(limitFound)
+	}
+	if fromFound := m.Get("from"); fromFound != "" && err == nil {
+		filter.From, err = time.Parse(time.RFC3339, fromFound)
+	}
+	if toFound := m.Get("to"); toFound != "" && err == nil {
+		filter.To, err = time.Parse(time.RFC3339, toFound)
+	}
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	records, err := l.GetRecords(filter)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(records)
+}
@@ -0,0 +1,278 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/natefinch/lumberjack"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+//DefaultAuditMaxBackups default number of rotated audit files kept
+const DefaultAuditMaxBackups = 10
+
+//Record an audit record of a mutating api call
+type Record struct {
+	Timestamp     string              `json:"timestamp"`
+	Caller        string              `json:"caller"`
+	RemoteAddress string              `json:"remote_address"`
+	Method        string              `json:"method"`
+	Endpoint      string              `json:"endpoint"`
+	ExtensionName string              `json:"extension_name,omitempty"`
+	Parameters    map[string][]string `json:"parameters,omitempty"`
+	Status        int                 `json:"status"`
+}
+
+//Filter criteria to search the audit records, empty criteria are ignored
+type Filter struct {
+	Caller        string
+	Method        string
+	Endpoint      string
+	ExtensionName string
+	Status        int
+	From          time.Time
+	To            time.Time
+	Limit         int
+}
+
+type contextKey string
+
+const recordKey contextKey = "auditRecord"
+
+//Log the audit file of a runner, nothing is recorded until it is opened
+type Log struct {
+	mux  sync.Mutex
+	file *lumberjack.Logger
+}
+
+//Open sets the audit file in the config directory
+func (l *Log) Open(configDir string, maxBackups int) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.file = &lumberjack.Logger{
+		Filename:   filepath.Join(configDir, global.AuditLogFileName),
+		MaxBackups: maxBackups,
+	}
+}
+
+//Close closes the audit file, nothing is recorded until it is opened again
+func (l *Log) Close() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+//Filename returns the path of the audit file, empty if it is not opened
+func (l *Log) Filename() string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return ""
+	}
+	return l.file.Filename
+}
+
+//isOpen returns true if the audit file is opened
+func (l *Log) isOpen() bool {
+	return l.Filename() != ""
+}
+
+//statusWriter captures the status code sent by the handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.status = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+//Handler records the non-GET requests in the audit file once handled
+func (l *Log) Handler(handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet || req.Method == http.MethodOptions || req.Method == http.MethodHead || !l.isOpen() {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		record := newRecord(req)
+		req = req.WithContext(context.WithValue(req.Context(), recordKey, record))
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			record.Status = sw.status
+			err := l.WriteRecord(record)
+			if err != nil {
+				log.Error("Unable to write the audit record: " + err.Error())
+			}
+		}()
+		handler.ServeHTTP(sw, req)
+	})
+}
+
+func newRecord(req *http.Request) *Record {
+	remoteAddress := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		remoteAddress = host
+	}
+	record := &Record{
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		RemoteAddress: remoteAddress,
+		Method:        req.Method,
+		Endpoint:      global.ToV1Path(req.URL.Path),
+	}
+	m, err := url.ParseQuery(req.URL.RawQuery)
+	if err == nil && len(m) > 0 {
+		record.Parameters = m
+		if extensionName, ok := m["extension-name"]; ok {
+			record.ExtensionName = extensionName[0]
+		}
+	}
+	return record
+}
+
+//SetCaller sets the identity of the caller (token name or certificate subject) in the audit record of the request
+func SetCaller(req *http.Request, caller string) {
+	if record, ok := req.Context().Value(recordKey).(*Record); ok {
+		record.Caller = caller
+	}
+}
+
+//WriteRecord appends a record as a json line in the audit file
+func (l *Log) WriteRecord(record *Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return errors.New("Audit file not initialized")
+	}
+	_, err = l.file.Write(append(b, '\n'))
+	return err
+}
+
+//getAuditFiles returns the rotated audit files followed by the current one
+func getAuditFiles(filename string) ([]string, error) {
+	dir := filepath.Dir(filename)
+	ext := filepath.Ext(global.AuditLogFileName)
+	prefix := strings.TrimSuffix(global.AuditLogFileName, ext)
+	backups, err := filepath.Glob(filepath.Join(dir, prefix+"-*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(backups)
+	return append(backups, filename), nil
+}
+
+func (f *Filter) match(record Record) bool {
+	if f.Caller != "" && f.Caller != record.Caller {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(f.Method, record.Method) {
+		return false
+	}
+	if f.Endpoint != "" && !strings.HasPrefix(record.Endpoint, global.ToV1Path(f.Endpoint)) {
+		return false
+	}
+	if f.ExtensionName != "" && f.ExtensionName != record.ExtensionName {
+		return false
+	}
+	if f.Status != 0 && f.Status != record.Status {
+		return false
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		timestamp, err := time.Parse(time.RFC3339, record.Timestamp)
+		if err != nil {
+			return false
+		}
+		if !f.From.IsZero() && timestamp.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && timestamp.After(f.To) {
+			return false
+		}
+	}
+	return true
+}
+
+//GetRecords returns the audit records matching the filter, oldest first.
+//If a limit is set only the most recent records are returned.
+func (l *Log) GetRecords(filter Filter) ([]Record, error) {
+	log.Debug("Entering in... GetRecords")
+	records := make([]Record, 0)
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return records, errors.New("Audit file not initialized")
+	}
+	files, err := getAuditFiles(l.file.Filename)
+	if err != nil {
+		return records, err
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return records, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				log.Warning("Malformed audit record in " + file + ": " + err.Error())
+				continue
+			}
+			if filter.match(record) {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return records, err
+		}
+	}
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records, nil
+}
@@ -0,0 +1,112 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package audit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAuditHandler(t *testing.T) {
+	t.Log("Entering... TestAuditHandler")
+	dir, err := ioutil.TempDir("", "TestAuditHandler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	auditLog := &Log{}
+	auditLog.Open(dir, DefaultAuditMaxBackups)
+	defer auditLog.Close()
+	handler := auditLog.Handler(func(w http.ResponseWriter, req *http.Request) {
+		SetCaller(req, "ci")
+		if req.URL.Query().Get("extension-name") == "forbidden" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		}
+	})
+	requests := []struct {
+		method string
+		url    string
+	}{
+		{"GET", "/cr/v1/states?extension-name=ext1"},
+		{"PUT", "/cr/v1/engine?action=reset&extension-name=ext1"},
+		{"DELETE", "/cr/v1/extension?extension-name=forbidden"},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.url, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	records, err := auditLog.GetRecords(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	record := records[0]
+	if record.Caller != "ci" || record.Method != "PUT" || record.Endpoint != "/cr/v1/engine" || record.ExtensionName != "ext1" || record.Status != http.StatusOK {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if record.Parameters["action"][0] != "reset" {
+		t.Errorf("Parameters not recorded %+v", record.Parameters)
+	}
+	if records[1].Status != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", records[1].Status)
+	}
+	records, err = auditLog.GetRecords(Filter{Status: http.StatusForbidden})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ExtensionName != "forbidden" {
+		t.Errorf("Unexpected records for status filter %+v", records)
+	}
+	records, err = auditLog.GetRecords(Filter{Endpoint: "/cr/v1/engine", Method: "put"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Errorf("Expected 1 record for endpoint filter, got %d", len(records))
+	}
+	records, err = auditLog.GetRecords(Filter{From: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no record in the future, got %d", len(records))
+	}
+	records, err = auditLog.GetRecords(Filter{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Method != "DELETE" {
+		t.Errorf("Expected the most recent record, got %+v", records)
+	}
+	//The v2 endpoints are recorded with their v1 path
+	req := httptest.NewRequest("PUT", "/cr/v2/engine?action=reset&extension-name=ext2", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	records, err = auditLog.GetRecords(Filter{Endpoint: "/cr/v2/engine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Endpoint != "/cr/v1/engine" {
+		t.Errorf("Expected the v2 record under the v1 endpoint, got %+v", records)
+	}
+}
@@ -18,18 +18,24 @@
 package commandsRunner
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	oconfig "github.com/olebedev/config"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/audit"
 	"github.com/IBM/commands-runner/api/commandsRunner/commandsRunner"
 	"github.com/IBM/commands-runner/api/commandsRunner/config"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
@@ -37,6 +43,9 @@ import (
 	"github.com/IBM/commands-runner/api/commandsRunner/properties"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
 	"github.com/IBM/commands-runner/api/commandsRunner/status"
+	"github.com/IBM/commands-runner/api/commandsRunner/storage"
+	"github.com/IBM/commands-runner/api/commandsRunner/token"
+	"github.com/IBM/commands-runner/api/commandsRunner/webhook"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
 	cli "gopkg.in/urfave/cli.v1"
 )
@@ -50,44 +59,62 @@ const COPYRIGHT string = `######################################################
 #  IBM Corporation - initial API and implementation
 ###############################################################################`
 
-func setupResponse(w *http.ResponseWriter, req *http.Request) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-	(*w).Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Force, RunningToFailed")
-}
-
-func validateToken(configDir string, protectedHandler http.HandlerFunc) http.HandlerFunc {
+func validateToken(tokens *token.Store, protectedHandler http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		setupResponse(&w, req)
 		if (*req).Method == "OPTIONS" {
 			return
 		}
 
-		//Retreive Authentication header from request
-		Auth := req.Header.Get("Authorization")
-		if Auth == "" {
-			logger.AddCallerField().Error("Auth header not found")
-			http.Error(w, "Token not provided", http.StatusForbidden)
-			return
+		//A process connected through the unix socket is authenticated by its peer credentials
+		var t *token.Token
+		var err error
+		if peer, ok := getPeer(req.RemoteAddr); ok {
+			t, err = token.AuthenticatePeer(peer.uid)
+			if err != nil {
+				log.Debug(err.Error())
+			}
 		}
-		//Split to find the provided token
-		receivedTokens := strings.Split(Auth, ":")
-		receivedToken := receivedTokens[1]
 
-		//Read official token
-		token, err := ioutil.ReadFile(filepath.Join(configDir, global.TokenFileName))
-		if err != nil {
-			http.Error(w, "Token file not found:"+filepath.Join(configDir, global.TokenFileName), http.StatusNotFound)
-			return
+		//A verified client certificate mapped to a role authenticates the request
+		if t == nil && req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+			t, err = token.AuthenticateCertificate(req.TLS.VerifiedChains[0][0])
+			if err != nil {
+				log.Debug(err.Error())
+			}
+		}
+
+		if t == nil {
+			//Retreive the token from the Authentication header
+			receivedToken, err := token.GetTokenFromRequest(req)
+			if err != nil {
+				logger.AddCallerField().Error(err.Error())
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			//Search the token in the token store and the cr-token file
+			t, err = tokens.Authenticate(receivedToken)
+			if err != nil {
+				configDir := tokens.ConfigDir()
+				logger.AddCallerField().Error(err.Error())
+				if _, errStat := os.Stat(filepath.Join(configDir, global.TokenFileName)); os.IsNotExist(errStat) {
+					if _, errStat := os.Stat(filepath.Join(configDir, global.TokensFileName)); os.IsNotExist(errStat) {
+						http.Error(w, "Token file not found:"+filepath.Join(configDir, global.TokenFileName), http.StatusNotFound)
+						return
+					}
+				}
+				http.Error(w, "Invalid token", http.StatusForbidden)
+				return
+			}
 		}
 
-		//Convert and trim token
-		tokenS := string(token)
-		tokenS = strings.TrimSuffix(tokenS, "\n")
+		audit.SetCaller(req, t.Name)
 
-		//Check if correct token
-		if receivedToken != tokenS {
-			http.Error(w, "Invalid token", http.StatusForbidden)
+		//Check if the token role and scope allow the request
+		err = t.Authorize(req)
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			http.Error(w, err.Error(), http.StatusForbidden)
 			return
 		}
 
@@ -97,16 +124,22 @@ func validateToken(configDir string, protectedHandler http.HandlerFunc) http.Han
 	})
 }
 
+//GetRoutes returns the patterns registered with AddHandler
+func GetRoutes() []string {
+	return defaultRunner.GetRoutes()
+}
+
+//AddHandler registers the handler on the pattern, a /cr/v1 pattern is also registered under /cr/v2 with json errors
 func AddHandler(pattern string, handler http.HandlerFunc, requireAuth bool) {
-	log.Debug("Entering... AddHandler")
-	log.Debug("pattern:" + pattern)
-	log.Debug("serverConfigDir:" + global.ServerConfigDir)
-	log.Debug("requireAuth:" + strconv.FormatBool(requireAuth))
-	if requireAuth {
-		http.HandleFunc(pattern, validateToken(global.ServerConfigDir, handler))
-	} else {
-		http.HandleFunc(pattern, handler)
-	}
+	defaultRunner.AddHandler(pattern, handler, requireAuth)
+}
+
+//secureResponse adds the CORS and security headers of the runner to the response
+func (r *Runner) secureResponse(handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Security.setupResponse(&w, req)
+		handler.ServeHTTP(w, req)
+	})
 }
 
 func readCommandsRunnerConfig(configDir string) error {
@@ -152,6 +185,89 @@ func readCommandsRunnerConfig(configDir string) error {
 		if val, ok := properties["extensions_logs_path"]; ok {
 			state.SetExtensionsLogsPath(val.(string))
 		}
+		if val, ok := properties["config_overlay"]; ok {
+			err = global.ValidateConfigOverlay(val.(string))
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+			global.ConfigOverlay = val.(string)
+		}
+		if val, ok := properties["shutdown_timeout"]; ok {
+			shutdownTimeout, err := strconv.Atoi(fmt.Sprint(val))
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+			global.ShutdownTimeout = shutdownTimeout
+		}
+		if val, ok := properties["metrics_port"]; ok {
+			global.MetricsPort = fmt.Sprint(val)
+		}
+		if val, ok := properties["unix_socket"]; ok {
+			global.UnixSocketPath = val.(string)
+		}
+		if val, ok := properties["unix_socket_mode"]; ok {
+			mode, err := parseFileMode(val)
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+			global.UnixSocketMode = mode
+		}
+		if val, ok := properties["disable_tcp"]; ok {
+			disableTCP, err := strconv.ParseBool(fmt.Sprint(val))
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+			global.TCPDisabled = disableTCP
+		}
+		if global.TCPDisabled && global.UnixSocketPath == "" {
+			return errors.New("disable_tcp requires unix_socket to be set")
+		}
+		if _, ok := properties["unix_socket_peer_roles"]; ok {
+			err = token.LoadPeerRoles(raw)
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+		}
+		if val, ok := properties["log_format"]; ok {
+			err = logger.SetLogFormat(val.(string))
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+		}
+		if val, ok := properties["client_ca_path"]; ok {
+			global.ClientCAPath = val.(string)
+		}
+		err = loadSecurityConfig(raw)
+		if err != nil {
+			log.Debug(err.Error())
+			return err
+		}
+		if _, ok := properties["client_cert_roles"]; ok {
+			err = token.LoadCertificateRoles(raw)
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+		}
+		if _, ok := properties["webhooks"]; ok {
+			configWebhooks, err = webhook.LoadWebhooks(raw)
+			if err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+		}
+		if val, ok := properties["storage"]; ok {
+			global.StorageBackend = val.(string)
+		}
+		if val, ok := properties["storage_path"]; ok {
+			global.StoragePath = val.(string)
+		}
 		if val, ok := properties["about_url"]; ok {
 			commandsRunner.SetAboutURL(val.(string))
 		}
@@ -164,75 +280,77 @@ func readCommandsRunnerConfig(configDir string) error {
 	return nil
 }
 
-func start() {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Fatal(r)
-				logger.LogFile.Close()
-			}
-		}()
-		log.Info("http://localhost:" + global.ServerPort)
-		if err := http.ListenAndServe(":"+global.ServerPort, nil); err != nil {
-			log.Fatalf("ListenAndServe error: %v", err)
+//parseFileMode converts an octal string (ie: "0660") or a number already decoded from yaml octal notation into a file mode
+func parseFileMode(val interface{}) (os.FileMode, error) {
+	switch v := val.(type) {
+	case int:
+		return os.FileMode(v), nil
+	case string:
+		mode, err := strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return 0, errors.New("Invalid file mode: " + v)
 		}
-	}()
-	_, errCertPath := os.Stat(global.ServerCertificatePath)
-	_, errKeyPath := os.Stat(global.ServerKeyPath)
-	if errCertPath == nil && errKeyPath == nil {
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					log.Fatal(r)
-					logger.LogFile.Close()
-				}
-			}()
-			log.Info("https://localhost:" + global.ServerPortSSL)
-			if err := http.ListenAndServeTLS(":"+global.ServerPortSSL, global.ServerCertificatePath, global.ServerKeyPath, nil); err != nil {
-				log.Errorf("ListenAndServeTLS error: %v", err)
-			}
-		}()
-	} else {
-		log.Info("SSL not enabled as " + global.ServerCertificatePath + " or " + global.ServerKeyPath + " is not present.")
+		return os.FileMode(mode), nil
 	}
-	status.SetStatus(status.CMStatus, "Up")
+	return 0, errors.New("Invalid file mode: " + fmt.Sprint(val))
 }
 
-func blockForever() {
-	select {}
+//openStorage opens the storage backend, the bolt database is by default in the config directory
+func openStorage(backend string, storagePath string, configDir string) (storage.Storage, error) {
+	if storagePath == "" {
+		storagePath = filepath.Join(configDir, storage.BoltFileName)
+	}
+	return storage.Open(backend, storagePath)
+}
+
+//waitForShutdown blocks until SIGTERM or SIGINT is received then stops the server gracefully:
+//no new execution is accepted, the running states get ShutdownTimeout seconds to complete before being cancelled,
+//then the http servers are shutdown and the logs flushed.
+func waitForShutdown() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-signals
+	log.Info("Signal " + sig.String() + " received, shutting down")
+	status.SetStatus(status.CMStatus, "Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(defaultRunner.ShutdownTimeout)*time.Second)
+	defer cancel()
+	ctxServers, cancelServers := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelServers()
+	err := defaultRunner.Shutdown(ctx, ctxServers)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	log.Info("Server stopped")
+	if logger.LogFile != nil {
+		logger.LogFile.Close()
+	}
 }
 
 type InitFunc func(port string, portSSL string, configDir string, certificatePath string, keyPath string)
 
 type PostStartFunc func(configDir string)
 
+//Init initializes the default runner, serving http.DefaultServeMux, with the settings of the process
 func Init(port string, portSSL string, configDir string, certificatePath string, keyPath string) {
-	global.ServerPort = port
-	global.ServerPortSSL = portSSL
-	global.ServerConfigDir = configDir
-	global.ServerCertificatePath = certificatePath
-	global.ServerKeyPath = keyPath
-	config.SetConfigPath(configDir)
-	AddHandler("/cr/v1/state", state.HandleState, true)
-	AddHandler("/cr/v1/state/", state.HandleState, true)
-	AddHandler("/cr/v1/states", state.HandleStates, true)
-	AddHandler("/cr/v1/engine", state.HandleEngine, true)
-	AddHandler("/cr/v1/cr/", commandsRunner.HandleCR, true)
-	AddHandler("/cr/v1/status", status.HandleStatus, true)
-	AddHandler("/cr/v1/extension", state.HandleExtension, true)
-	AddHandler("/cr/v1/extensions", state.HandleExtensions, true)
-	AddHandler("/cr/v1/extensions/", state.HandleExtensions, true)
-	AddHandler("/cr/v1/uimetadata", state.HandleUIMetadata, true)
-	AddHandler("/cr/v1/uimetadatas", state.HandleUIMetadatas, true)
-	AddHandler("/cr/v1/config", config.HandleConfig, true)
-	AddHandler("/cr/v1/config/", config.HandleConfig, true)
-	AddHandler("/cr/v1/template", state.HandleTemplate, true)
+	defaultRunner.loadServerConfig()
+	defaultRunner.Port = port
+	defaultRunner.PortSSL = portSSL
+	defaultRunner.ConfigDir = configDir
+	defaultRunner.CertificatePath = certificatePath
+	defaultRunner.KeyPath = keyPath
+	err := defaultRunner.Init()
+	if err != nil {
+		logger.AddCallerField().Fatal(err.Error())
+	}
 }
 
 func ServerStart(preInit InitFunc, postInit InitFunc, preStart InitFunc, postStart PostStartFunc) {
 	var configDir string
 	var port string
 	var portSSL string
+	var configOverlay string
+	var storageFrom string
+	var storageTo string
 	//	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	//If Panic close the current log.
 	defer func() {
@@ -268,6 +386,11 @@ func ServerStart(preInit InitFunc, postInit InitFunc, preStart InitFunc, postSta
 					Value:       global.DefaultPortSSL,
 					Destination: &portSSL,
 				},
+				cli.StringFlag{
+					Name:        "overlay, o",
+					Usage:       "Default config overlay, ie: 'prod' to merge config.prod.yml",
+					Destination: &configOverlay,
+				},
 			},
 			Action: func(c *cli.Context) error {
 				logMaxBackups := os.Getenv("CR_LOG_MAX_BACKUPS")
@@ -324,6 +447,13 @@ func ServerStart(preInit InitFunc, postInit InitFunc, preStart InitFunc, postSta
 				if err != nil {
 					logger.AddCallerField().Fatal(err.Error())
 				}
+				if configOverlay != "" {
+					err = global.ValidateConfigOverlay(configOverlay)
+					if err != nil {
+						logger.AddCallerField().Fatal(err.Error())
+					}
+					global.ConfigOverlay = configOverlay
+				}
 				if preInit != nil {
 					preInit(port, portSSL, configDir, filepath.Join(configDir, global.SSLCertFileName), filepath.Join(configDir, global.SSLKeyFileName))
 				}
@@ -331,22 +461,85 @@ func ServerStart(preInit InitFunc, postInit InitFunc, preStart InitFunc, postSta
 				if postInit != nil {
 					postInit(port, portSSL, configDir, filepath.Join(configDir, global.SSLCertFileName), filepath.Join(configDir, global.SSLKeyFileName))
 				}
-				err = state.DeleteFormerEmbeddedExtensions()
-				if err != nil {
-					logger.AddCallerField().Fatal(err.Error())
-				}
-				err = state.RegisterEmbededExtensions(true, true)
+				err = defaultRunner.RegisterExtensions()
 				if err != nil {
 					logger.AddCallerField().Fatal(err.Error())
 				}
 				if preStart != nil {
 					preStart(port, portSSL, configDir, filepath.Join(configDir, global.SSLCertFileName), filepath.Join(configDir, global.SSLKeyFileName))
 				}
-				start()
+				err = defaultRunner.Start()
+				if err != nil {
+					logger.AddCallerField().Fatal(err.Error())
+				}
 				if postStart != nil {
 					postStart(configDir)
 				}
-				blockForever()
+				waitForShutdown()
+				return nil
+			},
+		},
+		{
+			Name:  "migrate-storage",
+			Usage: "Copy the states and the config of the registered extensions from a storage backend to another, the server must be stopped",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "configDir, c",
+					Usage:       "Config Directory",
+					Destination: &configDir,
+				},
+				cli.StringFlag{
+					Name:        "from",
+					Usage:       "Storage backend to migrate from (" + storage.FileBackend + "," + storage.BoltBackend + ")",
+					Value:       storage.FileBackend,
+					Destination: &storageFrom,
+				},
+				cli.StringFlag{
+					Name:        "to",
+					Usage:       "Storage backend to migrate to (" + storage.FileBackend + "," + storage.BoltBackend + ")",
+					Value:       storage.BoltBackend,
+					Destination: &storageTo,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if configDir == "" {
+					return errors.New("Missing option -c to specif the directory where the config must be stored")
+				}
+				if !filepath.IsAbs(configDir) {
+					return errors.New("The path of config must be absolute: " + configDir)
+				}
+				if storageFrom == storageTo {
+					return errors.New("The storage backends to migrate from and to are the same: " + storageFrom)
+				}
+				err := readCommandsRunnerConfig(configDir)
+				if err != nil {
+					return err
+				}
+				global.ServerConfigDir = configDir
+				config.SetConfigPath(configDir)
+				//The extensions paths are set by the init functions
+				if preInit != nil {
+					preInit(port, portSSL, configDir, filepath.Join(configDir, global.SSLCertFileName), filepath.Join(configDir, global.SSLKeyFileName))
+				}
+				if postInit != nil {
+					postInit(port, portSSL, configDir, filepath.Join(configDir, global.SSLCertFileName), filepath.Join(configDir, global.SSLKeyFileName))
+				}
+				from, err := openStorage(storageFrom, global.StoragePath, configDir)
+				if err != nil {
+					return err
+				}
+				defer from.Close()
+				to, err := openStorage(storageTo, global.StoragePath, configDir)
+				if err != nil {
+					return err
+				}
+				defer to.Close()
+				migrated, err := storage.Migrate(state.DefaultInstance(), from, to)
+				if err != nil {
+					return err
+				}
+				fmt.Println("Extensions migrated from " + storageFrom + " to " + storageTo + ": " + strings.Join(migrated, ", "))
+				fmt.Println("Set \"storage: " + storageTo + "\" in " + filepath.Join(configDir, global.CommandsRunnerConfigFileName) + " to use it")
 				return nil
 			},
 		},
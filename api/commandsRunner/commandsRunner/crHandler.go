@@ -24,6 +24,7 @@ import (
 	"regexp"
 	"strconv"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
 
@@ -85,7 +86,7 @@ func GetLogLevelEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := enc.Encode(logLevel)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
 }
 
@@ -99,7 +100,7 @@ func GetLogMaxBackupsEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := enc.Encode(logMaxBackups)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
 }
 
@@ -111,7 +112,7 @@ func GetSettingsEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := enc.Encode(settings)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
 }
 
@@ -128,7 +129,7 @@ func GetAboutEndpoint(w http.ResponseWriter, req *http.Request) {
 		err := enc.Encode(about)
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		}
 	} else {
 		global.ForwardRequest(w, req, global.AboutURL)
@@ -148,7 +149,7 @@ func SetLogLevelEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := SetLogLevel(level)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
 }
 
@@ -165,7 +166,7 @@ func SetLogMaxBackupEndpoint(w http.ResponseWriter, req *http.Request) {
 	maxBackups, errMaxBackup := strconv.Atoi(logMaxBackups)
 	if errMaxBackup != nil {
 		logger.AddCallerField().Error(errMaxBackup.Error())
-		http.Error(w, errMaxBackup.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, errMaxBackup, http.StatusInternalServerError)
 	}
 	SetLogMaxBackups(maxBackups)
 }
@@ -64,7 +64,7 @@ func GetLogLevel() string {
 }
 
 func SetLogMaxBackups(maxBackups int) {
-	logger.InitLogFile(global.ConfigDirectory, maxBackups)
+	logger.SetLogMaxBackups(maxBackups)
 }
 
 //Retrieve maxBackup
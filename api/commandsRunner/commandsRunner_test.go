@@ -17,6 +17,17 @@
 */
 package commandsRunner
 
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/openapi"
+)
+
 const COPYRIGHT_TEST string = `###############################################################################
 # Licensed Materials - Property of IBM Copyright IBM Corporation 2017, 2019. All Rights Reserved.
 # U.S. Government Users Restricted Rights - Use, duplication or disclosure restricted by GSA ADP
@@ -25,3 +36,34 @@ const COPYRIGHT_TEST string = `#################################################
 # Contributors:
 #  IBM Corporation - initial API and implementation
 ###############################################################################`
+
+//TestRoutesDescribed checks that each route registered by Init is described in the OpenAPI document, a v2 route is described by its v1 route
+func TestRoutesDescribed(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "cr-openapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	Init("30101", "30103", configDir, "", "")
+	defer defaultRunner.Shutdown(context.Background(), context.Background())
+	paths, err := openapi.GetPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range GetRoutes() {
+		if !isRouteDescribed(global.ToV1Path(route), paths) {
+			t.Error("Route " + route + " is not described in the OpenAPI document")
+		}
+	}
+}
+
+//isRouteDescribed returns true if the route is a path of the document or, for a subtree route, if a path is under it
+func isRouteDescribed(route string, paths []string) bool {
+	trimmedRoute := strings.TrimSuffix(route, "/")
+	for _, path := range paths {
+		if path == route || path == trimmedRoute || strings.HasPrefix(path, trimmedRoute+"/") {
+			return true
+		}
+	}
+	return false
+}
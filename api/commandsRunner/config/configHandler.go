@@ -19,6 +19,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -28,6 +29,7 @@ import (
 	"github.com/olebedev/config"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/commandsRunner/properties"
@@ -49,7 +51,7 @@ func HandleConfig(w http.ResponseWriter, req *http.Request) {
 			m, errRQ := url.ParseQuery(req.URL.RawQuery)
 			if errRQ != nil {
 				logger.AddCallerField().Error(errRQ.Error())
-				http.Error(w, errRQ.Error(), 500)
+				apiError.HTTPError(w, req, errRQ, 500)
 				return
 			}
 			//Retreive the new status
@@ -74,80 +76,198 @@ func HandleConfig(w http.ResponseWriter, req *http.Request) {
 	case "POST":
 		SetPropertiesEndpoint(w, req)
 	default:
-		http.Error(w, "Unsupported method:"+req.Method, http.StatusNotFound)
+		apiError.HTTPError(w, req, apiError.New(http.StatusMethodNotAllowed, "", "Unsupported method:"+req.Method), http.StatusNotFound)
 	}
 }
 
 /*
 Validate the properties
+If the extension doesn't provide a validation_config_url, the validation_config_script is run
+and if none, the properties are validated against the ui metadata.
 URL: /cr/v1/config?action=validate
 MEthod: GET
 */
 func validateConfigEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in.... validateConfigEndpoint")
-	extensionName, _, err := global.GetExtensionNameFromRequest(req)
+	inst := state.GetInstance(req)
+	extensionName, m, err := global.GetExtensionNameFromRequest(req)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
-	extension, err := state.ReadRegisteredExtension(extensionName)
+	extension, err := inst.ReadRegisteredExtension(extensionName)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
 	log.Debug("extension.ValidationConfigURL:" + extension.ValidationConfigURL)
+	if extension.ValidationConfigURL == "" {
+		if extension.ValidationConfigScript != "" {
+			validateConfigScript(inst, w, req, extension, extensionName)
+			return
+		}
+		validateConfigNative(inst, w, req, extensionName, m)
+		return
+	}
 	global.ForwardRequest(w, req, extension.ValidationConfigURL)
 	log.Debug("Exiting in.... validateConfigEndpoint")
 }
 
+//validateConfigNative validates the properties against the ui metadata and returns 406 if not valid
+func validateConfigNative(inst *state.Instance, w http.ResponseWriter, req *http.Request, extensionName string, m url.Values) {
+	log.Debug("Entering in.... validateConfigNative")
+	ps, err := GetProperties(inst, extensionName)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
+		return
+	}
+	uiMetaDataName := global.DefaultUIMetaDataName
+	if configurationName, err := properties.GetValueAsString(ps, "configuration_name"); err == nil && configurationName != "" {
+		uiMetaDataName = configurationName
+	}
+	uiMetaDataNameFound, okuiMetaDataName := m["ui-metadata-name"]
+	if okuiMetaDataName {
+		log.Debugf("uiMetaDataName:%s", uiMetaDataNameFound)
+		uiMetaDataName = uiMetaDataNameFound[0]
+	}
+	ps, valid, err := ValidateProperties(inst, extensionName, uiMetaDataName, ps)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	result, err := json.Marshal(&Config{Properties: ps})
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	//The validation result is json to not be replaced by an error on /cr/v2
+	w.Header().Set("Content-Type", "application/json")
+	if !valid {
+		w.WriteHeader(http.StatusNotAcceptable)
+	}
+	_, err = w.Write(result)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+	}
+}
+
 /*
 Generate config
+The generate_config_url is called or if not provided the generate_config_script is run
+and its output saved as the new config.
 URL: /cr/v1/config
 Method: PUT
 */
 
 func generateConfigEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in.... generateConfigEndpoint")
+	inst := state.GetInstance(req)
 	extensionName, _, err := global.GetExtensionNameFromRequest(req)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
-	extension, err := state.ReadRegisteredExtension(extensionName)
+	extension, err := inst.ReadRegisteredExtension(extensionName)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
+		return
+	}
+	log.Debug("extension.GenerateConfigURL:" + extension.GenerateConfigURL)
+	if extension.GenerateConfigURL == "" && extension.GenerateConfigScript != "" {
+		generateConfigScript(inst, w, req, extension, extensionName)
 		return
 	}
-	log.Debug("extension.ValidationConfigURL:" + extension.ValidationConfigURL)
 	global.ForwardRequest(w, req, extension.GenerateConfigURL)
 	log.Debug("Exiting in.... generateConfigEndpoint")
 }
 
+//validateConfigScript runs the validation_config_script and returns 200, 299 or 406 depending of the message types found
+func validateConfigScript(inst *state.Instance, w http.ResponseWriter, req *http.Request, extension *state.Extension, extensionName string) {
+	log.Debug("Entering in.... validateConfigScript")
+	ps, succeeded, err := RunConfigScript(inst, extension, extensionName, extension.ValidationConfigScript)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	result, err := json.Marshal(&Config{Properties: ps})
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	status := GetValidationStatus(ps)
+	if !succeeded {
+		status = http.StatusNotAcceptable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(result)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+	}
+}
+
+//generateConfigScript runs the generate_config_script and saves its output as the new config
+func generateConfigScript(inst *state.Instance, w http.ResponseWriter, req *http.Request, extension *state.Extension, extensionName string) {
+	log.Debug("Entering in.... generateConfigScript")
+	ps, succeeded, err := RunConfigScript(inst, extension, extensionName, extension.GenerateConfigScript)
+	if err == nil && !succeeded {
+		err = errors.New("Config script " + extension.GenerateConfigScript + " failed, check the logs")
+	}
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	err = SetProperties(inst, extensionName, ps)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	result, err := json.Marshal(&Config{Properties: ps})
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(result)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+	}
+}
+
 /*
 Retrieve 1 single property
 URL: /cr/v1/config/<property_name>
 Method: GET
 */
 func getPropertyEndpoint(w http.ResponseWriter, req *http.Request) {
+	inst := state.GetInstance(req)
 	//Check format
 	validatePath := regexp.MustCompile("/cr/v1/(config)/([\\w]*)$")
 	params := validatePath.FindStringSubmatch(req.URL.Path)
 	extensionName, _, err := global.GetExtensionNameFromRequest(req)
 	//Retrieve the property name
-	property, err := FindProperty(extensionName, params[2])
+	property, err := FindProperty(inst, extensionName, params[2])
 	if err == nil {
 		err = json.NewEncoder(w).Encode(property)
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), http.StatusNotFound)
+			apiError.HTTPError(w, req, err, http.StatusNotFound)
 		}
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
 
@@ -164,14 +284,16 @@ func getPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 
 /*
 Retrieve all properties
-URL: /cr/v1/config/
+The overlay is deep-merged on top of the config, if provenance is true the origin file of each value is returned.
+URL: /cr/v1/config?extension-name=<extension_name>&overlay=<overlay>&provenance=<true|false>
 Method: GET
 */
 func GetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
+	inst := state.GetInstance(req)
 	extensionName, m, err := global.GetExtensionNameFromRequest(req)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
 	uiMetaDataName := global.DefaultUIMetaDataName
@@ -181,52 +303,111 @@ func GetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 		uiMetaDataName = uiMetaDataNameFound[0]
 	}
 
+	overlay := ""
+	overlayFound, okOverlay := m["overlay"]
+	if okOverlay {
+		log.Debugf("overlay:%s", overlayFound)
+		overlay = overlayFound[0]
+		err = global.ValidateConfigOverlay(overlay)
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
+			return
+		}
+	}
+	//The config file is returned unless an overlay is provided or the merged view is requested,
+	//so the result can be saved without writing the overlay values in the config file.
+	merged := okOverlay
+	mergedFound, okMerged := m["merged"]
+	if okMerged {
+		merged, err = strconv.ParseBool(mergedFound[0])
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
+			return
+		}
+	}
+	withProvenance := false
+	provenanceFound, okProvenance := m["provenance"]
+	if okProvenance {
+		withProvenance, err = strconv.ParseBool(provenanceFound[0])
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
+			return
+		}
+	}
+	//The ETag is read before the properties, if the file changes in between the next update will fail instead of being lost
+	etag, err := GetConfigETag(inst, extensionName)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
 	//Retrieve properties
-	properties, err := GetProperties(extensionName)
+	var properties, provenance properties.Properties
+	if merged {
+		properties, provenance, err = GetPropertiesWithProvenance(inst, extensionName, overlay)
+	} else {
+		properties, provenance, err = GetBasePropertiesWithProvenance(inst, extensionName)
+	}
 
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		return
 	}
-	properties, err = PropertiesEncodeDecode(extensionName, uiMetaDataName, properties, true)
+	properties, err = PropertiesEncodeDecode(inst, extensionName, uiMetaDataName, properties, true)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
 	cfg, err := config.ParseJson("{}")
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
 	err = cfg.Set(global.ConfigRootKey, properties)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
+	if withProvenance {
+		err = cfg.Set("provenance", map[string]interface{}(provenance))
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusNotFound)
+			return
+		}
+	}
 	result, err := config.RenderJson(cfg.Root)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
+	if etag != "" {
+		w.Header().Set(global.ETagHeader, etag)
+	}
 	_, err = w.Write([]byte(result))
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
 }
 
 /*
 Set the properties
+If the If-Match header is set and doesn't match the ETag of the config, the properties are not saved and a 412 is returned.
 URL: /cr/v1/config/
 Method: POST
 */
 func SetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering....... setPropertiesEndpoint")
+	inst := state.GetInstance(req)
 	var ps properties.Properties
 	var body []byte
 	var err error
@@ -238,7 +419,7 @@ func SetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 		form, err := mReader.ReadForm(100000)
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), 500)
+			apiError.HTTPError(w, req, err, 500)
 			return
 		}
 		if fileHeaders, ok := form.File["config"]; ok {
@@ -248,7 +429,7 @@ func SetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 				file, err := fileHeader.Open()
 				if err != nil {
 					logger.AddCallerField().Error(err.Error())
-					http.Error(w, err.Error(), 500)
+					apiError.HTTPError(w, req, err, 500)
 					return
 				}
 				content := make([]byte, fileHeader.Size)
@@ -261,7 +442,7 @@ func SetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 	extensionName, m, errExtName := global.GetExtensionNameFromRequest(req)
 	if errExtName != nil {
 		logger.AddCallerField().Error(errExtName.Error())
-		http.Error(w, errExtName.Error(), 500)
+		apiError.HTTPError(w, req, errExtName, 500)
 		return
 	}
 	uiMetaDataName := global.DefaultUIMetaDataName
@@ -277,22 +458,45 @@ func SetPropertiesEndpoint(w http.ResponseWriter, req *http.Request) {
 		cfg, err = config.ParseYaml(string(body))
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), 500)
+			apiError.HTTPError(w, req, err, 500)
 			return
 		}
 		ps, err = cfg.Map(global.ConfigRootKey)
 	} else {
 		ps, err = cfg.Map(global.ConfigRootKey)
-		ps, _ = PropertiesEncodeDecode(extensionName, uiMetaDataName, ps, false)
+		ps, _ = PropertiesEncodeDecode(inst, extensionName, uiMetaDataName, ps, false)
 	}
 	log.Debug("PS decoded")
 	if err == nil {
 		log.Debug("Set Properties")
 		log.Debug("ps len:" + strconv.Itoa(len(ps)))
-		err = SetProperties(extensionName, ps)
+		var invalidatedStates []string
+		invalidatedStates, err = SetPropertiesIfMatch(inst, extensionName, ps, func(etag string) bool {
+			return global.IfMatch(req, etag)
+		})
+		if e, ok := err.(*apiError.Error); ok && e.Status == http.StatusPreconditionFailed {
+			logger.AddCallerField().Error(err.Error())
+			if etag, errETag := GetConfigETag(inst, extensionName); errETag == nil {
+				w.Header().Set(global.ETagHeader, etag)
+			}
+			apiError.HTTPError(w, req, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err == nil {
+			out := make(map[string]interface{})
+			out["invalidated_states"] = invalidatedStates
+			var b []byte
+			b, err = json.Marshal(out)
+			if err == nil {
+				if etag, errETag := GetConfigETag(inst, extensionName); errETag == nil && etag != "" {
+					w.Header().Set(global.ETagHeader, etag)
+				}
+				w.Write(b)
+			}
+		}
 	}
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), 500)
+		apiError.HTTPError(w, req, err, 500)
 	}
 }
@@ -18,6 +18,7 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -25,6 +26,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/properties"
 
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
@@ -69,6 +71,49 @@ func TestSaveConfig(t *testing.T) {
 	global.RemoveTemp("TestSaveConfig")
 }
 
+func TestSaveConfigIfMatch(t *testing.T) {
+	t.Log("Entering................. TestSaveConfigIfMatch")
+	SetConfigPath("../../test/resource/ConfigDir")
+	extensionPath, err := global.CopyToTemp("TestSaveConfigIfMatch", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestSaveConfigIfMatch")
+	state.SetExtensionsPath(extensionPath)
+	handler := http.HandlerFunc(HandleConfig)
+	content, err := ioutil.ReadFile("../../test/resource/config-test-save.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/cr/v1/config?extension-name=config-handler-test", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	etag := rr.Header().Get(global.ETagHeader)
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expecting an ETag, got %v %s", rr.Code, etag)
+	}
+
+	req, _ = http.NewRequest("POST", "/cr/v1/config?extension-name=config-handler-test", bytes.NewReader(content))
+	req.Header.Set(global.IfMatchHeader, "\"outdated\"")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+
+	req, _ = http.NewRequest("POST", "/cr/v1/config?extension-name=config-handler-test", bytes.NewReader(content))
+	req.Header.Set(global.IfMatchHeader, etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v: %v", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rr.Header().Get(global.ETagHeader) == "" {
+		t.Error("Expecting the ETag of the saved config")
+	}
+}
+
 func TestGetConfig(t *testing.T) {
 	t.Log("Entering................. TestSaveConfig")
 	//log.SetLevel(log.DebugLevel)
@@ -168,3 +213,200 @@ func TestGetConfigCustomized(t *testing.T) {
 	SetConfigRootKey(bckConfigRootKey)
 	global.RemoveTemp("TestGetConfigCustomized")
 }
+
+func TestValidateConfigNative(t *testing.T) {
+	t.Log("Entering................. TestValidateConfigNative")
+	extensionPath, err := global.CopyToTemp("TestValidateConfigNative", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestValidateConfigNative")
+	req, err := http.NewRequest("GET", "/cr/v1/config?action=validate&extension-name=config-conditions-test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(HandleConfig)
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotAcceptable {
+		t.Errorf("handler returned wrong status code: got %v want %v: %v",
+			status, http.StatusNotAcceptable, rr.Body)
+	}
+	var result Config
+	err = json.Unmarshal(rr.Body.Bytes(), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyPort, ok := result.Properties["proxy_port"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected an error on proxy_port: %v", rr.Body)
+	}
+	if proxyPort["message"] != "The proxy port must be a number" {
+		t.Errorf("Unexpected message %v", proxyPort["message"])
+	}
+	//On /cr/v2 the validation result is returned instead of an error
+	rr = httptest.NewRecorder()
+	apiError.Handler(HandleConfig).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotAcceptable {
+		t.Errorf("v2 handler returned wrong status code: got %v want %v: %v",
+			status, http.StatusNotAcceptable, rr.Body)
+	}
+	result = Config{}
+	err = json.Unmarshal(rr.Body.Bytes(), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Properties["proxy_port"].(map[string]interface{}); !ok {
+		t.Errorf("Expected the validation result on v2: %v", rr.Body)
+	}
+}
+
+func TestGetConfigOverlay(t *testing.T) {
+	t.Log("Entering................. TestGetConfigOverlay")
+	extensionPath, err := global.CopyToTemp("TestGetConfigOverlay", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestGetConfigOverlay")
+	req, err := http.NewRequest("GET", "/cr/v1/config?extension-name=config-overlay-test&overlay=prod&provenance=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(HandleConfig)
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %v",
+			status, http.StatusOK, rr.Body)
+	}
+	var result map[string]map[string]interface{}
+	err = json.Unmarshal(rr.Body.Bytes(), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(rr.Body)
+	if result["config"]["env_name"] != "prod" {
+		t.Errorf("Expected env_name prod but got %v", result["config"]["env_name"])
+	}
+	proxy := result["config"]["proxy"].(map[string]interface{})
+	if proxy["host"] != "proxy.prod" || proxy["port"] != float64(3128) {
+		t.Errorf("Expected proxy to be deep-merged but got %v", proxy)
+	}
+	if result["provenance"]["subnet"] != "config.yml" {
+		t.Errorf("Expected subnet from config.yml but got %v", result["provenance"]["subnet"])
+	}
+	proxyProvenance := result["provenance"]["proxy"].(map[string]interface{})
+	if proxyProvenance["host"] != "config.prod.yml" || proxyProvenance["port"] != "config.yml" {
+		t.Errorf("Wrong proxy provenance %v", proxyProvenance)
+	}
+}
+
+func TestValidateAndGenerateConfigScript(t *testing.T) {
+	t.Log("Entering................. TestValidateAndGenerateConfigScript")
+	extensionPath, err := global.CopyToTemp("TestValidateAndGenerateConfigScript", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestValidateAndGenerateConfigScript")
+	handler := http.HandlerFunc(HandleConfig)
+	req, err := http.NewRequest("GET", "/cr/v1/config?action=validate&extension-name=config-script-test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotAcceptable {
+		t.Errorf("handler returned wrong status code: got %v want %v: %v",
+			status, http.StatusNotAcceptable, rr.Body)
+	}
+	req, err = http.NewRequest("PUT", "/cr/v1/config?extension-name=config-script-test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %v",
+			status, http.StatusOK, rr.Body)
+	}
+	ps, err := GetProperties(state.DefaultInstance(), "config-script-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps["env_name"] != "generated" {
+		t.Errorf("Expected the generated config to be saved but got %v", ps)
+	}
+	req, err = http.NewRequest("GET", "/cr/v1/config?action=validate&extension-name=config-script-test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v: %v",
+			status, http.StatusOK, rr.Body)
+	}
+}
+
+func TestGetConfigBase(t *testing.T) {
+	t.Log("Entering................. TestGetConfigBase")
+	extensionPath, err := global.CopyToTemp("TestGetConfigBase", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestGetConfigBase")
+	bckConfigOverlay := global.ConfigOverlay
+	global.ConfigOverlay = "prod"
+	defer func() { global.ConfigOverlay = bckConfigOverlay }()
+	getEnvName := func(url string) interface{} {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(HandleConfig)
+		handler.ServeHTTP(rr, req)
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %v",
+				status, http.StatusOK, rr.Body)
+		}
+		var result map[string]map[string]interface{}
+		err = json.Unmarshal(rr.Body.Bytes(), &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result["config"]["env_name"]
+	}
+	//The config file is returned by default so it can be saved back
+	if envName := getEnvName("/cr/v1/config?extension-name=config-overlay-test"); envName != "dev" {
+		t.Errorf("Expected env_name dev but got %v", envName)
+	}
+	if envName := getEnvName("/cr/v1/config?extension-name=config-overlay-test&merged=true"); envName != "prod" {
+		t.Errorf("Expected env_name prod but got %v", envName)
+	}
+}
+
+func TestGetConfigInvalidOverlay(t *testing.T) {
+	t.Log("Entering................. TestGetConfigInvalidOverlay")
+	extensionPath, err := global.CopyToTemp("TestGetConfigInvalidOverlay", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestGetConfigInvalidOverlay")
+	req, err := http.NewRequest("GET", "/cr/v1/config?extension-name=config-overlay-test&overlay=/../../x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(HandleConfig)
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
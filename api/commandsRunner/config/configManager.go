@@ -20,11 +20,11 @@ package config
 
 import (
 	"encoding/base64"
-	"errors"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/properties"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
@@ -45,18 +45,11 @@ type Config struct {
 	Properties properties.Properties `json:"config" yaml:"config"`
 }
 
-var props properties.Properties
-
-//Initialize the properties map
-func init() {
-	props = make(properties.Properties)
-}
-
 /* Search a property in a ui config object, return an error if not found
  */
-func SearchUIConfigProperty(extensionName, uiMetaDataName string, name string) (*config.Config, error) {
+func SearchUIConfigProperty(inst *state.Instance, extensionName, uiMetaDataName string, name string) (*config.Config, error) {
 	log.Debug("Entering... searchUIConfigProperty:" + name)
-	b, err := state.GetUIMetaDataConfig(extensionName, uiMetaDataName, []string{global.DefaultLanguage})
+	b, err := inst.GetUIMetaDataConfig(extensionName, uiMetaDataName, []string{global.DefaultLanguage})
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +92,7 @@ func SearchUIConfigProperty(extensionName, uiMetaDataName string, name string) (
 			}
 		}
 	}
-	return nil, errors.New(name + " not found!")
+	return nil, apiError.NotFound(apiError.CodeNotFound, name+" not found!")
 }
 
 /*
@@ -136,9 +129,9 @@ Set the client path
 /*
 Search the configuration_name property
 */
-func GetConfigurationName(extensionName string) (string, error) {
+func GetConfigurationName(inst *state.Instance, extensionName string) (string, error) {
 	log.Debug("Entering... GetConfigurationName")
-	props, err := properties.ReadProperties(extensionName)
+	props, err := properties.ReadProperties(inst, extensionName)
 	if err != nil {
 		return "", err
 	}
@@ -150,33 +143,61 @@ func GetConfigurationName(extensionName string) (string, error) {
 Save the property map in the property file
 Reread the file afterward
 */
-func SetProperties(extensionName string, ps properties.Properties) error {
+func SetProperties(inst *state.Instance, extensionName string, ps properties.Properties) error {
 	log.Debug("Entering... SetProperties")
-	registered := state.IsExtensionRegistered(extensionName)
+	_, err := SetPropertiesWithInvalidatedStates(inst, extensionName, ps)
+	return err
+}
+
+/*
+Save the property map in the property file, reread the file afterward
+and set to READY the states depending on a changed config property.
+It returns the names of the states set to READY.
+*/
+func SetPropertiesWithInvalidatedStates(inst *state.Instance, extensionName string, ps properties.Properties) ([]string, error) {
+	return SetPropertiesIfMatch(inst, extensionName, ps, nil)
+}
+
+/*
+SetPropertiesIfMatch saves the property map like SetPropertiesWithInvalidatedStates
+if ifMatch accepts the ETag of the current config, see properties.WritePropertiesIfMatch.
+*/
+func SetPropertiesIfMatch(inst *state.Instance, extensionName string, ps properties.Properties, ifMatch func(etag string) bool) ([]string, error) {
+	log.Debug("Entering... SetPropertiesIfMatch")
+	registered := inst.IsExtensionRegistered(extensionName)
 	if !registered {
-		err := errors.New("Extension " + extensionName + "not registered yet")
+		err := apiError.NotFound(apiError.CodeExtensionNotFound, "Extension "+extensionName+"not registered yet")
 		log.Debug(err.Error())
-		return err
+		return nil, err
 	}
-	err := properties.WriteProperties(extensionName, ps)
+	err := properties.WritePropertiesIfMatch(inst, extensionName, ps, ifMatch)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	props, err = properties.ReadProperties(extensionName)
+	props, err := properties.ReadProperties(inst, extensionName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	sm, err := inst.GetStatesManager(extensionName)
+	if err != nil {
+		return nil, err
+	}
+	return sm.InvalidateStatesOnConfigChange(props)
+}
+
+//GetConfigETag returns the ETag of the extension config, it changes each time the config is written.
+func GetConfigETag(inst *state.Instance, extensionName string) (string, error) {
+	return properties.GetConfigETag(inst, extensionName)
 }
 
 /*
 Encode decode properties
 */
-func PropertiesEncodeDecode(extensionName string, uiMetadataName string, ps properties.Properties, encode bool) (properties.Properties, error) {
+func PropertiesEncodeDecode(inst *state.Instance, extensionName string, uiMetadataName string, ps properties.Properties, encode bool) (properties.Properties, error) {
 	log.Debug("Entering in... PropertiesEncodeDecode")
 	pss := make(properties.Properties)
 	for key, val := range ps {
-		uiProperty, err := SearchUIConfigProperty(extensionName, uiMetadataName, key)
+		uiProperty, err := SearchUIConfigProperty(inst, extensionName, uiMetadataName, key)
 		if err == nil {
 			s, _ := config.RenderYaml(uiProperty)
 			log.Debug("uiProperty:" + s)
@@ -221,27 +242,47 @@ func PropertyEncodeDecode(encodingType string, val string, encode bool) (string,
 Read the property file and populate the map.
 If read can not be done, the error is forwarded
 */
-func GetProperties(extensionName string) (properties.Properties, error) {
+func GetProperties(inst *state.Instance, extensionName string) (properties.Properties, error) {
 	log.Debug("Entering in... GetProperties")
 	log.Debug("extensionName:" + extensionName)
-	properties, e := properties.ReadProperties(extensionName)
+	properties, e := properties.ReadProperties(inst, extensionName)
 	if e != nil {
 		return nil, e
 	}
 	return properties, nil
 }
 
+/*
+Read the property file, merge the overlay and return the provenance of each value.
+If the overlay is empty, the overlay of the current run or the server default overlay is used
+*/
+func GetPropertiesWithProvenance(inst *state.Instance, extensionName string, overlay string) (properties.Properties, properties.Properties, error) {
+	log.Debug("Entering in... GetPropertiesWithProvenance")
+	if overlay == "" {
+		overlay = inst.GetConfigOverlay(extensionName)
+	}
+	return properties.ReadPropertiesWithProvenance(inst, extensionName, overlay)
+}
+
+/*
+GetBasePropertiesWithProvenance reads the property file without merging any overlay, all values come from the property file.
+*/
+func GetBasePropertiesWithProvenance(inst *state.Instance, extensionName string) (properties.Properties, properties.Properties, error) {
+	log.Debug("Entering in... GetBasePropertiesWithProvenance")
+	return properties.ReadPropertiesWithProvenance(inst, extensionName, "")
+}
+
 /*
 Remove a property from the map
 */
-func RemoveProperty(extensionName string, key string) error {
-	propertiesAux, err := properties.ReadProperties(extensionName)
+func RemoveProperty(inst *state.Instance, extensionName string, key string) error {
+	propertiesAux, err := properties.ReadBaseProperties(inst, extensionName)
 	if err != nil {
 		return err
 	}
 	delete(propertiesAux, key)
-	SetProperties(extensionName, propertiesAux)
-	propertiesAux, err = properties.ReadProperties(extensionName)
+	SetProperties(inst, extensionName, propertiesAux)
+	propertiesAux, err = properties.ReadProperties(inst, extensionName)
 	if err != nil {
 		return err
 	}
@@ -251,10 +292,10 @@ func RemoveProperty(extensionName string, key string) error {
 /*
 Search for a given property
 */
-func FindProperty(extensionName string, key string) (properties.Properties, error) {
+func FindProperty(inst *state.Instance, extensionName string, key string) (properties.Properties, error) {
 	var pss properties.Properties
 	pss = make(properties.Properties)
-	properties, err := properties.ReadProperties(extensionName)
+	properties, err := properties.ReadProperties(inst, extensionName)
 	if err != nil {
 		return nil, err
 	}
@@ -263,20 +304,19 @@ func FindProperty(extensionName string, key string) (properties.Properties, erro
 		pss["value"] = p
 		return pss, nil
 	}
-	err = errors.New("Property " + key + " not found")
+	err = apiError.NotFound(apiError.CodeNotFound, "Property "+key+" not found")
 	return nil, err
 }
 
 /*
 Add a property
 */
-func AddProperty(extensionName string, key string, value interface{}) error {
-	var err error
-	props, err = properties.ReadProperties(extensionName)
+func AddProperty(inst *state.Instance, extensionName string, key string, value interface{}) error {
+	props, err := properties.ReadBaseProperties(inst, extensionName)
 	if err != nil {
 		return err
 	}
 	props[key] = value
-	SetProperties(extensionName, props)
+	SetProperties(inst, extensionName, props)
 	return nil
 }
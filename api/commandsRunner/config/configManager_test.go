@@ -19,10 +19,12 @@ package config
 
 import (
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/properties"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
@@ -53,7 +55,7 @@ func TestSetConfigPath(t *testing.T) {
 
 func TestSetProperties(t *testing.T) {
 	t.Log("Entering... TestSetproperties.Properties")
-	props = make(properties.Properties)
+	props := make(properties.Properties)
 	global.ConfigDirectory = "../../test/resource"
 	extensionPath, err := global.CopyToTemp("TestSetProperties", "../../test/resource/extensions/")
 	if err != nil {
@@ -65,7 +67,7 @@ func TestSetProperties(t *testing.T) {
 	props["Prop3"] = "Val3"
 	props["Prop4"] = "Val4"
 	props["subnet"] = "192.168.100.0/24"
-	err = SetProperties("config-manager-test", props)
+	err = SetProperties(state.DefaultInstance(), "config-manager-test", props)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -89,7 +91,7 @@ func TestGetProperties(t *testing.T) {
 	}
 	SetConfigPath(global.ConfigDirectory)
 	//t.Log(properties)
-	propertiesAux, err := GetProperties("config-manager-test")
+	propertiesAux, err := GetProperties(state.DefaultInstance(), "config-manager-test")
 	t.Logf("%s\n", propertiesAux)
 	if err != nil {
 		t.Error(err.Error())
@@ -121,7 +123,7 @@ func TestFindProperty(t *testing.T) {
 		t.Error("Can not create temp file")
 	}
 	SetConfigPath(global.ConfigDirectory)
-	p, err := FindProperty("config-manager-test", "env_name")
+	p, err := FindProperty(state.DefaultInstance(), "config-manager-test", "env_name")
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -136,7 +138,7 @@ func TestFindProperty(t *testing.T) {
 	} else {
 		t.Error("Not a string")
 	}
-	p, err = FindProperty("config-manager-test", "Prop3")
+	p, err = FindProperty(state.DefaultInstance(), "config-manager-test", "Prop3")
 	if err == nil {
 		t.Error("Expected not found and found")
 	}
@@ -158,13 +160,146 @@ func TestRemoveProperty(t *testing.T) {
 		t.Error("Can not create temp file")
 	}
 	SetConfigPath(global.ConfigDirectory)
-	err = RemoveProperty("config-manager-test", "Prop1")
+	err = RemoveProperty(state.DefaultInstance(), "config-manager-test", "Prop1")
 	if err != nil {
 		t.Error(err.Error())
 	}
-	p, err := FindProperty("config-manager-test", "Prop1")
+	p, err := FindProperty(state.DefaultInstance(), "config-manager-test", "Prop1")
 	if p != nil {
 		t.Error("Expected not found and found")
 	}
 	global.RemoveTemp("TestRemoveProperty")
 }
+
+func TestValidateProperties(t *testing.T) {
+	t.Log("Entering... TestValidateProperties")
+	extensionPath, err := global.CopyToTemp("TestValidateProperties", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestValidateProperties")
+	ps, err := GetProperties(state.DefaultInstance(), "config-conditions-test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ps, valid, err := ValidateProperties(state.DefaultInstance(), "config-conditions-test", "default", ps)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if valid {
+		t.Error("Expected the properties to be invalid")
+	}
+	//required_if takes precedence on mandatory false as in the template
+	for _, name := range []string{"proxy_host", "proxy_port", "proxy_user"} {
+		if _, ok := ps[name].(properties.Properties); !ok {
+			t.Errorf("Expected an error on %s but got %v", name, ps[name])
+		}
+	}
+	if _, ok := ps["subnet"].(string); !ok {
+		t.Errorf("Expected no error on subnet but got %v", ps["subnet"])
+	}
+	ps = properties.Properties{
+		"proxy_enabled": false,
+		"proxy_port":    "port",
+		"subnet":        "192.168.100.0/24",
+	}
+	_, valid, err = ValidateProperties(state.DefaultInstance(), "config-conditions-test", "default", ps)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !valid {
+		t.Errorf("Expected the properties to be valid: %v", ps)
+	}
+	ps = properties.Properties{
+		"proxy_enabled": false,
+	}
+	_, valid, err = ValidateProperties(state.DefaultInstance(), "config-conditions-test", "default", ps)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if valid {
+		t.Error("Expected an error as subnet is mandatory")
+	}
+}
+
+func TestAddPropertyWithOverlay(t *testing.T) {
+	t.Log("Entering... TestAddPropertyWithOverlay")
+	extensionPath, err := global.CopyToTemp("TestAddPropertyWithOverlay", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestAddPropertyWithOverlay")
+	bckConfigOverlay := global.ConfigOverlay
+	global.ConfigOverlay = "prod"
+	defer func() { global.ConfigOverlay = bckConfigOverlay }()
+	ps, err := GetProperties(state.DefaultInstance(), "config-overlay-test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if ps["env_name"] != "prod" {
+		t.Errorf("Expected the server overlay to be merged but got %v", ps["env_name"])
+	}
+	err = AddProperty(state.DefaultInstance(), "config-overlay-test", "new_property", "new_value")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ps, err = properties.ReadBaseProperties(state.DefaultInstance(), "config-overlay-test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if ps["env_name"] != "dev" || ps["new_property"] != "new_value" {
+		t.Errorf("The overlay must not be written in the config file: %v", ps)
+	}
+}
+
+func TestSetPropertiesIfMatch(t *testing.T) {
+	t.Log("Entering... TestSetPropertiesIfMatch")
+	global.ConfigDirectory = "../../test/resource"
+	extensionPath, err := global.CopyToTemp("TestSetPropertiesIfMatch", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestSetPropertiesIfMatch")
+	state.SetExtensionsPath(extensionPath)
+	inst := state.DefaultInstance()
+	props := make(properties.Properties)
+	props["Prop3"] = "Val3"
+	err = SetProperties(inst, "config-manager-test", props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag, err := GetConfigETag(inst, "config-manager-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The ETag is checked before the write
+	props["Prop3"] = "Val4"
+	_, err = SetPropertiesIfMatch(inst, "config-manager-test", props, func(current string) bool {
+		return current == "\"outdated\""
+	})
+	if e, ok := err.(*apiError.Error); !ok || e.Status != http.StatusPreconditionFailed {
+		t.Fatalf("Expecting a precondition failed error, got %v", err)
+	}
+	current, err := GetConfigETag(inst, "config-manager-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != etag {
+		t.Error("Expecting the config to be unchanged")
+	}
+	_, err = SetPropertiesIfMatch(inst, "config-manager-test", props, func(current string) bool {
+		return current == etag
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err = GetConfigETag(inst, "config-manager-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current == etag {
+		t.Error("Expecting a new ETag")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/properties"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+
+	"github.com/olebedev/config"
+)
+
+//ConfigScriptTimeout maximum duration of a validation or generation config script
+var ConfigScriptTimeout = 5 * time.Minute
+
+//MessageTypeWarning is the message_type set on a property raising a warning
+const MessageTypeWarning = "warning"
+
+//StatusWarning is the http status returned when the validation raises only warnings
+const StatusWarning = 299
+
+/*
+RunConfigScript runs a script in the extension directory.
+The current config is sent on stdin as {"config": {...}} and the stdout is parsed as json or yaml.
+The properties under the config root key are returned and false if the script exited with an error.
+*/
+func RunConfigScript(inst *state.Instance, extension *state.Extension, extensionName string, script string) (properties.Properties, bool, error) {
+	log.Debug("Entering in... RunConfigScript")
+	log.Debug("script: " + script)
+	ps, err := GetProperties(inst, extensionName)
+	if err != nil {
+		return nil, false, err
+	}
+	input, err := json.Marshal(&Config{Properties: ps})
+	if err != nil {
+		return nil, false, err
+	}
+	parts := strings.Fields(script)
+	if len(parts) == 0 {
+		return nil, false, errors.New("Empty config script for " + extensionName)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ConfigScriptTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = extension.ExtensionPath
+	cmd.Env = append(os.Environ(), "CR_CONFIG_OVERLAY="+inst.GetConfigOverlay(extensionName))
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	errRun := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, false, errors.New("Config script " + script + " killed as timeout reached")
+	}
+	log.Debug("stdout: " + stdout.String())
+	log.Debug("stderr: " + stderr.String())
+	var cfg *config.Config
+	cfg, err = config.ParseJson(stdout.String())
+	if err != nil {
+		cfg, err = config.ParseYaml(stdout.String())
+	}
+	if err != nil || stdout.Len() == 0 {
+		if errRun != nil {
+			return nil, false, errors.New("Config script " + script + " failed: " + errRun.Error() + "\n" + stderr.String())
+		}
+		return nil, false, errors.New("Unable to parse the output of " + script + ": " + stdout.String())
+	}
+	result, err := cfg.Map(global.ConfigRootKey)
+	if err != nil {
+		return nil, false, errors.New("Unable to find " + global.ConfigRootKey + " in the output of " + script + ": " + err.Error())
+	}
+	if errRun != nil {
+		log.Warning("Config script " + script + " failed: " + errRun.Error() + "\n" + stderr.String())
+		return result, false, nil
+	}
+	return result, true, nil
+}
+
+//GetValidationStatus returns 406 if at least one property has an error, 299 if only warnings and 200 otherwise.
+func GetValidationStatus(ps properties.Properties) int {
+	status := http.StatusOK
+	for _, value := range ps {
+		property, ok := value.(map[string]interface{})
+		if !ok {
+			if p, okP := value.(properties.Properties); okP {
+				property = p
+			} else {
+				continue
+			}
+		}
+		switch property["message_type"] {
+		case MessageTypeError:
+			return http.StatusNotAcceptable
+		case MessageTypeWarning:
+			status = StatusWarning
+		}
+	}
+	return status
+}
@@ -0,0 +1,173 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/properties"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+
+	"github.com/olebedev/config"
+)
+
+//MessageTypeError is the message_type set on a property failing the validation
+const MessageTypeError = "error"
+
+/*
+ValidateProperties validates the properties against the ui metadata of the extension.
+The mandatory, required_if, visible_if and validation_regex attributes are checked.
+Invalid properties are replaced by a map containing the value, message_type and message.
+It returns the properties and true if valid.
+*/
+func ValidateProperties(inst *state.Instance, extensionName string, uiMetadataName string, ps properties.Properties) (properties.Properties, bool, error) {
+	log.Debug("Entering in... ValidateProperties")
+	b, err := inst.GetUIMetaDataConfig(extensionName, uiMetadataName, []string{global.DefaultLanguage})
+	if err != nil {
+		return nil, false, err
+	}
+	uiMetadata, err := config.ParseJson(string(b))
+	if err != nil {
+		return nil, false, err
+	}
+	groups, err := uiMetadata.List("ui_metadata.groups")
+	if err != nil {
+		return nil, false, err
+	}
+	valid := true
+	for _, group := range groups {
+		groupMap, ok := group.(map[string]interface{})
+		if !ok {
+			return nil, false, errors.New("Expect a map[string]interface{} under groups")
+		}
+		groupProperties, ok := groupMap["properties"]
+		if !ok {
+			continue
+		}
+		propertiesList, ok := groupProperties.([]interface{})
+		if !ok {
+			return nil, false, errors.New("Expect a []interface{} under properties")
+		}
+		mandatory := true
+		if val, ok := groupMap["mandatory"].(bool); ok {
+			mandatory = val
+		}
+		groupValid, err := validateProperties(propertiesList, mandatory, ps, ps)
+		if err != nil {
+			return nil, false, err
+		}
+		valid = valid && groupValid
+	}
+	return ps, valid, nil
+}
+
+//validateProperties validates the values of an object, the conditions are evaluated on the object first and then on the root properties.
+func validateProperties(uiProperties []interface{}, mandatory bool, values properties.Properties, root properties.Properties) (bool, error) {
+	valid := true
+	for _, uiProperty := range uiProperties {
+		p, ok := uiProperty.(map[string]interface{})
+		if !ok {
+			return false, errors.New("Expect a map[string]interface{} under properties")
+		}
+		name, ok := p["name"].(string)
+		if !ok {
+			return false, errors.New("Property name missing")
+		}
+		visible, err := state.IsPropertyVisible(p, values, root)
+		if err != nil {
+			return false, err
+		}
+		if !visible {
+			log.Debug(name + " not visible, skip validation")
+			continue
+		}
+		required, err := state.IsPropertyMandatory(p, mandatory, values, root)
+		if err != nil {
+			return false, err
+		}
+		value, ok := values[name]
+		if !ok || value == nil || value == "" {
+			if required {
+				message := name + " is mandatory"
+				if condition, ok := p[state.RequiredIfKey]; ok {
+					message = fmt.Sprintf("%s is mandatory when %s", name, condition)
+				}
+				values[name] = properties.AddError(values, name, MessageTypeError, message)
+				valid = false
+			}
+			continue
+		}
+		if subProperties, ok := p["properties"].([]interface{}); ok {
+			subValid, err := validateSubProperties(subProperties, value, root)
+			if err != nil {
+				return false, err
+			}
+			valid = valid && subValid
+			continue
+		}
+		validationRegex, ok := p["validation_regex"].(string)
+		if !ok {
+			validationRegex, ok = p["validation-regex"].(string)
+		}
+		if ok {
+			matched, err := regexp.MatchString(validationRegex, fmt.Sprint(value))
+			if err != nil {
+				return false, errors.New("Invalid validation regex for " + name + ": " + err.Error())
+			}
+			if !matched {
+				message, ok := p["validation_error_message"].(string)
+				if !ok {
+					message = name + " doesn't match " + validationRegex
+				}
+				values[name] = properties.AddError(values, name, MessageTypeError, message)
+				valid = false
+			}
+		}
+	}
+	return valid, nil
+}
+
+//validateSubProperties validates an object or each object of an array
+func validateSubProperties(uiProperties []interface{}, value interface{}, root properties.Properties) (bool, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateProperties(uiProperties, true, properties.Properties(v), root)
+	case properties.Properties:
+		return validateProperties(uiProperties, true, v, root)
+	case []interface{}:
+		valid := true
+		for _, element := range v {
+			elementMap, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			elementValid, err := validateProperties(uiProperties, true, properties.Properties(elementMap), root)
+			if err != nil {
+				return false, err
+			}
+			valid = valid && elementValid
+		}
+		return valid, nil
+	}
+	return true, nil
+}
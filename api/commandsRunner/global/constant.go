@@ -47,15 +47,33 @@ const DefaultUrl = DefaultProtocol + "://" + DefaultHost + ":" + DefaultPort
 //BaseURL base url api
 const BaseURL = "/cr/v1/"
 
+//BaseURLV2 base url of the v2 api, same endpoints as v1 but with json errors
+const BaseURLV2 = "/cr/v2/"
+
+//ETagHeader the header returned with the revision of the states and the config
+const ETagHeader = "ETag"
+
+//IfMatchHeader the header containing the revision expected by an update, the update fails with a 412 if it doesn't match
+const IfMatchHeader = "If-Match"
+
 //CommandsRunnerLogFileName the logFile for CR
 const CommandsRunnerLogFileName = "commands-runner.log"
 
+//AuditLogFileName the audit file recording the mutating api calls
+const AuditLogFileName = "cr-audit.log"
+
 //DefaultOutputFormat default output format
 const DefaultOutputFormat = "text"
 
 //DefaultTimeout default timeout for http request
 const DefaultTimeout = 180
 
+//DefaultShutdownTimeout default time in seconds the server waits for the running states when it stops
+const DefaultShutdownTimeout = 60
+
+//DefaultUnixSocketMode default permissions of the unix socket, only the user running the server can access it
+const DefaultUnixSocketMode = 0600
+
 //DefaultInsecureSSL by defualt the request are secured.
 const DefaultInsecureSSL = false
 
@@ -68,12 +86,21 @@ const SSLKeyFileName = "cr-key.pem"
 //TokenFileName token file name
 const TokenFileName = "cr-token"
 
+//TokensFileName token store file name
+const TokensFileName = "cr-tokens.yml"
+
+//WebhooksFileName webhook store file name
+const WebhooksFileName = "cr-webhooks.yml"
+
 //DefaultUIMetaDataName default ui metadata attribute
 const DefaultUIMetaDataName = "default"
 
 //StatesFileName the states file name to use accross the different packages
 const StatesFileName = "states-file.yml"
 
+//RunsFileName the run history file of an extension, one json run per line
+const RunsFileName = "runs.jsonl"
+
 //DefaultLanguage
 const DefaultLanguage = "en-US"
 
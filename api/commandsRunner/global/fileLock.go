@@ -0,0 +1,183 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package global
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//LockFileSuffix the suffix of the sidecar lock file of a locked file
+const LockFileSuffix = ".lock"
+
+//FileLockTimeout how long LockFile waits for the lock
+var FileLockTimeout = 30 * time.Second
+
+//fileLock the advisory lock of a file, shared by the goroutines of the process
+type fileLock struct {
+	mux   sync.Mutex
+	file  *os.File
+	count int
+	//owner serializes the goroutines of LockFileExclusive
+	owner sync.Mutex
+}
+
+var fileLocks = make(map[string]*fileLock)
+var fileLocksMux sync.Mutex
+
+func getFileLock(lockPath string) *fileLock {
+	fileLocksMux.Lock()
+	defer fileLocksMux.Unlock()
+	fl, ok := fileLocks[lockPath]
+	if !ok {
+		fl = &fileLock{}
+		fileLocks[lockPath] = fl
+	}
+	return fl
+}
+
+//LockFile takes an advisory lock on the sidecar lock file of path (path+".lock") to serialize its updates between processes.
+//The directory of path must exist.
+//The lock is owned by the process and not by the goroutine: it is reentrant for any goroutine of the process,
+//the goroutines must be serialized by the caller with an in-process mutex or by using LockFileExclusive.
+//If the lock can not be taken within FileLockTimeout an error reporting the owner of the lock is returned.
+//It returns the function releasing the lock.
+func LockFile(path string) (func(), error) {
+	log.Debug("Entering in... LockFile")
+	lockPath := path + LockFileSuffix
+	fl := getFileLock(lockPath)
+	fl.mux.Lock()
+	defer fl.mux.Unlock()
+	if fl.count == 0 {
+		file, err := acquireFileLock(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		fl.file = file
+	}
+	fl.count++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			fl.mux.Lock()
+			defer fl.mux.Unlock()
+			fl.count--
+			if fl.count == 0 {
+				releaseFileLock(fl.file)
+				fl.file = nil
+			}
+		})
+	}, nil
+}
+
+//LockFileExclusive takes the lock of LockFile and serializes the goroutines of the process too, it is not reentrant.
+//The LockFile calls of its owner are not blocked.
+//It returns the function releasing the lock.
+func LockFileExclusive(path string) (func(), error) {
+	log.Debug("Entering in... LockFileExclusive")
+	fl := getFileLock(path + LockFileSuffix)
+	fl.owner.Lock()
+	unlockFile, err := LockFile(path)
+	if err != nil {
+		fl.owner.Unlock()
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			unlockFile()
+			fl.owner.Unlock()
+		})
+	}, nil
+}
+
+//Write the owner of the lock in the lock file, reported to the processes waiting for the lock
+func writeLockOwner(file *os.File) {
+	hostname, _ := os.Hostname()
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())+" "+hostname+"\n"), 0)
+}
+
+//Read the pid and hostname of the owner of a lock file, pid is 0 if unknown
+func readLockOwner(lockPath string) (int, string) {
+	content, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		return 0, ""
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 {
+		return 0, ""
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, ""
+	}
+	return pid, fields[1]
+}
+
+//Describe the owner of a lock file, ie: process 1234 on host1
+func lockOwner(lockPath string) string {
+	pid, hostname := readLockOwner(lockPath)
+	if pid == 0 {
+		return "another process"
+	}
+	return "process " + strconv.Itoa(pid) + " on " + hostname
+}
+
+//WriteFileAtomic writes a file through a temporary file which is synced and renamed,
+//a crash during the write leaves the previous content intact. The mode of an existing file is preserved.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	log.Debug("Entering in... WriteFileAtomic")
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmpPath := path + ".tmp" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.New("Unable to write " + path + ": " + err.Error())
+	}
+	//Persist the rename
+	if dir, errDir := os.Open(filepath.Dir(path)); errDir == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package global
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+//Take the flock of a lock file, waiting up to FileLockTimeout
+func acquireFileLock(lockPath string) (*os.File, error) {
+	deadline := time.Now().Add(FileLockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			writeLockOwner(file)
+			return file, nil
+		}
+		file.Close()
+		if err != syscall.EWOULDBLOCK {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("Unable to lock " + lockPath + ", it is locked by " + lockOwner(lockPath))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+//Release the flock of a lock file
+func releaseFileLock(file *os.File) {
+	if file == nil {
+		return
+	}
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	file.Close()
+}
@@ -0,0 +1,136 @@
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package global
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//Hold the lock of path from another file descriptor, like another process would do
+func holdLock(t *testing.T, path string, pid int) *os.File {
+	file, err := os.OpenFile(path+LockFileSuffix, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.Hostname()
+	file.WriteString(strconv.Itoa(pid) + " " + hostname + "\n")
+	return file
+}
+
+func TestLockFile(t *testing.T) {
+	t.Log("Entering... TestLockFile")
+	dir, err := ioutil.TempDir("", "TestLockFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "states-file.yml")
+	defer func(timeout time.Duration) { FileLockTimeout = timeout }(FileLockTimeout)
+	FileLockTimeout = 200 * time.Millisecond
+
+	//Reentrant inside the process
+	unlock, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockAgain, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockAgain()
+	unlock()
+	//The lock file is only accessible by the owner of the process
+	info, err := os.Stat(path + LockFileSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the lock file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	//Locked by another process, its pid is reported
+	holder := holdLock(t, path, 1234)
+	_, err = LockFile(path)
+	if err == nil {
+		t.Fatal("Expecting an error when the lock is owned by another process")
+	}
+	if !strings.Contains(err.Error(), "process 1234 on ") {
+		t.Error("Expecting the owner pid in the error, got: " + err.Error())
+	}
+	//The lock file is kept
+	if _, errStat := os.Stat(path + LockFileSuffix); errStat != nil {
+		t.Error(errStat)
+	}
+	holder.Close()
+	unlock, err = LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+func TestLockFileExclusive(t *testing.T) {
+	t.Log("Entering... TestLockFileExclusive")
+	dir, err := ioutil.TempDir("", "TestLockFileExclusive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	unlock, err := LockFileExclusive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The LockFile calls of the owner are not blocked
+	unlockFile, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockFile()
+	//The other goroutines wait for the owner
+	locked := make(chan func(), 1)
+	go func() {
+		unlockOther, _ := LockFileExclusive(path)
+		locked <- unlockOther
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Expecting the goroutine to wait for the lock")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case unlockOther := <-locked:
+		unlockOther()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expecting the goroutine to get the lock")
+	}
+}
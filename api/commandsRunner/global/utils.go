@@ -18,6 +18,8 @@
 package global
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -27,6 +29,8 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -114,6 +118,38 @@ func GetExecutableDir() (string, error) {
 	return launchingDir, nil
 }
 
+//ToV1Path returns the v1 path of a v2 path, other paths are returned unchanged
+func ToV1Path(path string) string {
+	if strings.HasPrefix(path, BaseURLV2) {
+		return BaseURL + strings.TrimPrefix(path, BaseURLV2)
+	}
+	return path
+}
+
+//ETag returns the ETag of a content, the sha256 of the content.
+func ETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "\"" + hex.EncodeToString(sum[:]) + "\""
+}
+
+//IfMatch returns true if the request has no If-Match header or if the header contains the etag or "*" and the resource exists.
+func IfMatch(req *http.Request, etag string) bool {
+	ifMatch := req.Header.Get(IfMatchHeader)
+	if ifMatch == "" {
+		return true
+	}
+	for _, value := range strings.Split(ifMatch, ",") {
+		value = strings.TrimSpace(value)
+		if (value == "*" && etag != "") || (value == etag && etag != "") {
+			return true
+		}
+	}
+	return false
+}
+
+//ErrExtensionNameNotFound returned when the extension-name parameter is missing
+var ErrExtensionNameNotFound = errors.New("extension-name not found in request")
+
 //getExtensionName from request
 func GetExtensionNameFromRequest(req *http.Request) (string, url.Values, error) {
 	log.Debug("Entering in GetExtensionNameFromRequest")
@@ -128,7 +164,7 @@ func GetExtensionNameFromRequest(req *http.Request) (string, url.Values, error)
 		log.Debugf("ExtensionName:%s", extensionNameFound)
 		extensionName = extensionNameFound[0]
 	} else {
-		return "", m, errors.New("extension-name not found in request")
+		return "", m, ErrExtensionNameNotFound
 	}
 	return extensionName, m, nil
 }
@@ -259,3 +295,20 @@ func RemoveTemp(fileName string) error {
 // 	log.Debug("Available:", avail)
 // 	return size, free, avail
 // }
+
+//configOverlayRegexp the overlay names allowed, an overlay name is part of a file name
+var configOverlayRegexp = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+//ValidateConfigOverlay returns an error if the overlay name is not made of letters, digits, '_' and '-'
+func ValidateConfigOverlay(overlay string) error {
+	if !configOverlayRegexp.MatchString(overlay) {
+		return errors.New("Invalid config overlay " + overlay + ", only letters, digits, '_' and '-' are allowed")
+	}
+	return nil
+}
+
+//GetConfigOverlayFileName returns the config file name of an overlay, ie: config.prod.yml for the overlay prod
+func GetConfigOverlayFileName(overlay string) string {
+	ext := filepath.Ext(ConfigYamlFileName)
+	return strings.TrimSuffix(ConfigYamlFileName, ext) + "." + overlay + ext
+}
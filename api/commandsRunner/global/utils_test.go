@@ -18,6 +18,10 @@
 package global
 
 import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	yaml "gopkg.in/yaml.v2"
@@ -56,3 +60,72 @@ func TestExtractKey(t *testing.T) {
 // 	RemoveTemp("TestCopyTemp/test")
 // 	t.Error("Caller:" + caller)
 // }
+
+func TestIfMatch(t *testing.T) {
+	t.Log("Entering... TestIfMatch")
+	tests := []struct {
+		ifMatch  string
+		etag     string
+		expected bool
+	}{
+		{"", "\"a\"", true},
+		{"\"a\"", "\"a\"", true},
+		{"\"b\", \"a\"", "\"a\"", true},
+		{"\"b\"", "\"a\"", false},
+		{"*", "\"a\"", true},
+		{"*", "", false},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("PUT", "/cr/v1/states", nil)
+		if test.ifMatch != "" {
+			req.Header.Set(IfMatchHeader, test.ifMatch)
+		}
+		if got := IfMatch(req, test.etag); got != test.expected {
+			t.Errorf("IfMatch(%s, %s) returned %t, expected %t", test.ifMatch, test.etag, got, test.expected)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Log("Entering... TestWriteFileAtomic")
+	dir, err := ioutil.TempDir("", "TestWriteFileAtomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	err = ioutil.WriteFile(path, []byte("old"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFileAtomic(path, []byte("new"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(path)
+	if string(content) != "new" {
+		t.Errorf("Got %s, expected new", content)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("The mode %v is not preserved", info.Mode().Perm())
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("The temporary file is not removed: %v", files)
+	}
+}
+
+func TestValidateConfigOverlay(t *testing.T) {
+	t.Log("Entering... TestValidateConfigOverlay")
+	for _, overlay := range []string{"prod", "pre-prod", "dev_2"} {
+		if err := ValidateConfigOverlay(overlay); err != nil {
+			t.Errorf("Expected %s to be valid: %s", overlay, err.Error())
+		}
+	}
+	for _, overlay := range []string{"", "/../../x", "../prod", "prod.yml", "prod/x"} {
+		if err := ValidateConfigOverlay(overlay); err == nil {
+			t.Errorf("Expected %s to be invalid", overlay)
+		}
+	}
+}
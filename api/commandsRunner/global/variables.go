@@ -17,12 +17,17 @@
 */
 package global
 
+import "os"
+
 //ConfigDirectory directory where the config file can be found
 var ConfigDirectory string
 
 //ConfigYamlFileName default file name for the config file.
 var ConfigYamlFileName = "config.yml"
 
+//ConfigOverlay default config overlay merged on top of the config file, ie: "prod" for config.prod.yml
+var ConfigOverlay string
+
 //ConfigRootKey default root key for the yaml config file.
 var ConfigRootKey = "config"
 
@@ -50,8 +55,32 @@ var ServerCertificatePath string
 //Server Key Path
 var ServerKeyPath string
 
+//ShutdownTimeout time in seconds the server waits for the running states when it stops before cancelling them
+var ShutdownTimeout = DefaultShutdownTimeout
+
+//MetricsPort if set the metrics are also served without authentication on that port
+var MetricsPort string
+
+//ClientCAPath CA bundle used to verify the client certificates (mTLS), mTLS is disabled if empty
+var ClientCAPath string
+
 //About URL
 var AboutURL string
 
 //Default About
 var About string
+
+//UnixSocketPath if set the server also listens on that unix socket
+var UnixSocketPath string
+
+//UnixSocketMode permissions of the unix socket, they control who can access the server through it
+var UnixSocketMode os.FileMode = DefaultUnixSocketMode
+
+//TCPDisabled if true the http and https ports are not opened, the server is only reachable through the unix socket
+var TCPDisabled = false
+
+//StorageBackend storage of the states and the config, "file" (default) or "bolt"
+var StorageBackend = "file"
+
+//StoragePath path of the bolt database, default <configDir>/commands-runner.db
+var StoragePath string
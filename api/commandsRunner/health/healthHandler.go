@@ -0,0 +1,60 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+/*
+Check the health of the server, returns 503 if a check failed
+URL: /healthz
+Method: GET
+*/
+func HandleHealthz(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleHealthz")
+	writeReport(w, req, HealthChecks)
+}
+
+/*
+Check if the server is ready to serve requests, returns 503 if a check failed
+URL: /readyz
+Method: GET
+*/
+func HandleReadyz(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleReadyz")
+	writeReport(w, req, append(append(make([]Check, 0), HealthChecks...), ReadinessChecks...))
+}
+
+func writeReport(w http.ResponseWriter, req *http.Request, checks []Check) {
+	if req.Method != "GET" {
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+		return
+	}
+	report := RunChecks(state.GetInstance(req), checks)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
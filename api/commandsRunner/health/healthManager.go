@@ -0,0 +1,128 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package health
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+	"github.com/IBM/commands-runner/api/commandsRunner/status"
+	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
+)
+
+//Check results
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+//StatusUp the cr_status value once the listeners are started
+const StatusUp = "Up"
+
+//Check a named verification of an instance
+type Check struct {
+	Name  string
+	Check func(inst *state.Instance) error
+}
+
+//CheckResult the result of a check
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+//Report the result of all checks, status is failed if one check failed
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+//HealthChecks the checks run by /healthz
+var HealthChecks = []Check{
+	{Name: "config_dir", Check: checkConfigDir},
+	{Name: "states_files", Check: (*state.Instance).CheckStatesFiles},
+	{Name: "i18n", Check: checkI18n},
+	{Name: "embedded_extensions", Check: (*state.Instance).CheckEmbeddedExtensionsRegistered},
+}
+
+//ReadinessChecks the checks run by /readyz in addition of the HealthChecks
+var ReadinessChecks = []Check{
+	{Name: "server", Check: checkServer},
+}
+
+//RunChecks runs the checks on the instance and returns the report
+func RunChecks(inst *state.Instance, checks []Check) Report {
+	log.Debug("Entering in... RunChecks")
+	report := Report{
+		Status: StatusOK,
+		Checks: make([]CheckResult, 0, len(checks)),
+	}
+	for _, c := range checks {
+		result := CheckResult{Name: c.Name, Status: StatusOK}
+		err := c.Check(inst)
+		if err != nil {
+			log.Warning("Check " + c.Name + " failed: " + err.Error())
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			report.Status = StatusFailed
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+//checkConfigDir checks that a file can be created in the config directory of the instance
+func checkConfigDir(inst *state.Instance) error {
+	if inst.GetConfigDir() == "" {
+		return errors.New("Config directory not set")
+	}
+	f, err := ioutil.TempFile(inst.GetConfigDir(), ".cr-health-")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+//checkI18n checks that the translation files are loaded
+func checkI18n(inst *state.Instance) error {
+	if i18nUtils.Bundle == nil {
+		return errors.New("i18n files not loaded")
+	}
+	if len(i18nUtils.Bundle.LanguageTags()) == 0 {
+		return errors.New("No i18n translation loaded")
+	}
+	return nil
+}
+
+//checkServer checks that the listeners are started and the server is not shutting down
+func checkServer(inst *state.Instance) error {
+	if inst.IsShuttingDown() {
+		return state.ErrShuttingDown
+	}
+	s, ok := status.GetStatus(status.CMStatus)
+	if !ok || s.Status != StatusUp {
+		return errors.New("Server status: " + s.Status)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+	"github.com/IBM/commands-runner/api/commandsRunner/status"
+)
+
+func TestRunChecks(t *testing.T) {
+	t.Log("Entering... TestRunChecks")
+	dir, err := ioutil.TempDir("", "TestRunChecks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	inst := state.NewInstance()
+	inst.SetConfigDir(dir)
+	report := RunChecks(inst, []Check{
+		{Name: "config_dir", Check: checkConfigDir},
+		{Name: "failing", Check: func(inst *state.Instance) error { return errors.New("check failed") }},
+	})
+	if report.Status != StatusFailed || len(report.Checks) != 2 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if report.Checks[0].Status != StatusOK {
+		t.Errorf("Expected config_dir ok, got %+v", report.Checks[0])
+	}
+	if report.Checks[1].Status != StatusFailed || report.Checks[1].Error != "check failed" {
+		t.Errorf("Expected failing check, got %+v", report.Checks[1])
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Error("The config_dir check left a file")
+	}
+	if checkConfigDir(state.NewInstance()) == nil {
+		t.Error("Expected an error when the config dir is not set")
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	t.Log("Entering... TestHandleReadyz")
+	healthChecks := HealthChecks
+	defer func() { HealthChecks = healthChecks }()
+	HealthChecks = []Check{}
+	defer status.SetStatus(status.CMStatus, "Initialization")
+	status.SetStatus(status.CMStatus, "Initialization")
+	w := httptest.NewRecorder()
+	HandleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	var report Report
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Checks) != 1 || report.Checks[0].Name != "server" || report.Checks[0].Status != StatusFailed {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	status.SetStatus(status.CMStatus, StatusUp)
+	w = httptest.NewRecorder()
+	HandleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	HandleHealthz(w, httptest.NewRequest("POST", "/healthz", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
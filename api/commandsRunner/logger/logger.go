@@ -19,6 +19,8 @@
 package logger
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"runtime"
@@ -35,6 +37,12 @@ const DefaultLogLevel = "info"
 //Default maximum backups for log
 const DefaultLogMaxBackup = "10"
 
+//Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 //Log writer
 var LogFile *lumberjack.Logger
 
@@ -57,3 +65,36 @@ func InitLogFile(configDir string, maxBackups int) {
 		// Compress:   true, // disabled by default
 	}
 }
+
+//SetLogMaxBackups sets the number of rotated log files kept, the log file of the process is kept in its directory
+func SetLogMaxBackups(maxBackups int) {
+	if LogFile == nil {
+		return
+	}
+	LogFile.MaxBackups = maxBackups
+}
+
+//SetLogFormat sets the format of the log entries, text or json
+func SetLogFormat(format string) error {
+	switch format {
+	case LogFormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	case LogFormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return errors.New("Invalid log format: " + format + ", expected " + LogFormatText + " or " + LogFormatJSON)
+	}
+	return nil
+}
+
+//NewID returns a random UUID (version 4), used to correlate the log entries of a run or a request
+func NewID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		logrus.Error(err.Error())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,115 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//RequestIDHeader the header returning the request id, a client can provide its own
+const RequestIDHeader = "X-Request-ID"
+
+//maxLoggedErrorSize the maximum size of an error response body written in the log
+const maxLoggedErrorSize = 1024
+
+type requestIDKey struct{}
+
+//validRequestID a request id provided by the client is kept only if it matches
+var validRequestID = regexp.MustCompile(`^[\w.-]{1,128}$`)
+
+//requestWriter records the status and the beginning of the error responses
+type requestWriter struct {
+	http.ResponseWriter
+	status    int
+	errorBody bytes.Buffer
+}
+
+func (w *requestWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *requestWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest && w.errorBody.Len() < maxLoggedErrorSize {
+		remaining := maxLoggedErrorSize - w.errorBody.Len()
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		w.errorBody.Write(b[:remaining])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+//Flush allows the streamed responses
+func (w *requestWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+//RequestHandler assigns a request id returned in the X-Request-ID header and logs the request with it
+func RequestHandler(handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = NewID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, requestID))
+		start := time.Now()
+		rw := &requestWriter{ResponseWriter: w}
+		handler.ServeHTTP(rw, req)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		entry := WithRequest(req).WithFields(logrus.Fields{
+			"method":      req.Method,
+			"path":        req.URL.Path,
+			"status":      rw.status,
+			"duration_ms": time.Since(start).Nanoseconds() / int64(time.Millisecond),
+		})
+		if rw.status >= http.StatusBadRequest {
+			entry.Warn("Request failed: " + strings.TrimSpace(rw.errorBody.String()))
+		} else {
+			entry.Debug("Request completed")
+		}
+	})
+}
+
+//GetRequestID returns the id assigned to the request by RequestHandler
+func GetRequestID(req *http.Request) string {
+	if requestID, ok := req.Context().Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+//WithRequest returns a log entry with the request id
+func WithRequest(req *http.Request) *logrus.Entry {
+	return logrus.WithField("request_id", GetRequestID(req))
+}
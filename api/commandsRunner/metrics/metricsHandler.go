@@ -0,0 +1,103 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//Metrics the metrics of the runs, the states and the http requests of a state instance
+type Metrics struct {
+	*Registry
+	RunsStarted   *Counter
+	RunsSucceeded *Counter
+	RunsFailed    *Counter
+	StateDuration *Histogram
+	RunningStates *Gauge
+	//ScriptTimeouts number of scripts killed as their timeout was reached
+	ScriptTimeouts *Counter
+	//ScriptKills number of scripts killed, reason is timeout or shutdown
+	ScriptKills         *Counter
+	HTTPRequests        *Counter
+	HTTPRequestDuration *Histogram
+}
+
+//New creates the metrics in their own registry
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		Registry:            r,
+		RunsStarted:         r.NewCounter("cr_runs_started_total", "Number of runs started", "extension"),
+		RunsSucceeded:       r.NewCounter("cr_runs_succeeded_total", "Number of runs succeeded", "extension"),
+		RunsFailed:          r.NewCounter("cr_runs_failed_total", "Number of runs failed", "extension"),
+		StateDuration:       r.NewHistogram("cr_state_duration_seconds", "Duration of the state scripts", DefaultDurationBuckets, "extension", "state"),
+		RunningStates:       r.NewGauge("cr_running_states", "Number of states currently running", "extension"),
+		ScriptTimeouts:      r.NewCounter("cr_script_timeouts_total", "Number of scripts reaching their timeout", "extension", "state"),
+		ScriptKills:         r.NewCounter("cr_script_kills_total", "Number of scripts killed", "extension", "state", "reason"),
+		HTTPRequests:        r.NewCounter("cr_http_requests_total", "Number of http requests", "endpoint", "method", "code"),
+		HTTPRequestDuration: r.NewHistogram("cr_http_request_duration_seconds", "Latency of the http requests", DefaultLatencyBuckets, "endpoint", "method"),
+	}
+}
+
+//statusWriter records the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+//Flush allows the streamed responses
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+//Handler counts the requests and measures their latency, endpoint is the handler pattern to keep a bounded number of series
+func (m *Metrics) Handler(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(sw, req)
+		m.HTTPRequests.Inc(endpoint, req.Method, strconv.Itoa(sw.status))
+		m.HTTPRequestDuration.Observe(time.Since(start).Seconds(), endpoint, req.Method)
+	}
+}
+
+/*
+Get the metrics in the Prometheus text format
+URL: /metrics
+Method: GET
+*/
+func (m *Metrics) HandleMetrics(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleMetrics")
+	if req.Method != "GET" {
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.Write(w)
+}
@@ -0,0 +1,321 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//DefaultDurationBuckets buckets in seconds of the state duration histogram
+var DefaultDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200}
+
+//DefaultLatencyBuckets buckets in seconds of the http request latency histogram
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+//metric a metric written in the Prometheus text format
+type metric interface {
+	write(w io.Writer)
+}
+
+//Registry the metrics written together in the Prometheus text format
+type Registry struct {
+	mux     sync.Mutex
+	metrics []metric
+	names   map[string]int
+}
+
+//NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make([]metric, 0),
+		names:   make(map[string]int),
+	}
+}
+
+//register adds the metric to the registry, a metric with the same name is replaced
+func (r *Registry) register(name string, m metric) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if index, ok := r.names[name]; ok {
+		r.metrics[index] = m
+		return
+	}
+	r.names[name] = len(r.metrics)
+	r.metrics = append(r.metrics, m)
+}
+
+//vector the values of a metric per label values
+type vector struct {
+	name   string
+	help   string
+	labels []string
+	mux    sync.Mutex
+}
+
+//key returns the label values as a map key
+func (v *vector) key(labelValues []string) string {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\x00")
+}
+
+//labelsString returns the labels in the Prometheus format, ie: {extension="ext1",state="task1"}
+func (v *vector) labelsString(key string, extra ...string) string {
+	pairs := make([]string, 0)
+	if len(v.labels) > 0 {
+		for i, value := range strings.Split(key, "\x00") {
+			pairs = append(pairs, v.labels[i]+"="+strconv.Quote(value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (v *vector) writeHeader(w io.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, metricType)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Counter a metric which only increases
+type Counter struct {
+	vector
+	values map[string]float64
+}
+
+//NewCounter creates and registers in the registry a counter
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	c := &Counter{
+		vector: vector{name: name, help: help, labels: labels},
+		values: make(map[string]float64),
+	}
+	r.register(name, c)
+	return c
+}
+
+//Inc increments the counter of the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+//Add adds the value to the counter of the label values
+func (c *Counter) Add(value float64, labelValues ...string) {
+	key := c.key(labelValues)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.values[key] += value
+}
+
+//Get returns the counter value of the label values
+func (c *Counter) Get(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.writeHeader(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelsString(key), formatValue(c.values[key]))
+	}
+}
+
+//Gauge a metric which can go up and down
+type Gauge struct {
+	vector
+	values map[string]float64
+}
+
+//NewGauge creates and registers in the registry a gauge
+func (r *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	g := &Gauge{
+		vector: vector{name: name, help: help, labels: labels},
+		values: make(map[string]float64),
+	}
+	r.register(name, g)
+	return g
+}
+
+//Add adds the value to the gauge of the label values
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.values[key] += value
+}
+
+//Inc increments the gauge of the label values
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+//Dec decrements the gauge of the label values
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+//Get returns the gauge value of the label values
+func (g *Gauge) Get(labelValues ...string) float64 {
+	key := g.key(labelValues)
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.values[key]
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.writeHeader(w, "gauge")
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelsString(key), formatValue(g.values[key]))
+	}
+}
+
+//GaugeFunc a gauge without label whose value is computed at each scrape
+type GaugeFunc struct {
+	vector
+	f func() float64
+}
+
+//NewGaugeFunc creates and registers in the registry a gauge computed by f
+func (r *Registry) NewGaugeFunc(name string, help string, f func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		vector: vector{name: name, help: help},
+		f:      f,
+	}
+	r.register(name, g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.writeHeader(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatValue(g.f()))
+}
+
+//histogramValue the observations of a label values
+type histogramValue struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+//Histogram counts the observations in buckets
+type Histogram struct {
+	vector
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+//NewHistogram creates and registers in the registry a histogram, the buckets are the sorted upper bounds
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		vector:  vector{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	r.register(name, h)
+	return h
+}
+
+//Observe adds an observation for the label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{buckets: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			v.buckets[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+//Count returns the number of observations of the label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if v, ok := h.values[key]; ok {
+		return v.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.writeHeader(w, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v := h.values[key]
+		for i, upperBound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelsString(key, "le", formatValue(upperBound)), v.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelsString(key, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelsString(key), formatValue(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelsString(key), v.count)
+	}
+}
+
+//Write writes the metrics of the registry in the Prometheus text format
+func (r *Registry) Write(w io.Writer) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, m := range r.metrics {
+		m.write(w)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsTextFormat(t *testing.T) {
+	t.Log("Entering... TestMetricsTextFormat")
+	registry := NewRegistry()
+	counter := registry.NewCounter("test_counter_total", "A test counter", "extension")
+	counter.Inc("ext1")
+	counter.Add(2, "ext1")
+	counter.Inc("ext2")
+	gauge := registry.NewGauge("test_gauge", "A test gauge")
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+	histogram := registry.NewHistogram("test_duration_seconds", "A test histogram", []float64{1, 5}, "extension", "state")
+	histogram.Observe(0.5, "ext1", "task1")
+	histogram.Observe(3, "ext1", "task1")
+	histogram.Observe(10, "ext1", "task1")
+	registry.NewGaugeFunc("test_gauge_func", "A test gauge func", func() float64 { return 42 })
+	var out bytes.Buffer
+	registry.Write(&out)
+	expected := []string{
+		"# HELP test_counter_total A test counter\n# TYPE test_counter_total counter\n",
+		"test_counter_total{extension=\"ext1\"} 3\n",
+		"test_counter_total{extension=\"ext2\"} 1\n",
+		"# TYPE test_gauge gauge\ntest_gauge 1\n",
+		"test_duration_seconds_bucket{extension=\"ext1\",state=\"task1\",le=\"1\"} 1\n",
+		"test_duration_seconds_bucket{extension=\"ext1\",state=\"task1\",le=\"5\"} 2\n",
+		"test_duration_seconds_bucket{extension=\"ext1\",state=\"task1\",le=\"+Inf\"} 3\n",
+		"test_duration_seconds_sum{extension=\"ext1\",state=\"task1\"} 13.5\n",
+		"test_duration_seconds_count{extension=\"ext1\",state=\"task1\"} 3\n",
+		"test_gauge_func 42\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("Expected %q in:\n%s", e, out.String())
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	t.Log("Entering... TestMetricsHandler")
+	m := New()
+	handler := m.Handler("/cr/v1/test", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/cr/v1/test?extension-name=ext1", nil))
+	if m.HTTPRequests.Get("/cr/v1/test", "GET", "404") != 1 {
+		t.Error("Expected the request to be counted")
+	}
+	if m.HTTPRequestDuration.Count("/cr/v1/test", "GET") != 1 {
+		t.Error("Expected the request latency to be observed")
+	}
+	w := httptest.NewRecorder()
+	m.HandleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Error("Unexpected content type " + w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "cr_http_requests_total{endpoint=\"/cr/v1/test\",method=\"GET\",code=\"404\"} 1\n") {
+		t.Error("Request count not exposed:\n" + w.Body.String())
+	}
+}
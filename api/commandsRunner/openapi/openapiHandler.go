@@ -0,0 +1,39 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package openapi
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+Get the OpenAPI 3 document of the API
+URL: /cr/v1/openapi.json
+Method: GET
+*/
+func HandleOpenAPI(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleOpenAPI")
+	if req.Method != "GET" {
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(Spec))
+}
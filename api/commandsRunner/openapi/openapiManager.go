@@ -0,0 +1,55 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package openapi
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//Document the parsed OpenAPI document, only the paths are decoded
+type Document struct {
+	OpenAPI string                 `json:"openapi"`
+	Paths   map[string]interface{} `json:"paths"`
+}
+
+//GetDocument parses the Spec
+func GetDocument() (*Document, error) {
+	log.Debug("Entering in... GetDocument")
+	var doc Document
+	err := json.Unmarshal([]byte(Spec), &doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+//GetPaths returns the paths described in the Spec
+func GetPaths() ([]string, error) {
+	log.Debug("Entering in... GetPaths")
+	doc, err := GetDocument()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,2541 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package openapi
+
+//Spec is the OpenAPI 3 document of the commands-runner API.
+//It must be updated each time an endpoint or a parameter is added, the commandsRunner tests check that every registered route is described.
+const Spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "commands-runner API",
+    "version": "v1",
+    "description": "REST API of the commands-runner server. Most endpoints multiplex several operations through the action query parameter. Each /cr/v1 path is also served under /cr/v2, the v2 endpoints return the errors as a json Error with a consistent status code (ie: 404 for a missing extension or state, 409 for a conflict) instead of plain text. Each response has a X-Request-ID header, also written in the server log, a client can provide its own X-Request-ID."
+  },
+  "servers": [
+    {
+      "url": "/"
+    }
+  ],
+  "security": [
+    {
+      "bearerAuth": []
+    },
+    {
+      "legacyToken": []
+    }
+  ],
+  "tags": [
+    {
+      "name": "states"
+    },
+    {
+      "name": "engine"
+    },
+    {
+      "name": "extensions"
+    },
+    {
+      "name": "config"
+    },
+    {
+      "name": "server"
+    },
+    {
+      "name": "tokens"
+    },
+    {
+      "name": "audit"
+    },
+    {
+      "name": "webhooks"
+    }
+  ],
+  "paths": {
+    "/cr/v1/states": {
+      "get": {
+        "summary": "Get the states of an extension",
+        "operationId": "getStates",
+        "tags": [
+          "states"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "Only the states with that status",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "extensions-only",
+            "in": "query",
+            "description": "Only the states which are extensions",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "recursive",
+            "in": "query",
+            "description": "Include the states of the inserted extensions",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "State name glob pattern",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "label",
+            "in": "query",
+            "description": "State label glob pattern",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "is-extension",
+            "in": "query",
+            "description": "Only the extension states (true) or the other states (false)",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "started-after",
+            "in": "query",
+            "description": "Only the states started after that date",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "started-before",
+            "in": "query",
+            "description": "Only the states started before that date",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "ended-after",
+            "in": "query",
+            "description": "Only the states ended after that date",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "ended-before",
+            "in": "query",
+            "description": "Only the states ended before that date",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "Sort order, prefix with - for descending",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "position",
+                "name",
+                "duration",
+                "-position",
+                "-name",
+                "-duration"
+              ]
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "Number of items to skip",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Maximum number of items to return, 0 for all",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "fields",
+            "in": "query",
+            "description": "Comma separated list of attributes to return",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The states",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/States"
+                }
+              }
+            },
+            "headers": {
+              "X-Total-Count": {
+                "description": "Number of items matching the filters before the pagination",
+                "schema": {
+                  "type": "integer"
+                }
+              },
+              "ETag": {
+                "description": "Revision of the states or config file, to provide in the If-Match header of an update",
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Update the states of an extension",
+        "operationId": "putStates",
+        "tags": [
+          "states"
+        ],
+        "description": "The action is selected with the action query parameter.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "If-Match",
+            "in": "header",
+            "description": "Only update if the ETag is still current, otherwise a 412 is returned",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "action",
+            "in": "query",
+            "description": "insert: insert a state at pos, delete: delete the state at pos or named state-name, set-statuses: set the status of a range of states, none: replace the states",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "insert",
+                "delete",
+                "set-statuses"
+              ]
+            }
+          },
+          {
+            "name": "overwrite",
+            "in": "query",
+            "description": "Replace the states even if they are running (no action)",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "pos",
+            "in": "query",
+            "description": "Position of the state to insert or delete (insert, delete)",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "before",
+            "in": "query",
+            "description": "Insert before the state at pos (insert)",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "state-name",
+            "in": "query",
+            "description": "Name of the state used as reference (insert, delete)",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "insert-extension-name",
+            "in": "query",
+            "description": "Insert the extension as a state (insert)",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "The new status (set-statuses)",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "READY",
+                "SKIP",
+                "SUCCEEDED",
+                "FAILED"
+              ]
+            }
+          },
+          {
+            "name": "from-state-name",
+            "in": "query",
+            "description": "First state of the range (set-statuses)",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "from-include",
+            "in": "query",
+            "description": "Include the first state (set-statuses)",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "to-state-name",
+            "in": "query",
+            "description": "Last state of the range (set-statuses)",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "to-include",
+            "in": "query",
+            "description": "Include the last state (set-statuses)",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "requestBody": {
+          "description": "The states (no action) or the state to insert (insert), in json or yaml",
+          "required": false,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/States"
+              }
+            },
+            "application/x-yaml": {
+              "schema": {
+                "$ref": "#/components/schemas/States"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated states",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/States"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "description": "Revision of the states or config file, to provide in the If-Match header of an update",
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "412": {
+            "$ref": "#/components/responses/Error"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/state/{state}": {
+      "parameters": [
+        {
+          "name": "state",
+          "in": "path",
+          "description": "State name",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "summary": "Get a state",
+        "operationId": "getState",
+        "tags": [
+          "states"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The state",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/State"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "description": "Revision of the states or config file, to provide in the If-Match header of an update",
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Update a state",
+        "operationId": "putState",
+        "tags": [
+          "states"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "If-Match",
+            "in": "header",
+            "description": "Only update if the ETag is still current, otherwise a 412 is returned",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "The new status",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "reason",
+            "in": "query",
+            "description": "The reason of the status",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "script",
+            "in": "query",
+            "description": "The new script",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "recursivelly",
+            "in": "query",
+            "description": "Set the status of the following states",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "script-timeout",
+            "in": "query",
+            "description": "The new script timeout in minutes",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The state is updated",
+            "headers": {
+              "ETag": {
+                "description": "Revision of the states or config file, to provide in the If-Match header of an update",
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "412": {
+            "$ref": "#/components/responses/Error"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/state/{state}/log": {
+      "parameters": [
+        {
+          "name": "state",
+          "in": "path",
+          "description": "State name",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "summary": "Get the log of a state",
+        "operationId": "getStateLog",
+        "tags": [
+          "states"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "first-line",
+            "in": "query",
+            "description": "First line to return, default 1",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "first-char",
+            "in": "query",
+            "description": "First character to return",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "length",
+            "in": "query",
+            "description": "Number of lines or characters to return",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The log",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/engine": {
+      "get": {
+        "summary": "Check if the engine is running, or get the mock mode with action=mock",
+        "operationId": "getEngine",
+        "tags": [
+          "engine"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "action",
+            "in": "query",
+            "description": "mock: get the mock mode",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "mock"
+              ]
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Not running, or the mock mode if action=mock",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Mock"
+                }
+              }
+            }
+          },
+          "201": {
+            "description": "Running"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Start or reset the engine",
+        "operationId": "putEngine",
+        "tags": [
+          "engine"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "action",
+            "in": "query",
+            "description": "start: run the states, reset: set all states to READY, reset-execution-info: clear the execution info, mock: set the mock mode",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "start",
+                "reset",
+                "reset-execution-info",
+                "mock"
+              ]
+            },
+            "required": true
+          },
+          {
+            "name": "from-state",
+            "in": "query",
+            "description": "First state to run (start), default the first state",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "to-state",
+            "in": "query",
+            "description": "Last state to run (start), default the last state",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "overlay",
+            "in": "query",
+            "description": "Config overlay to use (start), default the server overlay",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "mock",
+            "in": "query",
+            "description": "Mock mode (mock)",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "503": {
+            "description": "The server is shutting down",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/events": {
+      "get": {
+        "summary": "Stream the events of an extension",
+        "operationId": "getEvents",
+        "tags": [
+          "engine"
+        ],
+        "description": "The stream pushes the state status changes (state_status), the run start and end (run_start, run_end) and the log lines (log).",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Server-sent events, each data field is a json Event",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/cr/log/level": {
+      "get": {
+        "summary": "Get the log level",
+        "operationId": "getLogLevel",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The log level",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Log"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Set the log level",
+        "operationId": "setLogLevel",
+        "tags": [
+          "server"
+        ],
+        "parameters": [
+          {
+            "name": "level",
+            "in": "query",
+            "description": "The new log level",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "panic",
+                "fatal",
+                "error",
+                "warn",
+                "info",
+                "debug",
+                "trace"
+              ]
+            },
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/cr/log/max-backups": {
+      "get": {
+        "summary": "Get the maximum number of log backups",
+        "operationId": "getLogMaxBackups",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The maximum number of log backups",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Log"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Set the maximum number of log backups",
+        "operationId": "setLogMaxBackups",
+        "tags": [
+          "server"
+        ],
+        "parameters": [
+          {
+            "name": "max-backups",
+            "in": "query",
+            "description": "The new maximum number of backups",
+            "schema": {
+              "type": "integer"
+            },
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/cr/settings": {
+      "get": {
+        "summary": "Get the server settings",
+        "operationId": "getSettings",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The settings",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Settings"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/cr/about": {
+      "get": {
+        "summary": "Get the about information",
+        "operationId": "getAbout",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The about information",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "about": {
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/status": {
+      "get": {
+        "summary": "Get the server statuses",
+        "operationId": "getStatuses",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The statuses by name",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": {
+                    "$ref": "#/components/schemas/Status"
+                  }
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Set a status",
+        "operationId": "setStatus",
+        "tags": [
+          "server"
+        ],
+        "parameters": [
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Status name",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "Status value",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/extension": {
+      "post": {
+        "summary": "Register an extension",
+        "operationId": "registerExtension",
+        "tags": [
+          "extensions"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionNameOptional"
+          },
+          {
+            "name": "Force",
+            "in": "header",
+            "description": "Overwrite the extension if already registered",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "RunningToFailed",
+            "in": "header",
+            "description": "Set the running states to FAILED",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "requestBody": {
+          "description": "The extension zip, not needed for an embedded extension",
+          "required": false,
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "extension": {
+                    "type": "string",
+                    "format": "binary"
+                  }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "The extension is registered",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "409": {
+            "description": "The extension is already registered",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "summary": "Unregister an extension",
+        "operationId": "unregisterExtension",
+        "tags": [
+          "extensions"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/extensions": {
+      "get": {
+        "summary": "List the extensions",
+        "operationId": "listExtensions",
+        "tags": [
+          "extensions"
+        ],
+        "parameters": [
+          {
+            "name": "filter",
+            "in": "query",
+            "description": "Only the embedded or custom extensions",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "embedded",
+                "custom"
+              ]
+            }
+          },
+          {
+            "name": "catalog",
+            "in": "query",
+            "description": "List the embedded extensions available instead of the registered ones",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Extension name glob pattern",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "Number of items to skip",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Maximum number of items to return, 0 for all",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "fields",
+            "in": "query",
+            "description": "Comma separated list of attributes to return",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The extensions ordered by name",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Extensions"
+                }
+              }
+            },
+            "headers": {
+              "X-Total-Count": {
+                "description": "Number of items matching the filters before the pagination",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/uimetadata": {
+      "get": {
+        "summary": "Get a ui metadata of an extension",
+        "operationId": "getUIMetadata",
+        "tags": [
+          "config"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "ui-metadata-name",
+            "in": "query",
+            "description": "ui metadata name, default: default",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "format",
+            "in": "query",
+            "description": "json returns the ui metadata, jsonschema a JSON schema of the config",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "json",
+                "jsonschema"
+              ]
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The ui metadata or the JSON schema",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": true
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/uimetadatas": {
+      "get": {
+        "summary": "List the ui metadatas of an extension",
+        "operationId": "listUIMetadatas",
+        "tags": [
+          "config"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "names-only",
+            "in": "query",
+            "description": "Return only the names",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The ui metadatas",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": true
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/config": {
+      "get": {
+        "summary": "Get the config of an extension, or validate it with action=validate",
+        "operationId": "getConfig",
+        "tags": [
+          "config"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "action",
+            "in": "query",
+            "description": "validate: validate the config",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "validate"
+              ]
+            }
+          },
+          {
+            "name": "ui-metadata-name",
+            "in": "query",
+            "description": "ui metadata name",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "overlay",
+            "in": "query",
+            "description": "Config overlay to merge, the config file is returned if neither overlay nor merged is provided",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "merged",
+            "in": "query",
+            "description": "Merge the config overlay of the current run or the server default",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "provenance",
+            "in": "query",
+            "description": "Return for each property the file it comes from",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The config",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": true
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "description": "Revision of the states or config file, to provide in the If-Match header of an update",
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "put": {
+        "summary": "Generate the config of an extension",
+        "operationId": "generateConfig",
+        "tags": [
+          "config"
+        ],
+        "description": "The generate_config_url of the extension is called or, if not provided, the generate_config_script is run and its output saved as the new config.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "post": {
+        "summary": "Save the config of an extension",
+        "operationId": "setConfig",
+        "tags": [
+          "config"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "If-Match",
+            "in": "header",
+            "description": "Only update if the ETag is still current, otherwise a 412 is returned",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "ui-metadata-name",
+            "in": "query",
+            "description": "ui metadata name",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "requestBody": {
+          "description": "The config in json or yaml, or a multipart form with a config file",
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "additionalProperties": true
+              }
+            },
+            "application/x-yaml": {
+              "schema": {
+                "type": "object",
+                "additionalProperties": true
+              }
+            },
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "config": {
+                    "type": "string",
+                    "format": "binary"
+                  }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The states invalidated by the change",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/InvalidatedStates"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "description": "Revision of the states or config file, to provide in the If-Match header of an update",
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "412": {
+            "$ref": "#/components/responses/Error"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/config/{property}": {
+      "parameters": [
+        {
+          "name": "property",
+          "in": "path",
+          "description": "Property name",
+          "schema": {
+            "type": "string"
+          },
+          "required": true
+        }
+      ],
+      "get": {
+        "summary": "Get a property of the config",
+        "operationId": "getProperty",
+        "tags": [
+          "config"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The property",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": true
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/template": {
+      "get": {
+        "summary": "Get the config template of an extension",
+        "operationId": "getTemplate",
+        "tags": [
+          "config"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "ui-metadata-name",
+            "in": "query",
+            "description": "ui metadata name",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The template",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/token": {
+      "post": {
+        "summary": "Create or rotate a token, the token value is only returned here",
+        "operationId": "createToken",
+        "tags": [
+          "tokens"
+        ],
+        "parameters": [
+          {
+            "name": "action",
+            "in": "query",
+            "description": "rotate: issue a new value for the token",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "rotate"
+              ]
+            }
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Token name",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "role",
+            "in": "query",
+            "description": "Token role (create)",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "viewer",
+                "operator",
+                "admin"
+              ]
+            }
+          },
+          {
+            "name": "extensions",
+            "in": "query",
+            "description": "Comma separated extensions the token is restricted to (create)",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "expires-in",
+            "in": "query",
+            "description": "Duration after which the token expires, ie: 720h",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "overlap",
+            "in": "query",
+            "description": "Duration during which the previous value stays valid (rotate), default 1h",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The token",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Token"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "summary": "Revoke a token",
+        "operationId": "revokeToken",
+        "tags": [
+          "tokens"
+        ],
+        "parameters": [
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Token name",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/tokens": {
+      "get": {
+        "summary": "List the tokens",
+        "operationId": "listTokens",
+        "tags": [
+          "tokens"
+        ],
+        "responses": {
+          "200": {
+            "description": "The tokens without their value",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Token"
+                  }
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/audit": {
+      "get": {
+        "summary": "Search the audit records",
+        "operationId": "getAudit",
+        "tags": [
+          "audit"
+        ],
+        "parameters": [
+          {
+            "name": "caller",
+            "in": "query",
+            "description": "Token name or certificate subject",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "method",
+            "in": "query",
+            "description": "HTTP method",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "endpoint",
+            "in": "query",
+            "description": "Endpoint prefix",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "$ref": "#/components/parameters/ExtensionNameOptional"
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "Response status code",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "from",
+            "in": "query",
+            "description": "RFC3339 date",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "to",
+            "in": "query",
+            "description": "RFC3339 date",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Maximum number of records, the most recent are returned",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The audit records",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/AuditRecord"
+                  }
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/runs": {
+      "get": {
+        "summary": "Get the run history of an extension, the most recent run first",
+        "operationId": "getRuns",
+        "tags": [
+          "engine"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/ExtensionName"
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "Run status, ie: SUCCEEDED or FAILED",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Maximum number of runs, the most recent are returned",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The runs",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Runs"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/webhooks": {
+      "get": {
+        "summary": "List the webhooks, or their deliveries with action=deliveries",
+        "operationId": "listWebhooks",
+        "tags": [
+          "webhooks"
+        ],
+        "parameters": [
+          {
+            "name": "action",
+            "in": "query",
+            "description": "deliveries: list the deliveries",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "deliveries"
+              ]
+            }
+          },
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Webhook name (deliveries)",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The webhooks or the deliveries",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/Webhook"
+                      }
+                    },
+                    {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/Delivery"
+                      }
+                    }
+                  ]
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "post": {
+        "summary": "Register a webhook, the secret is only returned here",
+        "operationId": "createWebhook",
+        "tags": [
+          "webhooks"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Webhook"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The webhook",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Webhook"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      },
+      "delete": {
+        "summary": "Delete a webhook",
+        "operationId": "deleteWebhook",
+        "tags": [
+          "webhooks"
+        ],
+        "parameters": [
+          {
+            "name": "name",
+            "in": "query",
+            "description": "Webhook name",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success"
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/cr/v1/openapi.json": {
+      "get": {
+        "summary": "Get this OpenAPI document",
+        "operationId": "getOpenAPI",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The OpenAPI document",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": []
+      }
+    },
+    "/metrics": {
+      "get": {
+        "summary": "Get the metrics in the Prometheus text format",
+        "operationId": "getMetrics",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "The metrics",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        }
+      }
+    },
+    "/healthz": {
+      "get": {
+        "summary": "Check the health of the server",
+        "operationId": "getHealthz",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "All checks succeeded",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthReport"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "A check failed",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthReport"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": []
+      }
+    },
+    "/readyz": {
+      "get": {
+        "summary": "Check if the server is ready",
+        "operationId": "getReadyz",
+        "tags": [
+          "server"
+        ],
+        "responses": {
+          "200": {
+            "description": "All checks succeeded",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthReport"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "A check failed",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/HealthReport"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Error"
+          }
+        },
+        "security": []
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {
+        "type": "http",
+        "scheme": "bearer"
+      },
+      "legacyToken": {
+        "type": "apiKey",
+        "in": "header",
+        "name": "Authorization",
+        "description": "Token:<token>"
+      }
+    },
+    "parameters": {
+      "ExtensionName": {
+        "name": "extension-name",
+        "in": "query",
+        "description": "Extension name",
+        "schema": {
+          "type": "string"
+        },
+        "required": true
+      },
+      "ExtensionNameOptional": {
+        "name": "extension-name",
+        "in": "query",
+        "description": "Extension name",
+        "schema": {
+          "type": "string"
+        }
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "Error, plain text for /cr/v1 and json for /cr/v2",
+        "content": {
+          "text/plain": {
+            "schema": {
+              "type": "string"
+            }
+          },
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        }
+      }
+    },
+    "schemas": {
+      "State": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "phase": {
+            "type": "string"
+          },
+          "label": {
+            "type": "string"
+          },
+          "log_path": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "description": "READY, RUNNING, SUCCEEDED, FAILED or SKIP"
+          },
+          "start_time": {
+            "type": "string"
+          },
+          "end_time": {
+            "type": "string"
+          },
+          "reason": {
+            "type": "string"
+          },
+          "script": {
+            "type": "string"
+          },
+          "script_timeout": {
+            "type": "integer"
+          },
+          "protected": {
+            "type": "boolean"
+          },
+          "deleted": {
+            "type": "boolean"
+          },
+          "prerequisite_states": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "states_to_rerun": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "rerun_on_run_of_states": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "previous_states": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "next_states": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "executed_by_extension_name": {
+            "type": "string"
+          },
+          "execution_id": {
+            "type": "integer"
+          },
+          "next_run": {
+            "type": "boolean"
+          },
+          "is_extension": {
+            "type": "boolean"
+          },
+          "depends_on_config": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "config_snapshot": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "States": {
+        "type": "object",
+        "properties": {
+          "states": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/State"
+            }
+          },
+          "extension_name": {
+            "type": "string"
+          },
+          "parent_extension_name": {
+            "type": "string"
+          },
+          "executed_by_extension_name": {
+            "type": "string"
+          },
+          "execution_id": {
+            "type": "integer"
+          },
+          "start_time": {
+            "type": "string"
+          },
+          "end_time": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string"
+          },
+          "config_overlay": {
+            "type": "string"
+          }
+        }
+      },
+      "Mock": {
+        "type": "object",
+        "properties": {
+          "mock": {
+            "type": "boolean"
+          }
+        }
+      },
+      "Settings": {
+        "type": "object",
+        "properties": {
+          "deployment_name": {
+            "type": "string"
+          },
+          "default_extension_name": {
+            "type": "string"
+          },
+          "config_root_key": {
+            "type": "string"
+          },
+          "about_url": {
+            "type": "string"
+          }
+        }
+      },
+      "Log": {
+        "type": "object",
+        "properties": {
+          "level": {
+            "type": "string"
+          },
+          "max_backups": {
+            "type": "integer"
+          }
+        }
+      },
+      "Status": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "value": {
+            "type": "string"
+          }
+        }
+      },
+      "Extension": {
+        "type": "object",
+        "properties": {
+          "type": {
+            "type": "string",
+            "description": "embedded or custom"
+          },
+          "version": {
+            "type": "string"
+          },
+          "validation_config_url": {
+            "type": "string"
+          },
+          "generate_config_url": {
+            "type": "string"
+          },
+          "validation_config_script": {
+            "type": "string"
+          },
+          "generate_config_script": {
+            "type": "string"
+          },
+          "persisted_paths": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "Extensions": {
+        "type": "object",
+        "properties": {
+          "extensions": {
+            "type": "object",
+            "additionalProperties": {
+              "$ref": "#/components/schemas/Extension"
+            }
+          }
+        }
+      },
+      "InvalidatedStates": {
+        "type": "object",
+        "properties": {
+          "invalidated_states": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        }
+      },
+      "Token": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "token": {
+            "type": "string",
+            "description": "The token value, only returned at creation or rotation"
+          },
+          "role": {
+            "type": "string"
+          },
+          "extensions": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "expires_at": {
+            "type": "string"
+          },
+          "previous_expires_at": {
+            "type": "string"
+          }
+        }
+      },
+      "AuditRecord": {
+        "type": "object",
+        "properties": {
+          "timestamp": {
+            "type": "string"
+          },
+          "caller": {
+            "type": "string"
+          },
+          "remote_address": {
+            "type": "string"
+          },
+          "method": {
+            "type": "string"
+          },
+          "endpoint": {
+            "type": "string"
+          },
+          "extension_name": {
+            "type": "string"
+          },
+          "parameters": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          "status": {
+            "type": "integer"
+          }
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "url": {
+            "type": "string"
+          },
+          "extension_name": {
+            "type": "string",
+            "description": "Empty for all extensions"
+          },
+          "events": {
+            "type": "array",
+            "items": {
+              "type": "string",
+              "description": "<run_start|run_end|state_status|log>[:<status>]"
+            }
+          },
+          "secret": {
+            "type": "string",
+            "description": "Only returned at creation"
+          },
+          "read_only": {
+            "type": "boolean"
+          }
+        }
+      },
+      "Delivery": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "webhook": {
+            "type": "string"
+          },
+          "event_id": {
+            "type": "integer"
+          },
+          "event_type": {
+            "type": "string"
+          },
+          "extension_name": {
+            "type": "string"
+          },
+          "timestamp": {
+            "type": "string"
+          },
+          "attempts": {
+            "type": "integer"
+          },
+          "status_code": {
+            "type": "integer"
+          },
+          "delivered": {
+            "type": "boolean"
+          },
+          "error": {
+            "type": "string"
+          }
+        }
+      },
+      "Event": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "type": {
+            "type": "string",
+            "description": "state_status, run_start, run_end or log"
+          },
+          "timestamp": {
+            "type": "string"
+          },
+          "extension_name": {
+            "type": "string"
+          },
+          "executed_by_extension_name": {
+            "type": "string"
+          },
+          "execution_id": {
+            "type": "integer"
+          },
+          "state_name": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string"
+          },
+          "reason": {
+            "type": "string"
+          },
+          "line": {
+            "type": "string"
+          },
+          "position": {
+            "type": "integer"
+          }
+        }
+      },
+      "Run": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "The run UUID, the run_id of the log entries"
+          },
+          "extension_name": {
+            "type": "string"
+          },
+          "execution_id": {
+            "type": "integer"
+          },
+          "from_state": {
+            "type": "string"
+          },
+          "to_state": {
+            "type": "string"
+          },
+          "start_time": {
+            "type": "string",
+            "description": "RFC3339 date"
+          },
+          "end_time": {
+            "type": "string",
+            "description": "RFC3339 date"
+          },
+          "status": {
+            "type": "string",
+            "description": "SUCCEEDED or FAILED"
+          },
+          "reason": {
+            "type": "string"
+          }
+        }
+      },
+      "Runs": {
+        "type": "object",
+        "properties": {
+          "runs": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Run"
+            }
+          }
+        }
+      },
+      "Error": {
+        "type": "object",
+        "description": "Error returned by the /cr/v2 endpoints",
+        "properties": {
+          "code": {
+            "type": "string",
+            "description": "Stable identifier, ie: extension_not_found, state_not_found, not_found, conflict, bad_request"
+          },
+          "message": {
+            "type": "string"
+          },
+          "message_localized": {
+            "type": "string",
+            "description": "The message translated in the language of the request"
+          },
+          "details": {
+            "type": "object",
+            "description": "ie: extension_name, request_id",
+            "additionalProperties": true
+          }
+        }
+      },
+      "HealthReport": {
+        "type": "object",
+        "properties": {
+          "status": {
+            "type": "string",
+            "description": "ok or failed"
+          },
+          "checks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {
+                  "type": "string"
+                },
+                "status": {
+                  "type": "string"
+                },
+                "error": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
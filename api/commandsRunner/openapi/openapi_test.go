@@ -0,0 +1,59 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetDocument(t *testing.T) {
+	doc, err := GetDocument()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Error("Expected an OpenAPI 3 document, got:" + doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/cr/v1/openapi.json"]; !ok {
+		t.Error("Expected /cr/v1/openapi.json to be described")
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	req, err := http.NewRequest("GET", "/cr/v1/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(HandleOpenAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Error("Expected application/json, got:" + rr.Header().Get("Content-Type"))
+	}
+	req, _ = http.NewRequest("POST", "/cr/v1/openapi.json", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(HandleOpenAPI).ServeHTTP(rr, req)
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusMethodNotAllowed)
+	}
+}
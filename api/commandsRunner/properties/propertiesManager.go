@@ -19,15 +19,20 @@
 package properties
 
 import (
+	"bytes"
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/olebedev/config"
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
 )
@@ -35,9 +40,100 @@ import (
 //Properties map of interfaces
 type Properties map[string]interface{}
 
+//ConfigStorage persists the config of the extensions, see state.ConfigStorage
+type ConfigStorage = state.ConfigStorage
+
+//FileConfigStorage stores each config document in its config file, it is the default storage.
+type FileConfigStorage = state.FileConfigStorage
+
+//Register the config reader used by the states to snapshot their depends_on_config properties,
+//the config remover used when an extension is unregistered and the config exporter called before the scripts run
+func init() {
+	state.SetConfigReader(func(inst *state.Instance, extensionName string) (map[string]interface{}, error) {
+		return ReadProperties(inst, extensionName)
+	})
+	state.SetConfigRemover(func(inst *state.Instance, extensionName string) error {
+		return inst.GetConfigStorage().DeleteConfig(GetConfigFilePath(inst, extensionName))
+	})
+	state.SetConfigExporter(ExportConfig)
+}
+
 //GetConfigPath gets the statesFile path
-func GetConfigPath(extensionName string) string {
-	return state.GetRootExtensionPath(state.GetExtensionsPath(), extensionName)
+func GetConfigPath(inst *state.Instance, extensionName string) string {
+	return inst.GetRootExtensionPath(inst.GetExtensionsPath(), extensionName)
+}
+
+//GetConfigFilePath gets the config file path of an extension
+func GetConfigFilePath(inst *state.Instance, extensionName string) string {
+	return filepath.Join(GetConfigPath(inst, extensionName), global.ConfigYamlFileName)
+}
+
+//ReadConfigData returns the config document of an extension as stored
+func ReadConfigData(inst *state.Instance, extensionName string) ([]byte, error) {
+	return inst.GetConfigStorage().ReadConfig(GetConfigFilePath(inst, extensionName))
+}
+
+//ExportConfig writes the config document of an extension in its config file if they differ,
+//the scripts read the config file whatever the storage is. Nothing is written with the file storage.
+func ExportConfig(inst *state.Instance, extensionName string) error {
+	log.Debug("Entering in... ExportConfig")
+	configPath := GetConfigFilePath(inst, extensionName)
+	configData, err := inst.GetConfigStorage().ReadConfig(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fileData, err := ioutil.ReadFile(configPath)
+	if err == nil && bytes.Equal(fileData, configData) {
+		return nil
+	}
+	log.Debug("Export the config to " + configPath)
+	unlockFile, err := global.LockFile(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlockFile()
+	return global.WriteFileAtomic(configPath, configData, 0644)
+}
+
+//MigrateConfig copies the config of the registered extensions from a storage to another, it returns the migrated extensions.
+func MigrateConfig(inst *state.Instance, from ConfigStorage, to ConfigStorage) ([]string, error) {
+	log.Debug("Entering in... MigrateConfig")
+	extensions, err := inst.ListExtensions("", false)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(extensions.Extensions))
+	for name := range extensions.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	migrated := make([]string, 0)
+	for _, name := range names {
+		configPath := GetConfigFilePath(inst, name)
+		configData, err := from.ReadConfig(configPath)
+		if os.IsNotExist(err) {
+			//Extension without config
+			continue
+		}
+		if err != nil {
+			return migrated, err
+		}
+		unlockConfig, err := to.LockConfig(configPath)
+		if err != nil {
+			return migrated, err
+		}
+		err = to.WriteConfig(configPath, configData)
+		unlockConfig()
+		if err != nil {
+			return migrated, err
+		}
+		log.Info("Config of " + name + " migrated")
+		migrated = append(migrated, name)
+	}
+	return migrated, nil
 }
 
 func logProperties(ps Properties) {
@@ -60,19 +156,84 @@ func logProperties(ps Properties) {
 
 /*
 ReadProperties reads the property file and populate the properties map
+The config overlay of the current run or the server default overlay is merged on top of it.
 If the file is not present or can not be read an error is raised
 */
-func ReadProperties(extensionName string) (Properties, error) {
+func ReadProperties(inst *state.Instance, extensionName string) (Properties, error) {
 	log.Debug("Entering... readProperties")
-	dataDirectory := GetConfigPath(extensionName)
-	//properties = make(global.Properties)
-	log.Debugf("dataDirectory:%s\n", dataDirectory)
-	raw, e := ioutil.ReadFile(filepath.Join(dataDirectory, global.ConfigYamlFileName))
+	return ReadPropertiesWithOverlay(inst, extensionName, inst.GetConfigOverlay(extensionName))
+}
+
+/*
+ReadBaseProperties reads the property file without merging any overlay.
+It must be used to update the properties as the overlays are never written.
+*/
+func ReadBaseProperties(inst *state.Instance, extensionName string) (Properties, error) {
+	log.Debug("Entering... ReadBaseProperties")
+	configPath := GetConfigFilePath(inst, extensionName)
+	raw, e := inst.GetConfigStorage().ReadConfig(configPath)
+	if e != nil {
+		return nil, errors.New("Unable to read " + configPath + " " + e.Error())
+	}
+	return parseProperties(raw)
+}
+
+//ReadPropertiesWithOverlay reads the property file and deep-merges the overlay file on top of it.
+func ReadPropertiesWithOverlay(inst *state.Instance, extensionName string, overlay string) (Properties, error) {
+	log.Debug("Entering... ReadPropertiesWithOverlay")
+	ps, _, err := ReadPropertiesWithProvenance(inst, extensionName, overlay)
+	return ps, err
+}
+
+/*
+ReadPropertiesWithProvenance reads the property file and deep-merges the overlay file on top of it.
+It returns also the provenance, a tree with the same structure as the properties where each value is the file name it comes from.
+If the overlay file doesn't exist for that extension, only the property file is used.
+*/
+func ReadPropertiesWithProvenance(inst *state.Instance, extensionName string, overlay string) (Properties, Properties, error) {
+	log.Debug("Entering... ReadPropertiesWithProvenance")
+	ps, err := ReadBaseProperties(inst, extensionName)
+	if err != nil {
+		return nil, nil, err
+	}
+	provenance := make(Properties)
+	MergeProperties(make(Properties), ps, global.ConfigYamlFileName, provenance)
+	if overlay == "" {
+		logProperties(ps)
+		return ps, provenance, nil
+	}
+	err = global.ValidateConfigOverlay(overlay)
+	if err != nil {
+		return nil, nil, err
+	}
+	overlayFileName := global.GetConfigOverlayFileName(overlay)
+	overlayPath := filepath.Join(GetConfigPath(inst, extensionName), overlayFileName)
+	if _, err := os.Stat(overlayPath); os.IsNotExist(err) {
+		log.Debug("No overlay file " + overlayPath)
+		logProperties(ps)
+		return ps, provenance, nil
+	}
+	overlayPs, err := readPropertiesFile(overlayPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	ps = MergeProperties(ps, overlayPs, overlayFileName, provenance)
+	logProperties(ps)
+	return ps, provenance, nil
+}
+
+func readPropertiesFile(filePath string) (Properties, error) {
+	log.Debug("Entering... readPropertiesFile")
+	log.Debugf("filePath:%s\n", filePath)
+	raw, e := ioutil.ReadFile(filePath)
 	//log.Debugf("\n%s", string(raw))
 	if e != nil {
-		return nil, errors.New("Unable to read " + filepath.Join(dataDirectory, global.ConfigYamlFileName) + " " + e.Error())
+		return nil, errors.New("Unable to read " + filePath + " " + e.Error())
 	}
-	//	var bmxConfig BMXConfig
+	return parseProperties(raw)
+}
+
+func parseProperties(raw []byte) (Properties, error) {
 	uiConfigCfg, err := config.ParseYamlBytes(raw)
 	if err != nil {
 		log.Debug(err.Error())
@@ -80,14 +241,48 @@ func ReadProperties(extensionName string) (Properties, error) {
 	}
 	var properties Properties
 	properties, err = uiConfigCfg.Map(global.ConfigRootKey)
-	//	err := yaml.Unmarshal(raw, &bmxConfig)
 	if err != nil {
 		log.Debug(err.Error())
 		return nil, err
 	}
-	//	log.Debug("data:\n" + string(raw))
-	logProperties(properties)
-	return properties, err
+	return properties, nil
+}
+
+/*
+MergeProperties deep-merges the overlay properties into the base properties and returns the result.
+Maps are merged key by key, any other value (including arrays) of the overlay replaces the base value.
+The provenance tree is updated with the source for each value coming from the overlay.
+*/
+func MergeProperties(base Properties, overlay Properties, source string, provenance Properties) Properties {
+	for key, overlayValue := range overlay {
+		overlayMap, overlayIsMap := toPropertiesMap(overlayValue)
+		baseMap, baseIsMap := toPropertiesMap(base[key])
+		if overlayIsMap {
+			subProvenance, ok := toPropertiesMap(provenance[key])
+			if !ok || !baseIsMap {
+				subProvenance = make(Properties)
+			}
+			if !baseIsMap {
+				baseMap = make(Properties)
+			}
+			base[key] = map[string]interface{}(MergeProperties(baseMap, overlayMap, source, subProvenance))
+			provenance[key] = map[string]interface{}(subProvenance)
+			continue
+		}
+		base[key] = overlayValue
+		provenance[key] = source
+	}
+	return base
+}
+
+func toPropertiesMap(value interface{}) (Properties, bool) {
+	switch v := value.(type) {
+	case Properties:
+		return v, true
+	case map[string]interface{}:
+		return Properties(v), true
+	}
+	return nil, false
 }
 
 //RenderProperties converts properties into string
@@ -109,20 +304,56 @@ func RenderProperties(ps Properties) (string, error) {
 	return string(out), nil
 }
 
-//WriteProperties persists the properties
-func WriteProperties(extensionName string, ps Properties) error {
+//WriteProperties persists the properties, the config is locked and written atomically
+func WriteProperties(inst *state.Instance, extensionName string, ps Properties) error {
+	return WritePropertiesIfMatch(inst, extensionName, ps, nil)
+}
+
+//WritePropertiesIfMatch persists the properties like WriteProperties if ifMatch accepts the ETag of the current config,
+//the ETag is checked under the config lock. A precondition failed error is returned if it is not accepted, a nil ifMatch accepts any ETag.
+func WritePropertiesIfMatch(inst *state.Instance, extensionName string, ps Properties, ifMatch func(etag string) bool) error {
 	log.Debug("Entering... writeProperties")
-	dataDirectory := GetConfigPath(extensionName)
+	dataDirectory := GetConfigPath(inst, extensionName)
 	log.Debug("dataDirectory:" + dataDirectory)
 	propertiesYaml, err := RenderProperties(ps)
+	if err != nil {
+		return err
+	}
 	//	log.Debug("propertiesYaml:\n" + propertiesYaml)
-	err = ioutil.WriteFile(filepath.Join(dataDirectory, global.ConfigYamlFileName), []byte(propertiesYaml), 0644)
+	configPath := filepath.Join(dataDirectory, global.ConfigYamlFileName)
+	unlockConfig, err := inst.GetConfigStorage().LockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlockConfig()
+	if ifMatch != nil {
+		etag, err := GetConfigETag(inst, extensionName)
+		if err != nil {
+			return err
+		}
+		if !ifMatch(etag) {
+			return apiError.New(http.StatusPreconditionFailed, apiError.CodePreconditionFailed, "The config of "+extensionName+" was modified, the "+global.IfMatchHeader+" header doesn't match the current ETag "+etag)
+		}
+	}
+	err = inst.GetConfigStorage().WriteConfig(configPath, []byte(propertiesYaml))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+//GetConfigETag returns the ETag of the extension config, it changes each time the config is written.
+func GetConfigETag(inst *state.Instance, extensionName string) (string, error) {
+	configData, err := ReadConfigData(inst, extensionName)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return global.ETag(configData), nil
+}
+
 //GetValueAsString gets a property as string
 func GetValueAsString(ps Properties, key string) (string, error) {
 	if val, ok := ps[key]; ok {
@@ -0,0 +1,530 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package commandsRunner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/audit"
+	"github.com/IBM/commands-runner/api/commandsRunner/commandsRunner"
+	"github.com/IBM/commands-runner/api/commandsRunner/config"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/health"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+	"github.com/IBM/commands-runner/api/commandsRunner/openapi"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+	"github.com/IBM/commands-runner/api/commandsRunner/status"
+	"github.com/IBM/commands-runner/api/commandsRunner/storage"
+	"github.com/IBM/commands-runner/api/commandsRunner/token"
+	"github.com/IBM/commands-runner/api/commandsRunner/webhook"
+)
+
+/*
+Runner is a commands runner server. It owns its config directory, its extensions paths, its mock flag, its storage,
+its state managers, its metrics, its token store, its webhooks, its audit file, its http routes on its own ServeMux and its
+listeners, so several runners can serve in one process. The log file and the server status are shared by the runners of the process.
+*/
+type Runner struct {
+	//ConfigDir directory of the config, the tokens, the certificates and the storage
+	ConfigDir string
+	//Port http port, PortSSL https port
+	Port    string
+	PortSSL string
+	//CertificatePath and KeyPath of the https server, https is not enabled if one is missing
+	CertificatePath string
+	KeyPath         string
+	//ClientCAPath CA bundle used to verify the client certificates, the client certificates are not requested if empty
+	ClientCAPath string
+	//MetricsPort if set the metrics are also served without authentication on that port
+	MetricsPort string
+	//UnixSocketPath if set the api is also served on that unix socket created with the UnixSocketMode permissions
+	UnixSocketPath string
+	UnixSocketMode os.FileMode
+	//TCPDisabled if true the api is only served on the unix socket
+	TCPDisabled bool
+	//ShutdownTimeout seconds the running states get to complete at shutdown before being cancelled
+	ShutdownTimeout int
+	//The extensions settings, see state.InitExtensions, the extensions settings of the process are kept if ExtensionsPath is empty
+	ExtensionsEmbeddedFile           string
+	EmbeddedExtensionsRepositoryPath string
+	ExtensionsPath                   string
+	ExtensionsLogsPath               string
+	//Mock if true the scripts are skipped
+	Mock bool
+	//StorageBackend storage of the states and the config (file or bolt), StoragePath path of the bolt database
+	StorageBackend string
+	StoragePath    string
+	//AuditMaxBackups number of rotated audit files kept in ConfigDir
+	AuditMaxBackups int
+	//Security the CORS, security headers and TLS settings
+	Security SecurityConfig
+	//Webhooks the webhooks defined in the server config, they can not be deleted through the api
+	Webhooks []webhook.Webhook
+	//instance the state managers, extensions, mock flag and storage of the runner, passed to the handlers with the request
+	instance  *state.Instance
+	storage   storage.Storage
+	mux       *http.ServeMux
+	routes    []string
+	servers   []*http.Server
+	listeners []net.Listener
+	//audit the audit file of the runner, opened by Start
+	audit *audit.Log
+	//tokens the token store of ConfigDir, see getTokens
+	tokens *token.Store
+	//webhooks the webhooks of the runner instance, created by Init
+	webhooks *webhook.Manager
+	//stopWatch stops the certificate reloader of the https server
+	stopWatch   chan struct{}
+	initialized bool
+	//listenerFailed true once a listener failed, the server is not reported up anymore
+	listenerFailed bool
+	statusMux      sync.Mutex
+}
+
+//defaultRunner the runner of the package functions (Init, AddHandler, ServerStart...),
+//it serves http.DefaultServeMux with the default state instance used by the state package functions.
+var defaultRunner = newRunner(http.DefaultServeMux, state.DefaultInstance())
+
+//configWebhooks the webhooks defined in the server config of the process, copied by the runners
+var configWebhooks = make([]webhook.Webhook, 0)
+
+//NewRunner creates a runner serving its own ServeMux and state instance with the settings of the process (ie: read from commands-runner.yml)
+func NewRunner(configDir string) *Runner {
+	r := newRunner(http.NewServeMux(), state.NewInstance())
+	r.ConfigDir = configDir
+	r.CertificatePath = filepath.Join(configDir, global.SSLCertFileName)
+	r.KeyPath = filepath.Join(configDir, global.SSLKeyFileName)
+	return r
+}
+
+//newRunner creates a runner serving the mux and the state instance with the settings of the process
+func newRunner(mux *http.ServeMux, instance *state.Instance) *Runner {
+	r := &Runner{
+		instance: instance,
+		audit:    &audit.Log{},
+		mux:      mux,
+		routes:   make([]string, 0),
+	}
+	r.loadServerConfig()
+	return r
+}
+
+//loadServerConfig sets the runner settings from the settings of the process
+func (r *Runner) loadServerConfig() {
+	r.Port = global.ServerPort
+	if r.Port == "" {
+		r.Port = global.DefaultPort
+	}
+	r.PortSSL = global.ServerPortSSL
+	if r.PortSSL == "" {
+		r.PortSSL = global.DefaultPortSSL
+	}
+	r.ClientCAPath = global.ClientCAPath
+	r.MetricsPort = global.MetricsPort
+	r.UnixSocketPath = global.UnixSocketPath
+	r.UnixSocketMode = global.UnixSocketMode
+	r.TCPDisabled = global.TCPDisabled
+	r.ShutdownTimeout = global.ShutdownTimeout
+	r.Mock = global.Mock
+	r.StorageBackend = global.StorageBackend
+	r.StoragePath = global.StoragePath
+	r.AuditMaxBackups = audit.DefaultAuditMaxBackups
+	r.Security = securityConfig
+	r.Webhooks = configWebhooks
+}
+
+//Instance returns the state instance of the runner
+func (r *Runner) Instance() *state.Instance {
+	return r.instance
+}
+
+//Handler returns the handler serving the runner routes
+func (r *Runner) Handler() http.Handler {
+	return r.mux
+}
+
+//GetRoutes returns the patterns registered with AddHandler
+func (r *Runner) GetRoutes() []string {
+	return append(make([]string, 0, len(r.routes)), r.routes...)
+}
+
+//AddHandler registers the handler on the pattern, a /cr/v1 pattern is also registered under /cr/v2 with json errors
+func (r *Runner) AddHandler(pattern string, handler http.HandlerFunc, requireAuth bool) {
+	log.Debug("Entering... AddHandler")
+	r.routes = append(r.routes, pattern)
+	log.Debug("pattern:" + pattern)
+	log.Debug("configDir:" + r.ConfigDir)
+	log.Debug("requireAuth:" + strconv.FormatBool(requireAuth))
+	handler = r.withInstance(handler)
+	if requireAuth {
+		r.mux.HandleFunc(pattern, r.secureResponse(logger.RequestHandler(r.instance.Metrics().Handler(pattern, r.audit.Handler(validateToken(r.getTokens(), handler))))))
+	} else {
+		r.mux.HandleFunc(pattern, r.secureResponse(logger.RequestHandler(r.instance.Metrics().Handler(pattern, r.audit.Handler(handler)))))
+	}
+	if strings.HasPrefix(pattern, global.BaseURL) {
+		r.addV2Handler(global.BaseURLV2+strings.TrimPrefix(pattern, global.BaseURL), handler, requireAuth)
+	}
+}
+
+//getTokens returns the token store of the runner, created on the ConfigDir set when the first protected route is added
+func (r *Runner) getTokens() *token.Store {
+	if r.tokens == nil {
+		r.tokens = token.NewStore(r.ConfigDir)
+	}
+	return r.tokens
+}
+
+//withInstance marks the requests as served by the runner state instance
+func (r *Runner) withInstance(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		handler(w, state.WithInstance(req, r.instance))
+	}
+}
+
+//addV2Handler registers the v2 pattern, the request path is rewritten to v1 before calling the handler
+func (r *Runner) addV2Handler(pattern string, handler http.HandlerFunc, requireAuth bool) {
+	log.Debug("Entering... addV2Handler")
+	r.routes = append(r.routes, pattern)
+	v1Handler := func(w http.ResponseWriter, req *http.Request) {
+		v1URL := *req.URL
+		v1URL.Path = global.ToV1Path(v1URL.Path)
+		v1URL.RawPath = global.ToV1Path(v1URL.RawPath)
+		v1Req := *req
+		v1Req.URL = &v1URL
+		handler.ServeHTTP(w, &v1Req)
+	}
+	if requireAuth {
+		r.mux.HandleFunc(pattern, r.secureResponse(logger.RequestHandler(r.instance.Metrics().Handler(pattern, apiError.Handler(r.audit.Handler(validateToken(r.getTokens(), v1Handler)))))))
+	} else {
+		r.mux.HandleFunc(pattern, r.secureResponse(logger.RequestHandler(r.instance.Metrics().Handler(pattern, apiError.Handler(r.audit.Handler(v1Handler))))))
+	}
+}
+
+/*
+Init configures the state instance of the runner with its settings, opens its storage and registers its api routes.
+A runner can be initialized only once.
+*/
+func (r *Runner) Init() error {
+	log.Debug("Entering in... Runner.Init")
+	if r.initialized {
+		return errors.New("The runner is already initialized")
+	}
+	if r.ConfigDir == "" {
+		return errors.New("The config directory of the runner is not set")
+	}
+	err := r.configureInstance()
+	if err != nil {
+		return err
+	}
+	r.initialized = true
+	r.AddHandler("/cr/v1/state", state.HandleState, true)
+	r.AddHandler("/cr/v1/state/", state.HandleState, true)
+	r.AddHandler("/cr/v1/states", state.HandleStates, true)
+	r.AddHandler("/cr/v1/engine", state.HandleEngine, true)
+	r.AddHandler("/cr/v1/events", state.HandleEvents, true)
+	r.AddHandler("/cr/v1/cr/", commandsRunner.HandleCR, true)
+	r.AddHandler("/cr/v1/status", status.HandleStatus, true)
+	r.AddHandler("/cr/v1/extension", state.HandleExtension, true)
+	r.AddHandler("/cr/v1/extensions", state.HandleExtensions, true)
+	r.AddHandler("/cr/v1/extensions/", state.HandleExtensions, true)
+	r.AddHandler("/cr/v1/uimetadata", state.HandleUIMetadata, true)
+	r.AddHandler("/cr/v1/uimetadatas", state.HandleUIMetadatas, true)
+	r.AddHandler("/cr/v1/config", config.HandleConfig, true)
+	r.AddHandler("/cr/v1/config/", config.HandleConfig, true)
+	r.AddHandler("/cr/v1/template", state.HandleTemplate, true)
+	r.AddHandler("/cr/v1/runs", state.HandleRuns, true)
+	r.AddHandler("/cr/v1/token", r.getTokens().HandleToken, true)
+	r.AddHandler("/cr/v1/tokens", r.getTokens().HandleTokens, true)
+	r.AddHandler("/cr/v1/audit", r.audit.HandleAudit, true)
+	r.AddHandler("/cr/v1/webhooks", r.webhooks.HandleWebhooks, true)
+	r.AddHandler("/metrics", r.instance.Metrics().HandleMetrics, true)
+	r.AddHandler("/healthz", health.HandleHealthz, false)
+	r.AddHandler("/readyz", health.HandleReadyz, false)
+	r.AddHandler("/cr/v1/openapi.json", openapi.HandleOpenAPI, false)
+	r.instance.Metrics().NewGaugeFunc("cr_registered_extensions", "Number of registered extensions", func() float64 {
+		extensions, err := r.instance.ListExtensions("", false)
+		if err != nil {
+			log.Error(err.Error())
+			return 0
+		}
+		return float64(len(extensions.Extensions))
+	})
+	return nil
+}
+
+//configureInstance sets the config directory, the extensions settings and the mock flag of the runner instance,
+//opens the storage of the runner and registers the webhooks of the config directory as event listener
+func (r *Runner) configureInstance() error {
+	r.instance.SetConfigDir(r.ConfigDir)
+	if r.ExtensionsPath != "" {
+		r.instance.SetExtensionsEmbeddedFile(r.ExtensionsEmbeddedFile)
+		err := r.instance.SetEmbeddedExtensionsRepositoryPath(r.EmbeddedExtensionsRepositoryPath)
+		if err != nil {
+			return err
+		}
+		r.instance.SetExtensionsPath(r.ExtensionsPath)
+		r.instance.SetExtensionsLogsPath(r.ExtensionsLogsPath)
+	} else if r.instance != state.DefaultInstance() {
+		r.instance.CopyExtensionsSettings(state.DefaultInstance())
+	}
+	r.instance.SetMock(r.Mock)
+	r.webhooks = webhook.NewManager(r.instance)
+	err := r.webhooks.SetConfigWebhooks(r.Webhooks)
+	if err != nil {
+		return err
+	}
+	s, err := openStorage(r.StorageBackend, r.StoragePath, r.ConfigDir)
+	if err != nil {
+		return err
+	}
+	storage.SetStorage(r.instance, s)
+	r.storage = s
+	log.Info("Storage: " + r.StorageBackend)
+	r.webhooks.Start()
+	return nil
+}
+
+//RegisterExtensions unregisters the embedded extensions not listed anymore and registers the embedded extensions,
+//the running states of a re-registered extension are set to FAILED.
+func (r *Runner) RegisterExtensions() error {
+	err := r.instance.DeleteFormerEmbeddedExtensions()
+	if err != nil {
+		return err
+	}
+	return r.instance.RegisterEmbededExtensions(true, true)
+}
+
+//Start opens the listeners and serves the runner routes, the servers already started are closed if a listener can not be opened
+func (r *Runner) Start() error {
+	if r.ConfigDir != "" {
+		r.audit.Open(r.ConfigDir, r.AuditMaxBackups)
+	}
+	if r.UnixSocketPath != "" {
+		err := r.startUnixSocketServer()
+		if err != nil {
+			return err
+		}
+	}
+	if r.TCPDisabled {
+		log.Info("TCP disabled, the server is only reachable through " + r.UnixSocketPath)
+		r.setStatusUp()
+		return nil
+	}
+	server := &http.Server{Addr: ":" + r.Port, Handler: r.mux}
+	r.servers = append(r.servers, server)
+	//Listen before serving to report the server up only once the ports are bound
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		r.closeServers()
+		return errors.New("Listen error: " + err.Error())
+	}
+	r.listeners = append(r.listeners, listener)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Fatal(rec)
+				logger.LogFile.Close()
+			}
+		}()
+		log.Info("http://localhost:" + r.Port)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Serve error: %v", err)
+		}
+	}()
+	if r.MetricsPort != "" {
+		err := r.startMetricsServer()
+		if err != nil {
+			return err
+		}
+	}
+	_, errCertPath := os.Stat(r.CertificatePath)
+	_, errKeyPath := os.Stat(r.KeyPath)
+	if errCertPath == nil && errKeyPath == nil {
+		reloader, err := newCertificateReloader(r.CertificatePath, r.KeyPath)
+		if err != nil {
+			r.closeServers()
+			return errors.New("Certificate error: " + err.Error())
+		}
+		r.stopWatch = make(chan struct{})
+		go reloader.watch(CertificateReloadInterval, r.stopWatch)
+		tlsConfig, err := r.Security.getTLSConfig(reloader, r.ClientCAPath)
+		if err != nil {
+			r.closeServers()
+			return errors.New("TLS config error: " + err.Error())
+		}
+		if r.ClientCAPath != "" {
+			log.Info("Client certificate authentication enabled with CA " + r.ClientCAPath)
+		}
+		serverSSL := &http.Server{
+			Addr:      ":" + r.PortSSL,
+			Handler:   r.mux,
+			TLSConfig: tlsConfig,
+		}
+		r.servers = append(r.servers, serverSSL)
+		listenerSSL, err := net.Listen("tcp", serverSSL.Addr)
+		if err != nil {
+			r.closeServers()
+			return errors.New("Listen TLS error: " + err.Error())
+		}
+		r.listeners = append(r.listeners, listenerSSL)
+		go func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Fatal(rec)
+					logger.LogFile.Close()
+				}
+			}()
+			log.Info("https://localhost:" + r.PortSSL)
+			if err := serverSSL.ServeTLS(listenerSSL, "", ""); err != nil && err != http.ErrServerClosed {
+				log.Errorf("ServeTLS error: %v", err)
+				r.setStatusFailed("TLS server failed: " + err.Error())
+			}
+		}()
+	} else {
+		log.Info("SSL not enabled as " + r.CertificatePath + " or " + r.KeyPath + " is not present.")
+	}
+	r.setStatusUp()
+	return nil
+}
+
+//setStatusUp reports the server up unless a listener failed
+func (r *Runner) setStatusUp() {
+	r.statusMux.Lock()
+	defer r.statusMux.Unlock()
+	if !r.listenerFailed {
+		status.SetStatus(status.CMStatus, health.StatusUp)
+	}
+}
+
+//setStatusFailed records the listener failure in the server status, it is never overwritten by setStatusUp
+func (r *Runner) setStatusFailed(reason string) {
+	r.statusMux.Lock()
+	defer r.statusMux.Unlock()
+	r.listenerFailed = true
+	status.SetStatus(status.CMStatus, reason)
+}
+
+//startUnixSocketServer serves the api on the unix socket, the access is controlled by the socket permissions
+func (r *Runner) startUnixSocketServer() error {
+	server := &http.Server{Handler: r.mux}
+	r.servers = append(r.servers, server)
+	listener, err := listenUnixSocket(r.UnixSocketPath, r.UnixSocketMode)
+	if err != nil {
+		r.closeServers()
+		return errors.New("Unix socket listen error: " + err.Error())
+	}
+	r.listeners = append(r.listeners, listener)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Fatal(rec)
+				logger.LogFile.Close()
+			}
+		}()
+		log.Info("unix://" + r.UnixSocketPath)
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Unix socket serve error: %v", err)
+		}
+	}()
+	return nil
+}
+
+//closeServers closes the servers and the listeners already opened when Start fails, Start can then be called again.
+//The listeners are closed as well because a server goroutine may not serve its listener yet.
+func (r *Runner) closeServers() {
+	r.stopCertificateWatch()
+	for _, listener := range r.listeners {
+		listener.Close()
+	}
+	r.listeners = nil
+	for _, server := range r.servers {
+		err := server.Close()
+		if err != nil {
+			log.Error("Server " + server.Addr + " close error: " + err.Error())
+		}
+	}
+	r.servers = nil
+	r.audit.Close()
+}
+
+//stopCertificateWatch stops the certificate reloader, if started
+func (r *Runner) stopCertificateWatch() {
+	if r.stopWatch != nil {
+		close(r.stopWatch)
+		r.stopWatch = nil
+	}
+}
+
+//startMetricsServer serves the metrics without authentication on the metrics port, the servers already started are closed if the port can not be bound
+func (r *Runner) startMetricsServer() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", r.instance.Metrics().HandleMetrics)
+	server := &http.Server{Addr: ":" + r.MetricsPort, Handler: mux}
+	r.servers = append(r.servers, server)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		r.closeServers()
+		return errors.New("Metrics listen error: " + err.Error())
+	}
+	r.listeners = append(r.listeners, listener)
+	go func() {
+		log.Info("http://localhost:" + r.MetricsPort + "/metrics")
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("Metrics serve error: %v", err)
+		}
+	}()
+	return nil
+}
+
+/*
+Shutdown stops the engine of the runner: no new execution is accepted, the running states get until stateCtx deadline
+to complete before being cancelled. Then the servers of the runner are stopped within ctx and its storage is closed.
+*/
+func (r *Runner) Shutdown(stateCtx context.Context, ctx context.Context) error {
+	log.Debug("Entering in... Runner.Shutdown")
+	errShutdown := r.instance.Shutdown(stateCtx)
+	for _, server := range r.servers {
+		err := server.Shutdown(ctx)
+		if err != nil {
+			log.Error("Server " + server.Addr + " shutdown error: " + err.Error())
+		}
+	}
+	r.servers = nil
+	r.listeners = nil
+	r.stopCertificateWatch()
+	r.audit.Close()
+	if r.storage != nil {
+		err := r.storage.Close()
+		if err != nil && errShutdown == nil {
+			errShutdown = err
+		}
+		r.storage = nil
+	}
+	return errShutdown
+}
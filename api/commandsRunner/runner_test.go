@@ -0,0 +1,415 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package commandsRunner
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/audit"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/storage"
+	"github.com/IBM/commands-runner/api/commandsRunner/token"
+	"github.com/IBM/commands-runner/api/commandsRunner/webhook"
+)
+
+//TestRunners checks that the runners of a process serve their own routes
+func TestRunners(t *testing.T) {
+	t.Log("Entering... TestRunners")
+	configDir, err := ioutil.TempDir("", "TestRunners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	r1 := NewRunner(configDir)
+	err = r1.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := NewRunner(configDir)
+	err = r2.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r2.Init(); err == nil {
+		t.Error("Expected an error when a runner is initialized twice")
+	}
+	r1.AddHandler("/r1", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("r1"))
+	}, false)
+	server1 := httptest.NewServer(r1.Handler())
+	defer server1.Close()
+	server2 := httptest.NewServer(r2.Handler())
+	defer server2.Close()
+	get := func(url string) int {
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if code := get(server1.URL + "/cr/v1/openapi.json"); code != http.StatusOK {
+		t.Errorf("Expected %d from the first runner, got %d", http.StatusOK, code)
+	}
+	if code := get(server2.URL + "/cr/v1/openapi.json"); code != http.StatusOK {
+		t.Errorf("Expected %d from the second runner, got %d", http.StatusOK, code)
+	}
+	if code := get(server1.URL + "/r1"); code != http.StatusOK {
+		t.Errorf("Expected %d for a route of the first runner, got %d", http.StatusOK, code)
+	}
+	if code := get(server2.URL + "/r1"); code != http.StatusNotFound {
+		t.Errorf("Expected %d for a route of the first runner on the second runner, got %d", http.StatusNotFound, code)
+	}
+	if len(r1.GetRoutes()) != len(r2.GetRoutes())+1 {
+		t.Errorf("Expected %d routes for the first runner, got %d", len(r2.GetRoutes())+1, len(r1.GetRoutes()))
+	}
+	err = r1.Shutdown(context.Background(), context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r2.Shutdown(context.Background(), context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//newTestRunner creates a runner with its own config directory, extensions path and token
+func newTestRunner(t *testing.T, name string, mock bool, tokenValue string) *Runner {
+	configDir, err := ioutil.TempDir("", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(configDir, global.TokenFileName), []byte(tokenValue), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRunner(configDir)
+	r.EmbeddedExtensionsRepositoryPath = configDir
+	r.ExtensionsPath = filepath.Join(configDir, "extensions")
+	r.ExtensionsLogsPath = filepath.Join(configDir, "logs")
+	r.Mock = mock
+	r.StorageBackend = storage.FileBackend
+	err = r.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+//TestRunnersConfigDirs checks that runners with different config directories serve at the same time with their own settings
+func TestRunnersConfigDirs(t *testing.T) {
+	t.Log("Entering... TestRunnersConfigDirs")
+	r1 := newTestRunner(t, "TestRunnersConfigDirs1", true, "token1")
+	defer os.RemoveAll(r1.ConfigDir)
+	r2 := newTestRunner(t, "TestRunnersConfigDirs2", false, "token2")
+	defer os.RemoveAll(r2.ConfigDir)
+	if r1.Instance().GetExtensionsPath() == r2.Instance().GetExtensionsPath() {
+		t.Error("Expected a different extensions path for each runner, got " + r1.Instance().GetExtensionsPath())
+	}
+	if !r1.Instance().GetMock() || r2.Instance().GetMock() {
+		t.Error("Expected the mock flag of each runner")
+	}
+	//The audit file is opened by Start
+	r1.audit.Open(r1.ConfigDir, audit.DefaultAuditMaxBackups)
+	r2.audit.Open(r2.ConfigDir, audit.DefaultAuditMaxBackups)
+	server1 := httptest.NewServer(r1.Handler())
+	defer server1.Close()
+	server2 := httptest.NewServer(r2.Handler())
+	defer server2.Close()
+	do := func(method string, url string, tokenValue string, body string) int {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", token.BearerScheme+tokenValue)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	//Each runner authenticates with the token of its config directory
+	if code := do(http.MethodGet, server1.URL+"/cr/v1/extensions", "token1", ""); code != http.StatusOK {
+		t.Errorf("Expected %d with the token of the first runner, got %d", http.StatusOK, code)
+	}
+	if code := do(http.MethodGet, server2.URL+"/cr/v1/extensions", "token2", ""); code != http.StatusOK {
+		t.Errorf("Expected %d with the token of the second runner, got %d", http.StatusOK, code)
+	}
+	if code := do(http.MethodGet, server2.URL+"/cr/v1/extensions", "token1", ""); code != http.StatusForbidden {
+		t.Errorf("Expected %d with the token of the first runner on the second runner, got %d", http.StatusForbidden, code)
+	}
+	//A webhook registered on a runner is stored in its config directory
+	if code := do(http.MethodPost, server1.URL+"/cr/v1/webhooks", "token1", `{"name":"hook","url":"https://example.com/hook"}`); code != http.StatusOK {
+		t.Errorf("Expected %d for the webhook creation, got %d", http.StatusOK, code)
+	}
+	if _, err := os.Stat(filepath.Join(r1.ConfigDir, global.WebhooksFileName)); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(r2.ConfigDir, global.WebhooksFileName)); !os.IsNotExist(err) {
+		t.Error("Expected no webhook file in the config directory of the second runner")
+	}
+	//The request is recorded in the audit file of the runner which served it
+	records, err := r1.audit.GetRecords(audit.Filter{Endpoint: "/cr/v1/webhooks"})
+	if err != nil || len(records) != 1 {
+		t.Errorf("Expected the webhook creation in the audit of the first runner, got %v %v", records, err)
+	}
+	records, err = r2.audit.GetRecords(audit.Filter{})
+	if err != nil || len(records) != 0 {
+		t.Errorf("Expected no record in the audit of the second runner, got %v %v", records, err)
+	}
+	//The request is counted in the metrics of the runner which served it
+	if count := r1.Instance().Metrics().HTTPRequests.Get("/cr/v1/webhooks", http.MethodPost, "200"); count != 1 {
+		t.Errorf("Expected the webhook creation in the metrics of the first runner, got %v", count)
+	}
+	if count := r2.Instance().Metrics().HTTPRequests.Get("/cr/v1/webhooks", http.MethodPost, "200"); count != 0 {
+		t.Errorf("Expected no webhook creation in the metrics of the second runner, got %v", count)
+	}
+	err = r1.Shutdown(context.Background(), context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The second runner still serves once the first one is shutdown
+	if code := do(http.MethodGet, server2.URL+"/cr/v1/extensions", "token2", ""); code != http.StatusOK {
+		t.Errorf("Expected %d from the second runner, got %d", http.StatusOK, code)
+	}
+	err = r2.Shutdown(context.Background(), context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//freePort returns a tcp port not in use
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+//TestStartTLSListenerFailure checks that Start fails and closes the http server when the https port can not be bound
+func TestStartTLSListenerFailure(t *testing.T) {
+	t.Log("Entering... TestStartTLSListenerFailure")
+	configDir, err := ioutil.TempDir("", "TestStartTLSListenerFailure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	r := NewRunner(configDir)
+	writeTestCertificate(t, r.CertificatePath, r.KeyPath, "cr")
+	r.Port = freePort(t)
+	r.PortSSL = strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+	r.MetricsPort = ""
+	r.UnixSocketPath = ""
+	r.TCPDisabled = false
+	err = r.Start()
+	if err == nil {
+		r.Shutdown(context.Background(), context.Background())
+		t.Fatal("Expected a TLS listen error")
+	}
+	if len(r.servers) != 0 {
+		t.Errorf("Expected no server left, got %d", len(r.servers))
+	}
+	conn, err := net.Dial("tcp", "localhost:"+r.Port)
+	if err == nil {
+		conn.Close()
+		t.Error("Expected the http port to be closed")
+	}
+}
+
+//TestStartTCPListenerFailure checks that the unix socket server is closed when the http port can not be bound
+func TestStartTCPListenerFailure(t *testing.T) {
+	t.Log("Entering... TestStartTCPListenerFailure")
+	configDir, err := ioutil.TempDir("", "TestStartTCPListenerFailure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	r := NewRunner(configDir)
+	r.Port = strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+	r.MetricsPort = ""
+	r.UnixSocketPath = filepath.Join(configDir, "cr.sock")
+	r.UnixSocketMode = 0600
+	r.TCPDisabled = false
+	err = r.Start()
+	if err == nil {
+		r.Shutdown(context.Background(), context.Background())
+		t.Fatal("Expected a listen error")
+	}
+	if len(r.servers) != 0 {
+		t.Errorf("Expected no server left, got %d", len(r.servers))
+	}
+	conn, err := net.Dial("unix", r.UnixSocketPath)
+	if err == nil {
+		conn.Close()
+		t.Error("Expected the unix socket to be closed")
+	}
+}
+
+//TestStartMetricsListenerFailure checks that Start fails and closes the http server when the metrics port can not be bound
+func TestStartMetricsListenerFailure(t *testing.T) {
+	t.Log("Entering... TestStartMetricsListenerFailure")
+	configDir, err := ioutil.TempDir("", "TestStartMetricsListenerFailure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	r := NewRunner(configDir)
+	r.Port = freePort(t)
+	r.MetricsPort = strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+	r.UnixSocketPath = ""
+	r.TCPDisabled = false
+	err = r.Start()
+	if err == nil {
+		r.Shutdown(context.Background(), context.Background())
+		t.Fatal("Expected a metrics listen error")
+	}
+	if len(r.servers) != 0 {
+		t.Errorf("Expected no server left, got %d", len(r.servers))
+	}
+	conn, err := net.Dial("tcp", "localhost:"+r.Port)
+	if err == nil {
+		conn.Close()
+		t.Error("Expected the http port to be closed")
+	}
+}
+
+func TestStartAuditFile(t *testing.T) {
+	t.Log("Entering... TestStartAuditFile")
+	configDir, err := ioutil.TempDir("", "TestStartAuditFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	r := NewRunner(configDir)
+	r.Port = freePort(t)
+	r.MetricsPort = ""
+	r.UnixSocketPath = ""
+	r.TCPDisabled = false
+	err = r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.audit.Filename() != filepath.Join(configDir, global.AuditLogFileName) {
+		t.Errorf("Expected the audit file in %s, got %s", configDir, r.audit.Filename())
+	}
+	err = r.Shutdown(context.Background(), context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.audit.Filename() != "" {
+		t.Error("Expected the audit file to be closed by Shutdown")
+	}
+}
+
+//TestRunnersSecurity checks that each runner sets its own CORS headers
+func TestRunnersSecurity(t *testing.T) {
+	t.Log("Entering... TestRunnersSecurity")
+	configDir, err := ioutil.TempDir("", "TestRunnersSecurity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	r1 := NewRunner(configDir)
+	r1.Security.CORSAllowedOrigins = []string{"https://ui.example.com"}
+	err = r1.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Shutdown(context.Background(), context.Background())
+	r2 := NewRunner(configDir)
+	err = r2.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Shutdown(context.Background(), context.Background())
+	origins := map[*Runner]string{r1: "https://ui.example.com", r2: "*"}
+	for r, expected := range origins {
+		req := httptest.NewRequest("GET", "/cr/v1/openapi.json", nil)
+		req.Header.Set("Origin", "https://ui.example.com")
+		rr := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rr, req)
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != expected {
+			t.Errorf("Expected allowed origin %q, got %q", expected, got)
+		}
+	}
+}
+
+//TestRunnersWebhooks checks that each runner has its own webhooks defined in the server config
+func TestRunnersWebhooks(t *testing.T) {
+	t.Log("Entering... TestRunnersWebhooks")
+	configDir, err := ioutil.TempDir("", "TestRunnersWebhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	r1 := NewRunner(configDir)
+	r1.Webhooks = []webhook.Webhook{{Name: "config", URL: "https://example.com/hook", Secret: "secret"}}
+	err = r1.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Shutdown(context.Background(), context.Background())
+	r2 := NewRunner(configDir)
+	err = r2.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Shutdown(context.Background(), context.Background())
+	//The webhooks defined in the server config of a runner are not shared with the other runners
+	webhooks, err := r1.webhooks.ListWebhooks()
+	if err != nil || len(webhooks) != 1 || !webhooks[0].ReadOnly {
+		t.Errorf("Expected the config webhook on the first runner, got %v %v", webhooks, err)
+	}
+	webhooks, err = r2.webhooks.ListWebhooks()
+	if err != nil || len(webhooks) != 0 {
+		t.Errorf("Expected no webhook on the second runner, got %v %v", webhooks, err)
+	}
+	//A runner with an invalid config webhook can not be initialized
+	r3 := NewRunner(configDir)
+	r3.Webhooks = []webhook.Webhook{{Name: "config", URL: "https://example.com/hook"}}
+	if r3.Init() == nil {
+		t.Error("Expected an error for a config webhook without secret")
+	}
+}
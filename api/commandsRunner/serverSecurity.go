@@ -0,0 +1,273 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package commandsRunner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+//CertificateReloadInterval how often the certificate and key files are checked for changes
+var CertificateReloadInterval = 10 * time.Second
+
+//SecurityConfig the CORS, security headers and TLS settings of commands-runner.yml
+type SecurityConfig struct {
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+	CORSAllowedMethods []string `yaml:"cors_allowed_methods"`
+	CORSAllowedHeaders []string `yaml:"cors_allowed_headers"`
+	//TLSMinVersion 1.0, 1.1, 1.2 or 1.3
+	TLSMinVersion string `yaml:"tls_min_version"`
+	//TLSCipherSuites names of the allowed cipher suites (ie: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256), empty for the Go defaults
+	TLSCipherSuites []string `yaml:"tls_cipher_suites"`
+	//HSTSMaxAge Strict-Transport-Security max-age in seconds, 0 disables the header
+	HSTSMaxAge            int  `yaml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool `yaml:"hsts_include_subdomains"`
+}
+
+//securityConfig the security settings of the process, copied by the runners
+var securityConfig = defaultSecurityConfig()
+
+func defaultSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedMethods: []string{"POST", "GET", "OPTIONS", "PUT", "DELETE"},
+		CORSAllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Force", "RunningToFailed", global.IfMatchHeader},
+	}
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_AES_128_CBC_SHA":                  tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":                  tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":               tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":               tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":          tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":        tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+//loadSecurityConfig reads the security settings of the commands-runner.yml content, missing settings keep their default value.
+func loadSecurityConfig(raw []byte) error {
+	log.Debug("Entering in... loadSecurityConfig")
+	cfg := defaultSecurityConfig()
+	err := yaml.Unmarshal(raw, &cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.TLSMinVersion != "" {
+		if _, ok := tlsVersions[cfg.TLSMinVersion]; !ok {
+			return errors.New("Invalid tls_min_version: " + cfg.TLSMinVersion + ", expected 1.0, 1.1, 1.2 or 1.3")
+		}
+	}
+	for _, name := range cfg.TLSCipherSuites {
+		if _, ok := cipherSuites[name]; !ok {
+			return errors.New("Unsupported cipher suite: " + name)
+		}
+	}
+	if cfg.HSTSMaxAge < 0 {
+		return errors.New("Invalid hsts_max_age: " + strconv.Itoa(cfg.HSTSMaxAge))
+	}
+	securityConfig = cfg
+	return nil
+}
+
+//setupResponse sets the CORS and security headers
+func (cfg *SecurityConfig) setupResponse(w *http.ResponseWriter, req *http.Request) {
+	header := (*w).Header()
+	origin := req.Header.Get("Origin")
+	allowAll := false
+	for _, allowedOrigin := range cfg.CORSAllowedOrigins {
+		if allowedOrigin == "*" {
+			header.Set("Access-Control-Allow-Origin", "*")
+			allowAll = true
+			break
+		}
+		if origin != "" && allowedOrigin == origin {
+			header.Set("Access-Control-Allow-Origin", origin)
+			break
+		}
+	}
+	//The response depends on the origin, a cache must not serve it to another origin
+	if !allowAll {
+		header.Add("Vary", "Origin")
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(cfg.CORSAllowedMethods, ", "))
+	header.Set("Access-Control-Allow-Headers", strings.Join(cfg.CORSAllowedHeaders, ", "))
+	header.Set("Access-Control-Expose-Headers", logger.RequestIDHeader+", "+state.TotalCountHeader+", "+global.ETagHeader)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("X-Frame-Options", "DENY")
+	if req.TLS != nil && cfg.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		header.Set("Strict-Transport-Security", hsts)
+	}
+}
+
+//getTLSConfig returns the TLS config of the https server
+func (cfg *SecurityConfig) getTLSConfig(reloader *certificateReloader, clientCAPath string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.TLSMinVersion != "" {
+		tlsConfig.MinVersion = tlsVersions[cfg.TLSMinVersion]
+	}
+	for _, name := range cfg.TLSCipherSuites {
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, cipherSuites[name])
+	}
+	//The client certificate is optional as the client can still authenticate with a token.
+	if clientCAPath != "" {
+		caPEM, err := ioutil.ReadFile(clientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("No certificate found in " + clientCAPath)
+		}
+		tlsConfig.ClientCAs = caPool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+//certificateReloader serves the server certificate and reloads it when the files change or on SIGHUP
+type certificateReloader struct {
+	certPath string
+	keyPath  string
+	mux      sync.RWMutex
+	cert     *tls.Certificate
+	certInfo os.FileInfo
+	keyInfo  os.FileInfo
+}
+
+func newCertificateReloader(certPath string, keyPath string) (*certificateReloader, error) {
+	reloader := &certificateReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+	err := reloader.reload()
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+//reload loads the certificate and key, the current certificate is kept if they are invalid
+func (cr *certificateReloader) reload() error {
+	certInfo, err := os.Stat(cr.certPath)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(cr.keyPath)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certPath, cr.keyPath)
+	if err != nil {
+		return err
+	}
+	cr.mux.Lock()
+	defer cr.mux.Unlock()
+	cr.cert = &cert
+	cr.certInfo = certInfo
+	cr.keyInfo = keyInfo
+	return nil
+}
+
+//hasChanged returns true if the certificate or the key file changed since the last load
+func (cr *certificateReloader) hasChanged() bool {
+	certInfo, err := os.Stat(cr.certPath)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(cr.keyPath)
+	if err != nil {
+		return false
+	}
+	cr.mux.RLock()
+	defer cr.mux.RUnlock()
+	return !certInfo.ModTime().Equal(cr.certInfo.ModTime()) || certInfo.Size() != cr.certInfo.Size() ||
+		!keyInfo.ModTime().Equal(cr.keyInfo.ModTime()) || keyInfo.Size() != cr.keyInfo.Size()
+}
+
+//GetCertificate returns the current certificate, used as tls.Config.GetCertificate
+func (cr *certificateReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mux.RLock()
+	defer cr.mux.RUnlock()
+	return cr.cert, nil
+}
+
+//watch reloads the certificate on SIGHUP or when the files change until stop is closed
+func (cr *certificateReloader) watch(interval time.Duration, stop <-chan struct{}) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sighup:
+			log.Info("SIGHUP received, reloading " + cr.certPath + " and " + cr.keyPath)
+		case <-ticker.C:
+			if !cr.hasChanged() {
+				continue
+			}
+			log.Info("Certificate change detected, reloading " + cr.certPath + " and " + cr.keyPath)
+		}
+		err := cr.reload()
+		if err != nil {
+			log.Error("Certificate reload failed, the current certificate is kept: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,208 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package commandsRunner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, certPath string, keyPath string, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSecurityConfig(t *testing.T) {
+	t.Log("Entering... TestLoadSecurityConfig")
+	defer func() { securityConfig = defaultSecurityConfig() }()
+	err := loadSecurityConfig([]byte("tls_min_version: \"1.4\"\n"))
+	if err == nil {
+		t.Error("Expected an error for an invalid tls version")
+	}
+	err = loadSecurityConfig([]byte("tls_cipher_suites: [TLS_UNKNOWN]\n"))
+	if err == nil {
+		t.Error("Expected an error for an unknown cipher suite")
+	}
+	err = loadSecurityConfig([]byte(`cors_allowed_origins: [https://ui.example.com]
+tls_min_version: "1.2"
+tls_cipher_suites: [TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256]
+hsts_max_age: 31536000
+hsts_include_subdomains: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(securityConfig.CORSAllowedMethods) == 0 {
+		t.Error("Default CORS methods not kept")
+	}
+	tests := map[string]string{
+		"https://ui.example.com":   "https://ui.example.com",
+		"https://evil.example.com": "",
+	}
+	for origin, expected := range tests {
+		req := httptest.NewRequest("GET", "/cr/v1/states", nil)
+		req.Header.Set("Origin", origin)
+		req.TLS = &tls.ConnectionState{}
+		rr := httptest.NewRecorder()
+		var w http.ResponseWriter = rr
+		securityConfig.setupResponse(&w, req)
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != expected {
+			t.Errorf("Expected allowed origin %q for %s, got %q", expected, origin, got)
+		}
+		if got := rr.Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+			t.Errorf("Unexpected HSTS header %q", got)
+		}
+		if rr.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Error("X-Content-Type-Options not set")
+		}
+		if got := rr.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("Expected Vary Origin for %s, got %q", origin, got)
+		}
+	}
+	dir, err := ioutil.TempDir("", "TestLoadSecurityConfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath := filepath.Join(dir, "cr-cert.crt")
+	keyPath := filepath.Join(dir, "cr-key.pem")
+	writeTestCertificate(t, certPath, keyPath, "first")
+	reloader, err := newCertificateReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig, err := securityConfig.getTLSConfig(reloader, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 || len(tlsConfig.CipherSuites) != 1 {
+		t.Errorf("Unexpected TLS config min version %x, cipher suites %v", tlsConfig.MinVersion, tlsConfig.CipherSuites)
+	}
+}
+
+//TestCertificateWatchStop checks that the certificate reloader returns once stopped
+func TestCertificateWatchStop(t *testing.T) {
+	t.Log("Entering... TestCertificateWatchStop")
+	dir, err := ioutil.TempDir("", "TestCertificateWatchStop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath := filepath.Join(dir, "cr-cert.crt")
+	keyPath := filepath.Join(dir, "cr-key.pem")
+	writeTestCertificate(t, certPath, keyPath, "cr")
+	reloader, err := newCertificateReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		reloader.watch(time.Millisecond, stop)
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("The certificate reloader is still running")
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	t.Log("Entering... TestCertificateReload")
+	dir, err := ioutil.TempDir("", "TestCertificateReload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath := filepath.Join(dir, "cr-cert.crt")
+	keyPath := filepath.Join(dir, "cr-key.pem")
+	writeTestCertificate(t, certPath, keyPath, "first")
+	reloader, err := newCertificateReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloader.hasChanged() {
+		t.Error("Certificate not expected to have changed")
+	}
+	writeTestCertificate(t, certPath, keyPath, "second")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certPath, future, future)
+	if !reloader.hasChanged() {
+		t.Fatal("Certificate change not detected")
+	}
+	err = reloader.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := reloader.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.Subject.CommonName != "second" {
+		t.Error("Expected the reloaded certificate, got " + leaf.Subject.CommonName)
+	}
+	//An invalid key keeps the current certificate
+	ioutil.WriteFile(keyPath, []byte("invalid"), 0600)
+	if reloader.reload() == nil {
+		t.Error("Expected an error for an invalid key")
+	}
+	cert, _ = reloader.GetCertificate(nil)
+	if cert == nil {
+		t.Error("Current certificate lost")
+	}
+}
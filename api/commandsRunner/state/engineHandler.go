@@ -21,11 +21,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 )
 
@@ -40,7 +44,7 @@ func HandleEngine(w http.ResponseWriter, req *http.Request) {
 	m, errRQ := url.ParseQuery(req.URL.RawQuery)
 	if errRQ != nil {
 		logger.AddCallerField().Error(errRQ.Error())
-		http.Error(w, errRQ.Error(), 500)
+		apiError.HTTPError(w, req, errRQ, 500)
 		return
 	}
 	//Retreive the new status
@@ -98,24 +102,26 @@ func HandleEngine(w http.ResponseWriter, req *http.Request) {
 
 /*
 Start the engine
-URL: /cr/v1/egine?action=<action>&from_state=<from_state>&to_state=<to_state>
+URL: /cr/v1/egine?action=<action>&from_state=<from_state>&to_state=<to_state>&overlay=<overlay>
 Method: PUT
 action: 'start'
 first-state default = first state
 to-state default = last staten
+overlay default = server config overlay
 */
 func PutStartEngineEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in PutStartEngineEndpoint")
+	inst := GetInstance(req)
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	running, errRunning := sm.IsRunning()
 	if errRunning != nil {
 		logger.AddCallerField().Error(errRunning.Error())
-		http.Error(w, errRunning.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errRunning, http.StatusBadRequest)
 		return
 	}
 	if running {
@@ -123,6 +129,11 @@ func PutStartEngineEndpoint(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusConflict)
 		return
 	}
+	if inst.IsShuttingDown() {
+		logger.AddCallerField().Error(ErrShuttingDown.Error())
+		apiError.HTTPError(w, req, ErrShuttingDown, http.StatusServiceUnavailable)
+		return
+	}
 	//Retreive the new status
 	fromState := FirstState
 	fromFound, okFrom := m["from-state"]
@@ -136,6 +147,30 @@ func PutStartEngineEndpoint(w http.ResponseWriter, req *http.Request) {
 		log.Debugf("To State:%s", toFound)
 		toState = toFound[0]
 	}
+	//Retrieve the config overlay for this run, the server default is used if not provided
+	configOverlay := ""
+	overlayFound, okOverlay := m["overlay"]
+	if okOverlay {
+		log.Debugf("Overlay:%s", overlayFound)
+		configOverlay = overlayFound[0]
+		if err := global.ValidateConfigOverlay(configOverlay); err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
+			return
+		}
+		overlayPath := filepath.Join(inst.GetRootExtensionPath(inst.GetExtensionsPath(), sm.ExtensionName), global.GetConfigOverlayFileName(configOverlay))
+		if _, err := os.Stat(overlayPath); err != nil {
+			logger.AddCallerField().Error(err.Error())
+			http.Error(w, "Config overlay "+configOverlay+" not found: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	errOverlay := sm.SetConfigOverlay(configOverlay)
+	if errOverlay != nil {
+		logger.AddCallerField().Error(errOverlay.Error())
+		apiError.HTTPError(w, req, errOverlay, http.StatusBadRequest)
+		return
+	}
 	timeNow := time.Now().UTC()
 	time.Sleep(1 * time.Second)
 	go sm.Execute(fromState, toState, nil, nil)
@@ -144,7 +179,7 @@ func PutStartEngineEndpoint(w http.ResponseWriter, req *http.Request) {
 			timeStart, errTime := time.Parse(time.UnixDate, sm.StartTime)
 			if errTime != nil {
 				logger.AddCallerField().Error(errTime.Error())
-				http.Error(w, errTime.Error(), http.StatusBadRequest)
+				apiError.HTTPError(w, req, errTime, http.StatusBadRequest)
 				return
 			}
 			log.Debug("Waiting sm.StartTime:" + sm.StartTime)
@@ -168,14 +203,14 @@ func PutResetEngineEndpoint(w http.ResponseWriter, req *http.Request) {
 	sm, _, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	log.Debug(req.URL.Path)
 	errReset := sm.ResetEngine()
 	if errReset != nil {
 		logger.AddCallerField().Error(errReset.Error())
-		http.Error(w, errReset.Error(), 500)
+		apiError.HTTPError(w, req, errReset, 500)
 		return
 	}
 }
@@ -191,14 +226,14 @@ func PutResetEngineExecutionInfoEndpoint(w http.ResponseWriter, req *http.Reques
 	sm, _, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	log.Debug(req.URL.Path)
 	errReset := sm.ResetEngineExecutionInfo()
 	if errReset != nil {
 		logger.AddCallerField().Error(errReset.Error())
-		http.Error(w, errReset.Error(), 500)
+		apiError.HTTPError(w, req, errReset, 500)
 		return
 	}
 }
@@ -213,14 +248,14 @@ func GetIsRunningEndpoint(w http.ResponseWriter, req *http.Request) {
 	sm, _, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		log.Debug(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	log.Debug(req.URL.Path)
 	running, err := sm.IsRunning()
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), 500)
+		apiError.HTTPError(w, req, err, 500)
 		return
 	}
 	if running {
@@ -231,7 +266,8 @@ func GetIsRunningEndpoint(w http.ResponseWriter, req *http.Request) {
 }
 
 func GetMockEndpoint(w http.ResponseWriter, req *http.Request) {
-	data := GetMock()
+	inst := GetInstance(req)
+	data := inst.GetMock()
 	mock := &Mock{
 		Mock: data,
 	}
@@ -240,11 +276,12 @@ func GetMockEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := enc.Encode(mock)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
 }
 
 func SetMockEndpoint(w http.ResponseWriter, req *http.Request) {
+	inst := GetInstance(req)
 	query, _ := url.ParseQuery(req.URL.RawQuery)
 	log.Debugf("Query: %s", query)
 
@@ -257,7 +294,7 @@ func SetMockEndpoint(w http.ResponseWriter, req *http.Request) {
 	mockBool, err := strconv.ParseBool(mock)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	}
-	SetMock(mockBool)
+	inst.SetMock(mockBool)
 }
@@ -32,7 +32,7 @@ func TestEngineStartPUT(t *testing.T) {
 	//log.SetLevel(log.DebugLevel)
 	t.Log("Entering................. TestEngineStartPUT")
 	// addStateManager("TestEngineStartPUT", "../../test/resource/engine-run-success.yaml")
-	addStateManager("TestEngineStartPUT")
+	defaultInstance.addStateManager("TestEngineStartPUT")
 	extensionPath, err := global.CopyToTemp("TestEngineStartPUT", "../../test/data/extensions/")
 	if err != nil {
 		t.Fatal(err)
@@ -205,7 +205,7 @@ func TestEnginePUTRunning(t *testing.T) {
 	}
 	SetExtensionsPath(extensionPath)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
-	addStateManager("TestEnginePUTRunning")
+	defaultInstance.addStateManager("TestEnginePUTRunning")
 	// Create a request to pass to our handler. We don't have any query parameters for now, so we'll
 	// pass 'nil' as the third parameter.
 	req, err := http.NewRequest("PUT", "/cr/v1/engine?action=start&extension-name=TestEnginePUTRunning", nil)
@@ -258,7 +258,7 @@ func TestEngineResetRunning(t *testing.T) {
 	}
 	SetExtensionsPath(extensionPath)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
-	addStateManager("TestEngineResetRunning")
+	defaultInstance.addStateManager("TestEngineResetRunning")
 
 	rr := httptest.NewRecorder()
 
@@ -304,7 +304,7 @@ func TestEngineReset(t *testing.T) {
 	}
 	SetExtensionsPath(extensionPath)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
-	addStateManager("TestEngineReset")
+	defaultInstance.addStateManager("TestEngineReset")
 
 	rr := httptest.NewRecorder()
 
@@ -360,3 +360,26 @@ func TestEngineReset(t *testing.T) {
 	}
 	global.RemoveTemp("TestEngineReset")
 }
+
+func TestEngineStartOverlayNotFound(t *testing.T) {
+	t.Log("Entering................. TestEngineStartOverlayNotFound")
+	defaultInstance.addStateManager("TestEngineStartOverlayNotFound")
+	extensionPath, err := global.CopyToTemp("TestEngineStartOverlayNotFound", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetExtensionsPath(extensionPath)
+	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
+	defer global.RemoveTemp("TestEngineStartOverlayNotFound")
+	req, err := http.NewRequest("PUT", "/cr/v1/engine?action=start&extension-name=TestEngineStartOverlayNotFound&overlay=does-not-exist", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(HandleEngine)
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
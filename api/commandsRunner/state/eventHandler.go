@@ -0,0 +1,107 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+)
+
+//EventKeepAliveInterval interval between two keep-alive comments sent on an idle event stream
+var EventKeepAliveInterval = 15 * time.Second
+
+//handle Events rest api requests
+func HandleEvents(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleEvents")
+	switch req.Method {
+	case "GET":
+		GetEventsEndpoint(w, req)
+	default:
+		logger.AddCallerField().Error("Unsupported method:" + req.Method)
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+	}
+}
+
+/*
+Stream the state status changes, the runs start/end and the log lines of an extension as server-sent events
+URL: /cr/v1/events?extension-name=<extension-name>
+Method: GET
+*/
+func GetEventsEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in GetEventsEndpoint")
+	inst := GetInstance(req)
+	extensionName, _, err := global.GetExtensionNameFromRequest(req)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.AddCallerField().Error("Streaming not supported")
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	if inst.IsShuttingDown() {
+		apiError.HTTPError(w, req, ErrShuttingDown, http.StatusServiceUnavailable)
+		return
+	}
+	events, unsubscribe := inst.SubscribeEvents(extensionName)
+	defer unsubscribe()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepAlive := time.NewTicker(EventKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			log.Debug("Event stream of " + extensionName + " closed by the client")
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				log.Debug("Event stream of " + extensionName + " closed by the server")
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.AddCallerField().Error(err.Error())
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
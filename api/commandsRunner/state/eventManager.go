@@ -0,0 +1,199 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//Event types
+const (
+	EventStateStatus = "state_status"
+	EventRunStart    = "run_start"
+	EventRunEnd      = "run_end"
+	EventLog         = "log"
+)
+
+//EventSubscriberBufferSize number of events buffered per subscriber, a subscriber not reading fast enough is disconnected.
+var EventSubscriberBufferSize = 1024
+
+//Event a state transition, a run start/end or a log line
+type Event struct {
+	ID            int64  `json:"id"`
+	Type          string `json:"type"`
+	Timestamp     string `json:"timestamp"`
+	ExtensionName string `json:"extension_name"`
+	//ExecutedByExtensionName the top level extension of the run, differs from ExtensionName for the inserted extensions
+	ExecutedByExtensionName string `json:"executed_by_extension_name,omitempty"`
+	ExecutionID             int    `json:"execution_id,omitempty"`
+	StateName               string `json:"state_name,omitempty"`
+	Status                  string `json:"status,omitempty"`
+	Reason                  string `json:"reason,omitempty"`
+	//Line a log line including its end of line
+	Line string `json:"line,omitempty"`
+	//Position offset of the line in the state log file
+	Position int64 `json:"position,omitempty"`
+}
+
+//EventListener is called for each event of any extension, it must not block
+type EventListener func(event Event)
+
+type eventSubscriber struct {
+	extensionName string
+	events        chan Event
+}
+
+var eventID int64
+
+//AddEventListener registers a listener receiving the events of all extensions
+func (inst *Instance) AddEventListener(listener EventListener) {
+	inst.subscribersMux.Lock()
+	defer inst.subscribersMux.Unlock()
+	inst.eventListeners = append(inst.eventListeners, listener)
+}
+
+//SubscribeEvents returns a channel receiving the events of an extension (including the events of the extensions it runs),
+//the channel is closed when the subscriber is too slow or the server shuts down.
+func (inst *Instance) SubscribeEvents(extensionName string) (<-chan Event, func()) {
+	subscriber := &eventSubscriber{
+		extensionName: extensionName,
+		events:        make(chan Event, EventSubscriberBufferSize),
+	}
+	inst.subscribersMux.Lock()
+	inst.subscribers[subscriber] = true
+	inst.subscribersMux.Unlock()
+	unsubscribe := func() {
+		inst.subscribersMux.Lock()
+		defer inst.subscribersMux.Unlock()
+		if inst.subscribers[subscriber] {
+			delete(inst.subscribers, subscriber)
+			close(subscriber.events)
+		}
+	}
+	return subscriber.events, unsubscribe
+}
+
+//closeEventSubscribers closes all subscriber channels
+func (inst *Instance) closeEventSubscribers() {
+	inst.subscribersMux.Lock()
+	defer inst.subscribersMux.Unlock()
+	for subscriber := range inst.subscribers {
+		delete(inst.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+//PublishEvent sends the event to the subscribers of its extension
+func (inst *Instance) PublishEvent(event Event) {
+	event.ID = atomic.AddInt64(&eventID, 1)
+	if event.Timestamp == "" {
+		event.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	inst.subscribersMux.Lock()
+	defer inst.subscribersMux.Unlock()
+	for _, listener := range inst.eventListeners {
+		listener(event)
+	}
+	for subscriber := range inst.subscribers {
+		if subscriber.extensionName != event.ExtensionName && subscriber.extensionName != event.ExecutedByExtensionName {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			log.Warning("Event subscriber of " + subscriber.extensionName + " too slow, disconnecting it")
+			delete(inst.subscribers, subscriber)
+			close(subscriber.events)
+		}
+	}
+}
+
+//publishStateEvent publishes an event related to a state of the states file
+func (sm *States) publishStateEvent(eventType string, state *State) {
+	inst := sm.instance()
+	event := Event{
+		Type:                    eventType,
+		ExtensionName:           sm.ExtensionName,
+		ExecutedByExtensionName: sm.ExecutedByExtensionName,
+		ExecutionID:             sm.ExecutionID,
+	}
+	if state != nil {
+		event.StateName = state.Name
+		event.Status = state.Status
+		event.Reason = state.Reason
+	} else {
+		event.Status = sm.Status
+	}
+	inst.PublishEvent(event)
+}
+
+//eventLogWriter publishes each line written in a state log as an event
+type eventLogWriter struct {
+	sm       *States
+	state    State
+	position int64
+	buffer   bytes.Buffer
+}
+
+func newEventLogWriter(sm *States, state State) *eventLogWriter {
+	return &eventLogWriter{
+		sm:    sm,
+		state: state,
+	}
+}
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+	for {
+		line, err := w.buffer.ReadBytes('\n')
+		if err != nil {
+			//Incomplete line, kept for the next write
+			w.buffer.Reset()
+			w.buffer.Write(line)
+			break
+		}
+		w.publish(line)
+	}
+	return len(p), nil
+}
+
+//Flush publishes the last incomplete line
+func (w *eventLogWriter) Flush() {
+	if w.buffer.Len() > 0 {
+		w.publish(w.buffer.Bytes())
+		w.buffer.Reset()
+	}
+}
+
+func (w *eventLogWriter) publish(line []byte) {
+	inst := w.sm.instance()
+	inst.PublishEvent(Event{
+		Type:                    EventLog,
+		ExtensionName:           w.sm.ExtensionName,
+		ExecutedByExtensionName: w.sm.ExecutedByExtensionName,
+		ExecutionID:             w.sm.ExecutionID,
+		StateName:               w.state.Name,
+		Line:                    string(line),
+		Position:                w.position,
+	})
+	w.position += int64(len(line))
+}
@@ -0,0 +1,108 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventsExecution(t *testing.T) {
+	t.Log("Entering... TestEventsExecution")
+	dir, err := ioutil.TempDir("", "TestEventsExecution")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sm := newStateManager("states-TestEventsExecution")
+	sm.StatesPath = filepath.Join(dir, "states-TestEventsExecution.yaml")
+	sm.StateArray = []State{
+		{Name: "echo", Status: StateREADY, Script: "echo line1", ScriptTimeout: 10, LogPath: filepath.Join(dir, "echo.log")},
+	}
+	err = sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := SubscribeEvents("states-TestEventsExecution")
+	defer unsubscribe()
+	otherEvents, unsubscribeOther := SubscribeEvents("other-extension")
+	defer unsubscribeOther()
+	err = sm.Execute(FirstState, LastState, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Event{
+		{Type: EventRunStart, Status: StateRUNNING},
+		{Type: EventStateStatus, StateName: "echo", Status: StateRUNNING},
+		{Type: EventLog, StateName: "echo", Line: "line1\n", Position: 0},
+		{Type: EventStateStatus, StateName: "echo", Status: StateSUCCEEDED},
+		{Type: EventRunEnd, Status: StateSUCCEEDED},
+	}
+	var previousID int64
+	for _, e := range expected {
+		select {
+		case event := <-events:
+			if event.Type != e.Type || event.StateName != e.StateName || event.Status != e.Status || event.Line != e.Line || event.Position != e.Position {
+				t.Errorf("Expected %+v, got %+v", e, event)
+			}
+			if event.ExtensionName != "states-TestEventsExecution" {
+				t.Error("Unexpected extension name " + event.ExtensionName)
+			}
+			if event.ID <= previousID {
+				t.Errorf("Expected increasing event ids, got %d after %d", event.ID, previousID)
+			}
+			previousID = event.ID
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Event %+v not received", e)
+		}
+	}
+	select {
+	case event := <-otherEvents:
+		t.Errorf("Unexpected event for an other extension: %+v", event)
+	default:
+	}
+}
+
+func TestEventLogWriterPartialLines(t *testing.T) {
+	t.Log("Entering... TestEventLogWriterPartialLines")
+	sm := newStateManager("states-TestEventLogWriterPartialLines")
+	events, unsubscribe := SubscribeEvents("states-TestEventLogWriterPartialLines")
+	defer unsubscribe()
+	w := newEventLogWriter(sm, State{Name: "partial"})
+	w.Write([]byte("hel"))
+	w.Write([]byte("lo\nwor"))
+	w.Write([]byte("ld"))
+	w.Flush()
+	expected := []Event{
+		{Line: "hello\n", Position: 0},
+		{Line: "world", Position: 6},
+	}
+	for _, e := range expected {
+		select {
+		case event := <-events:
+			if event.Line != e.Line || event.Position != e.Position || event.StateName != "partial" {
+				t.Errorf("Expected %+v, got %+v", e, event)
+			}
+		default:
+			t.Fatalf("Event %+v not received", e)
+		}
+	}
+}
@@ -19,16 +19,17 @@ package state
 
 import (
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 )
 
@@ -58,6 +59,7 @@ func HandleExtensions(w http.ResponseWriter, req *http.Request) {
 }
 
 func listExtensions(w http.ResponseWriter, req *http.Request) {
+	inst := GetInstance(req)
 	query, _ := url.ParseQuery(req.URL.RawQuery)
 	log.Debugf("Query: %s", query)
 
@@ -75,32 +77,64 @@ func listExtensions(w http.ResponseWriter, req *http.Request) {
 	}
 	catalog, err := strconv.ParseBool(catalogString)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
 		return
 	}
+	extensionsFilter := ExtensionsFilter{
+		Name: query.Get("name"),
+	}
+	if offsetFound := query.Get("offset"); offsetFound != "" {
+		extensionsFilter.Offset, err = strconv.Atoi(offsetFound)
+	}
+	if limitFound := query.Get("limit"); limitFound != "" && err == nil {
+		extensionsFilter.Limit, err = strconv.Atoi(limitFound)
+	}
+	if err != nil {
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	if fieldsFound := query.Get("fields"); fieldsFound != "" {
+		extensionsFilter.Fields = strings.Split(fieldsFound, ",")
+	}
 	log.Debugf("Query: %s", filter)
-	extensions, err := ListExtensions(filter, catalog)
+	extensions, err := inst.ListExtensions(filter, catalog)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		return
 	}
+	extensions, total, err := extensionsFilter.Apply(extensions)
+	if err != nil {
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+	var result interface{} = extensions
+	if len(extensionsFilter.Fields) > 0 {
+		selectedExtensions, err := SelectFields(extensions.Extensions, extensionsFilter.Fields)
+		if err != nil {
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
+			return
+		}
+		result = map[string]interface{}{"extensions": selectedExtensions}
+	}
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
-	err = enc.Encode(extensions)
+	err = enc.Encode(result)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		return
 	}
 }
 
 func unregisterExtension(w http.ResponseWriter, req *http.Request) {
+	inst := GetInstance(req)
 	query, _ := url.ParseQuery(req.URL.RawQuery)
 	extensionName := query["extension-name"][0]
 	log.Debugf("Query: %s", extensionName)
 
-	err := UnregisterExtension(extensionName)
+	err := inst.UnregisterExtension(extensionName)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -109,6 +143,7 @@ func unregisterExtension(w http.ResponseWriter, req *http.Request) {
 
 func registerExtension(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in... registerExtension")
+	inst := GetInstance(req)
 	extensionName := ""
 	m, _ := url.ParseQuery(req.URL.RawQuery)
 	if extensionNameFound, okExtensionName := m["extension-name"]; okExtensionName {
@@ -124,7 +159,7 @@ func registerExtension(w http.ResponseWriter, req *http.Request) {
 	force, err := strconv.ParseBool(forceString)
 	if err != nil {
 		logger.AddCallerField().Errorf("Error converting force to boolean: %v", err)
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		apiError.HTTPError(w, req, err, http.StatusBadGateway)
 		return
 	}
 	runningToFailedString := req.Header.Get("RunningToFailed")
@@ -135,13 +170,13 @@ func registerExtension(w http.ResponseWriter, req *http.Request) {
 	runningToFailed, err := strconv.ParseBool(runningToFailedString)
 	if err != nil {
 		logger.AddCallerField().Errorf("Error converting runningToFailed to boolean: %v", err)
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		apiError.HTTPError(w, req, err, http.StatusBadGateway)
 		return
 	}
 	file, header, _ := req.FormFile("extension")
 	// if err != nil {
 	// 	// logger.AddCallerField().Errorf("Unable to parse the form: %v", err)
-	// 	// http.Error(w, err.Error(), http.StatusInternalServerError)
+	// 	// apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	// 	// return
 	// }
 	if file != nil {
@@ -156,15 +191,15 @@ func registerExtension(w http.ResponseWriter, req *http.Request) {
 	// if free < uint64(2*header.Size) {
 	// 	err = errors.New("Not enough free space to install the extension")
 	// 	logger.AddCallerField().Errorf("Error while registring: %v", err)
-	// 	http.Error(w, err.Error(), http.StatusInternalServerError)
+	// 	apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 	// 	return
 	// }
 	log.Debug("ExtensionName:" + extensionName)
 	//This test is done later too but I added here too to avoid to load the whole extension zip.
-	if !force && IsExtensionRegistered(extensionName) {
-		err = errors.New("Extension " + extensionName + " already registered")
+	if !force && inst.IsExtensionRegistered(extensionName) {
+		err = apiError.Conflict("Extension " + extensionName + " already registered")
 		logger.AddCallerField().Errorf("Error while registring: %v", err)
-		http.Error(w, err.Error(), http.StatusConflict)
+		apiError.HTTPError(w, req, err, http.StatusConflict)
 		return
 	}
 	var zipPath string
@@ -172,7 +207,7 @@ func registerExtension(w http.ResponseWriter, req *http.Request) {
 		out, err := os.Create(filepath.Join("/tmp", extensionName))
 		if err != nil {
 			logger.AddCallerField().Errorf("Unable to create temp file: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 			return
 		}
 		defer out.Close()
@@ -181,15 +216,15 @@ func registerExtension(w http.ResponseWriter, req *http.Request) {
 		defer os.Remove(out.Name())
 		if err != nil {
 			logger.AddCallerField().Errorf("Unable to copy file: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 			return
 		}
 		zipPath = out.Name()
 	}
-	err = RegisterExtension(extensionName, zipPath, force, runningToFailed)
+	err = inst.RegisterExtension(extensionName, zipPath, force, runningToFailed)
 	if err != nil {
 		logger.AddCallerField().Errorf("Error while registring: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
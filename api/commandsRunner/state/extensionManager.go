@@ -32,6 +32,7 @@ import (
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/olebedev/config"
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
@@ -40,20 +41,8 @@ import (
 const EmbeddedExtensions = "embedded"
 const CustomExtensions = "custom"
 
-var extensionsEmbeddedFile string
-var embeddedExtensionsRepositoryPath string
-
-var extensionsPath string
 var extensionsDirEmbedded = EmbeddedExtensions
-var extensionsPathEmbedded = extensionsPath + extensionsDirEmbedded
 var extensionsDirCustom = CustomExtensions + string(filepath.Separator)
-var extensionsPathCustom = extensionsPath + extensionsDirCustom
-
-var extensionsLogsPath string
-var extensionsLogsDirEmbedded = extensionsDirEmbedded
-var extensionsLogsPathEmbedded = extensionsLogsPath + extensionsLogsDirEmbedded
-var extensionsLogsDirCustom = CustomExtensions
-var extensionsLogsPathCustom = extensionsLogsPath + extensionsLogsDirCustom
 
 /*
 Extension structure
@@ -64,7 +53,11 @@ type Extension struct {
 	CallState           CallState `yaml:"call_state" json:"call_state"`
 	ValidationConfigURL string    `yaml:"validation_config_url" json:"validation_config_url"`
 	GenerateConfigURL   string    `yaml:"generate_config_url" json:"generate_config_url"`
-	ExtensionPath       string    `yaml:"-" json:"-"`
+	//ValidationConfigScript and GenerateConfigScript are run in the extension directory when no url is provided.
+	//The config is sent on stdin and the result is read from stdout.
+	ValidationConfigScript string `yaml:"validation_config_script" json:"validation_config_script"`
+	GenerateConfigScript   string `yaml:"generate_config_script" json:"generate_config_script"`
+	ExtensionPath          string `yaml:"-" json:"-"`
 	//PersistedPaths The path listed in that array will be not erased between upgrades.
 	//The states-file path is always added to that array.
 	//The path is a pattern relative to the extension home directory.
@@ -94,108 +87,120 @@ extensionsLogsPath the directory path where the logs will be save.
 This path is relative to extension deployment location which is <extensionPath>/<embedded|custom>/<extensionName>.
 This path will be extended with "embeded" or "custom" depending of the type of extension.
 */
-func InitExtensions(embeddedExtensionsDescriptor string, embeddedExtensionsRepositoryPath string, extensionsPath string, extensionsLogsPath string) {
-	SetExtensionsEmbeddedFile(embeddedExtensionsDescriptor)
-	err := SetEmbeddedExtensionsRepositoryPath(embeddedExtensionsRepositoryPath)
+func (inst *Instance) InitExtensions(embeddedExtensionsDescriptor string, embeddedExtensionsRepositoryPath string, extensionsPath string, extensionsLogsPath string) {
+	inst.SetExtensionsEmbeddedFile(embeddedExtensionsDescriptor)
+	err := inst.SetEmbeddedExtensionsRepositoryPath(embeddedExtensionsRepositoryPath)
 	if err != nil {
 		log.Fatal(err)
 	}
-	SetExtensionsPath(extensionsPath)
-	SetExtensionsLogsPath(extensionsLogsPath)
+	inst.SetExtensionsPath(extensionsPath)
+	inst.SetExtensionsLogsPath(extensionsLogsPath)
 }
 
 //SetExtensionEmbeddedFile sets the embedded extension path file descriptor
-func SetExtensionsEmbeddedFile(_extensionsEmbeddedFile string) {
+func (inst *Instance) SetExtensionsEmbeddedFile(_extensionsEmbeddedFile string) {
 	log.Debug("Entering in... SetExtensionsEmbeddedFile")
-	extensionsEmbeddedFile = strings.TrimRight(_extensionsEmbeddedFile, string(filepath.Separator))
+	inst.extensionsEmbeddedFile = strings.TrimRight(_extensionsEmbeddedFile, string(filepath.Separator))
 }
 
 /*
 SetEmbeddedExtensionsRepositoryPath set the path where the embedded extension are stored
 */
-func SetEmbeddedExtensionsRepositoryPath(_embeddedExtensionsRepositoryPath string) error {
+func (inst *Instance) SetEmbeddedExtensionsRepositoryPath(_embeddedExtensionsRepositoryPath string) error {
 	if _, err := os.Stat(_embeddedExtensionsRepositoryPath); os.IsNotExist(err) {
 		return err
 	}
-	embeddedExtensionsRepositoryPath = strings.TrimRight(_embeddedExtensionsRepositoryPath, string(filepath.Separator))
+	inst.embeddedExtensionsRepositoryPath = strings.TrimRight(_embeddedExtensionsRepositoryPath, string(filepath.Separator))
 	return nil
 }
 
 //SetExtensionPath set the path where the extensions must be deployed
-func SetExtensionsPath(_extensionsPath string) {
-	extensionsPath = strings.TrimRight(_extensionsPath, string(filepath.Separator))
-	extensionsPathEmbedded = filepath.Join(extensionsPath, extensionsDirEmbedded)
-	if _, err := os.Stat(extensionsPathEmbedded); os.IsNotExist(err) {
-		log.Debug("Create dir:" + extensionsPathEmbedded)
-		os.MkdirAll(extensionsPathEmbedded, 0744)
-	}
-	extensionsPathCustom = filepath.Join(extensionsPath, extensionsDirCustom)
-	if _, err := os.Stat(extensionsPathCustom); os.IsNotExist(err) {
-		log.Debug("Create dir:" + extensionsPathCustom)
-		os.MkdirAll(extensionsPathCustom, 0744)
+func (inst *Instance) SetExtensionsPath(_extensionsPath string) {
+	inst.extensionsPath = strings.TrimRight(_extensionsPath, string(filepath.Separator))
+	inst.extensionsPathEmbedded = filepath.Join(inst.extensionsPath, extensionsDirEmbedded)
+	if _, err := os.Stat(inst.extensionsPathEmbedded); os.IsNotExist(err) {
+		log.Debug("Create dir:" + inst.extensionsPathEmbedded)
+		os.MkdirAll(inst.extensionsPathEmbedded, 0744)
+	}
+	inst.extensionsPathCustom = filepath.Join(inst.extensionsPath, extensionsDirCustom)
+	if _, err := os.Stat(inst.extensionsPathCustom); os.IsNotExist(err) {
+		log.Debug("Create dir:" + inst.extensionsPathCustom)
+		os.MkdirAll(inst.extensionsPathCustom, 0744)
 	}
 }
 
 //SetExtensionLogsPath set the path where the extensions logs are kept
-func SetExtensionsLogsPath(_extensionsLogsPath string) {
-	extensionsLogsPath = strings.TrimRight(_extensionsLogsPath, string(filepath.Separator))
-	extensionsLogsPathEmbedded = filepath.Join(extensionsLogsPath, extensionsDirEmbedded)
-	extensionsLogsPathCustom = filepath.Join(extensionsLogsPath, extensionsDirCustom)
+func (inst *Instance) SetExtensionsLogsPath(_extensionsLogsPath string) {
+	inst.extensionsLogsPath = strings.TrimRight(_extensionsLogsPath, string(filepath.Separator))
+	inst.extensionsLogsPathEmbedded = filepath.Join(inst.extensionsLogsPath, extensionsDirEmbedded)
+	inst.extensionsLogsPathCustom = filepath.Join(inst.extensionsLogsPath, extensionsDirCustom)
+}
+
+//CopyExtensionsSettings sets the extensions settings of the instance from another instance
+func (inst *Instance) CopyExtensionsSettings(from *Instance) {
+	inst.extensionsEmbeddedFile = from.extensionsEmbeddedFile
+	inst.embeddedExtensionsRepositoryPath = from.embeddedExtensionsRepositoryPath
+	inst.extensionsPath = from.extensionsPath
+	inst.extensionsPathEmbedded = from.extensionsPathEmbedded
+	inst.extensionsPathCustom = from.extensionsPathCustom
+	inst.extensionsLogsPath = from.extensionsLogsPath
+	inst.extensionsLogsPathEmbedded = from.extensionsLogsPathEmbedded
+	inst.extensionsLogsPathCustom = from.extensionsLogsPathCustom
 }
 
 //GetExtensionPath retrieves the extension path
-func GetExtensionsPath() string {
-	return extensionsPath
+func (inst *Instance) GetExtensionsPath() string {
+	return inst.extensionsPath
 }
 
 //GetExtensionPathEmbedded retrieves the extension path for embedded extensions
-func GetExtensionsPathEmbedded() string {
-	return extensionsPathEmbedded
+func (inst *Instance) GetExtensionsPathEmbedded() string {
+	return inst.extensionsPathEmbedded
 }
 
 //GetExtensionPathCustom retrieves the extension path for the custom extensions
-func GetExtensionsPathCustom() string {
-	return extensionsPathCustom
+func (inst *Instance) GetExtensionsPathCustom() string {
+	return inst.extensionsPathCustom
 }
 
 //GetExtensionLogsPathEmbedded retrieves the embedded extensions logs path
-func GetExtensionsLogsPathEmbedded() string {
-	return extensionsLogsPathEmbedded
+func (inst *Instance) GetExtensionsLogsPathEmbedded() string {
+	return inst.extensionsLogsPathEmbedded
 }
 
 //GetExtensionLogsPathCustom retrieves the custom extensions logs path
-func GetExtensionsLogsPathCustom() string {
-	return extensionsLogsPathCustom
+func (inst *Instance) GetExtensionsLogsPathCustom() string {
+	return inst.extensionsLogsPathCustom
 }
 
 //GetRepoLocalPath retrieves the location of the embedded extensions packages
-func GetRepoLocalPath() string {
-	return embeddedExtensionsRepositoryPath
+func (inst *Instance) GetRepoLocalPath() string {
+	return inst.embeddedExtensionsRepositoryPath
 }
 
 //GetRegisteredExtensionPath gets the extension path for a given registered extension
-func GetRegisteredExtensionPath(extensionName string) (string, error) {
-	if !IsExtensionRegistered(extensionName) {
-		return "", errors.New(extensionName + " is not registered")
+func (inst *Instance) GetRegisteredExtensionPath(extensionName string) (string, error) {
+	if !inst.IsExtensionRegistered(extensionName) {
+		return "", apiError.NotFound(apiError.CodeExtensionNotFound, extensionName+" is not registered")
 	}
 	var extensionPath string
-	isEmbeddedExtension, err := IsEmbeddedExtension(extensionName)
+	isEmbeddedExtension, err := inst.IsEmbeddedExtension(extensionName)
 	if err != nil {
 		return "", err
 	}
 	if isEmbeddedExtension {
-		extensionPath = filepath.Join(GetExtensionsPathEmbedded(), extensionName)
+		extensionPath = filepath.Join(inst.GetExtensionsPathEmbedded(), extensionName)
 	} else {
-		extensionPath = filepath.Join(GetExtensionsPathCustom(), extensionName)
+		extensionPath = filepath.Join(inst.GetExtensionsPathCustom(), extensionName)
 	}
 	return extensionPath, nil
 }
 
 //GetRelativeExtensionPath gets the relative extension path for a given registered extension
-func GetRelativeExtensionPath(extensionName string) string {
+func (inst *Instance) GetRelativeExtensionPath(extensionName string) string {
 	log.Debug("Entering in... GetRelativeExtensionPath")
 	var extensionPath string
-	isEmbeddedExtension, _ := IsEmbeddedExtension(extensionName)
+	isEmbeddedExtension, _ := inst.IsEmbeddedExtension(extensionName)
 	log.Debug("isEmbeddedExtension:" + extensionName + " =>" + strconv.FormatBool(isEmbeddedExtension))
 	if isEmbeddedExtension {
 		extensionPath = filepath.Join(extensionsDirEmbedded, extensionName)
@@ -206,7 +211,7 @@ func GetRelativeExtensionPath(extensionName string) string {
 }
 
 //GetRootExtensionPath gets the root extension path
-func GetRootExtensionPath(rootDir string, extensionName string) string {
+func (inst *Instance) GetRootExtensionPath(rootDir string, extensionName string) string {
 	log.Debug("Entering in... GetRootExtensionPath")
 	if rootDir == "" {
 		return rootDir
@@ -215,30 +220,30 @@ func GetRootExtensionPath(rootDir string, extensionName string) string {
 		rootDir += string(filepath.Separator)
 	}
 	extensionPath := rootDir
-	extensionPath += GetRelativeExtensionPath(extensionName)
+	extensionPath += inst.GetRelativeExtensionPath(extensionName)
 	return extensionPath
 }
 
 //IsExtensionRegistered Check if an extension is register by browzing the extensions directory
-func IsExtensionRegistered(extensionName string) bool {
+func (inst *Instance) IsExtensionRegistered(extensionName string) bool {
 	log.Debug("Entering in... IsExtensionRegistered")
-	return IsEmbeddedExtensionRegistered(extensionName) || IsCustomExtensionRegistered(extensionName)
+	return inst.IsEmbeddedExtensionRegistered(extensionName) || inst.IsCustomExtensionRegistered(extensionName)
 }
 
 //IsCustomExtensionRegistered Check if an extension is register by browzing the extensions directory
-func IsCustomExtensionRegistered(filename string) bool {
+func (inst *Instance) IsCustomExtensionRegistered(filename string) bool {
 	log.Debug("Entering in... IsCustomExtensionRegistered")
-	if _, err := os.Stat(filepath.Join(GetExtensionsPathCustom(), filename)); os.IsNotExist(err) {
+	if _, err := os.Stat(filepath.Join(inst.GetExtensionsPathCustom(), filename)); os.IsNotExist(err) {
 		return false
 	}
 	return true
 }
 
 //IsEmbeddedxtensionRegistered Check if an extension is register by browzing the extensions directory
-func IsEmbeddedExtensionRegistered(filename string) bool {
+func (inst *Instance) IsEmbeddedExtensionRegistered(filename string) bool {
 	log.Debug("Entering in... IsEmbeddedExtensionRegistered")
-	log.Debug(filepath.Join(GetExtensionsPathEmbedded(), filename))
-	if _, err := os.Stat(filepath.Join(GetExtensionsPathEmbedded(), filename)); os.IsNotExist(err) {
+	log.Debug(filepath.Join(inst.GetExtensionsPathEmbedded(), filename))
+	if _, err := os.Stat(filepath.Join(inst.GetExtensionsPathEmbedded(), filename)); os.IsNotExist(err) {
 		return false
 	}
 	return true
@@ -326,11 +331,11 @@ func extractAndWriteFile(src, targetdir, extensionName string, zf *zip.File) err
 	return nil
 }
 
-func getEmbeddedExtensionRepoPath(extensionName string) (string, error) {
+func (inst *Instance) getEmbeddedExtensionRepoPath(extensionName string) (string, error) {
 	log.Debug("Entering in... getEmbeddedExtensionRepoPath")
 	log.Debug("extensionName:" + extensionName)
-	log.Debug("embeddedExtensionsRepositoryPath:" + embeddedExtensionsRepositoryPath)
-	extensions, err := ListEmbeddedExtensions()
+	log.Debug("embeddedExtensionsRepositoryPath:" + inst.embeddedExtensionsRepositoryPath)
+	extensions, err := inst.ListEmbeddedExtensions()
 	if err != nil {
 		log.Error(err.Error())
 		return "", err
@@ -338,21 +343,21 @@ func getEmbeddedExtensionRepoPath(extensionName string) (string, error) {
 	log.Debug(extensions)
 	extension, ok := extensions.Extensions[extensionName]
 	if !ok {
-		err := errors.New("Extension name: " + extensionName + " not found")
+		err := apiError.NotFound(apiError.CodeExtensionNotFound, "Extension name: "+extensionName+" not found")
 		log.Error(err.Error())
 		return "", err
 	}
 	if extension.Version == "" {
-		return filepath.Join(embeddedExtensionsRepositoryPath, extensionName), nil
+		return filepath.Join(inst.embeddedExtensionsRepositoryPath, extensionName), nil
 	}
-	return filepath.Join(embeddedExtensionsRepositoryPath, extensionName, extension.Version), nil
+	return filepath.Join(inst.embeddedExtensionsRepositoryPath, extensionName, extension.Version), nil
 }
 
 //CopyExtensionToEmbeddedExtensionPath copy the extension to the extension directory
-func CopyExtensionToEmbeddedExtensionPath(extensionName string) error {
+func (inst *Instance) CopyExtensionToEmbeddedExtensionPath(extensionName string) error {
 	log.Debug("Entering in... CopyExtensionToEmbeddedExtensionPath")
-	destDir := filepath.Join(GetExtensionsPathEmbedded(), extensionName)
-	extensionRepoPath, err := getEmbeddedExtensionRepoPath(extensionName)
+	destDir := filepath.Join(inst.GetExtensionsPathEmbedded(), extensionName)
+	extensionRepoPath, err := inst.getEmbeddedExtensionRepoPath(extensionName)
 	if err != nil {
 		return err
 	}
@@ -371,8 +376,8 @@ func CopyExtensionToEmbeddedExtensionPath(extensionName string) error {
 		}
 		log.Debug("path:" + path)
 		log.Debug("extensionRepoPath:" + extensionRepoPath)
-		log.Debug("GetExtensionPathEmbedded()+extensionName:" + filepath.Join(GetExtensionsPathEmbedded(), extensionName))
-		newPath := strings.Replace(path, extensionRepoPath, filepath.Join(GetExtensionsPathEmbedded(), extensionName), 1)
+		log.Debug("GetExtensionPathEmbedded()+extensionName:" + filepath.Join(inst.GetExtensionsPathEmbedded(), extensionName))
+		newPath := strings.Replace(path, extensionRepoPath, filepath.Join(inst.GetExtensionsPathEmbedded(), extensionName), 1)
 		log.Debug("newPath:" + newPath)
 		switch {
 		case f.IsDir():
@@ -429,9 +434,9 @@ func CopyExtensionToEmbeddedExtensionPath(extensionName string) error {
 // }
 
 //IsCustomExtension Checks if extensionName is a custom extension
-func IsCustomExtension(extensionName string) (bool, error) {
+func (inst *Instance) IsCustomExtension(extensionName string) (bool, error) {
 	log.Debug("Entering in... IsCustomExtension")
-	extensions, err := ListRegisteredCustomExtensions()
+	extensions, err := inst.ListRegisteredCustomExtensions()
 	if err != nil {
 		return false, err
 	}
@@ -440,9 +445,9 @@ func IsCustomExtension(extensionName string) (bool, error) {
 }
 
 //IsEmbeddedExtension Checks if extensionName is a Embedded extension
-func IsEmbeddedExtension(extensionName string) (bool, error) {
+func (inst *Instance) IsEmbeddedExtension(extensionName string) (bool, error) {
 	log.Debug("Entering in... IsEmbeddedExtension")
-	extensions, err := ListEmbeddedExtensions()
+	extensions, err := inst.ListEmbeddedExtensions()
 	if err != nil {
 		return false, err
 	}
@@ -450,7 +455,7 @@ func IsEmbeddedExtension(extensionName string) (bool, error) {
 	return ok, nil
 }
 
-func listRegisteredExtensionsDir(extensionPath string) (*Extensions, error) {
+func (inst *Instance) listRegisteredExtensionsDir(extensionPath string) (*Extensions, error) {
 	log.Debug("Entering in... listRegisteredExtensionsDir")
 	var extensionList Extensions
 	extensionList.Extensions = make(map[string]Extension)
@@ -464,7 +469,7 @@ func listRegisteredExtensionsDir(extensionPath string) (*Extensions, error) {
 			var extension Extension
 			var callState CallState
 			extensionName := file.Name()
-			if extensionPath == GetExtensionsPathCustom() {
+			if extensionPath == inst.GetExtensionsPathCustom() {
 				extension.Type = CustomExtensions
 			} else {
 				extension.Type = EmbeddedExtensions
@@ -500,13 +505,13 @@ func listRegisteredExtensionsDir(extensionPath string) (*Extensions, error) {
 	return &extensionList, nil
 }
 
-func ReadRegisteredExtension(extensionName string) (*Extension, error) {
+func (inst *Instance) ReadRegisteredExtension(extensionName string) (*Extension, error) {
 	var extension Extension
-	extensionPath, err := GetRegisteredExtensionPath(extensionName)
+	extensionPath, err := inst.GetRegisteredExtensionPath(extensionName)
 	if err != nil {
 		return &extension, err
 	}
-	embeddedExtension, err := IsEmbeddedExtension(extensionName)
+	embeddedExtension, err := inst.IsEmbeddedExtension(extensionName)
 	if err != nil {
 		return &extension, err
 	}
@@ -531,9 +536,9 @@ func ReadRegisteredExtension(extensionName string) (*Extension, error) {
 }
 
 //ListEmbeddedRegisteredExtensions lists the registered embedded exxtensions
-func ListEmbeddedRegisteredExtensions() (*Extensions, error) {
+func (inst *Instance) ListEmbeddedRegisteredExtensions() (*Extensions, error) {
 	log.Debug("Entering in... ListEmbeddedRegisteredExtensions")
-	extensions, err := listRegisteredExtensionsDir(GetExtensionsPathEmbedded())
+	extensions, err := inst.listRegisteredExtensionsDir(inst.GetExtensionsPathEmbedded())
 	if err != nil {
 		return nil, err
 	}
@@ -542,9 +547,9 @@ func ListEmbeddedRegisteredExtensions() (*Extensions, error) {
 
 //ListCustomExtensions returns extensions by reading the custom extension directory
 //Custom extensions get be listed only if registered.
-func ListRegisteredCustomExtensions() (*Extensions, error) {
+func (inst *Instance) ListRegisteredCustomExtensions() (*Extensions, error) {
 	log.Debug("Entering in... ListRegisteredCustomExtensions")
-	extensions, err := listRegisteredExtensionsDir(GetExtensionsPathCustom())
+	extensions, err := inst.listRegisteredExtensionsDir(inst.GetExtensionsPathCustom())
 	if err != nil {
 		return nil, err
 	}
@@ -552,21 +557,21 @@ func ListRegisteredCustomExtensions() (*Extensions, error) {
 }
 
 //ListEmbeddedExtensions returns extensions by reading the resourceManager extension file.
-func ListEmbeddedExtensions() (*Extensions, error) {
+func (inst *Instance) ListEmbeddedExtensions() (*Extensions, error) {
 	log.Debug("Entering in... ListEmbeddedExtensions")
 	var extensionList Extensions
 	//extensionList.Extensions = make(map[string]Extension)
-	log.Debug("extensionEmbeddedFile:" + extensionsEmbeddedFile)
-	if extensionsEmbeddedFile == "" {
+	log.Debug("extensionEmbeddedFile:" + inst.extensionsEmbeddedFile)
+	if inst.extensionsEmbeddedFile == "" {
 		return &extensionList, nil
 	}
-	resource, err := ioutil.ReadFile(extensionsEmbeddedFile)
+	resource, err := ioutil.ReadFile(inst.extensionsEmbeddedFile)
 	if err != nil {
 		log.Debug(err.Error())
 		return nil, err
 	}
-	log.Info("content extensionsEmbeddedFile " + extensionsEmbeddedFile)
-	log.Info(string(resource))
+	log.Debug("content extensionsEmbeddedFile " + inst.extensionsEmbeddedFile)
+	log.Debug(string(resource))
 	err = yaml.Unmarshal(resource, &extensionList)
 	if err != nil {
 		log.Debug(err.Error())
@@ -583,19 +588,19 @@ func ListEmbeddedExtensions() (*Extensions, error) {
 }
 
 //ListExtensions lists all extensions
-func ListExtensions(filter string, catalog bool) (*Extensions, error) {
+func (inst *Instance) ListExtensions(filter string, catalog bool) (*Extensions, error) {
 	log.Debug("Entering in... ListExtensions")
 	var extensionList Extensions
 	extensionList.Extensions = make(map[string]Extension)
 	if catalog {
-		extensions, err := ListEmbeddedExtensions()
+		extensions, err := inst.ListEmbeddedExtensions()
 		if err != nil {
 			return nil, err
 		}
 		extensionList = *extensions
 	} else {
 		if filter == "" || filter == CustomExtensions {
-			extensions, err := ListRegisteredCustomExtensions()
+			extensions, err := inst.ListRegisteredCustomExtensions()
 			if err != nil {
 				return nil, err
 			}
@@ -605,7 +610,7 @@ func ListExtensions(filter string, catalog bool) (*Extensions, error) {
 		if filter == "" || filter == EmbeddedExtensions {
 			var extensions *Extensions
 			var err error
-			extensions, err = ListEmbeddedRegisteredExtensions()
+			extensions, err = inst.ListEmbeddedRegisteredExtensions()
 			if err != nil {
 				return nil, err
 			}
@@ -623,8 +628,8 @@ func ListExtensions(filter string, catalog bool) (*Extensions, error) {
 }
 
 //Take a backup of an extension on /tmp
-func backupExtension(extensionName string) (string, error) {
-	extensionPath, err := GetRegisteredExtensionPath(extensionName)
+func (inst *Instance) backupExtension(extensionName string) (string, error) {
+	extensionPath, err := inst.GetRegisteredExtensionPath(extensionName)
 	if err != nil && extensionPath != "" {
 		return "", err
 	}
@@ -642,8 +647,8 @@ func backupExtension(extensionName string) (string, error) {
 	return backupPath, err
 }
 
-func restoreExtension(extensionName string, backupPath string) error {
-	extensionPath, err := GetRegisteredExtensionPath(extensionName)
+func (inst *Instance) restoreExtension(extensionName string, backupPath string) error {
+	extensionPath, err := inst.GetRegisteredExtensionPath(extensionName)
 	if err != nil {
 		return err
 	}
@@ -658,9 +663,9 @@ func restoreExtension(extensionName string, backupPath string) error {
 	return err
 }
 
-func restoreExtensionPersistedPaths(extensionName string, backupPath string) error {
+func (inst *Instance) restoreExtensionPersistedPaths(extensionName string, backupPath string) error {
 	log.Debug("Entering in... restoreExtensionPersistedPaths")
-	extensionPath, err := GetRegisteredExtensionPath(extensionName)
+	extensionPath, err := inst.GetRegisteredExtensionPath(extensionName)
 	if err != nil {
 		return err
 	}
@@ -721,15 +726,15 @@ func restoreExtensionPersistedPaths(extensionName string, backupPath string) err
 //registerEmbededExtension register all embeded extensions
 //force: if true and already registered the extension is overwritten
 //runningToFailed: if true and the extension is running then the running state will be set to FAILED
-func RegisterEmbededExtensions(force bool, runningToFailed bool) error {
+func (inst *Instance) RegisterEmbededExtensions(force bool, runningToFailed bool) error {
 	log.Debug("Entering in... registerEmbededExtensions")
-	extensions, err := ListEmbeddedExtensions()
+	extensions, err := inst.ListEmbeddedExtensions()
 	if err != nil {
 		return err
 	}
 	for key := range extensions.Extensions {
 		log.Debug("key: " + key)
-		err := RegisterExtension(key, "", force, runningToFailed)
+		err := inst.RegisterExtension(key, "", force, runningToFailed)
 		if err != nil {
 			return err
 		}
@@ -740,15 +745,15 @@ func RegisterEmbededExtensions(force bool, runningToFailed bool) error {
 //RegisterExtension register an extension
 //force: if true and already registered the extension is overwritten
 //runningToFailed: if true and the extension is running then the running state will be set to FAILED
-func RegisterExtension(extensionName, zipPath string, force bool, runningToFailed bool) error {
+func (inst *Instance) RegisterExtension(extensionName, zipPath string, force bool, runningToFailed bool) error {
 	log.Debug("Entering in... RegisterExtension")
 	log.Debug("extensionName: " + extensionName)
 	log.Debug("zipPath: " + zipPath)
-	isExtensionRegistered := IsExtensionRegistered(extensionName)
+	isExtensionRegistered := inst.IsExtensionRegistered(extensionName)
 	if !force && isExtensionRegistered {
-		return errors.New("Extension " + extensionName + " already registered")
+		return apiError.Conflict("Extension " + extensionName + " already registered")
 	}
-	isEmbeddedExtension, err := IsEmbeddedExtension(extensionName)
+	isEmbeddedExtension, err := inst.IsEmbeddedExtension(extensionName)
 	log.Debug("isEmbeddedExtension:" + extensionName + " =>" + strconv.FormatBool(isEmbeddedExtension))
 	if err != nil {
 		return err
@@ -756,14 +761,14 @@ func RegisterExtension(extensionName, zipPath string, force bool, runningToFaile
 
 	var extensionPath string
 	if isEmbeddedExtension {
-		extensionPath = filepath.Join(GetExtensionsPathEmbedded(), extensionName)
+		extensionPath = filepath.Join(inst.GetExtensionsPathEmbedded(), extensionName)
 	} else {
-		extensionPath = filepath.Join(GetExtensionsPathCustom(), extensionName)
+		extensionPath = filepath.Join(inst.GetExtensionsPathCustom(), extensionName)
 	}
 	var errInstall, errGenStatesFile, errLoadTranslation, errRestorePersistedPaths, errUpdateCallers error
 	var backupPath string
 	if isExtensionRegistered {
-		backupPath, err = backupExtension(extensionName)
+		backupPath, err = inst.backupExtension(extensionName)
 		if err != nil {
 			return err
 		}
@@ -785,7 +790,7 @@ func RegisterExtension(extensionName, zipPath string, force bool, runningToFaile
 		}
 
 		//Restore persisted_path from backup
-		errRestorePersistedPaths = restoreExtensionPersistedPaths(extensionName, backupPath)
+		errRestorePersistedPaths = inst.restoreExtensionPersistedPaths(extensionName, backupPath)
 	}
 	if errRestorePersistedPaths == nil {
 		if isEmbeddedExtension {
@@ -796,11 +801,11 @@ func RegisterExtension(extensionName, zipPath string, force bool, runningToFaile
 				}
 			}
 			if errInstall == nil {
-				errInstall = CopyExtensionToEmbeddedExtensionPath(extensionName)
+				errInstall = inst.CopyExtensionToEmbeddedExtensionPath(extensionName)
 			}
 		} else {
 			if zipPath != "" {
-				errInstall = Unzip(zipPath, GetExtensionsPathCustom(), extensionName)
+				errInstall = Unzip(zipPath, inst.GetExtensionsPathCustom(), extensionName)
 				if errInstall == nil {
 					os.Remove(zipPath)
 				}
@@ -809,10 +814,10 @@ func RegisterExtension(extensionName, zipPath string, force bool, runningToFaile
 			}
 		}
 		if errInstall == nil {
-			errGenStatesFile = generateStatesFile(extensionName, extensionPath, runningToFailed)
+			errGenStatesFile = inst.generateStatesFile(extensionName, extensionPath, runningToFailed)
 			errLoadTranslation = i18nUtils.LoadTranslationFilesFromDir(filepath.Join(extensionPath, i18nUtils.I18nDirectory))
 			//Failure to generate the template files must not stop the installation.
-			GenerateTemplateFiles(extensionName, extensionPath)
+			inst.GenerateTemplateFiles(extensionName, extensionPath)
 		}
 
 	}
@@ -820,12 +825,12 @@ func RegisterExtension(extensionName, zipPath string, force bool, runningToFaile
 	log.Debug("if isExtensionRegistered then updateCallers")
 	if isExtensionRegistered {
 		log.Debug("Update Caller: " + extensionName)
-		errUpdateCallers = updateCallers(extensionName)
+		errUpdateCallers = inst.updateCallers(extensionName)
 	}
 	if errUpdateCallers != nil || errInstall != nil || errGenStatesFile != nil || errLoadTranslation != nil || errRestorePersistedPaths != nil {
 		if backupPath != "" {
 			log.Debug("Rolled back due to the error below")
-			restoreExtension(extensionName, backupPath)
+			inst.restoreExtension(extensionName, backupPath)
 		} else {
 			os.RemoveAll(extensionPath)
 			os.Remove(extensionPath)
@@ -849,7 +854,7 @@ func RegisterExtension(extensionName, zipPath string, force bool, runningToFaile
 	return nil
 }
 
-func generateStatesFile(extensionName string, extensionPath string, runningToFailed bool) error {
+func (inst *Instance) generateStatesFile(extensionName string, extensionPath string, runningToFailed bool) error {
 	log.Debug("Entering in... generateStatesFile")
 	log.Debug("Extension:" + extensionName)
 	manifestPath := filepath.Join(extensionPath, global.DefaultExtenstionManifestFile)
@@ -874,7 +879,7 @@ func generateStatesFile(extensionName string, extensionPath string, runningToFai
 	}
 	switch statesUpdateMode {
 	case "merge":
-		sm, err := GetStatesManager(extensionName)
+		sm, err := inst.GetStatesManager(extensionName)
 		if err != nil {
 			return err
 		}
@@ -912,7 +917,9 @@ func generateStatesFile(extensionName string, extensionPath string, runningToFai
 			return err
 		}
 	case "replace":
-		err = ioutil.WriteFile(filepath.Join(extensionPath, global.StatesFileName), newStatesB, 0644)
+		//The deferred updates of the replaced states are dropped
+		inst.removeStatesFile(filepath.Join(extensionPath, global.StatesFileName))
+		err = inst.statesStorage.WriteStates(filepath.Join(extensionPath, global.StatesFileName), newStatesB)
 		if err != nil {
 			return err
 		}
@@ -928,7 +935,7 @@ func generateStatesFile(extensionName string, extensionPath string, runningToFai
 	return nil
 }
 
-func GenerateTemplateFiles(extensionName string, extensionPath string) error {
+func (inst *Instance) GenerateTemplateFiles(extensionName string, extensionPath string) error {
 	log.Debug("Entering in... GenerateTemplateFiles")
 	manifestPath := filepath.Join(extensionPath, global.DefaultExtenstionManifestFile)
 	input, err := ioutil.ReadFile(manifestPath)
@@ -955,7 +962,7 @@ func GenerateTemplateFiles(extensionName string, extensionPath string) error {
 	}
 	for _, lang := range langs {
 		for uiMetadataName := range uiMetaData {
-			data, err := GenerateUIMetaDataTemplate(extensionName, uiMetadataName.(string), []string{lang.String()})
+			data, err := inst.GenerateUIMetaDataTemplate(extensionName, uiMetadataName.(string), []string{lang.String()})
 			if err != nil {
 				return err
 			}
@@ -969,10 +976,10 @@ func GenerateTemplateFiles(extensionName string, extensionPath string) error {
 }
 
 //UnregisterExtension delete and extension, deletion of Embedded extension is not permitted.
-func UnregisterExtension(extensionName string) error {
+func (inst *Instance) UnregisterExtension(extensionName string) error {
 	log.Debug("Entering in... UnregisterExtension")
 	log.Debug("extensionName:", extensionName)
-	isEmbeddedExtension, err := IsEmbeddedExtension(extensionName)
+	isEmbeddedExtension, err := inst.IsEmbeddedExtension(extensionName)
 	log.Debug("isEmbeddedExtension:" + strconv.FormatBool(isEmbeddedExtension))
 	if err != nil {
 		log.Debug(err.Error())
@@ -981,11 +988,11 @@ func UnregisterExtension(extensionName string) error {
 	if isEmbeddedExtension {
 		return errors.New("Deletion of embedded extension is not permitted")
 	}
-	log.Debug("IsCustomExtensionRegistered:" + strconv.FormatBool(IsCustomExtensionRegistered(extensionName)))
-	if !IsCustomExtensionRegistered(extensionName) {
-		return errors.New("This extension is not registered")
+	log.Debug("IsCustomExtensionRegistered:" + strconv.FormatBool(inst.IsCustomExtensionRegistered(extensionName)))
+	if !inst.IsCustomExtensionRegistered(extensionName) {
+		return apiError.NotFound(apiError.CodeExtensionNotFound, "This extension is not registered")
 	}
-	stateManager, errStateManager := GetStatesManager(extensionName)
+	stateManager, errStateManager := inst.GetStatesManager(extensionName)
 	if errStateManager == nil {
 		err = stateManager.readStates()
 		if err == nil {
@@ -994,7 +1001,11 @@ func UnregisterExtension(extensionName string) error {
 			}
 		}
 	}
-	err = os.RemoveAll(filepath.Join(GetExtensionsPathCustom(), extensionName))
+	err = inst.deleteExtensionDocuments(extensionName)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(filepath.Join(inst.GetExtensionsPathCustom(), extensionName))
 	if err != nil {
 		return err
 	}
@@ -1002,23 +1013,23 @@ func UnregisterExtension(extensionName string) error {
 }
 
 //DeleteFormerEmbeddedExtensions delete extensions which are not anymore defined in the extensionsEmbeddedFile
-func DeleteFormerEmbeddedExtensions() error {
+func (inst *Instance) DeleteFormerEmbeddedExtensions() error {
 	log.Debug("Entering in... deleteFormerEmbeddedExtensions")
 	//Retrieve the list of extension by browsing the extension embedded path directory
-	extensions, err := ListEmbeddedRegisteredExtensions()
+	extensions, err := inst.ListEmbeddedRegisteredExtensions()
 	if err != nil {
 		return err
 	}
 	//Loop on each extension found
 	for extensionName := range extensions.Extensions {
 		//Check if the extension is present in the extensionsEmbeddedFile
-		stillExtension, err := IsEmbeddedExtension(extensionName)
+		stillExtension, err := inst.IsEmbeddedExtension(extensionName)
 		if err != nil {
 			return err
 		}
 		//If not present then unregister it
 		if !stillExtension {
-			err = unregisterExtensionEmbedded(extensionName)
+			err = inst.unregisterExtensionEmbedded(extensionName)
 			if err != nil {
 				return err
 			}
@@ -1028,12 +1039,12 @@ func DeleteFormerEmbeddedExtensions() error {
 }
 
 //unregisterExtensionEmbedded delete an extension and the caller state if the exention was still inserted into another extension.
-func unregisterExtensionEmbedded(extensionName string) error {
+func (inst *Instance) unregisterExtensionEmbedded(extensionName string) error {
 	log.Debug("Entering in... unregisterExtensionEmbedded")
 	if extensionName == global.DefaultExtensionName {
 		return errors.New("The default extension " + extensionName + " can not be unregisted")
 	}
-	stateManager, errStateManager := GetStatesManager(extensionName)
+	stateManager, errStateManager := inst.GetStatesManager(extensionName)
 	if errStateManager != nil {
 		return errStateManager
 	}
@@ -1041,7 +1052,7 @@ func unregisterExtensionEmbedded(extensionName string) error {
 	if err == nil {
 		//Check if the extension has a parent
 		if stateManager.ParentExtensionName != "" {
-			parentStateManager, errParentStateManager := GetStatesManager(stateManager.ParentExtensionName)
+			parentStateManager, errParentStateManager := inst.GetStatesManager(stateManager.ParentExtensionName)
 			if errParentStateManager != nil {
 				return errParentStateManager
 			}
@@ -1052,8 +1063,12 @@ func unregisterExtensionEmbedded(extensionName string) error {
 			}
 		}
 	}
+	err = inst.deleteExtensionDocuments(stateManager.ExtensionName)
+	if err != nil {
+		return err
+	}
 	//Remove the extension from the extension embedded path
-	err = os.RemoveAll(filepath.Join(GetExtensionsPathEmbedded(), stateManager.ExtensionName))
+	err = os.RemoveAll(filepath.Join(inst.GetExtensionsPathEmbedded(), stateManager.ExtensionName))
 	if err != nil {
 		return err
 	}
@@ -101,11 +101,11 @@ func TestExtensionPathWithVersion(t *testing.T) {
 	}
 	SetExtensionsPath(extensionPath)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
-	path, err := getEmbeddedExtensionRepoPath("ext-template-v")
+	path, err := defaultInstance.getEmbeddedExtensionRepoPath("ext-template-v")
 	if err != nil {
 		t.Error(err.Error())
 	}
-	expectedPath := filepath.Join(embeddedExtensionsRepositoryPath, "ext-template-v", "1.0.0")
+	expectedPath := filepath.Join(defaultInstance.embeddedExtensionsRepositoryPath, "ext-template-v", "1.0.0")
 	if expectedPath != path {
 		t.Errorf("Got %s expected %s", path, expectedPath)
 	}
@@ -120,11 +120,11 @@ func TestExtensionPathWithOutVersion(t *testing.T) {
 	}
 	SetExtensionsPath(extensionPath)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
-	path, err := getEmbeddedExtensionRepoPath("ext-template")
+	path, err := defaultInstance.getEmbeddedExtensionRepoPath("ext-template")
 	if err != nil {
 		t.Error(err.Error())
 	}
-	expectedPath := filepath.Join(embeddedExtensionsRepositoryPath, "ext-template")
+	expectedPath := filepath.Join(defaultInstance.embeddedExtensionsRepositoryPath, "ext-template")
 	if expectedPath != path {
 		t.Errorf("Got %s expected %s", path, expectedPath)
 	}
@@ -139,7 +139,7 @@ func TestExtensionPathForNonExistExtension(t *testing.T) {
 	}
 	SetExtensionsPath(extensionPath)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
-	_, err = getEmbeddedExtensionRepoPath("not-exist")
+	_, err = defaultInstance.getEmbeddedExtensionRepoPath("not-exist")
 	if err == nil {
 		t.Error("Expecting an error as extension name not-exist doesn't exist")
 	}
@@ -0,0 +1,88 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+)
+
+//CheckStatesFiles reads and parses the states file of each registered extension
+func (inst *Instance) CheckStatesFiles() error {
+	log.Debug("Entering in... CheckStatesFiles")
+	extensions, err := inst.ListExtensions("", false)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(extensions.Extensions))
+	for name := range extensions.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errMessages := make([]string, 0)
+	for _, name := range names {
+		statesPath, err := inst.getStatePath(name)
+		if err != nil {
+			errMessages = append(errMessages, name+": "+err.Error())
+			continue
+		}
+		statesData, err := inst.statesStorage.ReadStates(statesPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				//Extension without states file
+				continue
+			}
+			errMessages = append(errMessages, name+": "+err.Error())
+			continue
+		}
+		var states States
+		err = yaml.Unmarshal(statesData, &states)
+		if err != nil {
+			errMessages = append(errMessages, name+": "+err.Error())
+		}
+	}
+	if len(errMessages) > 0 {
+		return errors.New(strings.Join(errMessages, ", "))
+	}
+	return nil
+}
+
+//CheckEmbeddedExtensionsRegistered checks that all embedded extensions are registered
+func (inst *Instance) CheckEmbeddedExtensionsRegistered() error {
+	log.Debug("Entering in... CheckEmbeddedExtensionsRegistered")
+	extensions, err := inst.ListEmbeddedExtensions()
+	if err != nil {
+		return err
+	}
+	notRegistered := make([]string, 0)
+	for name := range extensions.Extensions {
+		if !inst.IsEmbeddedExtensionRegistered(name) {
+			notRegistered = append(notRegistered, name)
+		}
+	}
+	if len(notRegistered) > 0 {
+		sort.Strings(notRegistered)
+		return errors.New("Embedded extensions not registered: " + strings.Join(notRegistered, ", "))
+	}
+	return nil
+}
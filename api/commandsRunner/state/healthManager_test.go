@@ -0,0 +1,72 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+func TestCheckStatesFiles(t *testing.T) {
+	t.Log("Entering... TestCheckStatesFiles")
+	extensionPath, err := global.CopyToTemp("TestCheckStatesFiles", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestCheckStatesFiles")
+	SetExtensionsPath(extensionPath)
+	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
+	err = CheckStatesFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	statesPath, err := defaultInstance.getStatePath("ext-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(statesPath, []byte("states: [ not valid"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckStatesFiles()
+	if err == nil || !strings.Contains(err.Error(), "ext-template:") {
+		t.Errorf("Expected an error for ext-template, got %v", err)
+	}
+}
+
+func TestCheckEmbeddedExtensionsRegistered(t *testing.T) {
+	t.Log("Entering... TestCheckEmbeddedExtensionsRegistered")
+	extensionPath, err := global.CopyToTemp("TestCheckEmbeddedExtensionsRegistered", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestCheckEmbeddedExtensionsRegistered")
+	SetExtensionsPath(extensionPath)
+	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
+	//ext-template-v is declared in the embedded extensions but not present in the test data
+	err = CheckEmbeddedExtensionsRegistered()
+	if err == nil || !strings.Contains(err.Error(), "ext-template-v") {
+		t.Errorf("Expected ext-template-v not registered, got %v", err)
+	}
+	if strings.Contains(err.Error(), "ext-template,") {
+		t.Errorf("ext-template is registered, got %v", err)
+	}
+}
@@ -0,0 +1,424 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/metrics"
+)
+
+/*
+Instance holds what a runner serves: its config directory, its extensions paths, its state managers, its mock flag,
+its storage, its event subscribers and its running executions. Several instances can serve in one process,
+the package functions and the requests not served by a runner use the default instance.
+*/
+type Instance struct {
+	//configDir directory of the config, the tokens and the webhooks of the instance
+	configDir string
+	//The extensions settings, see InitExtensions
+	extensionsEmbeddedFile           string
+	embeddedExtensionsRepositoryPath string
+	extensionsPath                   string
+	extensionsPathEmbedded           string
+	extensionsPathCustom             string
+	extensionsLogsPath               string
+	extensionsLogsPathEmbedded       string
+	extensionsLogsPathCustom         string
+	//mock if true the scripts are skipped
+	mock bool
+	//stateManagers the state managers by extension name
+	stateManagers    map[string]States
+	stateManagersMux sync.Mutex
+	statesStorage    StatesStorage
+	runsStorage      RunsStorage
+	configStorage    ConfigStorage
+	//statesCache the parsed states files by path, it is the in-memory model of the states.
+	//An entry is used as long as the version of the states in the storage doesn't change (ie: the file size and modification time),
+	//otherwise the states are read and only parsed if their content changed.
+	statesCache    map[string]*statesCacheEntry
+	statesCacheMux sync.Mutex
+	//runIDs the run UUID of the running extensions, an extension executed by another one shares the run of its caller
+	runIDs    map[string]string
+	runIDsMux sync.Mutex
+	//The event subscribers and listeners
+	subscribersMux sync.Mutex
+	subscribers    map[*eventSubscriber]bool
+	eventListeners []EventListener
+	//The running executions, see Shutdown
+	executionsMux     sync.Mutex
+	shuttingDown      bool
+	runningExecutions sync.WaitGroup
+	cancelExecutions  chan struct{}
+	cancelOnce        sync.Once
+	//metrics the metrics of the runs, the states and the http requests served with the instance
+	metrics *metrics.Metrics
+}
+
+type contextKey int
+
+//instanceKey the context key set on the requests served by an instance
+const instanceKey contextKey = 0
+
+//lockedStatesKey the context key of the states locked by updateStates
+const lockedStatesKey contextKey = 1
+
+//defaultInstance the instance of the package functions
+var defaultInstance = NewInstance()
+
+//NewInstance creates an instance using the file storage, its extensions paths must be set before it serves.
+func NewInstance() *Instance {
+	return &Instance{
+		extensionsPathEmbedded:     extensionsDirEmbedded,
+		extensionsPathCustom:       extensionsDirCustom,
+		extensionsLogsPathEmbedded: extensionsDirEmbedded,
+		extensionsLogsPathCustom:   CustomExtensions,
+		stateManagers:              make(map[string]States),
+		statesStorage:              FileStatesStorage{},
+		runsStorage:                FileStatesStorage{},
+		configStorage:              FileConfigStorage{},
+		statesCache:                make(map[string]*statesCacheEntry),
+		runIDs:                     make(map[string]string),
+		subscribers:                make(map[*eventSubscriber]bool),
+		eventListeners:             make([]EventListener, 0),
+		cancelExecutions:           make(chan struct{}),
+		metrics:                    metrics.New(),
+	}
+}
+
+//DefaultInstance returns the instance of the package functions
+func DefaultInstance() *Instance {
+	return defaultInstance
+}
+
+//Metrics returns the metrics of the instance
+func (inst *Instance) Metrics() *metrics.Metrics {
+	return inst.metrics
+}
+
+//WithInstance returns the request marked as served by the instance
+func WithInstance(req *http.Request, inst *Instance) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), instanceKey, inst))
+}
+
+//GetInstance returns the instance serving the request, the default instance if the request is not served by a runner
+func GetInstance(req *http.Request) *Instance {
+	if inst, ok := req.Context().Value(instanceKey).(*Instance); ok {
+		return inst
+	}
+	return defaultInstance
+}
+
+//instance returns the instance of the state manager, the default instance if it was not created by an instance
+func (sm *States) instance() *Instance {
+	if sm.inst == nil {
+		return defaultInstance
+	}
+	return sm.inst
+}
+
+//SetConfigDir sets the directory of the config, the tokens and the webhooks of the instance
+func (inst *Instance) SetConfigDir(configDir string) {
+	inst.configDir = configDir
+}
+
+//GetConfigDir returns the directory of the config, the tokens and the webhooks of the instance
+func (inst *Instance) GetConfigDir() string {
+	return inst.configDir
+}
+
+/*
+The package functions below call the methods of the default instance.
+*/
+
+//AddEventListener calls Instance.AddEventListener on the default instance
+func AddEventListener(listener EventListener) {
+	defaultInstance.AddEventListener(listener)
+}
+
+//SubscribeEvents calls Instance.SubscribeEvents on the default instance
+func SubscribeEvents(extensionName string) (<-chan Event, func()) {
+	return defaultInstance.SubscribeEvents(extensionName)
+}
+
+//PublishEvent calls Instance.PublishEvent on the default instance
+func PublishEvent(event Event) {
+	defaultInstance.PublishEvent(event)
+}
+
+//InitExtensions calls Instance.InitExtensions on the default instance
+func InitExtensions(embeddedExtensionsDescriptor string, embeddedExtensionsRepositoryPath string, extensionsPath string, extensionsLogsPath string) {
+	defaultInstance.InitExtensions(embeddedExtensionsDescriptor, embeddedExtensionsRepositoryPath, extensionsPath, extensionsLogsPath)
+}
+
+//SetExtensionsEmbeddedFile calls Instance.SetExtensionsEmbeddedFile on the default instance
+func SetExtensionsEmbeddedFile(_extensionsEmbeddedFile string) {
+	defaultInstance.SetExtensionsEmbeddedFile(_extensionsEmbeddedFile)
+}
+
+//SetEmbeddedExtensionsRepositoryPath calls Instance.SetEmbeddedExtensionsRepositoryPath on the default instance
+func SetEmbeddedExtensionsRepositoryPath(_embeddedExtensionsRepositoryPath string) error {
+	return defaultInstance.SetEmbeddedExtensionsRepositoryPath(_embeddedExtensionsRepositoryPath)
+}
+
+//SetExtensionsPath calls Instance.SetExtensionsPath on the default instance
+func SetExtensionsPath(_extensionsPath string) {
+	defaultInstance.SetExtensionsPath(_extensionsPath)
+}
+
+//SetExtensionsLogsPath calls Instance.SetExtensionsLogsPath on the default instance
+func SetExtensionsLogsPath(_extensionsLogsPath string) {
+	defaultInstance.SetExtensionsLogsPath(_extensionsLogsPath)
+}
+
+//GetExtensionsPath calls Instance.GetExtensionsPath on the default instance
+func GetExtensionsPath() string {
+	return defaultInstance.GetExtensionsPath()
+}
+
+//GetExtensionsPathEmbedded calls Instance.GetExtensionsPathEmbedded on the default instance
+func GetExtensionsPathEmbedded() string {
+	return defaultInstance.GetExtensionsPathEmbedded()
+}
+
+//GetExtensionsPathCustom calls Instance.GetExtensionsPathCustom on the default instance
+func GetExtensionsPathCustom() string {
+	return defaultInstance.GetExtensionsPathCustom()
+}
+
+//GetExtensionsLogsPathEmbedded calls Instance.GetExtensionsLogsPathEmbedded on the default instance
+func GetExtensionsLogsPathEmbedded() string {
+	return defaultInstance.GetExtensionsLogsPathEmbedded()
+}
+
+//GetExtensionsLogsPathCustom calls Instance.GetExtensionsLogsPathCustom on the default instance
+func GetExtensionsLogsPathCustom() string {
+	return defaultInstance.GetExtensionsLogsPathCustom()
+}
+
+//GetRepoLocalPath calls Instance.GetRepoLocalPath on the default instance
+func GetRepoLocalPath() string {
+	return defaultInstance.GetRepoLocalPath()
+}
+
+//GetRegisteredExtensionPath calls Instance.GetRegisteredExtensionPath on the default instance
+func GetRegisteredExtensionPath(extensionName string) (string, error) {
+	return defaultInstance.GetRegisteredExtensionPath(extensionName)
+}
+
+//GetRelativeExtensionPath calls Instance.GetRelativeExtensionPath on the default instance
+func GetRelativeExtensionPath(extensionName string) string {
+	return defaultInstance.GetRelativeExtensionPath(extensionName)
+}
+
+//GetRootExtensionPath calls Instance.GetRootExtensionPath on the default instance
+func GetRootExtensionPath(rootDir string, extensionName string) string {
+	return defaultInstance.GetRootExtensionPath(rootDir, extensionName)
+}
+
+//IsExtensionRegistered calls Instance.IsExtensionRegistered on the default instance
+func IsExtensionRegistered(extensionName string) bool {
+	return defaultInstance.IsExtensionRegistered(extensionName)
+}
+
+//IsCustomExtensionRegistered calls Instance.IsCustomExtensionRegistered on the default instance
+func IsCustomExtensionRegistered(filename string) bool {
+	return defaultInstance.IsCustomExtensionRegistered(filename)
+}
+
+//IsEmbeddedExtensionRegistered calls Instance.IsEmbeddedExtensionRegistered on the default instance
+func IsEmbeddedExtensionRegistered(filename string) bool {
+	return defaultInstance.IsEmbeddedExtensionRegistered(filename)
+}
+
+//CopyExtensionToEmbeddedExtensionPath calls Instance.CopyExtensionToEmbeddedExtensionPath on the default instance
+func CopyExtensionToEmbeddedExtensionPath(extensionName string) error {
+	return defaultInstance.CopyExtensionToEmbeddedExtensionPath(extensionName)
+}
+
+//IsCustomExtension calls Instance.IsCustomExtension on the default instance
+func IsCustomExtension(extensionName string) (bool, error) {
+	return defaultInstance.IsCustomExtension(extensionName)
+}
+
+//IsEmbeddedExtension calls Instance.IsEmbeddedExtension on the default instance
+func IsEmbeddedExtension(extensionName string) (bool, error) {
+	return defaultInstance.IsEmbeddedExtension(extensionName)
+}
+
+//ReadRegisteredExtension calls Instance.ReadRegisteredExtension on the default instance
+func ReadRegisteredExtension(extensionName string) (*Extension, error) {
+	return defaultInstance.ReadRegisteredExtension(extensionName)
+}
+
+//ListEmbeddedRegisteredExtensions calls Instance.ListEmbeddedRegisteredExtensions on the default instance
+func ListEmbeddedRegisteredExtensions() (*Extensions, error) {
+	return defaultInstance.ListEmbeddedRegisteredExtensions()
+}
+
+//ListRegisteredCustomExtensions calls Instance.ListRegisteredCustomExtensions on the default instance
+func ListRegisteredCustomExtensions() (*Extensions, error) {
+	return defaultInstance.ListRegisteredCustomExtensions()
+}
+
+//ListEmbeddedExtensions calls Instance.ListEmbeddedExtensions on the default instance
+func ListEmbeddedExtensions() (*Extensions, error) {
+	return defaultInstance.ListEmbeddedExtensions()
+}
+
+//ListExtensions calls Instance.ListExtensions on the default instance
+func ListExtensions(filter string, catalog bool) (*Extensions, error) {
+	return defaultInstance.ListExtensions(filter, catalog)
+}
+
+//RegisterEmbededExtensions calls Instance.RegisterEmbededExtensions on the default instance
+func RegisterEmbededExtensions(force bool, runningToFailed bool) error {
+	return defaultInstance.RegisterEmbededExtensions(force, runningToFailed)
+}
+
+//RegisterExtension calls Instance.RegisterExtension on the default instance
+func RegisterExtension(extensionName, zipPath string, force bool, runningToFailed bool) error {
+	return defaultInstance.RegisterExtension(extensionName, zipPath, force, runningToFailed)
+}
+
+//GenerateTemplateFiles calls Instance.GenerateTemplateFiles on the default instance
+func GenerateTemplateFiles(extensionName string, extensionPath string) error {
+	return defaultInstance.GenerateTemplateFiles(extensionName, extensionPath)
+}
+
+//UnregisterExtension calls Instance.UnregisterExtension on the default instance
+func UnregisterExtension(extensionName string) error {
+	return defaultInstance.UnregisterExtension(extensionName)
+}
+
+//DeleteFormerEmbeddedExtensions calls Instance.DeleteFormerEmbeddedExtensions on the default instance
+func DeleteFormerEmbeddedExtensions() error {
+	return defaultInstance.DeleteFormerEmbeddedExtensions()
+}
+
+//CheckStatesFiles calls Instance.CheckStatesFiles on the default instance
+func CheckStatesFiles() error {
+	return defaultInstance.CheckStatesFiles()
+}
+
+//CheckEmbeddedExtensionsRegistered calls Instance.CheckEmbeddedExtensionsRegistered on the default instance
+func CheckEmbeddedExtensionsRegistered() error {
+	return defaultInstance.CheckEmbeddedExtensionsRegistered()
+}
+
+//SetRunsStorage calls Instance.SetRunsStorage on the default instance
+func SetRunsStorage(storage RunsStorage) {
+	defaultInstance.SetRunsStorage(storage)
+}
+
+//GetRunsStorage calls Instance.GetRunsStorage on the default instance
+func GetRunsStorage() RunsStorage {
+	return defaultInstance.GetRunsStorage()
+}
+
+//GetRuns calls Instance.GetRuns on the default instance
+func GetRuns(extensionName string, status string, limit int) (*Runs, error) {
+	return defaultInstance.GetRuns(extensionName, status, limit)
+}
+
+//MigrateRuns calls Instance.MigrateRuns on the default instance
+func MigrateRuns(from RunsStorage, to RunsStorage) ([]string, error) {
+	return defaultInstance.MigrateRuns(from, to)
+}
+
+//GetRunID calls Instance.GetRunID on the default instance
+func GetRunID(extensionName string) string {
+	return defaultInstance.GetRunID(extensionName)
+}
+
+//IsShuttingDown calls Instance.IsShuttingDown on the default instance
+func IsShuttingDown() bool {
+	return defaultInstance.IsShuttingDown()
+}
+
+//Shutdown calls Instance.Shutdown on the default instance
+func Shutdown(ctx context.Context) error {
+	return defaultInstance.Shutdown(ctx)
+}
+
+//GetStatesManager calls Instance.GetStatesManager on the default instance
+func GetStatesManager(extensionName string) (*States, error) {
+	return defaultInstance.GetStatesManager(extensionName)
+}
+
+//GetConfigOverlay calls Instance.GetConfigOverlay on the default instance
+func GetConfigOverlay(extensionName string) string {
+	return defaultInstance.GetConfigOverlay(extensionName)
+}
+
+//SetMock calls Instance.SetMock on the default instance
+func SetMock(mock bool) {
+	defaultInstance.SetMock(mock)
+}
+
+//GetMock calls Instance.GetMock on the default instance
+func GetMock() bool {
+	return defaultInstance.GetMock()
+}
+
+//SetStatesStorage calls Instance.SetStatesStorage on the default instance
+func SetStatesStorage(storage StatesStorage) {
+	defaultInstance.SetStatesStorage(storage)
+}
+
+//GetStatesStorage calls Instance.GetStatesStorage on the default instance
+func GetStatesStorage() StatesStorage {
+	return defaultInstance.GetStatesStorage()
+}
+
+//MigrateStates calls Instance.MigrateStates on the default instance
+func MigrateStates(from StatesStorage, to StatesStorage) ([]string, error) {
+	return defaultInstance.MigrateStates(from, to)
+}
+
+//GenerateUIMetaDataTemplate calls Instance.GenerateUIMetaDataTemplate on the default instance
+func GenerateUIMetaDataTemplate(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+	return defaultInstance.GenerateUIMetaDataTemplate(extensionName, uiMetadataName, langs)
+}
+
+//GetUIMetaDataConfig calls Instance.GetUIMetaDataConfig on the default instance
+func GetUIMetaDataConfig(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+	return defaultInstance.GetUIMetaDataConfig(extensionName, uiMetadataName, langs)
+}
+
+//GetUIMetaDataJSONSchema calls Instance.GetUIMetaDataJSONSchema on the default instance
+func GetUIMetaDataJSONSchema(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+	return defaultInstance.GetUIMetaDataJSONSchema(extensionName, uiMetadataName, langs)
+}
+
+//GetUIMetaDataConfigs calls Instance.GetUIMetaDataConfigs on the default instance
+func GetUIMetaDataConfigs(extensionName string, namesOnly bool, langs []string) ([]byte, error) {
+	return defaultInstance.GetUIMetaDataConfigs(extensionName, namesOnly, langs)
+}
+
+//SetConfigStorage calls Instance.SetConfigStorage on the default instance
+func SetConfigStorage(storage ConfigStorage) {
+	defaultInstance.SetConfigStorage(storage)
+}
+
+//GetConfigStorage calls Instance.GetConfigStorage on the default instance
+func GetConfigStorage() ConfigStorage {
+	return defaultInstance.GetConfigStorage()
+}
@@ -0,0 +1,261 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//TotalCountHeader is the response header containing the number of items matching a filter before pagination
+const TotalCountHeader = "X-Total-Count"
+
+const SortByPosition = "position"
+const SortByName = "name"
+const SortByDuration = "duration"
+
+//StatesFilter selects, sorts and paginates a list of states.
+//Name and Label are glob patterns, times are compared with the state start_time/end_time and
+//states without the corresponding time never match a time range.
+//Sort is position, name or duration, prefixed with "-" for a descending order.
+//A Limit of 0 returns all states after the Offset.
+type StatesFilter struct {
+	Name          string
+	Label         string
+	IsExtension   *bool
+	StartedAfter  time.Time
+	StartedBefore time.Time
+	EndedAfter    time.Time
+	EndedBefore   time.Time
+	Sort          string
+	Offset        int
+	Limit         int
+	Fields        []string
+}
+
+//ExtensionsFilter selects and paginates a list of extensions, the extensions are paginated in name order.
+type ExtensionsFilter struct {
+	Name   string
+	Offset int
+	Limit  int
+	Fields []string
+}
+
+//Apply returns the page of states matching the filter and the total number of matching states.
+func (f StatesFilter) Apply(states []State) ([]State, int, error) {
+	log.Debug("Entering in... StatesFilter.Apply")
+	if f.Offset < 0 || f.Limit < 0 {
+		return nil, 0, errors.New("offset and limit must be positive")
+	}
+	result := make([]State, 0)
+	for _, state := range states {
+		ok, err := f.match(state)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			result = append(result, state)
+		}
+	}
+	err := sortStates(result, f.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	start, end := pageBounds(len(result), f.Offset, f.Limit)
+	return result[start:end], len(result), nil
+}
+
+func (f StatesFilter) match(state State) (bool, error) {
+	if f.Name != "" {
+		ok, err := path.Match(f.Name, state.Name)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if f.Label != "" {
+		ok, err := path.Match(f.Label, state.Label)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if f.IsExtension != nil && *f.IsExtension != state.IsExtension {
+		return false, nil
+	}
+	return inTimeRange(state.StartTime, f.StartedAfter, f.StartedBefore) &&
+		inTimeRange(state.EndTime, f.EndedAfter, f.EndedBefore), nil
+}
+
+//Check if a state time is in the [after, before] range, a zero bound is ignored.
+func inTimeRange(value string, after time.Time, before time.Time) bool {
+	if after.IsZero() && before.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.UnixDate, value)
+	if err != nil {
+		return false
+	}
+	return (after.IsZero() || !t.Before(after)) && (before.IsZero() || !t.After(before))
+}
+
+//Returns the execution duration of a state, 0 if the state didn't run or is still running.
+func stateDuration(state State) time.Duration {
+	startTime, err := time.Parse(time.UnixDate, state.StartTime)
+	if err != nil {
+		return 0
+	}
+	endTime, err := time.Parse(time.UnixDate, state.EndTime)
+	if err != nil {
+		return 0
+	}
+	return endTime.Sub(startTime)
+}
+
+func sortStates(states []State, sortBy string) error {
+	descending := strings.HasPrefix(sortBy, "-")
+	var less func(i, j int) bool
+	switch strings.TrimPrefix(sortBy, "-") {
+	case "", SortByPosition:
+		if descending {
+			for i, j := 0, len(states)-1; i < j; i, j = i+1, j-1 {
+				states[i], states[j] = states[j], states[i]
+			}
+		}
+		return nil
+	case SortByName:
+		less = func(i, j int) bool { return states[i].Name < states[j].Name }
+	case SortByDuration:
+		less = func(i, j int) bool { return stateDuration(states[i]) < stateDuration(states[j]) }
+	default:
+		return errors.New("Unsupported sort: " + sortBy + ", must be " + SortByPosition + ", " + SortByName + " or " + SortByDuration)
+	}
+	if descending {
+		sort.SliceStable(states, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(states, less)
+	}
+	return nil
+}
+
+//Apply returns the page of extensions matching the filter and the total number of matching extensions.
+func (f ExtensionsFilter) Apply(extensions *Extensions) (*Extensions, int, error) {
+	log.Debug("Entering in... ExtensionsFilter.Apply")
+	if f.Offset < 0 || f.Limit < 0 {
+		return nil, 0, errors.New("offset and limit must be positive")
+	}
+	names := make([]string, 0)
+	for name := range extensions.Extensions {
+		if f.Name != "" {
+			ok, err := path.Match(f.Name, name)
+			if err != nil {
+				return nil, 0, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	start, end := pageBounds(len(names), f.Offset, f.Limit)
+	result := &Extensions{
+		Extensions: make(map[string]Extension),
+	}
+	for _, name := range names[start:end] {
+		result.Extensions[name] = extensions.Extensions[name]
+	}
+	return result, len(names), nil
+}
+
+func pageBounds(total int, offset int, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+//SelectFields converts a value to json and keeps only the listed fields.
+//The value must be a struct, a slice of structs or a map of structs, the fields are the json names of the struct fields.
+func SelectFields(value interface{}, fields []string) (interface{}, error) {
+	log.Debug("Entering in... SelectFields")
+	elemType := reflect.TypeOf(value)
+	for elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Map {
+		elemType = elemType.Elem()
+	}
+	known := make(map[string]bool)
+	for i := 0; i < elemType.NumField(); i++ {
+		name := strings.Split(elemType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	for _, field := range fields {
+		if !known[field] {
+			return nil, errors.New("Unknown field: " + field)
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	selectFields := func(elem interface{}) interface{} {
+		in, ok := elem.(map[string]interface{})
+		if !ok {
+			return elem
+		}
+		out := make(map[string]interface{})
+		for _, field := range fields {
+			if v, ok := in[field]; ok {
+				out[field] = v
+			}
+		}
+		return out
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Slice:
+		if list, ok := raw.([]interface{}); ok {
+			for i := range list {
+				list[i] = selectFields(list[i])
+			}
+		}
+		return raw, nil
+	case reflect.Map:
+		if m, ok := raw.(map[string]interface{}); ok {
+			for k := range m {
+				m[k] = selectFields(m[k])
+			}
+		}
+		return raw, nil
+	}
+	return selectFields(raw), nil
+}
@@ -0,0 +1,171 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+func queryTestStates() []State {
+	return []State{
+		{Name: "state1", Label: "Step 1", StartTime: "Mon Jan  7 10:00:00 UTC 2019", EndTime: "Mon Jan  7 10:05:00 UTC 2019"},
+		{Name: "ext1", Label: "Extension 1", IsExtension: true, StartTime: "Mon Jan  7 10:05:00 UTC 2019", EndTime: "Mon Jan  7 10:06:00 UTC 2019"},
+		{Name: "state2", Label: "Step 2", StartTime: "Mon Jan  7 10:06:00 UTC 2019", EndTime: "Mon Jan  7 10:16:00 UTC 2019"},
+		{Name: "state3", Label: "Step 3"},
+	}
+}
+
+func stateNames(states []State) []string {
+	names := make([]string, 0)
+	for _, state := range states {
+		names = append(names, state.Name)
+	}
+	return names
+}
+
+func TestStatesFilterApply(t *testing.T) {
+	t.Log("Entering................. TestStatesFilterApply")
+	isExtension := false
+	tests := []struct {
+		filter   StatesFilter
+		expected []string
+		total    int
+	}{
+		{StatesFilter{}, []string{"state1", "ext1", "state2", "state3"}, 4},
+		{StatesFilter{Name: "state*"}, []string{"state1", "state2", "state3"}, 3},
+		{StatesFilter{Label: "Extension*"}, []string{"ext1"}, 1},
+		{StatesFilter{IsExtension: &isExtension, Limit: 2}, []string{"state1", "state2"}, 3},
+		{StatesFilter{Offset: 1, Limit: 2}, []string{"ext1", "state2"}, 4},
+		{StatesFilter{Offset: 10}, []string{}, 4},
+		{StatesFilter{StartedAfter: time.Date(2019, 1, 7, 10, 5, 0, 0, time.UTC)}, []string{"ext1", "state2"}, 2},
+		{StatesFilter{EndedBefore: time.Date(2019, 1, 7, 10, 6, 0, 0, time.UTC)}, []string{"state1", "ext1"}, 2},
+		{StatesFilter{Sort: "-position"}, []string{"state3", "state2", "ext1", "state1"}, 4},
+		{StatesFilter{Sort: "name"}, []string{"ext1", "state1", "state2", "state3"}, 4},
+		{StatesFilter{Sort: "-duration"}, []string{"state2", "state1", "ext1", "state3"}, 4},
+	}
+	for _, test := range tests {
+		states, total, err := test.filter.Apply(queryTestStates())
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := stateNames(states)
+		if total != test.total || len(names) != len(test.expected) {
+			t.Errorf("Filter %+v returned %v (total %d), expected %v (total %d)", test.filter, names, total, test.expected, test.total)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.expected[i] {
+				t.Errorf("Filter %+v returned %v, expected %v", test.filter, names, test.expected)
+				break
+			}
+		}
+	}
+	_, _, err := StatesFilter{Sort: "label"}.Apply(queryTestStates())
+	if err == nil {
+		t.Error("Expecting an error for an unsupported sort")
+	}
+	_, _, err = StatesFilter{Name: "["}.Apply(queryTestStates())
+	if err == nil {
+		t.Error("Expecting an error for a malformed pattern")
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	t.Log("Entering................. TestSelectFields")
+	selected, err := SelectFields(queryTestStates()[:1], []string{"name", "start_time"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(selected)
+	expected := "[{\"name\":\"state1\",\"start_time\":\"Mon Jan  7 10:00:00 UTC 2019\"}]"
+	if string(data) != expected {
+		t.Errorf("Got %s, expected %s", data, expected)
+	}
+	_, err = SelectFields(queryTestStates(), []string{"unknown"})
+	if err == nil {
+		t.Error("Expecting an error for an unknown field")
+	}
+}
+
+func TestExtensionsFilterApply(t *testing.T) {
+	t.Log("Entering................. TestExtensionsFilterApply")
+	extensions := &Extensions{
+		Extensions: map[string]Extension{
+			"ext-b": {Type: "custom"},
+			"ext-a": {Type: "custom"},
+			"other": {Type: "embedded"},
+		},
+	}
+	result, total, err := ExtensionsFilter{Name: "ext-*", Limit: 1}.Apply(extensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Extensions["ext-a"]; total != 2 || len(result.Extensions) != 1 || !ok {
+		t.Errorf("Unexpected result %v (total %d)", result.Extensions, total)
+	}
+}
+
+func TestStatesPaginated(t *testing.T) {
+	t.Log("Entering................. TestStatesPaginated")
+	extensionPath, err := global.CopyToTemp("TestStatesPaginated", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestStatesPaginated")
+	SetExtensionsPath(extensionPath)
+	req, err := http.NewRequest("GET", "/cr/v1/states?extension-name=state-handler-test&sort=-name&offset=1&limit=2&fields=name,status", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(HandleStates)
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if total := rr.Header().Get(TotalCountHeader); total != "5" {
+		t.Errorf("Wrong total count: got %s want 5", total)
+	}
+	var result struct {
+		States        []map[string]string `json:"states"`
+		ExtensionName string              `json:"extension_name"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExtensionName != "state-handler-test" || len(result.States) != 2 {
+		t.Fatalf("Unexpected body: %s", rr.Body.String())
+	}
+	if result.States[0]["name"] != "state2" || result.States[1]["name"] != "state1" || len(result.States[0]) != 2 {
+		t.Errorf("Unexpected states: %v", result.States)
+	}
+
+	req, _ = http.NewRequest("GET", "/cr/v1/states?extension-name=state-handler-test&limit=-1", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+//Run a completed run of an extension, the runs of the inserted extensions are part of the run of their caller
+type Run struct {
+	//ID the run UUID, the run_id of the log entries
+	ID            string `json:"id"`
+	ExtensionName string `json:"extension_name"`
+	ExecutionID   int    `json:"execution_id"`
+	FromState     string `json:"from_state"`
+	ToState       string `json:"to_state"`
+	//StartTime and EndTime are RFC3339 dates
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+//Runs the run history of an extension, the most recent run first
+type Runs struct {
+	Runs []Run `json:"runs"`
+}
+
+//RunsStorage persists the run history of the extensions, the history of an extension is identified by the path of its states file.
+type RunsStorage interface {
+	//AddRun appends a run to the history
+	AddRun(path string, run Run) error
+	//ListRuns returns the runs having the status (all if empty), the most recent first and at most limit runs (all if 0)
+	ListRuns(path string, status string, limit int) ([]Run, error)
+	//DeleteRuns removes the history, it doesn't fail if there is no history
+	DeleteRuns(path string) error
+}
+
+//SetRunsStorage sets the storage of the run history, it must be called before the runs are accessed.
+func (inst *Instance) SetRunsStorage(storage RunsStorage) {
+	inst.runsStorage = storage
+}
+
+//GetRunsStorage returns the storage of the run history
+func (inst *Instance) GetRunsStorage() RunsStorage {
+	return inst.runsStorage
+}
+
+//getRunsPath returns the run history file next to the states file
+func getRunsPath(path string) string {
+	return filepath.Join(filepath.Dir(path), global.RunsFileName)
+}
+
+//AddRun appends the run to the run history file under its file lock
+func (FileStatesStorage) AddRun(path string, run Run) error {
+	runsPath := getRunsPath(path)
+	unlockFile, err := global.LockFileExclusive(runsPath)
+	if err != nil {
+		return err
+	}
+	defer unlockFile()
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(runsPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	errClose := file.Close()
+	if err != nil {
+		return err
+	}
+	return errClose
+}
+
+//ListRuns reads the run history file, the lines which can not be parsed are skipped
+func (FileStatesStorage) ListRuns(path string, status string, limit int) ([]Run, error) {
+	runs := make([]Run, 0)
+	file, err := os.Open(getRunsPath(path))
+	if os.IsNotExist(err) {
+		return runs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if errRun := json.Unmarshal(scanner.Bytes(), &run); errRun != nil {
+			log.Warning("Invalid run in " + getRunsPath(path) + ": " + errRun.Error())
+			continue
+		}
+		if status == "" || run.Status == status {
+			runs = append(runs, run)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	//Most recent first
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+//DeleteRuns removes the run history file
+func (FileStatesStorage) DeleteRuns(path string) error {
+	err := os.Remove(getRunsPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//GetRuns returns the run history of an extension, the most recent run first.
+//status filters the runs (all if empty) and limit is the maximum number of runs (all if 0).
+func (inst *Instance) GetRuns(extensionName string, status string, limit int) (*Runs, error) {
+	log.Debug("Entering in... GetRuns")
+	_, err := inst.GetRegisteredExtensionPath(extensionName)
+	if err != nil {
+		return nil, err
+	}
+	statesPath, err := inst.getStatePath(extensionName)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := inst.runsStorage.ListRuns(statesPath, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Runs{Runs: runs}, nil
+}
+
+//recordRun adds the completed run to the run history, a failure is only logged to not fail the run
+func (sm *States) recordRun(fromState string, toState string, startTime time.Time, status string, errRun error) {
+	inst := sm.instance()
+	run := Run{
+		ID:            inst.GetRunID(sm.ExtensionName),
+		ExtensionName: sm.ExtensionName,
+		ExecutionID:   sm.ExecutionID,
+		FromState:     fromState,
+		ToState:       toState,
+		StartTime:     startTime.UTC().Format(time.RFC3339),
+		EndTime:       time.Now().UTC().Format(time.RFC3339),
+		Status:        status,
+	}
+	if errRun != nil {
+		run.Reason = errRun.Error()
+	}
+	err := inst.runsStorage.AddRun(sm.StatesPath, run)
+	if err != nil {
+		sm.runLog("").Error("Unable to record the run: " + err.Error())
+	}
+}
+
+//MigrateRuns copies the run history of the registered extensions from a storage to another, it returns the migrated extensions.
+func (inst *Instance) MigrateRuns(from RunsStorage, to RunsStorage) ([]string, error) {
+	log.Debug("Entering in... MigrateRuns")
+	extensions, err := inst.ListExtensions("", false)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(extensions.Extensions))
+	for name := range extensions.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	migrated := make([]string, 0)
+	for _, name := range names {
+		statesPath, err := inst.getStatePath(name)
+		if err != nil {
+			return migrated, err
+		}
+		runs, err := from.ListRuns(statesPath, "", 0)
+		if err != nil {
+			return migrated, err
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		err = to.DeleteRuns(statesPath)
+		if err != nil {
+			return migrated, err
+		}
+		//Oldest first
+		for i := len(runs) - 1; i >= 0; i-- {
+			err = to.AddRun(statesPath, runs[i])
+			if err != nil {
+				return migrated, err
+			}
+		}
+		log.Info("Run history of " + name + " migrated")
+		migrated = append(migrated, name)
+	}
+	return migrated, nil
+}
@@ -0,0 +1,119 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFileRunsStorage(t *testing.T) {
+	t.Log("Entering... TestFileRunsStorage")
+	dir, err := ioutil.TempDir("", "TestFileRunsStorage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statesPath := filepath.Join(dir, "states-file.yml")
+	storage := FileStatesStorage{}
+	runs, err := storage.ListRuns(statesPath, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("Expected no run got %d", len(runs))
+	}
+	for i := 1; i <= 5; i++ {
+		status := StateSUCCEEDED
+		if i%2 == 0 {
+			status = StateFAILED
+		}
+		err = storage.AddRun(statesPath, Run{ID: "run" + strconv.Itoa(i), ExecutionID: i, Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err = storage.ListRuns(statesPath, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run5" || runs[1].ID != "run4" {
+		t.Errorf("Expected the 2 most recent runs got %v", runs)
+	}
+	runs, err = storage.ListRuns(statesPath, StateFAILED, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run4" || runs[1].ID != "run2" {
+		t.Errorf("Expected the failed runs got %v", runs)
+	}
+	err = storage.DeleteRuns(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err = storage.ListRuns(statesPath, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("Expected no run after the deletion got %d", len(runs))
+	}
+}
+
+func TestRecordRun(t *testing.T) {
+	t.Log("Entering... TestRecordRun")
+	dir, err := ioutil.TempDir("", "TestRecordRun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sm := newStateManager("states-TestRecordRun")
+	sm.StatesPath = filepath.Join(dir, "states-TestRecordRun.yaml")
+	sm.StateArray = []State{
+		{Name: "echo", Status: StateREADY, Script: "echo line1", ScriptTimeout: 10, LogPath: filepath.Join(dir, "echo.log")},
+		{Name: "fail", Status: StateREADY, Script: "false", ScriptTimeout: 10, LogPath: filepath.Join(dir, "fail.log")},
+	}
+	err = sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sm.Execute(FirstState, LastState, nil, nil)
+	if err == nil {
+		t.Fatal("Expecting the run to fail")
+	}
+	runs, err := defaultInstance.runsStorage.ListRuns(sm.StatesPath, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("Expected 1 run got %d", len(runs))
+	}
+	run := runs[0]
+	if run.ID == "" || run.ExtensionName != "states-TestRecordRun" || run.ExecutionID != sm.ExecutionID {
+		t.Errorf("Unexpected run %+v", run)
+	}
+	if run.Status != StateFAILED || run.Reason == "" || run.StartTime == "" || run.EndTime == "" {
+		t.Errorf("Expected a failed run with its reason and times got %+v", run)
+	}
+	if run.FromState != FirstState || run.ToState != LastState {
+		t.Errorf("Expected the run from %s to %s got %+v", FirstState, LastState, run)
+	}
+}
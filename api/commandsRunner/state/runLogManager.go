@@ -0,0 +1,57 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+//startRun registers the run UUID of an extension
+func (inst *Instance) startRun(extensionName string, runID string) {
+	inst.runIDsMux.Lock()
+	defer inst.runIDsMux.Unlock()
+	inst.runIDs[extensionName] = runID
+}
+
+//endRun unregisters the run of an extension
+func (inst *Instance) endRun(extensionName string) {
+	inst.runIDsMux.Lock()
+	defer inst.runIDsMux.Unlock()
+	delete(inst.runIDs, extensionName)
+}
+
+//GetRunID returns the run UUID of a running extension or an empty string
+func (inst *Instance) GetRunID(extensionName string) string {
+	inst.runIDsMux.Lock()
+	defer inst.runIDsMux.Unlock()
+	return inst.runIDs[extensionName]
+}
+
+//runLog returns a log entry with the extension, the execution_id, the run_id and, if not empty, the state
+func (sm *States) runLog(stateName string) *log.Entry {
+	inst := sm.instance()
+	fields := log.Fields{
+		"extension":    sm.ExtensionName,
+		"execution_id": sm.ExecutionID,
+		"run_id":       inst.GetRunID(sm.ExtensionName),
+	}
+	if stateName != "" {
+		fields["state"] = stateName
+	}
+	return log.WithFields(fields)
+}
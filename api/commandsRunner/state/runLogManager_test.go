@@ -0,0 +1,99 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//recordHook records the log entries of an extension
+type recordHook struct {
+	extensionName string
+	entries       []*log.Entry
+	mux           sync.Mutex
+}
+
+func (h *recordHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *recordHook) Fire(entry *log.Entry) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if entry.Data["extension"] == h.extensionName {
+		h.entries = append(h.entries, entry)
+	}
+	return nil
+}
+
+func TestRunLogFields(t *testing.T) {
+	t.Log("Entering... TestRunLogFields")
+	dir, err := ioutil.TempDir("", "TestRunLogFields")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sm := newStateManager("states-TestRunLogFields")
+	sm.StatesPath = filepath.Join(dir, "states-TestRunLogFields.yaml")
+	sm.StateArray = []State{
+		{Name: "echo", Status: StateREADY, Script: "echo line1", ScriptTimeout: 10, LogPath: filepath.Join(dir, "echo.log")},
+	}
+	err = sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &recordHook{extensionName: "states-TestRunLogFields"}
+	log.AddHook(hook)
+	level := log.GetLevel()
+	log.SetLevel(log.DebugLevel)
+	defer log.SetLevel(level)
+	err = sm.Execute(FirstState, LastState, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetRunID("states-TestRunLogFields") != "" {
+		t.Error("Expected the run to be unregistered at the end of the execution")
+	}
+	hook.mux.Lock()
+	defer hook.mux.Unlock()
+	if len(hook.entries) == 0 {
+		t.Fatal("No log entry with the run fields")
+	}
+	runID := hook.entries[0].Data["run_id"]
+	stateEntries := 0
+	for _, entry := range hook.entries {
+		if entry.Data["run_id"] == "" || entry.Data["run_id"] != runID {
+			t.Errorf("Expected the run_id %v, got %v for '%s'", runID, entry.Data["run_id"], entry.Message)
+		}
+		if entry.Data["execution_id"] != sm.ExecutionID {
+			t.Errorf("Expected the execution_id %d, got %v for '%s'", sm.ExecutionID, entry.Data["execution_id"], entry.Message)
+		}
+		if entry.Data["state"] == "echo" {
+			stateEntries++
+		}
+	}
+	if stateEntries == 0 {
+		t.Error("No log entry with the state field")
+	}
+}
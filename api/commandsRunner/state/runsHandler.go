@@ -0,0 +1,73 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+)
+
+//HandleRuns handles the run history rest api requests
+func HandleRuns(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleRuns")
+	switch req.Method {
+	case "GET":
+		getRunsEndpoint(w, req)
+	default:
+		logger.AddCallerField().Error("Unsupported method:" + req.Method)
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+	}
+}
+
+/*
+Retrieve the run history of an extension, the most recent run first
+URL: /cr/v1/runs?extension-name=<extension_name>[&status=<status>][&limit=<n>]
+Method: GET
+*/
+func getRunsEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in getRunsEndpoint")
+	inst := GetInstance(req)
+	extensionName, m, err := global.GetExtensionNameFromRequest(req)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if limitFound := m.Get("limit"); limitFound != "" {
+		limit, err = strconv.Atoi(limitFound)
+		if err != nil || limit < 0 {
+			apiError.HTTPError(w, req, apiError.BadRequest("Invalid limit parameter: "+limitFound), http.StatusBadRequest)
+			return
+		}
+	}
+	runs, err := inst.GetRuns(extensionName, m.Get("status"), limit)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(runs)
+}
@@ -0,0 +1,103 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+)
+
+//ShutdownCancelGracePeriod how long to wait for the cancelled states to be marked as FAILED
+var ShutdownCancelGracePeriod = 30 * time.Second
+
+//ErrShuttingDown returned when an execution is requested while the server is shutting down
+var ErrShuttingDown = apiError.New(http.StatusServiceUnavailable, apiError.CodeServiceUnavailable, "The server is shutting down, no new execution can be started")
+
+//IsShuttingDown returns true once Shutdown is called
+func (inst *Instance) IsShuttingDown() bool {
+	inst.executionsMux.Lock()
+	defer inst.executionsMux.Unlock()
+	return inst.shuttingDown
+}
+
+//startExecution registers a new top level execution, it fails if the server is shutting down
+func (inst *Instance) startExecution() error {
+	inst.executionsMux.Lock()
+	defer inst.executionsMux.Unlock()
+	if inst.shuttingDown {
+		return ErrShuttingDown
+	}
+	inst.runningExecutions.Add(1)
+	return nil
+}
+
+func (inst *Instance) endExecution() {
+	inst.runningExecutions.Done()
+}
+
+//executionsCancelled returns a channel closed when the running executions must be cancelled
+func (inst *Instance) executionsCancelled() <-chan struct{} {
+	return inst.cancelExecutions
+}
+
+func (inst *Instance) waitExecutions(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		inst.runningExecutions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*
+Shutdown stops accepting new executions and waits for the running states until the context deadline.
+Once the deadline is reached, the running scripts are killed and their states are marked as FAILED.
+*/
+func (inst *Instance) Shutdown(ctx context.Context) error {
+	log.Info("Shutting down the engine")
+	inst.executionsMux.Lock()
+	inst.shuttingDown = true
+	inst.executionsMux.Unlock()
+	//The event streams are closed to not block the http servers shutdown
+	defer inst.closeEventSubscribers()
+	err := inst.waitExecutions(ctx)
+	if err == nil {
+		log.Info("No running execution")
+		return nil
+	}
+	log.Warning("Running executions not completed before the deadline, cancelling them")
+	inst.cancelOnce.Do(func() { close(inst.cancelExecutions) })
+	cancelCtx, cancel := context.WithTimeout(context.Background(), ShutdownCancelGracePeriod)
+	defer cancel()
+	err = inst.waitExecutions(cancelCtx)
+	if err != nil {
+		return errors.New("Running executions not cancelled: " + err.Error())
+	}
+	return nil
+}
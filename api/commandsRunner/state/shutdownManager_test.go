@@ -0,0 +1,104 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//resetShutdown allows the next tests to run executions
+func resetShutdown() {
+	defaultInstance.executionsMux.Lock()
+	defer defaultInstance.executionsMux.Unlock()
+	defaultInstance.shuttingDown = false
+	defaultInstance.cancelExecutions = make(chan struct{})
+	defaultInstance.cancelOnce = sync.Once{}
+}
+
+func TestShutdownCancelRunningStates(t *testing.T) {
+	t.Log("Entering... TestShutdownCancelRunningStates")
+	defer resetShutdown()
+	dir, err := ioutil.TempDir("", "TestShutdownCancelRunningStates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statesPath := filepath.Join(dir, "states-TestShutdownCancelRunningStates.yaml")
+	sm := newStateManager("states-TestShutdownCancelRunningStates")
+	sm.StatesPath = statesPath
+	sm.StateArray = []State{
+		{Name: "long", Status: StateREADY, Script: "sleep 30", ScriptTimeout: 10, LogPath: filepath.Join(dir, "long.log"), NextStates: []string{"next"}},
+		{Name: "next", Status: StateREADY, Script: "echo next", ScriptTimeout: 10, LogPath: filepath.Join(dir, "next.log"), PreviousStates: []string{"long"}},
+	}
+	err = sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sm.Execute(FirstState, LastState, nil, nil)
+	}()
+	reader := newStateManager("states-TestShutdownCancelRunningStates")
+	reader.StatesPath = statesPath
+	for i := 0; i < 50; i++ {
+		state, err := reader.GetState("long", nil)
+		if err == nil && state.Status == StateRUNNING {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("Shutdown didn't cancel the running script")
+	}
+	err = <-errCh
+	if err == nil {
+		t.Error("Expected the execution to fail")
+	}
+	state, err := reader.GetState("long", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StateFAILED || !strings.Contains(state.Reason, "shutting down") {
+		t.Errorf("Expected FAILED with a shutdown reason, got %s: %s", state.Status, state.Reason)
+	}
+	state, err = reader.GetState("next", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StateREADY {
+		t.Error("Expected next state not started, got " + state.Status)
+	}
+	err = sm.Execute(FirstState, LastState, nil, nil)
+	if err != ErrShuttingDown {
+		t.Errorf("Expected %v, got %v", ErrShuttingDown, err)
+	}
+}
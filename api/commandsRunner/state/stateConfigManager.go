@@ -0,0 +1,201 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+//ConfigReader reads the config properties of an extension of the instance
+type ConfigReader func(inst *Instance, extensionName string) (map[string]interface{}, error)
+
+var configReader ConfigReader
+
+//SetConfigReader sets the function used to read the config when a snapshot of the depends_on_config properties is taken.
+//The properties package registers it as it can not be imported here.
+func SetConfigReader(reader ConfigReader) {
+	configReader = reader
+}
+
+//ConfigRemover removes the config of an extension from the storage
+type ConfigRemover func(inst *Instance, extensionName string) error
+
+var configRemover ConfigRemover
+
+//SetConfigRemover sets the function used to remove the config of an extension when it is unregistered.
+//The properties package registers it as it can not be imported here.
+func SetConfigRemover(remover ConfigRemover) {
+	configRemover = remover
+}
+
+//ConfigExporter writes the config of an extension in its config file
+type ConfigExporter func(inst *Instance, extensionName string) error
+
+var configExporter ConfigExporter
+
+//SetConfigExporter sets the function used to write the config file read by the scripts before they run,
+//the config may be stored elsewhere (ie: in a database). The properties package registers it as it can not be imported here.
+func SetConfigExporter(exporter ConfigExporter) {
+	configExporter = exporter
+}
+
+//ConfigStorage persists the config of the extensions, a config document is identified by the path of the extension config file.
+//The config overlays are files provided by the operator and are always read from the extension directory.
+type ConfigStorage interface {
+	//ReadConfig returns the config document, the error satisfies os.IsNotExist if it doesn't exist.
+	ReadConfig(path string) ([]byte, error)
+	//WriteConfig replaces the config document atomically.
+	WriteConfig(path string, data []byte) error
+	//DeleteConfig removes the config document, it doesn't fail if the document doesn't exist.
+	DeleteConfig(path string) error
+	//LockConfig protects the config document against the updates of the other processes until the returned function is called.
+	LockConfig(path string) (func(), error)
+}
+
+//FileConfigStorage stores each config document in its config file, it is the default storage.
+type FileConfigStorage struct{}
+
+//SetConfigStorage sets the storage of the config, it must be called before the config is accessed.
+func (inst *Instance) SetConfigStorage(storage ConfigStorage) {
+	inst.configStorage = storage
+}
+
+//GetConfigStorage returns the storage of the config
+func (inst *Instance) GetConfigStorage() ConfigStorage {
+	return inst.configStorage
+}
+
+//ReadConfig reads the config file
+func (FileConfigStorage) ReadConfig(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+//WriteConfig writes the config file atomically under its file lock,
+//the writers of the process are serialized by LockConfig.
+func (FileConfigStorage) WriteConfig(path string, data []byte) error {
+	unlockFile, err := global.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlockFile()
+	return global.WriteFileAtomic(path, data, 0644)
+}
+
+//DeleteConfig removes the config file
+func (FileConfigStorage) DeleteConfig(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//LockConfig takes the file lock of the config file, the goroutines of the process are serialized too
+func (FileConfigStorage) LockConfig(path string) (func(), error) {
+	return global.LockFileExclusive(path)
+}
+
+//getConfigSnapshot reads the config and returns the json value of each depends_on_config property of the state
+func (sm *States) getConfigSnapshot(state State) (map[string]string, error) {
+	log.Debug("Entering in... getConfigSnapshot")
+	if configReader == nil {
+		return nil, errors.New("No config reader set")
+	}
+	values, err := configReader(sm.instance(), sm.ExtensionName)
+	if err != nil {
+		return nil, err
+	}
+	return calculateConfigSnapshot(state, values)
+}
+
+func calculateConfigSnapshot(state State, values map[string]interface{}) (map[string]string, error) {
+	snapshot := make(map[string]string)
+	for _, name := range state.DependsOnConfig {
+		value, _ := lookupConditionValue(values, name)
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[name] = string(valueJSON)
+	}
+	return snapshot, nil
+}
+
+/*
+InvalidateStatesOnConfigChange sets to READY the succeeded states having a depends_on_config property
+with a value different from their last successful run, their states_to_rerun are set to READY too.
+It returns the names of the states set to READY.
+*/
+func (sm *States) InvalidateStatesOnConfigChange(values map[string]interface{}) ([]string, error) {
+	log.Debug("Entering in... InvalidateStatesOnConfigChange")
+	inst := sm.instance()
+	errLock := sm.lock()
+	if errLock != nil {
+		return nil, errLock
+	}
+	defer sm.unlock()
+	invalidatedStates := make([]string, 0)
+	//No states file yet, nothing to invalidate
+	if _, err := inst.statesStorage.StatesVersion(sm.StatesPath); os.IsNotExist(err) {
+		return invalidatedStates, nil
+	}
+	errStates := sm.readStates()
+	if errStates != nil {
+		return invalidatedStates, errStates
+	}
+	statesToInvalidate := make([]string, 0)
+	for _, state := range sm.StateArray {
+		if state.Status != StateSUCCEEDED || len(state.DependsOnConfig) == 0 || state.ConfigSnapshot == nil {
+			continue
+		}
+		snapshot, err := calculateConfigSnapshot(state, values)
+		if err != nil {
+			return invalidatedStates, err
+		}
+		for name, value := range snapshot {
+			if previousValue, ok := state.ConfigSnapshot[name]; !ok || previousValue != value {
+				log.Info("Config " + name + " changed, " + state.Name + " will be rerun")
+				statesToInvalidate = append(statesToInvalidate, state.Name)
+				statesToInvalidate = append(statesToInvalidate, state.StatesToRerun...)
+				break
+			}
+		}
+	}
+	for _, stateName := range statesToInvalidate {
+		state, err := sm._getState(stateName)
+		if err != nil {
+			return invalidatedStates, err
+		}
+		if state.Status == StateREADY || state.Status == StateSKIP || state.Status == StateRUNNING {
+			continue
+		}
+		err = sm.setStateStatus(*state, StateREADY, true)
+		if err != nil {
+			return invalidatedStates, err
+		}
+		invalidatedStates = append(invalidatedStates, stateName)
+	}
+	return invalidatedStates, nil
+}
@@ -0,0 +1,78 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"os"
+	"testing"
+)
+
+func TestInvalidateStatesOnConfigChange(t *testing.T) {
+	t.Log("Entering... TestInvalidateStatesOnConfigChange")
+	statesPath := "/tmp/states-TestInvalidateStatesOnConfigChange.yaml"
+	defer os.Remove(statesPath)
+	sm := newStateManager("states-TestInvalidateStatesOnConfigChange")
+	sm.StatesPath = statesPath
+	sm.StateArray = []State{
+		{Name: "network", Status: StateREADY, DependsOnConfig: []string{"network.cidr"}, StatesToRerun: []string{"deploy"}},
+		{Name: "nodes", Status: StateREADY, DependsOnConfig: []string{"number_of_nodes"}},
+		{Name: "deploy", Status: StateSUCCEEDED},
+	}
+	err := sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{
+		"network":         map[string]interface{}{"cidr": "10.0.0.0/16"},
+		"number_of_nodes": 3,
+	}
+	SetConfigReader(func(inst *Instance, extensionName string) (map[string]interface{}, error) {
+		return values, nil
+	})
+	defer SetConfigReader(nil)
+	for _, name := range []string{"network", "nodes"} {
+		err = sm.setStateStatusWithTimeStamp(false, name, StateSUCCEEDED, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	invalidatedStates, err := sm.InvalidateStatesOnConfigChange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalidatedStates) != 0 {
+		t.Errorf("No state expected to be invalidated, got %v", invalidatedStates)
+	}
+	values["network"] = map[string]interface{}{"cidr": "10.1.0.0/16"}
+	invalidatedStates, err = sm.InvalidateStatesOnConfigChange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalidatedStates) != 2 || invalidatedStates[0] != "network" || invalidatedStates[1] != "deploy" {
+		t.Errorf("Expected network and deploy to be invalidated, got %v", invalidatedStates)
+	}
+	for name, status := range map[string]string{"network": StateREADY, "deploy": StateREADY, "nodes": StateSUCCEEDED} {
+		state, err := sm.GetState(name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Status != status {
+			t.Errorf("Expected %s for %s, got %s", status, name, state.Status)
+		}
+	}
+}
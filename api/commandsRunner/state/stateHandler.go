@@ -19,6 +19,7 @@ package state
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"html"
@@ -28,9 +29,12 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
@@ -42,13 +46,18 @@ import (
 //Search the stateManager based on the extension-name parameter's request.
 func getStateManagerFromRequest(req *http.Request) (*States, url.Values, error) {
 	log.Debug("Entering in getStateManagerFromRequest")
+	inst := GetInstance(req)
 	log.Debug(req.URL.Path)
 	extensionName, m, err := global.GetExtensionNameFromRequest(req)
 	if err != nil {
 		return nil, nil, err
 	}
 	log.Debug("ExtensionName:" + extensionName)
-	sm, errSM := GetStatesManager(extensionName)
+	//The states locked by updateStates
+	if sm, ok := req.Context().Value(lockedStatesKey).(*States); ok && sm.ExtensionName == extensionName {
+		return sm, m, nil
+	}
+	sm, errSM := inst.GetStatesManager(extensionName)
 	if errSM != nil {
 		return nil, nil, errSM
 	}
@@ -66,7 +75,7 @@ func HandleStates(w http.ResponseWriter, req *http.Request) {
 	m, errRQ := url.ParseQuery(req.URL.RawQuery)
 	if errRQ != nil {
 		logger.AddCallerField().Error(errRQ.Error())
-		http.Error(w, errRQ.Error(), 500)
+		apiError.HTTPError(w, req, errRQ, 500)
 		return
 	}
 	log.Debug(m)
@@ -78,7 +87,7 @@ func HandleStates(w http.ResponseWriter, req *http.Request) {
 		case "insert":
 			switch req.Method {
 			case "PUT":
-				PutInsertStateStatesEndpoint(w, req)
+				updateStates(w, req, PutInsertStateStatesEndpoint)
 			default:
 				logger.AddCallerField().Error("Unsupported method:" + req.Method)
 				http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
@@ -86,7 +95,7 @@ func HandleStates(w http.ResponseWriter, req *http.Request) {
 		case "delete":
 			switch req.Method {
 			case "PUT":
-				PutDeleteStateStatesEndpoint(w, req)
+				updateStates(w, req, PutDeleteStateStatesEndpoint)
 			default:
 				logger.AddCallerField().Error("Unsupported method:" + req.Method)
 				http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
@@ -94,7 +103,7 @@ func HandleStates(w http.ResponseWriter, req *http.Request) {
 		case "set-statuses":
 			switch req.Method {
 			case "PUT":
-				PutSetStatusesStatesEndpoint(w, req)
+				updateStates(w, req, PutSetStatusesStatesEndpoint)
 			default:
 				logger.AddCallerField().Error("Unsupported method:" + req.Method)
 				http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
@@ -105,7 +114,7 @@ func HandleStates(w http.ResponseWriter, req *http.Request) {
 		case "GET":
 			GetStatesEndpoint(w, req)
 		case "PUT":
-			PutStatesEndpoint(w, req)
+			updateStates(w, req, PutStatesEndpoint)
 		default:
 			logger.AddCallerField().Error("Unsupported method:" + req.Method)
 			http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
@@ -113,9 +122,84 @@ func HandleStates(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+//etagWriter sets the ETag of the updated states when the update succeeds
+type etagWriter struct {
+	http.ResponseWriter
+	inst        *Instance
+	statesPath  string
+	wroteHeader bool
+}
+
+func (ew *etagWriter) setETag() {
+	etag, err := ew.inst.statesETag(ew.statesPath)
+	if err == nil && etag != "" {
+		ew.Header().Set(global.ETagHeader, etag)
+	}
+}
+
+func (ew *etagWriter) WriteHeader(status int) {
+	if !ew.wroteHeader {
+		ew.wroteHeader = true
+		if status < http.StatusMultipleChoices {
+			ew.setETag()
+		}
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+//Run a states update handler, the states lock is held from the If-Match check to the write
+//and the update is rejected with a 412 if the If-Match header doesn't match the current states ETag.
+func updateStates(w http.ResponseWriter, req *http.Request, handler http.HandlerFunc) {
+	log.Debug("Entering in updateStates")
+	inst := GetInstance(req)
+	sm, _, errSM := getStateManagerFromRequest(req)
+	if errSM != nil {
+		//Let the handler report the error
+		handler(w, req)
+		return
+	}
+	errLock := sm.lock()
+	if errLock != nil {
+		logger.AddCallerField().Error(errLock.Error())
+		apiError.HTTPError(w, req, errLock, http.StatusInternalServerError)
+		return
+	}
+	defer sm.unlock()
+	etag, err := inst.statesETag(sm.StatesPath)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	if !global.IfMatch(req, etag) {
+		err = errors.New("The states of " + sm.ExtensionName + " were modified, the " + global.IfMatchHeader + " header doesn't match the current ETag " + etag)
+		logger.AddCallerField().Error(err.Error())
+		w.Header().Set(global.ETagHeader, etag)
+		apiError.HTTPError(w, req, err, http.StatusPreconditionFailed)
+		return
+	}
+	locked := *sm
+	locked.locked = true
+	ew := &etagWriter{ResponseWriter: w, inst: inst, statesPath: sm.StatesPath}
+	handler(ew, req.WithContext(context.WithValue(req.Context(), lockedStatesKey, &locked)))
+	if !ew.wroteHeader {
+		ew.setETag()
+	}
+}
+
 /*
 Retrieve the states
-URL: /cr/v1/states
+The ETag header contains the revision of the states file, it can be provided in the If-Match header of the states updates.
+name and label are glob patterns, the times are RFC3339 dates, sort is position, name or duration (prefix with - for descending)
+and fields is a comma separated list of state attributes. The total number of matching states is returned in the X-Total-Count header.
+URL: /cr/v1/states?[status=<status>][&extensions-only=<true|false>][&recursive=<true|false>][&name=<glob>][&label=<glob>][&is-extension=<true|false>][&started-after=<date>][&started-before=<date>][&ended-after=<date>][&ended-before=<date>][&sort=<sort>][&offset=<n>][&limit=<n>][&fields=<fields>]
 Method: GET
 */
 func GetStatesEndpoint(w http.ResponseWriter, req *http.Request) {
@@ -127,7 +211,7 @@ func GetStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	status := ""
@@ -150,20 +234,92 @@ func GetStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 	}
 	recursive, err := strconv.ParseBool(recursiveString)
 
+	filter, err := parseStatesFilter(m)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+
+	//The ETag is read before the states, if the file changes in between the next update will fail instead of being lost
+	etag, err := sm.GetETag()
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
 	states, err := sm.GetStates(status, extensionsOnly, recursive, langs)
 	if err == nil {
+		w.Header().Set(global.ETagHeader, etag)
+		var total int
+		states.StateArray, total, err = filter.Apply(states.StateArray)
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+		var result interface{} = states
+		if len(filter.Fields) > 0 {
+			selectedStates, err := SelectFields(states.StateArray, filter.Fields)
+			if err != nil {
+				logger.AddCallerField().Error(err.Error())
+				apiError.HTTPError(w, req, err, http.StatusBadRequest)
+				return
+			}
+			result = &struct {
+				*States
+				StateArray interface{} `json:"states"`
+			}{states, selectedStates}
+		}
 		//		json.NewEncoder(w).Encode(states)
 		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
-		err := enc.Encode(states)
+		err := enc.Encode(result)
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 		}
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
+	}
+}
+
+//Parse the filtering, sorting and pagination parameters of the states request.
+func parseStatesFilter(m url.Values) (StatesFilter, error) {
+	var filter StatesFilter
+	var err error
+	filter.Name = m.Get("name")
+	filter.Label = m.Get("label")
+	filter.Sort = m.Get("sort")
+	if isExtensionFound := m.Get("is-extension"); isExtensionFound != "" {
+		var isExtension bool
+		isExtension, err = strconv.ParseBool(isExtensionFound)
+		filter.IsExtension = &isExtension
+	}
+	for param, value := range map[string]*int{
+		"offset": &filter.Offset,
+		"limit":  &filter.Limit,
+	} {
+		if found := m.Get(param); found != "" && err == nil {
+			*value, err = strconv.Atoi(found)
+		}
 	}
+	for param, value := range map[string]*time.Time{
+		"started-after":  &filter.StartedAfter,
+		"started-before": &filter.StartedBefore,
+		"ended-after":    &filter.EndedAfter,
+		"ended-before":   &filter.EndedBefore,
+	} {
+		if found := m.Get(param); found != "" && err == nil {
+			*value, err = time.Parse(time.RFC3339, found)
+		}
+	}
+	if fieldsFound := m.Get("fields"); fieldsFound != "" {
+		filter.Fields = strings.Split(fieldsFound, ",")
+	}
+	return filter, err
 }
 
 /*
@@ -177,7 +333,7 @@ func PutStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		log.Debug(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	overwrite := true
@@ -186,7 +342,7 @@ func PutStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		overwrite, errCvt = strconv.ParseBool(overwriteFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert overwrite parameter to boolean "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert overwrite parameter to boolean "+errCvt.Error()), 500)
 			return
 		}
 	}
@@ -205,20 +361,20 @@ func PutStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 				err = sm.SetStates(states, overwrite)
 				if err != nil {
 					logger.AddCallerField().Error(err.Error())
-					http.Error(w, err.Error(), 500)
+					apiError.HTTPError(w, req, err, 500)
 				}
 			} else {
 				err = errors.New("No states provided")
 				logger.AddCallerField().Error(err.Error())
-				http.Error(w, err.Error(), 500)
+				apiError.HTTPError(w, req, err, 500)
 			}
 		} else {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), 500)
+			apiError.HTTPError(w, req, err, 500)
 		}
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), 500)
+		apiError.HTTPError(w, req, err, 500)
 	}
 }
 
@@ -229,17 +385,18 @@ Method: PUT
 */
 func PutInsertStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering.... PutInsertStateStatesEndpoint")
+	inst := GetInstance(req)
 	log.Debug(req.URL.Path)
 	log.Debugf("RawQuery:%s", req.URL.RawQuery)
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
-	if _, err := os.Stat(sm.StatesPath); os.IsNotExist(err) {
+	if _, err := inst.statesStorage.StatesVersion(sm.StatesPath); os.IsNotExist(err) {
 		logger.AddCallerField().Error(errors.New("State file " + sm.StatesPath + " doesn't exist"))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
 		return
 	}
 	var pos int
@@ -248,7 +405,7 @@ func PutInsertStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		pos, errCvt = strconv.Atoi(posFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert first-char parameter to integer "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert first-char parameter to integer "+errCvt.Error()), 500)
 			return
 		}
 	} else {
@@ -260,7 +417,7 @@ func PutInsertStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		before, errCvt = strconv.ParseBool(beforeFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert before parameter to boolean "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert before parameter to boolean "+errCvt.Error()), 500)
 			return
 		}
 	}
@@ -273,7 +430,7 @@ func PutInsertStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		overwrite, errCvt = strconv.ParseBool(overwriteFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert overwrite parameter to boolean "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert overwrite parameter to boolean "+errCvt.Error()), 500)
 			return
 		}
 	}
@@ -309,11 +466,11 @@ func PutInsertStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		}
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), 500)
+			apiError.HTTPError(w, req, err, 500)
 		}
 	} else {
 		logger.AddCallerField().Error(errBody.Error())
-		http.Error(w, errBody.Error(), 500)
+		apiError.HTTPError(w, req, errBody, 500)
 	}
 }
 
@@ -323,17 +480,18 @@ URL: /cr/v1/states?extension-name=<extension-name>&action=delete&pos=<int>
 Method: PUT
 */
 func PutDeleteStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
+	inst := GetInstance(req)
 	log.Debug(req.URL.Path)
 	log.Debugf("RawQuery:%s", req.URL.RawQuery)
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
-	if _, err := os.Stat(sm.StatesPath); os.IsNotExist(err) {
+	if _, err := inst.statesStorage.StatesVersion(sm.StatesPath); os.IsNotExist(err) {
 		logger.AddCallerField().Error(errors.New("State file " + sm.StatesPath + " doesn't exist"))
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	var pos int
@@ -342,7 +500,7 @@ func PutDeleteStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		pos, errCvt = strconv.Atoi(posFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert first-char parameter to integer "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert first-char parameter to integer "+errCvt.Error()), 500)
 			return
 		}
 	} else {
@@ -356,7 +514,7 @@ func PutDeleteStateStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := sm.DeleteState(pos, stateName)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), 500)
+		apiError.HTTPError(w, req, err, 500)
 	}
 }
 
@@ -367,17 +525,18 @@ Method: PUT
 */
 func PutSetStatusesStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering.... PutSetStatusesStatesEndpoint")
+	inst := GetInstance(req)
 	log.Debug(req.URL.Path)
 	log.Debugf("RawQuery:%s", req.URL.RawQuery)
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
-	if _, err := os.Stat(sm.StatesPath); os.IsNotExist(err) {
+	if _, err := inst.statesStorage.StatesVersion(sm.StatesPath); os.IsNotExist(err) {
 		logger.AddCallerField().Error(errors.New("State file " + sm.StatesPath + " doesn't exist"))
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	//Retreive the new status
@@ -393,7 +552,7 @@ func PutSetStatusesStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		fromIncluded, errCvt = strconv.ParseBool(fromIncludedFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert from-included parameter to boolean "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert from-included parameter to boolean "+errCvt.Error()), 500)
 			return
 		}
 	}
@@ -403,7 +562,7 @@ func PutSetStatusesStatesEndpoint(w http.ResponseWriter, req *http.Request) {
 		toIncluded, errCvt = strconv.ParseBool(toIncludedFound[0])
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert to-included parameter to boolean "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert to-included parameter to boolean "+errCvt.Error()), 500)
 			return
 		}
 	}
@@ -436,7 +595,7 @@ func HandleState(w http.ResponseWriter, req *http.Request) {
 		case "GET":
 			GetStateEndpoint(w, req)
 		case "PUT":
-			PutStateEndpoint(w, req)
+			updateStates(w, req, PutStateEndpoint)
 		default:
 			logger.AddCallerField().Error("Unsupported method:" + req.Method)
 			http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
@@ -483,22 +642,29 @@ func GetStateEndpoint(w http.ResponseWriter, req *http.Request) {
 		sm, _, errSM := getStateManagerFromRequest(req)
 		if errSM != nil {
 			logger.AddCallerField().Error(errSM.Error())
-			http.Error(w, errSM.Error(), http.StatusBadRequest)
+			apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
+			return
+		}
+		etag, err := sm.GetETag()
+		if err != nil {
+			logger.AddCallerField().Error(err.Error())
+			apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 			return
 		}
 		state, err := sm.GetState(params[1], langs)
 		if err == nil {
+			w.Header().Set(global.ETagHeader, etag)
 			//			json.NewEncoder(w).Encode(state)
 			enc := json.NewEncoder(w)
 			enc.SetIndent("", "  ")
 			err := enc.Encode(state)
 			if err != nil {
 				logger.AddCallerField().Error(err.Error())
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				apiError.HTTPError(w, req, err, http.StatusInternalServerError)
 			}
 		} else {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), http.StatusNotFound)
+			apiError.HTTPError(w, req, err, http.StatusNotFound)
 		}
 	}
 }
@@ -523,7 +689,7 @@ func GetStateLogEndpoint(w http.ResponseWriter, req *http.Request) {
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	//Retreive the first-line
@@ -535,7 +701,7 @@ func GetStateLogEndpoint(w http.ResponseWriter, req *http.Request) {
 		firstPos, errCvt = strconv.ParseInt(firstLineFound[0], 10, 64)
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert first-line parameter to integer "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert first-line parameter to integer "+errCvt.Error()), 500)
 			return
 		}
 		bychar = false
@@ -545,7 +711,7 @@ func GetStateLogEndpoint(w http.ResponseWriter, req *http.Request) {
 		firstPos, errCvt = strconv.ParseInt(firsCharFound[0], 10, 64)
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert first-char parameter to integer "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert first-char parameter to integer "+errCvt.Error()), 500)
 			return
 		}
 		bychar = true
@@ -560,7 +726,7 @@ func GetStateLogEndpoint(w http.ResponseWriter, req *http.Request) {
 		length, errCvt = strconv.ParseInt(lengthFound[0], 10, 64)
 		if errCvt != nil {
 			logger.AddCallerField().Error(errCvt.Error())
-			http.Error(w, "Can not convert length parameter to integer "+errCvt.Error(), 500)
+			apiError.HTTPError(w, req, apiError.BadRequest("Can not convert length parameter to integer "+errCvt.Error()), 500)
 			return
 		}
 	}
@@ -570,7 +736,7 @@ func GetStateLogEndpoint(w http.ResponseWriter, req *http.Request) {
 		w.Write(logData)
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
 
@@ -592,7 +758,7 @@ func PutStateEndpoint(w http.ResponseWriter, req *http.Request) {
 	sm, m, errSM := getStateManagerFromRequest(req)
 	if errSM != nil {
 		logger.AddCallerField().Error(errSM.Error())
-		http.Error(w, errSM.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, errSM, http.StatusBadRequest)
 		return
 	}
 	//Retreive the new status
@@ -621,7 +787,7 @@ func PutStateEndpoint(w http.ResponseWriter, req *http.Request) {
 		recursivelly, errRecursivelly = strconv.ParseBool(recursivellyFound[0])
 		if errRecursivelly != nil {
 			logger.AddCallerField().Error(errRecursivelly.Error())
-			http.Error(w, errRecursivelly.Error(), http.StatusBadRequest)
+			apiError.HTTPError(w, req, errRecursivelly, http.StatusBadRequest)
 			return
 		}
 	}
@@ -633,7 +799,7 @@ func PutStateEndpoint(w http.ResponseWriter, req *http.Request) {
 		scriptTimeout, err = strconv.Atoi(scriptTimeoutFound[0])
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apiError.HTTPError(w, req, err, http.StatusBadRequest)
 			return
 		}
 	}
@@ -642,6 +808,6 @@ func PutStateEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := sm.SetState(params[1], status, reason, script, scriptTimeout, recursivelly)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
 	}
 }
@@ -27,6 +27,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 
@@ -160,7 +161,7 @@ func TestInsertDeleteStateStates(t *testing.T) {
 	stateFile := filepath.Join(extensionPath, "embedded/ext-insert-delete-handler/states-file.yml")
 	ioutil.WriteFile(stateFile, inFileData, 0644)
 	SetExtensionsEmbeddedFile("../../test/data/extensions/test-extensions.yml")
-	addStateManager("ext-insert-delete")
+	defaultInstance.addStateManager("ext-insert-delete")
 	req, err := http.NewRequest("PUT", "/cr/v1/states?extension-name=ext-insert-delete-handler&action=insert&pos=1&before=true", strings.NewReader(stateInsertDeleteJson))
 	if err != nil {
 		t.Fatal(err)
@@ -222,7 +223,7 @@ func TestSetEmptyStates(t *testing.T) {
 	stateFile := filepath.Join(extensionPath, "embedded/ext-insert-delete/states-file.yml")
 	ioutil.WriteFile(stateFile, inFileData, 0644)
 	SetExtensionsEmbeddedFile("../../test/data/extensions/test-extensions.yml")
-	addStateManager("ext-insert-delete")
+	defaultInstance.addStateManager("ext-insert-delete")
 	req, err := http.NewRequest("PUT", "/cr/v1/states?extension-name=ext-template", strings.NewReader(""))
 	if err != nil {
 		t.Fatal(err)
@@ -258,7 +259,7 @@ func TestInsertDeleteStateStatesAutoLocation(t *testing.T) {
 	stateFile := filepath.Join(extensionPath, "embedded/ext-insert-delete-auto-location/states-file.yml")
 	ioutil.WriteFile(stateFile, inFileData, 0644)
 	SetExtensionsEmbeddedFile("../../test/data/extensions/test-extensions.yml")
-	addStateManager("ext-insert-delete-auto-location")
+	defaultInstance.addStateManager("ext-insert-delete-auto-location")
 
 	req, err := http.NewRequest("PUT", "/cr/v1/states?extension-name=ext-insert-delete-auto-location&insert-extension-name=ext-template-auto&state-name=task1&action=insert&pos=0", nil)
 	if err != nil {
@@ -323,7 +324,7 @@ func TestInsertDeleteStateStatesByName(t *testing.T) {
 	stateFile := filepath.Join(extensionPath, "embedded/ext-insert-delete-by-name/states-file.yml")
 	ioutil.WriteFile(stateFile, inFileData, 0644)
 	SetExtensionsEmbeddedFile("../../test/data/extensions/test-extensions.yml")
-	addStateManager("ext-insert-delete-by-name")
+	defaultInstance.addStateManager("ext-insert-delete-by-name")
 
 	req, err := http.NewRequest("PUT", "/cr/v1/states?extension-name=ext-insert-delete-by-name&action=insert&pos=0&before=true&state-name=task1", strings.NewReader(stateInsertDeleteJson))
 	if err != nil {
@@ -589,8 +590,8 @@ func TestAddStateManagerIBM(t *testing.T) {
 	SetExtensionsEmbeddedFile("../../test/data/extensions/test-extensions.yml")
 	//	global.SetExtensionResourcePath("../../test/resource/extensions/")
 	extension := "ext-template"
-	addStateManager(extension)
-	sm, err := getStatesManager(extension)
+	defaultInstance.addStateManager(extension)
+	sm, err := defaultInstance.getStatesManager(extension)
 	if err != nil {
 		t.Error("Unable to retrieve state manager " + extension)
 	}
@@ -828,3 +829,115 @@ func TestStateLogToNotInteger(t *testing.T) {
 	}
 	global.RemoveTemp("TestStateLogToNotInteger")
 }
+
+func TestStatesIfMatch(t *testing.T) {
+	t.Log("Entering................. TestStatesIfMatch")
+	extensionPath, err := global.CopyToTemp("TestStatesIfMatch", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestStatesIfMatch")
+	SetExtensionsPath(extensionPath)
+	handler := http.HandlerFunc(HandleStates)
+
+	req, _ := http.NewRequest("GET", "/cr/v1/states?extension-name=state-handler-test", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	etag := rr.Header().Get(global.ETagHeader)
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expecting an ETag, got %v %s", rr.Code, etag)
+	}
+
+	req, _ = http.NewRequest("PUT", "/cr/v1/states?extension-name=state-handler-test&action=set-statuses&status=SKIP", nil)
+	req.Header.Set(global.IfMatchHeader, "\"outdated\"")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+	if rr.Header().Get(global.ETagHeader) != etag {
+		t.Errorf("Expecting the current ETag %s, got %s", etag, rr.Header().Get(global.ETagHeader))
+	}
+
+	req, _ = http.NewRequest("PUT", "/cr/v1/states?extension-name=state-handler-test&action=set-statuses&status=SKIP", nil)
+	req.Header.Set(global.IfMatchHeader, etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	newETag := rr.Header().Get(global.ETagHeader)
+	if newETag == "" || newETag == etag {
+		t.Errorf("Expecting a new ETag, got %s", newETag)
+	}
+
+	//The first ETag is now outdated
+	req, _ = http.NewRequest("PUT", "/cr/v1/states?extension-name=state-handler-test&action=set-statuses&status=READY", nil)
+	req.Header.Set(global.IfMatchHeader, etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestStatesIfMatchLocked(t *testing.T) {
+	t.Log("Entering................. TestStatesIfMatchLocked")
+	extensionPath, err := global.CopyToTemp("TestStatesIfMatchLocked", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestStatesIfMatchLocked")
+	SetExtensionsPath(extensionPath)
+	sm, err := GetStatesManager("state-handler-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sm.readStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateName := sm.StateArray[0].Name
+	etag, err := sm.GetETag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//An engine update made between the If-Match check and the handler write waits for the handler
+	setState := make(chan error, 1)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		go func() {
+			engineSM, _ := GetStatesManager("state-handler-test")
+			setState <- engineSM.SetState(stateName, StateFAILED, "", "", -1, false)
+		}()
+		select {
+		case <-setState:
+			t.Error("Expecting the engine update to wait for the states lock")
+		case <-time.After(300 * time.Millisecond):
+		}
+		lockedSM, _, err := getStateManagerFromRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = lockedSM.SetState(stateName, StateSKIP, "", "", -1, false)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	req, _ := http.NewRequest("PUT", "/cr/v1/states?extension-name=state-handler-test", nil)
+	req.Header.Set(global.IfMatchHeader, etag)
+	rr := httptest.NewRecorder()
+	updateStates(rr, req, handler)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if err := <-setState; err != nil {
+		t.Fatal(err)
+	}
+	state, err := sm.GetState(stateName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StateFAILED {
+		t.Errorf("Expecting the engine update to be applied after the handler, got %s", state.Status)
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
 
@@ -124,6 +125,10 @@ type State struct {
 	NextRun bool `yaml:"next_run" json:"next_run"`
 	//This is true when the state is a extension
 	IsExtension bool `yaml:"is_extension" json:"is_extension"`
+	//DependsOnConfig list of config properties (dotted path for nested properties), if one of them changes the state and its StatesToRerun will be set to READY.
+	DependsOnConfig []string `yaml:"depends_on_config,omitempty" json:"depends_on_config,omitempty"`
+	//ConfigSnapshot (calculated) values of the DependsOnConfig properties at the last successful run
+	ConfigSnapshot map[string]string `yaml:"config_snapshot,omitempty" json:"config_snapshot,omitempty"`
 }
 
 type States struct {
@@ -143,72 +148,80 @@ type States struct {
 	//EndTime if not empty, it contains the last end execution time of the state
 	EndTime string `yaml:"end_time" json:"end_time"`
 	//Status states status
-	Status     string `yaml:"status" json:"status"`
-	StatesPath string `yaml:"-" json:"-"`
-	mux        *sync.Mutex
+	Status string `yaml:"status" json:"status"`
+	//ConfigOverlay is the config overlay selected for the last run, empty if the server default is used
+	ConfigOverlay string `yaml:"config_overlay,omitempty" json:"config_overlay,omitempty"`
+	StatesPath    string `yaml:"-" json:"-"`
+	mux           *sync.Mutex
+	unlockFile    func()
+	//locked is set on the states given to the handlers of updateStates, they already hold the states lock
+	locked bool
+	//inst the instance of the state manager
+	inst *Instance
 }
 
 var crLogTempFile *os.File
 
-var stateManagers map[string]States
-
-//initialize the map of stateManagers
-func init() {
-	stateManagers = make(map[string]States)
-}
-
 //get the statesFile path
-func getStatePath(extensionName string) (string, error) {
+func (inst *Instance) getStatePath(extensionName string) (string, error) {
 	if extensionName == "" {
 		return "", errors.New("extensionName is empty")
 	}
 	var statesPathAux string
-	statesPathAux = GetRootExtensionPath(GetExtensionsPath(), extensionName)
+	statesPathAux = inst.GetRootExtensionPath(inst.GetExtensionsPath(), extensionName)
 	statesPathAux = filepath.Join(statesPathAux, global.StatesFileName)
 	return statesPathAux, nil
 }
 
 //Add a state manager to the map, directly used only for test method.
-func addStateManager(extensionName string) {
+func (inst *Instance) addStateManager(extensionName string) {
 	log.Debug("Entering in addStateManager")
 	log.Debug("Extension name: " + extensionName)
 	sm := newStateManager(extensionName)
-	stateManagers[extensionName] = *sm
+	sm.inst = inst
+	inst.stateManagersMux.Lock()
+	defer inst.stateManagersMux.Unlock()
+	inst.stateManagers[extensionName] = *sm
 	log.Debug("State Manager added for " + extensionName)
 }
 
 //Remove a stateManager
-func removeStateManager(extensionName string) error {
-	delete(stateManagers, extensionName)
+func (inst *Instance) removeStateManager(extensionName string) error {
+	inst.stateManagersMux.Lock()
+	defer inst.stateManagersMux.Unlock()
+	delete(inst.stateManagers, extensionName)
 	return nil
 }
 
 //Find a stateManager based on the extensionNAme
-func getStatesManager(extensionName string) (*States, error) {
+func (inst *Instance) getStatesManager(extensionName string) (*States, error) {
 	log.Debug("Entering in getStatesManager")
 	log.Debug("ExtensionName: " + extensionName)
-	if val, ok := stateManagers[extensionName]; ok {
-		statePath, _ := getStatePath(extensionName)
+	inst.stateManagersMux.Lock()
+	val, ok := inst.stateManagers[extensionName]
+	inst.stateManagersMux.Unlock()
+	if ok {
+		statePath, _ := inst.getStatePath(extensionName)
 		log.Debug("statePath:" + statePath)
 		val.StatesPath = statePath
 		return &val, nil
 	}
-	return nil, errors.New("stateManager not found for " + extensionName)
+	return nil, apiError.NotFound(apiError.CodeExtensionNotFound, "stateManager not found for "+extensionName)
 }
 
 //Search for a stateManager and if not found create it
-func GetStatesManager(extensionName string) (*States, error) {
+func (inst *Instance) GetStatesManager(extensionName string) (*States, error) {
 	log.Debug("Entering in getAddStateManagersss")
 	log.Debug("Search for manager: " + extensionName)
-	sm, err := getStatesManager(extensionName)
+	sm, err := inst.getStatesManager(extensionName)
 	if err == nil {
 		log.Debug("Manager already exists, returning it")
 		return sm, nil
 	}
 	log.Debug("Manager doesn't exist, creating")
-	addStateManager(extensionName)
+	inst.addStateManager(extensionName)
 	log.Debug("returning created manager")
-	return getStatesManager(extensionName)
+	return inst.getStatesManager(extensionName)
 }
 
 //NewClient creates a new client
@@ -239,12 +252,31 @@ func (sm *States) isCustomStatePath() bool {
 	return false
 }
 
-func (sm *States) lock() {
+//lock serializes the updates of the states, between the goroutines with the mutex and between the processes with the states storage lock.
+func (sm *States) lock() error {
+	if sm.locked {
+		return nil
+	}
+	inst := sm.instance()
 	log.Debug("Lock states")
 	sm.mux.Lock()
+	unlockFile, err := inst.statesStorage.LockStates(sm.StatesPath)
+	if err != nil {
+		sm.mux.Unlock()
+		return err
+	}
+	sm.unlockFile = unlockFile
+	return nil
 }
 func (sm *States) unlock() {
+	if sm.locked {
+		return
+	}
 	log.Debug("Unlock states")
+	if sm.unlockFile != nil {
+		sm.unlockFile()
+		sm.unlockFile = nil
+	}
 	sm.mux.Unlock()
 }
 
@@ -252,21 +284,16 @@ func (sm *States) unlock() {
 // We can not use defer on sm.unlock because it is not an argument of the function
 func (sm *States) readStates() error {
 	log.Debug("Entering... readStates")
+	inst := sm.instance()
 	log.Debug("statesPath... " + sm.StatesPath)
 	log.Debugf("sm address %p:", &sm)
-	statesData, err := ioutil.ReadFile(sm.StatesPath)
-	//	log.Debugf("StatesData=%s", statesData)
+	// The file is parsed only if it changed since the last read or write
+	content, err := inst.loadStatesFile(sm.StatesPath)
 	if err != nil {
 		return err
 	}
 	log.Debug("states has been read")
-	//	log.Debug("States:\n" + string(statesData))
-	// Parse state file into the States structure
-	err = yaml.Unmarshal(statesData, &sm)
-	log.Debugf("sm address %p:", &sm)
-	if err != nil {
-		return err
-	}
+	sm.loadFrom(content)
 	// err = sm.setCalculatedStatesToRerun()
 	// if err != nil {
 	// 	return err
@@ -302,6 +329,7 @@ func (sm *States) setCalculatedStatesToRerun() error {
 //Set default value for states
 func (sm *States) setDefaultValues() {
 	log.Debug("Entering... setDefaultValues")
+	inst := sm.instance()
 	log.Debug("StatePath:" + sm.StatesPath)
 	//	isNextStateMigrationDone := false
 	for index := range sm.StateArray {
@@ -318,10 +346,10 @@ func (sm *States) setDefaultValues() {
 		//		log.Debug("Check LogPath/Script")
 
 		if sm.StateArray[index].LogPath == "" {
-			dir := GetExtensionsLogsPathEmbedded()
+			dir := inst.GetExtensionsLogsPathEmbedded()
 			if sm.isCustomStatePath() {
 				log.Debug("Customer extension")
-				dir = GetExtensionsLogsPathCustom()
+				dir = inst.GetExtensionsLogsPathCustom()
 			} else {
 				log.Debug("Embbeded extension")
 			}
@@ -381,6 +409,7 @@ func (sm *States) searchNextStates(start int) int {
 // We can not use defer on sm.unlock because it is not an argument of the function
 func (sm *States) writeStates() error {
 	log.Debug("Entering... writeStates")
+	inst := sm.instance()
 	log.Debug("Marshal states")
 	//	log.Debug(sm )
 	//	sm.lock()
@@ -391,15 +420,12 @@ func (sm *States) writeStates() error {
 		return err
 	}
 	//log.Debugf("Write states to %s", statesPath)
-	dir := filepath.Dir(sm.StatesPath)
-	errMkDir := os.MkdirAll(dir, 0777)
-	if errMkDir != nil {
-		return errMkDir
-	}
-	err = ioutil.WriteFile(sm.StatesPath, statesData, 0666)
+	err = inst.statesStorage.WriteStates(sm.StatesPath, statesData)
 	if err != nil {
+		inst.removeStatesFile(sm.StatesPath)
 		return err
 	}
+	inst.storeStatesFile(sm.StatesPath, statesData, sm)
 	return nil
 }
 
@@ -431,12 +457,19 @@ func (sm *States) _getState(state string) (*State, error) {
 			return &sm.StateArray[i], nil
 		}
 	}
-	return nil, errors.New("State: " + state + " not found!")
+	return nil, apiError.NotFound(apiError.CodeStateNotFound, "State: "+state+" not found!")
+}
+
+//GetETag returns the ETag of the states, it changes each time the states are written.
+func (sm *States) GetETag() (string, error) {
+	inst := sm.instance()
+	return inst.statesETag(sm.StatesPath)
 }
 
 //GetStates returns the list of states with a given status. if the status is an empty string then it returns all states.
 func (sm *States) GetStates(status string, extensionsOnly bool, recursive bool, langs []string) (*States, error) {
 	log.Debug("Entering... GetStates")
+	inst := sm.instance()
 	errStates := sm.readStates()
 	if errStates != nil {
 		return nil, errStates
@@ -452,6 +485,7 @@ func (sm *States) GetStates(status string, extensionsOnly bool, recursive bool,
 		Status:                  sm.Status,
 		ParentExtensionName:     sm.ParentExtensionName,
 		mux:                     &sync.Mutex{},
+		inst:                    sm.inst,
 	}
 	statuses, err := sm.CalculateStatesToRun(FirstState, LastState)
 	if err != nil {
@@ -469,7 +503,7 @@ func (sm *States) GetStates(status string, extensionsOnly bool, recursive bool,
 			//			if strings.HasPrefix(sm.StateArray[i].Script, global.ClientPath+" extension") {
 			states.StateArray = append(states.StateArray, sm.StateArray[i])
 			if recursive {
-				smp, err := GetStatesManager(sm.StateArray[i].Name)
+				smp, err := inst.GetStatesManager(sm.StateArray[i].Name)
 				if err != nil {
 					return nil, err
 				}
@@ -507,14 +541,18 @@ func (sm *States) GetStates(status string, extensionsOnly bool, recursive bool,
 //States marked deleted in the new states will be removed for the current states.
 func (sm *States) SetStates(states States, overwrite bool) error {
 	log.Debug("Entering... SetStates")
+	inst := sm.instance()
 	log.Debug("ExtensionPath:" + sm.StatesPath)
-	sm.lock()
+	errLock := sm.lock()
+	if errLock != nil {
+		return errLock
+	}
 	defer sm.unlock()
 	err := states.topoSort()
 	if err != nil {
 		return err
 	}
-	if _, err := os.Stat(sm.StatesPath); os.IsNotExist(err) {
+	if _, err := inst.statesStorage.StatesVersion(sm.StatesPath); os.IsNotExist(err) {
 		log.Debug(errors.New("State file " + sm.StatesPath + " doesn't exist"))
 		overwrite = true
 	} else {
@@ -524,7 +562,7 @@ func (sm *States) SetStates(states States, overwrite bool) error {
 		}
 	}
 	if sm.isRunning() {
-		return errors.New("The current state file has a running, action forbidden:" + sm.StatesPath)
+		return apiError.Conflict("The current state file has a running, action forbidden:" + sm.StatesPath)
 	}
 	if overwrite {
 		newStates, errDelete := sm.removeDeletedStates(states)
@@ -570,7 +608,7 @@ func (sm *States) SetStatesStatuses(status string, fromStateName string, fromInc
 		fromIndex = sm.getStatePosition(fromStateName)
 		log.Debug("From Position: " + fromStateName + " index: " + strconv.Itoa(fromIndex))
 		if fromIndex == -1 {
-			return errors.New(fromStateName + " not found!")
+			return apiError.NotFound(apiError.CodeStateNotFound, fromStateName+" not found!")
 		}
 		if !fromIncluded {
 			log.Debug("From excluded -1")
@@ -584,7 +622,7 @@ func (sm *States) SetStatesStatuses(status string, fromStateName string, fromInc
 		toIndex = sm.getStatePosition(toStateName)
 		log.Debug("To Position: " + toStateName + " index: " + strconv.Itoa(toIndex))
 		if toIndex == -1 {
-			return errors.New(toStateName + " not found!")
+			return apiError.NotFound(apiError.CodeStateNotFound, toStateName+" not found!")
 		}
 		if !toIncluded {
 			log.Debug("To excluded -1")
@@ -1075,6 +1113,39 @@ func (sm *States) IsRunning() (bool, error) {
 	return false, nil
 }
 
+//SetConfigOverlay sets the config overlay used by the next run, an empty overlay means the server default.
+func (sm *States) SetConfigOverlay(overlay string) error {
+	log.Debug("Entering in... SetConfigOverlay")
+	if overlay != "" {
+		err := global.ValidateConfigOverlay(overlay)
+		if err != nil {
+			return err
+		}
+	}
+	errStates := sm.readStates()
+	if errStates != nil {
+		return errStates
+	}
+	if sm.isRunning() {
+		return errors.New("Unable to set the config overlay, " + sm.ExtensionName + " is running")
+	}
+	sm.ConfigOverlay = overlay
+	return sm.writeStates()
+}
+
+//GetConfigOverlay returns the config overlay of the current or last run of the extension, if none the server default is returned.
+func (inst *Instance) GetConfigOverlay(extensionName string) string {
+	log.Debug("Entering in... GetConfigOverlay")
+	sm, err := inst.GetStatesManager(extensionName)
+	if err == nil {
+		err = sm.readStates()
+		if err == nil && sm.ConfigOverlay != "" {
+			return sm.ConfigOverlay
+		}
+	}
+	return global.ConfigOverlay
+}
+
 //Check if a status is Running in the current states
 func (sm *States) isResetRunning() bool {
 	for i := 0; i < len(sm.StateArray); i++ {
@@ -1095,9 +1166,10 @@ func (sm *States) isRunning() bool {
 //f recusively is true and if the state is an extension then the states of the extension will be set to the status and this recursively.
 func (sm *States) setStateStatus(state State, status string, recursively bool) error {
 	log.Debug("Entering.... setStateStatus state:" + state.Name + " status:" + status + " recursively:" + strconv.FormatBool(recursively))
+	inst := sm.instance()
 	index := indexState(sm.StateArray, state.Name)
 	if index == -1 {
-		return errors.New("State: " + state.Name + " not found!")
+		return apiError.NotFound(apiError.CodeStateNotFound, "State: "+state.Name+" not found!")
 	}
 	if state.Status != StateSKIP {
 		log.Debugln("Change status of " + state.Name + " to " + status)
@@ -1108,7 +1180,7 @@ func (sm *States) setStateStatus(state State, status string, recursively bool) e
 	sm.StateArray[index].Reason = ""
 	if recursively && state.IsExtension {
 		log.Debug(state.Name + " is an extension")
-		extensionStateManager, err := GetStatesManager(state.Name)
+		extensionStateManager, err := inst.GetStatesManager(state.Name)
 		if err != nil {
 			return err
 		}
@@ -1139,12 +1211,13 @@ func (sm *States) setStateStatus(state State, status string, recursively bool) e
 }
 
 func (sm *States) setParentStateStatus(status string) error {
+	inst := sm.instance()
 	//No parent
 	if sm.ParentExtensionName == "" {
 		return nil
 	}
 	//Update the parent extension name in the inserted extension state-file
-	stateManager, errStateManager := GetStatesManager(sm.ParentExtensionName)
+	stateManager, errStateManager := inst.GetStatesManager(sm.ParentExtensionName)
 	if errStateManager != nil {
 		logger.AddCallerField().Error(errStateManager.Error())
 		return errStateManager
@@ -1172,20 +1245,23 @@ func (sm *States) setParentStateStatus(status string) error {
 	return nil
 }
 
-func SetMock(mock bool) {
-	global.Mock = mock
+func (inst *Instance) SetMock(mock bool) {
+	inst.mock = mock
 }
 
 //Retrieve level
-func GetMock() bool {
-	return global.Mock
+func (inst *Instance) GetMock() bool {
+	return inst.mock
 }
 
 //ResetEngine Reset states, all non-skip state will be set to READY recursively
 //No RUNNING state must be found.
 func (sm *States) ResetEngine() error {
 	log.Debug("Entering... ResetEngine")
-	sm.lock()
+	errLock := sm.lock()
+	if errLock != nil {
+		return errLock
+	}
 	defer sm.unlock()
 	//Read states
 	errStates := sm.readStates()
@@ -1195,7 +1271,7 @@ func (sm *States) ResetEngine() error {
 	log.Debug("ResetEngine... states has been read")
 	//Check if states running
 	if sm.isResetRunning() {
-		err := errors.New("Deployment is running, can not proceed")
+		err := apiError.Conflict("Deployment is running, can not proceed")
 		log.Debug(err.Error())
 		return err
 	}
@@ -1218,7 +1294,10 @@ func (sm *States) ResetEngine() error {
 //No RUNNING state must be found.
 func (sm *States) ResetEngineExecutionInfo() error {
 	log.Debug("Entering... ResetEngineExecutionInfo")
-	sm.lock()
+	errLock := sm.lock()
+	if errLock != nil {
+		return errLock
+	}
 	defer sm.unlock()
 	errStates := sm.readStates()
 	if errStates != nil {
@@ -1227,7 +1306,7 @@ func (sm *States) ResetEngineExecutionInfo() error {
 	log.Debug("ResetEngine... states has been read")
 	//Check if states running
 	if sm.isRunning() {
-		err := errors.New("Deployment is running, can not proceed")
+		err := apiError.Conflict("Deployment is running, can not proceed")
 		log.Debug(err.Error())
 		return err
 	}
@@ -1259,7 +1338,10 @@ func (sm *States) GetState(state string, langs []string) (*State, error) {
 //SetState Set a state status
 func (sm *States) SetState(state string, status string, reason string, script string, scriptTimout int, recursivelly bool) error {
 	log.Debugf("Read states=%s\n", state)
-	sm.lock()
+	errLock := sm.lock()
+	if errLock != nil {
+		return errLock
+	}
 	defer sm.unlock()
 	errStates := sm.readStates()
 	if errStates != nil {
@@ -1276,7 +1358,7 @@ func (sm *States) SetState(state string, status string, reason string, script st
 			status != StateRUNNING &&
 			status != StateSKIP &&
 			status != StateSUCCEEDED {
-			return errors.New("Invalid status:" + status + " (" + StateREADY + "," + StateSKIP + "," + StateRUNNING + "," + StateSUCCEEDED + "," + StateFAILED + ")")
+			return apiError.BadRequest("Invalid status:" + status + " (" + StateREADY + "," + StateSKIP + "," + StateRUNNING + "," + StateSUCCEEDED + "," + StateFAILED + ")")
 		}
 	}
 	//if status READY go recursivelly
@@ -1354,19 +1436,29 @@ func (sm *States) setStateStatusWithTimeStamp(isStart bool, state string, status
 	} else {
 		stateFound.EndTime = timeNow
 	}
+	if !isStart && status == StateSUCCEEDED && len(stateFound.DependsOnConfig) > 0 {
+		snapshot, err := sm.getConfigSnapshot(*stateFound)
+		if err != nil {
+			log.Warning("Unable to take the config snapshot of " + stateFound.Name + ": " + err.Error())
+		} else {
+			stateFound.ConfigSnapshot = snapshot
+		}
+	}
 	errWriteStates := sm.writeStates()
 	if errWriteStates != nil {
 		return errWriteStates
 	}
+	sm.publishStateEvent(EventStateStatus, stateFound)
 	return nil
 }
 
 func (sm *States) InsertStateFromExtensionName(extensionName string, pos int, stateName string, before bool, overwrite bool) error {
 	log.Debug("Entering..... InsertStateFromExtensionName")
+	inst := sm.instance()
 	log.Debug("State name: " + stateName)
 	log.Debug("State position: " + strconv.Itoa(pos))
 	log.Debug("State before: " + strconv.FormatBool(before))
-	manifestPath, err := GetRegisteredExtensionPath(extensionName)
+	manifestPath, err := inst.GetRegisteredExtensionPath(extensionName)
 	if err != nil {
 		return err
 	}
@@ -1435,9 +1527,13 @@ func (sm *States) InsertStateFromString(stateDef string, pos int, stateName stri
 //Array start in Go at 0 but here the pos 1 is the elem 0
 func (sm *States) InsertState(state State, referencePosition int, referenceStateName string, before bool, overwrite bool) error {
 	log.Debug("Entering..... InsertState")
+	inst := sm.instance()
 	log.Debug("Reference State name: " + referenceStateName)
 	log.Debugf("State to be inserted: %v", state)
-	sm.lock()
+	errLock := sm.lock()
+	if errLock != nil {
+		return errLock
+	}
 	defer sm.unlock()
 	errStates := sm.readStates()
 	if errStates != nil {
@@ -1445,10 +1541,10 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 		return errStates
 	}
 	if sm.isRunning() {
-		return errors.New("Insert can not be executed while a deployment is running")
+		return apiError.Conflict("Insert can not be executed while a deployment is running")
 	}
 	if state.Name == "" || (!state.IsExtension && state.Script == "") {
-		return errors.New("The state name or script is missing")
+		return apiError.BadRequest("The state name or script is missing")
 	}
 	mustUpdateCallerState := false
 	existingPosition := sm.getStatePosition(state.Name)
@@ -1456,7 +1552,7 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 		if overwrite {
 			mustUpdateCallerState = true
 		} else {
-			return errors.New("State name " + state.Name + " already exists")
+			return apiError.Conflict("State name " + state.Name + " already exists")
 		}
 	}
 	// valid, err := IsExtension(state.Name)
@@ -1466,9 +1562,9 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 	// }
 	//We are inserting an extension and so the extension must be registered
 	if state.IsExtension {
-		registered := IsExtensionRegistered(state.Name)
+		registered := inst.IsExtensionRegistered(state.Name)
 		if !registered {
-			return errors.New("The state name " + state.Name + " is not registered")
+			return apiError.NotFound(apiError.CodeStateNotFound, "The state name "+state.Name+" is not registered")
 		}
 	}
 	//Set the position with the provided position
@@ -1478,7 +1574,7 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 	if referenceStateName != "" {
 		whereToInsertedPosition = sm.getStatePosition(referenceStateName)
 		if whereToInsertedPosition == -1 {
-			err := errors.New("state : " + referenceStateName + " not found")
+			err := apiError.NotFound(apiError.CodeStateNotFound, "state : "+referenceStateName+" not found")
 			logger.AddCallerField().Error(err.Error())
 			return err
 		}
@@ -1526,7 +1622,7 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 				log.Debugf("Cleaning Referenced NextStates of %s", stateName)
 				statePos := sm.getStatePosition(stateName)
 				if statePos == -1 {
-					err := errors.New("state : " + stateName + " not found")
+					err := apiError.NotFound(apiError.CodeStateNotFound, "state : "+stateName+" not found")
 					logger.AddCallerField().Error(err.Error())
 					return err
 				}
@@ -1546,7 +1642,7 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 		for _, stateName := range state.PreviousStates {
 			statePos := sm.getStatePosition(stateName)
 			if statePos == -1 {
-				err := errors.New("state : " + stateName + " not found")
+				err := apiError.NotFound(apiError.CodeStateNotFound, "state : "+stateName+" not found")
 				logger.AddCallerField().Error(err.Error())
 				return err
 			}
@@ -1579,7 +1675,7 @@ func (sm *States) InsertState(state State, referencePosition int, referenceState
 	//We are inserting an extension
 	if state.IsExtension {
 		//Update the parent extension name in the inserted extension state-file
-		stateManager, errStateManager := GetStatesManager(state.Name)
+		stateManager, errStateManager := inst.GetStatesManager(state.Name)
 		if errStateManager != nil {
 			logger.AddCallerField().Error(errStateManager.Error())
 			return errStateManager
@@ -1614,10 +1710,10 @@ func updateState(currentState *State, newState State) {
 }
 
 //updateCallers update the caller state already inserted in an another extension states-file
-func updateCallers(extensionName string) error {
+func (inst *Instance) updateCallers(extensionName string) error {
 	log.Debug("Entering... updateCallers: " + extensionName)
 	//Search manager
-	stateManager, errStateManager := GetStatesManager(extensionName)
+	stateManager, errStateManager := inst.GetStatesManager(extensionName)
 	if errStateManager != nil {
 		logger.AddCallerField().Error(errStateManager.Error())
 		return errStateManager
@@ -1630,7 +1726,7 @@ func updateCallers(extensionName string) error {
 	//If no parent nothing to do
 	log.Debug("stateManager.ParentExtensionName:" + stateManager.ParentExtensionName)
 	if stateManager.ParentExtensionName != "" {
-		stateParentManager, errStateManager := GetStatesManager(stateManager.ParentExtensionName)
+		stateParentManager, errStateManager := inst.GetStatesManager(stateManager.ParentExtensionName)
 		if errStateManager != nil {
 			logger.AddCallerField().Error(errStateManager.Error())
 			return errStateManager
@@ -1659,21 +1755,25 @@ func updateCallers(extensionName string) error {
 //Array start in Go at 0 but here the pos 1 is the elem 0
 func (sm *States) DeleteState(pos int, stateName string) error {
 	log.Debug("Entering..... DeleteState")
-	sm.lock()
+	inst := sm.instance()
+	errLock := sm.lock()
+	if errLock != nil {
+		return errLock
+	}
 	defer sm.unlock()
 	errStates := sm.readStates()
 	if errStates != nil {
 		return errStates
 	}
 	if sm.isRunning() {
-		return errors.New("Insert can not be executed while a deployment is running")
+		return apiError.Conflict("Insert can not be executed while a deployment is running")
 	}
 	position := pos
 	var err error
 	if stateName != "" {
 		position = sm.getStatePosition(stateName)
 		if position == -1 {
-			return errors.New("state :" + stateName + " not found")
+			return apiError.NotFound(apiError.CodeStateNotFound, "state :"+stateName+" not found")
 		}
 		position++
 	}
@@ -1706,7 +1806,7 @@ func (sm *States) DeleteState(pos int, stateName string) error {
 	// 	return err
 	// }
 	if isExtension {
-		stateManager, errStateManager := GetStatesManager(stateNameAux)
+		stateManager, errStateManager := inst.GetStatesManager(stateNameAux)
 		if errStateManager != nil {
 			logger.AddCallerField().Error(errStateManager.Error())
 			return errStateManager
@@ -1923,7 +2023,7 @@ func (sm *States) CalculateStatesToRun(fromState string, toState string) (map[st
 			for _, stateName := range currentState.CalculatedStatesToRerun {
 				state, err := sm._getState(stateName)
 				if err != nil {
-					return nil, errors.New("The state " + stateName + " is not an existing state")
+					return nil, apiError.NotFound(apiError.CodeStateNotFound, "The state "+stateName+" is not an existing state")
 				}
 				if state.Status != StateSKIP {
 					statuses[stateName] = StateREADY
@@ -1944,7 +2044,7 @@ func (sm *States) setCalculatedStatesToRun(statuses map[string]string) error {
 	for stateName, status := range statuses {
 		state, err := sm._getState(stateName)
 		if err != nil {
-			return errors.New("The state " + stateName + " is not an existing state. error=" + err.Error())
+			return apiError.NotFound(apiError.CodeStateNotFound, "The state "+stateName+" is not an existing state. error="+err.Error())
 		}
 		err = sm.setStateStatus(*state, status, true)
 		if err != nil {
@@ -1962,7 +2062,7 @@ func (sm *States) preprocessingExecute(fromState string, toState string) error {
 		return errStates
 	}
 	if sm.isRunning() {
-		err := errors.New("Already running")
+		err := apiError.Conflict("Already running")
 		log.Debug(err.Error())
 		return err
 	}
@@ -1994,7 +2094,7 @@ func (sm *States) setStatesExecutionID(callerState *State) error {
 		return errStates
 	}
 	if sm.isRunning() {
-		err := errors.New("Already running")
+		err := apiError.Conflict("Already running")
 		log.Debug(err.Error())
 		return err
 	}
@@ -2045,46 +2145,73 @@ func (sm *States) setExecutionTimesAndStatesStatus(status string, callerState *S
 		log.Debug(errStates.Error())
 		return errStates
 	}
+	if status == StateRUNNING {
+		sm.publishStateEvent(EventRunStart, nil)
+	} else {
+		sm.publishStateEvent(EventRunEnd, nil)
+	}
 	return nil
 }
 
 //Execute states from state 'fromState' to state 'toState'
 func (sm *States) Execute(fromState string, toState string, callerState *State, callerOutFile *os.File) error {
+	inst := sm.instance()
 	if callerState == nil {
+		errExecution := inst.startExecution()
+		if errExecution != nil {
+			log.Error(errExecution.Error())
+			return errExecution
+		}
+		defer inst.endExecution()
 		err := logger.LogFile.Rotate()
 		if err != nil {
 			log.Error(err.Error())
 		}
-		log.Infof("Execute %s from %s to %s", sm.ExtensionName, fromState, toState)
+		inst.startRun(sm.ExtensionName, logger.NewID())
+	} else {
+		inst.startRun(sm.ExtensionName, inst.GetRunID(callerState.ExecutedByExtensionName))
 	}
-	log.Debug("Enterring... Execute from " + fromState + " to " + toState)
-	log.Debug("State:" + sm.StatesPath)
-	log.Debug("From state:" + fromState)
-	log.Debug("To   state:" + toState)
+	defer inst.endRun(sm.ExtensionName)
 	err := sm.setStatesExecutionID(callerState)
+	if callerState == nil {
+		sm.runLog("").Infof("Execute %s from %s to %s", sm.ExtensionName, fromState, toState)
+	}
+	sm.runLog("").Debug("Enterring... Execute from " + fromState + " to " + toState)
+	sm.runLog("").Debug("State:" + sm.StatesPath)
+	sm.runLog("").Debug("From state:" + fromState)
+	sm.runLog("").Debug("To   state:" + toState)
 	//Init executionBy and ID, for the time being it is set to the sm.ExecutionName but later it could be set to the calling extension name
 	err = sm.preprocessingExecute(fromState, toState)
 	if err != nil {
-		log.Debug(err.Error())
+		sm.runLog("").Debug(err.Error())
 		return err
 	}
+	startTime := time.Now()
 	errStartTime := sm.setExecutionTimesAndStatesStatus(StateRUNNING, callerState)
 	if errStartTime != nil {
-		log.Debug(errStartTime.Error())
+		sm.runLog("").Debug(errStartTime.Error())
 		return errStartTime
 	}
+	inst.Metrics().RunsStarted.Inc(sm.ExtensionName)
 	err = sm.executeStates(fromState, toState, callerState, callerOutFile)
 	status := StateSUCCEEDED
 	if err != nil {
 		status = StateFAILED
+		inst.Metrics().RunsFailed.Inc(sm.ExtensionName)
+	} else {
+		inst.Metrics().RunsSucceeded.Inc(sm.ExtensionName)
 	}
 	errStopTime := sm.setExecutionTimesAndStatesStatus(status, callerState)
+	//The inserted extensions are part of the run of their caller
+	if callerState == nil {
+		sm.recordRun(fromState, toState, startTime, status, err)
+	}
 	if errStopTime != nil {
-		log.Debug(errStopTime.Error())
+		sm.runLog("").Debug(errStopTime.Error())
 		return errStopTime
 	}
 	if err != nil {
-		log.Debug(err.Error())
+		sm.runLog("").Debug(err.Error())
 		return err
 	}
 	return nil
@@ -2092,6 +2219,7 @@ func (sm *States) Execute(fromState string, toState string, callerState *State,
 
 //Execute states
 func (sm *States) executeStates(fromState string, toState string, callerState *State, callerOutFile *os.File) error {
+	inst := sm.instance()
 	// if callerState != nil {
 	// 	sm.ExecutedByExtensionName = callerState.ExecutedByExtensionName
 	// 	sm.ExecutionID = callerState.ExecutionID
@@ -2102,51 +2230,58 @@ func (sm *States) executeStates(fromState string, toState string, callerState *S
 		//Executed in panic case
 		defer func() {
 			if r := recover(); r != nil {
-				log.Debug(r)
+				sm.runLog(state.Name).Error(r)
 				sm.setStateStatusWithTimeStamp(false, state.Name, StateFAILED, "Panic Error, check logs")
 			}
 		}()
-		log.Debug("Processing state:" + state.Name)
+		sm.runLog(state.Name).Debug("Processing state:" + state.Name)
 		if state.Name == fromState {
 			toExecute = true
 		}
-		log.Debug("To execute:" + strconv.FormatBool(toExecute))
+		sm.runLog(state.Name).Debug("To execute:" + strconv.FormatBool(toExecute))
 		//Set to Ready to rerun PhaseAtEachRun state.
 		if state.Phase == PhaseAtEachRun && state.Status != StateSKIP {
 			errSetReady := sm.setStateStatusWithTimeStamp(true, state.Name, StateREADY, "")
 			if errSetReady != nil {
-				log.Debug(errSetReady.Error())
+				sm.runLog(state.Name).Debug(errSetReady.Error())
 				return errSetReady
 			}
 			state.Status = StateREADY
 		}
 		if state.Status == StateSUCCEEDED || state.Status == StateSKIP {
-			log.Debug("Skip:" + state.Name)
+			sm.runLog(state.Name).Debug("Skip:" + state.Name)
 			continue
 		}
 		if state.Status == StateRUNNING {
 			return errors.New("State:" + state.Name + " is " + StateRUNNING + "... Please wait before submitting again")
 		}
 		if toExecute {
-			log.Debug("Execute..." + state.Name)
+			//No new state is started while the server is shutting down
+			if inst.IsShuttingDown() {
+				return ErrShuttingDown
+			}
+			sm.runLog(state.Name).Debug("Execute..." + state.Name)
 			errSetRunning := sm.setStateStatusWithTimeStamp(true, state.Name, StateRUNNING, "")
 			if errSetRunning != nil {
-				log.Debug(errSetRunning.Error())
+				sm.runLog(state.Name).Debug(errSetRunning.Error())
 				return errSetRunning
 			}
 			state, errSetExecutionID := sm.setExecutionID(state.Name, callerState)
 			if errSetExecutionID != nil {
-				log.Debug(errSetExecutionID.Error())
+				sm.runLog(state.Name).Debug(errSetExecutionID.Error())
 				return errSetExecutionID
 			}
+			sm.runLog(state.Name).Info("State " + state.Name + " started")
 			err := sm.executeState(*state, callerState, callerOutFile)
 			if err != nil {
+				sm.runLog(state.Name).Error("State " + state.Name + " failed: " + err.Error())
 				errSetFailed := sm.setStateStatusWithTimeStamp(false, state.Name, StateFAILED, "Cmd failed:"+err.Error())
 				if errSetFailed != nil {
 					return errSetFailed
 				}
 				return err
 			}
+			sm.runLog(state.Name).Info("State " + state.Name + " succeeded")
 			errSetSucceed := sm.setStateStatusWithTimeStamp(false, state.Name, StateSUCCEEDED, "")
 			if errSetSucceed != nil {
 				return errSetSucceed
@@ -2161,7 +2296,9 @@ func (sm *States) executeStates(fromState string, toState string, callerState *S
 
 //Execute a state
 func (sm *States) executeState(state State, callerState *State, callerOutFile *os.File) error {
-	log.Debug("Entering... executeState " + state.Name)
+	inst := sm.instance()
+	runLog := sm.runLog(state.Name)
+	runLog.Debug("Entering... executeState " + state.Name)
 	//Check if there is a script
 	//Create the log directory if not exists
 	dir := filepath.Dir(state.LogPath)
@@ -2170,7 +2307,7 @@ func (sm *States) executeState(state State, callerState *State, callerOutFile *o
 	}
 	errMkDir := os.MkdirAll(dir, 0777)
 	if errMkDir != nil {
-		logger.AddCallerField().Error(errMkDir.Error())
+		logger.AddCallerField().WithFields(runLog.Data).Error(errMkDir.Error())
 		return errMkDir
 	}
 	//Check if log exists and rename it for backup
@@ -2179,14 +2316,14 @@ func (sm *States) executeState(state State, callerState *State, callerOutFile *o
 		newOutfilePath := filepath.Join(dir, filepath.Base(state.LogPath)+"-"+time.Now().Format("2006-01-02T150405.999999-07:00"))
 		err := os.Rename(outfilePath, newOutfilePath)
 		if err != nil {
-			logger.AddCallerField().Error(err.Error())
+			logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 			return err
 		}
 	}
 	//Create the log file.
 	outfile, err := os.OpenFile(outfilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
-		logger.AddCallerField().Error(err.Error())
+		logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 		return err
 	}
 	defer outfile.Close()
@@ -2197,22 +2334,34 @@ func (sm *States) executeState(state State, callerState *State, callerOutFile *o
 	// 	return errExt
 	// }
 	if state.IsExtension {
-		stateManager, errStateManager := GetStatesManager(state.Name)
+		stateManager, errStateManager := inst.GetStatesManager(state.Name)
 		if errStateManager != nil {
-			logger.AddCallerField().Error(errStateManager.Error())
+			logger.AddCallerField().WithFields(runLog.Data).Error(errStateManager.Error())
 			return errStateManager
 		}
-		errExec = stateManager.Execute(FirstState, LastState, &state, outfile)
+		//The inserted extension runs with the same config overlay
+		errExec = stateManager.SetConfigOverlay(sm.ConfigOverlay)
+		if errExec == nil {
+			errExec = stateManager.Execute(FirstState, LastState, &state, outfile)
+		}
 	} else {
 		if state.Script == "" {
 			err := errors.New("The state " + state.Name + " has no script defined")
-			logger.AddCallerField().Error(err.Error())
+			logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 			return err
 		}
-		log.Debug("script: " + state.Script)
+		runLog.Debug("script: " + state.Script)
+		//The script reads the config file
+		if configExporter != nil {
+			err := configExporter(inst, sm.ExtensionName)
+			if err != nil {
+				logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
+				return err
+			}
+		}
 		//Build the command line
 		script := state.Script
-		if global.Mock {
+		if inst.mock {
 			timeNow := time.Now().UTC().Format(time.UnixDate)
 			script = "echo \"Mock mode: " + timeNow + " extension name: " + sm.ExtensionName + " script for state " + state.Name + " is skipped!\""
 		}
@@ -2224,83 +2373,106 @@ func (sm *States) executeState(state State, callerState *State, callerOutFile *o
 			cmd = exec.Command(parts[0])
 		}
 		cmd.Dir = filepath.Dir(sm.StatesPath)
-		log.Debug("Execution directory: " + cmd.Dir)
+		configOverlay := sm.ConfigOverlay
+		if configOverlay == "" {
+			configOverlay = global.ConfigOverlay
+		}
+		cmd.Env = append(os.Environ(), "CR_CONFIG_OVERLAY="+configOverlay)
+		runLog.Debug("Execution directory: " + cmd.Dir)
 		//Redirect the std to the log file.
 		var multiWriter io.Writer
 		wOutFile := bufio.NewWriterSize(outfile, 40)
-		log.Debug("wOutFile: " + strconv.Itoa(wOutFile.Size()))
+		runLog.Debug("wOutFile: " + strconv.Itoa(wOutFile.Size()))
+		//Publish the log lines to the event subscribers
+		wEvents := newEventLogWriter(sm, state)
 		var wCallerOutFile *bufio.Writer
 		if callerOutFile != nil {
 			wCallerOutFile = bufio.NewWriterSize(callerOutFile, 40)
-			multiWriter = io.MultiWriter(wOutFile, wCallerOutFile)
+			multiWriter = io.MultiWriter(wOutFile, wCallerOutFile, wEvents)
 		} else {
-			multiWriter = io.MultiWriter(wOutFile)
+			multiWriter = io.MultiWriter(wOutFile, wEvents)
 		}
 		cmd.Stdout = multiWriter
 		cmd.Stderr = multiWriter
 		errExec = cmd.Start()
 		if errExec == nil {
+			inst.Metrics().RunningStates.Inc(sm.ExtensionName)
+			startTime := time.Now()
+			defer func() {
+				inst.Metrics().RunningStates.Dec(sm.ExtensionName)
+				inst.Metrics().StateDuration.Observe(time.Since(startTime).Seconds(), sm.ExtensionName, state.Name)
+			}()
 			done := make(chan error, 1)
 			//Wait signal from channel
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Error("Error while running "+state.Name+" ", r)
+						runLog.Error("Error while running "+state.Name+" ", r)
 					}
 				}()
 				done <- cmd.Wait()
 			}()
 			select {
 			case <-time.After(time.Duration(state.ScriptTimeout) * time.Minute):
-				log.Debug("Start Test timeout of " + state.Name)
+				runLog.Debug("Start Test timeout of " + state.Name)
 				if state.ScriptTimeout != 0 {
+					inst.Metrics().ScriptTimeouts.Inc(sm.ExtensionName, state.Name)
 					if err := cmd.Process.Kill(); err != nil {
-						log.Fatal("failed to kill: ", err)
+						runLog.Fatal("failed to kill: ", err)
 					}
+					inst.Metrics().ScriptKills.Inc(sm.ExtensionName, state.Name, "timeout")
 					errExec = errors.New("State " + state.Name + " killed as timeout reached")
 				}
-				log.Debug("End Test timeout of " + state.Name)
+				runLog.Debug("End Test timeout of " + state.Name)
+			case <-inst.executionsCancelled():
+				if err := cmd.Process.Kill(); err != nil {
+					runLog.Error("failed to kill: ", err)
+				}
+				inst.Metrics().ScriptKills.Inc(sm.ExtensionName, state.Name, "shutdown")
+				<-done
+				errExec = errors.New("State " + state.Name + " cancelled as the server is shutting down")
 			case err := <-done:
-				log.Debug("End of processing of " + state.Name)
+				runLog.Debug("End of processing of " + state.Name)
 				if err != nil {
 					errExec = errors.New("process done with error = " + err.Error())
 				}
 			}
 		}
 		if callerOutFile != nil {
-			logger.AddCallerField().Debug("wCallerOutFile.Flush()")
+			logger.AddCallerField().WithFields(runLog.Data).Debug("wCallerOutFile.Flush()")
 			err := wCallerOutFile.Flush()
 			if err != nil {
-				logger.AddCallerField().Error(err.Error())
+				logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 			}
-			logger.AddCallerField().Debug("callerOutFile.Sync()")
+			logger.AddCallerField().WithFields(runLog.Data).Debug("callerOutFile.Sync()")
 			err = callerOutFile.Sync()
 			if err != nil {
-				logger.AddCallerField().Error(err.Error())
+				logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 			}
 			// callerOutFile.Close()
 		}
-		logger.AddCallerField().Debug("wOutFile.Flush()")
+		logger.AddCallerField().WithFields(runLog.Data).Debug("wOutFile.Flush()")
 		err := wOutFile.Flush()
 		if err != nil {
-			logger.AddCallerField().Error(err.Error())
+			logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 		}
+		wEvents.Flush()
 	}
-	logger.AddCallerField().Debug("outfile.Sync()")
+	logger.AddCallerField().WithFields(runLog.Data).Debug("outfile.Sync()")
 	err = outfile.Sync()
 	if err != nil {
-		logger.AddCallerField().Error(err.Error())
+		logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 	}
-	logger.AddCallerField().Debug("outfile.Close()")
+	logger.AddCallerField().WithFields(runLog.Data).Debug("outfile.Close()")
 	err = outfile.Close()
 	if err != nil {
-		logger.AddCallerField().Error(err.Error())
+		logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 	}
 	if errExec != nil {
-		logger.AddCallerField().Error(errExec.Error())
+		logger.AddCallerField().WithFields(runLog.Data).Error(errExec.Error())
 		f, err := os.OpenFile(outfilePath, os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
-			logger.AddCallerField().Error(err.Error())
+			logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 			return errExec
 		}
 
@@ -2311,13 +2483,13 @@ func (sm *States) executeState(state State, callerState *State, callerOutFile *o
 		for scanner.Scan() {
 			line = scanner.Text()
 		}
-		log.Debug("last line in log:" + line)
+		runLog.Debug("last line in log:" + line)
 		if line != "" {
 			errExec = errors.New(errExec.Error() + "\n" + line)
 		}
-		log.Debug("errExec:" + errExec.Error())
+		runLog.Debug("errExec:" + errExec.Error())
 		if _, err = f.WriteString("\nstate:" + state.Name + "\nscript:" + state.Script + "\nlog:" + state.LogPath + "\n" + errExec.Error()); err != nil {
-			logger.AddCallerField().Error(err.Error())
+			logger.AddCallerField().WithFields(runLog.Data).Error(err.Error())
 		}
 	}
 	return errExec
@@ -0,0 +1,215 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"crypto/sha256"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+)
+
+//statesFile is the content of a states file, a nil field is absent from the file.
+//readStates keeps the current value of the absent fields like yaml.Unmarshal does.
+type statesFile struct {
+	StateArray              *[]State `yaml:"states"`
+	ExtensionName           *string  `yaml:"extension_name"`
+	ParentExtensionName     *string  `yaml:"parent_extension_name"`
+	ExecutedByExtensionName *string  `yaml:"executed_by_extension_name"`
+	ExecutionID             *int     `yaml:"execution_id"`
+	StartTime               *string  `yaml:"start_time"`
+	EndTime                 *string  `yaml:"end_time"`
+	Status                  *string  `yaml:"status"`
+	ConfigOverlay           *string  `yaml:"config_overlay"`
+}
+
+//statesCacheEntry the parsed content of a states file and what identifies the version of the file
+type statesCacheEntry struct {
+	version string
+	hash    [sha256.Size]byte
+	content statesFile
+}
+
+//loadStatesFile returns the content of a states file, parsing it only if it changed since the last read or write.
+func (inst *Instance) loadStatesFile(path string) (*statesFile, error) {
+	//The version is read before the content, if the states change in between they will be read again next time
+	version, err := inst.statesStorage.StatesVersion(path)
+	if err == nil {
+		inst.statesCacheMux.Lock()
+		entry, ok := inst.statesCache[path]
+		inst.statesCacheMux.Unlock()
+		if ok && entry.version == version {
+			return &entry.content, nil
+		}
+	}
+	statesData, err := inst.statesStorage.ReadStates(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(statesData)
+	inst.statesCacheMux.Lock()
+	defer inst.statesCacheMux.Unlock()
+	if entry, ok := inst.statesCache[path]; ok && entry.hash == hash {
+		log.Debug("States file " + path + " touched but not changed")
+		entry.version = version
+		return &entry.content, nil
+	}
+	log.Debug("Parse states file " + path)
+	entry := &statesCacheEntry{
+		version: version,
+		hash:    hash,
+	}
+	err = yaml.Unmarshal(statesData, &entry.content)
+	if err != nil {
+		return nil, err
+	}
+	inst.statesCache[path] = entry
+	return &entry.content, nil
+}
+
+//storeStatesFile caches the states written in a states file without parsing it again.
+func (inst *Instance) storeStatesFile(path string, statesData []byte, sm *States) {
+	version, err := inst.statesStorage.StatesVersion(path)
+	if err != nil {
+		inst.removeStatesFile(path)
+		return
+	}
+	entry := &statesCacheEntry{
+		version: version,
+		hash:    sha256.Sum256(statesData),
+		content: sm.toStatesFile(),
+	}
+	inst.statesCacheMux.Lock()
+	inst.statesCache[path] = entry
+	inst.statesCacheMux.Unlock()
+}
+
+//removeStatesFile removes a states file from the cache
+func (inst *Instance) removeStatesFile(path string) {
+	inst.statesCacheMux.Lock()
+	delete(inst.statesCache, path)
+	inst.statesCacheMux.Unlock()
+}
+
+//loadFrom sets the states with a copy of the content of a states file, the absent fields are not changed.
+func (sm *States) loadFrom(content *statesFile) {
+	if content.StateArray != nil {
+		sm.StateArray = make([]State, len(*content.StateArray))
+		for i, state := range *content.StateArray {
+			sm.StateArray[i] = state.clone()
+		}
+	}
+	if content.ExtensionName != nil {
+		sm.ExtensionName = *content.ExtensionName
+	}
+	if content.ParentExtensionName != nil {
+		sm.ParentExtensionName = *content.ParentExtensionName
+	}
+	if content.ExecutedByExtensionName != nil {
+		sm.ExecutedByExtensionName = *content.ExecutedByExtensionName
+	}
+	if content.ExecutionID != nil {
+		sm.ExecutionID = *content.ExecutionID
+	}
+	if content.StartTime != nil {
+		sm.StartTime = *content.StartTime
+	}
+	if content.EndTime != nil {
+		sm.EndTime = *content.EndTime
+	}
+	if content.Status != nil {
+		sm.Status = *content.Status
+	}
+	if content.ConfigOverlay != nil {
+		sm.ConfigOverlay = *content.ConfigOverlay
+	}
+}
+
+//toStatesFile returns the content of the states file written for the states,
+//it is what parsing the result of convert2ByteArray gives.
+func (sm *States) toStatesFile() statesFile {
+	stateArray := make([]State, len(sm.StateArray))
+	for i, state := range sm.StateArray {
+		stateArray[i] = state.clone()
+		stateArray[i].normalize()
+	}
+	content := statesFile{
+		StateArray:              &stateArray,
+		ExtensionName:           stringPtr(sm.ExtensionName),
+		ParentExtensionName:     stringPtr(sm.ParentExtensionName),
+		ExecutedByExtensionName: stringPtr(sm.ExecutedByExtensionName),
+		StartTime:               stringPtr(sm.StartTime),
+		EndTime:                 stringPtr(sm.EndTime),
+		Status:                  stringPtr(sm.Status),
+	}
+	executionID := sm.ExecutionID
+	content.ExecutionID = &executionID
+	//omitempty
+	if sm.ConfigOverlay != "" {
+		content.ConfigOverlay = stringPtr(sm.ConfigOverlay)
+	}
+	return content
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+//clone returns a deep copy of a state.
+//A new slice or map attribute must be copied here.
+func (state State) clone() State {
+	state.PrerequisiteStates = cloneStrings(state.PrerequisiteStates)
+	state.StatesToRerun = cloneStrings(state.StatesToRerun)
+	state.RerunOnRunOfStates = cloneStrings(state.RerunOnRunOfStates)
+	state.CalculatedStatesToRerun = cloneStrings(state.CalculatedStatesToRerun)
+	state.PreviousStates = cloneStrings(state.PreviousStates)
+	state.NextStates = cloneStrings(state.NextStates)
+	state.DependsOnConfig = cloneStrings(state.DependsOnConfig)
+	if state.ConfigSnapshot != nil {
+		configSnapshot := make(map[string]string, len(state.ConfigSnapshot))
+		for k, v := range state.ConfigSnapshot {
+			configSnapshot[k] = v
+		}
+		state.ConfigSnapshot = configSnapshot
+	}
+	return state
+}
+
+//normalize sets the attributes of a state as they are after a yaml marshal/unmarshal:
+//the not persisted attributes are cleared, the empty omitempty attributes are nil and the other nil slices are empty.
+func (state *State) normalize() {
+	state.CalculatedStatesToRerun = nil
+	for _, s := range []*[]string{&state.PrerequisiteStates, &state.StatesToRerun, &state.RerunOnRunOfStates, &state.PreviousStates, &state.NextStates} {
+		if *s == nil {
+			*s = make([]string, 0)
+		}
+	}
+	if len(state.DependsOnConfig) == 0 {
+		state.DependsOnConfig = nil
+	}
+	if len(state.ConfigSnapshot) == 0 {
+		state.ConfigSnapshot = nil
+	}
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
@@ -0,0 +1,197 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+)
+
+//cacheTestStates creates a states manager with n states in a temporary directory
+func cacheTestStates(t testing.TB, n int) (*States, func()) {
+	dir, err := ioutil.TempDir("", "states-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := newStateManager("states-cache")
+	sm.StatesPath = filepath.Join(dir, "states-file.yml")
+	for i := 0; i < n; i++ {
+		name := "state" + strconv.Itoa(i)
+		state := State{
+			Name:          name,
+			Label:         "State " + strconv.Itoa(i),
+			Status:        StateREADY,
+			LogPath:       filepath.Join(dir, name+".log"),
+			Script:        "echo " + name,
+			ScriptTimeout: 60,
+		}
+		if i > 0 {
+			state.PreviousStates = []string{"state" + strconv.Itoa(i-1)}
+		}
+		if i < n-1 {
+			state.NextStates = []string{"state" + strconv.Itoa(i+1)}
+		}
+		sm.StateArray = append(sm.StateArray, state)
+	}
+	err = sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sm, func() {
+		defaultInstance.removeStatesFile(sm.StatesPath)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStatesCacheWrite(t *testing.T) {
+	t.Log("Entering................. TestStatesCacheWrite")
+	sm, clean := cacheTestStates(t, 3)
+	defer clean()
+	sm.ExecutionID = 2
+	sm.ConfigOverlay = "overlay1"
+	sm.StateArray[0].DependsOnConfig = []string{"prop1"}
+	sm.StateArray[0].ConfigSnapshot = map[string]string{"prop1": "value1"}
+	sm.StateArray[1].DependsOnConfig = []string{}
+	sm.StateArray[1].CalculatedStatesToRerun = []string{"state2"}
+	sm.StateArray[2].StatesToRerun = []string{"state0"}
+	err := sm.writeStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The cached states must be the ones parsed from the file
+	statesData, err := ioutil.ReadFile(sm.StatesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected statesFile
+	err = yaml.Unmarshal(statesData, &expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := defaultInstance.loadStatesFile(sm.StatesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, expected) {
+		t.Errorf("Cached states differ from the file: got \n%+v\nwant\n%+v", *got, expected)
+	}
+	//The cache must not share memory with the states
+	sm.StateArray[0].DependsOnConfig[0] = "prop2"
+	sm.StateArray[0].ConfigSnapshot["prop1"] = "value2"
+	err = sm.readStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.StateArray[0].DependsOnConfig[0] != "prop1" || sm.StateArray[0].ConfigSnapshot["prop1"] != "value1" {
+		t.Error("Cached states modified by the states manager")
+	}
+}
+
+func TestStatesCacheExternalEdit(t *testing.T) {
+	t.Log("Entering................. TestStatesCacheExternalEdit")
+	sm, clean := cacheTestStates(t, 3)
+	defer clean()
+	err := sm.readStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.StateArray[1].Status = StateSKIP
+	statesData, err := sm.convert2ByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(sm.StatesPath, statesData, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.StateArray[1].Status = StateREADY
+	state, err := sm.GetState("state1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StateSKIP {
+		t.Errorf("Expected status %s got %s", StateSKIP, state.Status)
+	}
+}
+
+func BenchmarkReadStates(b *testing.B) {
+	sm, clean := cacheTestStates(b, 1000)
+	defer clean()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := sm.readStates()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadStatesNotCached(b *testing.B) {
+	sm, clean := cacheTestStates(b, 1000)
+	defer clean()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		defaultInstance.removeStatesFile(sm.StatesPath)
+		err := sm.readStates()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetState(b *testing.B) {
+	sm, clean := cacheTestStates(b, 1000)
+	defer clean()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := sm.GetState("state500", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetState(b *testing.B) {
+	sm, clean := cacheTestStates(b, 1000)
+	defer clean()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := sm.SetState("state500", StateSKIP, "", "", -1, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteStates(b *testing.B) {
+	sm, clean := cacheTestStates(b, 1000)
+	defer clean()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := sm.writeStates()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
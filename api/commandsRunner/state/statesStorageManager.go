@@ -0,0 +1,176 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+//StatesStorage persists the states of the extensions, a states document is identified by the path of the extension states file.
+type StatesStorage interface {
+	//ReadStates returns the states document, the error satisfies os.IsNotExist if it doesn't exist.
+	ReadStates(path string) ([]byte, error)
+	//WriteStates replaces the states document atomically.
+	WriteStates(path string, data []byte) error
+	//DeleteStates removes the states document, it doesn't fail if the document doesn't exist.
+	DeleteStates(path string) error
+	//LockStates protects the states document against the updates of the other processes until the returned function is called.
+	LockStates(path string) (func(), error)
+	//StatesVersion returns a version of the states document which changes each time the document is written,
+	//the error satisfies os.IsNotExist if it doesn't exist.
+	StatesVersion(path string) (string, error)
+}
+
+//FileStatesStorage stores each states document in its states file, it is the default storage.
+type FileStatesStorage struct{}
+
+//SetStatesStorage sets the storage of the states, it must be called before the states are accessed.
+func (inst *Instance) SetStatesStorage(storage StatesStorage) {
+	inst.statesCacheMux.Lock()
+	defer inst.statesCacheMux.Unlock()
+	inst.statesStorage = storage
+	inst.statesCache = make(map[string]*statesCacheEntry)
+}
+
+//GetStatesStorage returns the storage of the states
+func (inst *Instance) GetStatesStorage() StatesStorage {
+	return inst.statesStorage
+}
+
+//ReadStates reads the states file
+func (FileStatesStorage) ReadStates(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+//WriteStates writes the states file atomically under its file lock, the writers of the process are serialized too
+func (FileStatesStorage) WriteStates(path string, data []byte) error {
+	errMkDir := os.MkdirAll(filepath.Dir(path), 0777)
+	if errMkDir != nil {
+		return errMkDir
+	}
+	unlockFile, err := global.LockFileExclusive(path)
+	if err != nil {
+		return err
+	}
+	defer unlockFile()
+	return global.WriteFileAtomic(path, data, 0666)
+}
+
+//DeleteStates removes the states file
+func (FileStatesStorage) DeleteStates(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//LockStates takes the file lock of the states file, nothing is locked if the extension directory doesn't exist yet.
+//The goroutines of the process are serialized by the mutex of the states manager.
+func (FileStatesStorage) LockStates(path string) (func(), error) {
+	if _, err := os.Stat(filepath.Dir(path)); os.IsNotExist(err) {
+		return func() {}, nil
+	}
+	return global.LockFile(path)
+}
+
+//StatesVersion returns the size and the modification time of the states file
+func (FileStatesStorage) StatesVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(info.Size(), 10) + "-" + strconv.FormatInt(info.ModTime().UnixNano(), 10), nil
+}
+
+//statesETag returns the ETag of a states document, an empty string if it doesn't exist
+func (inst *Instance) statesETag(path string) (string, error) {
+	statesData, err := inst.statesStorage.ReadStates(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return global.ETag(statesData), nil
+}
+
+//deleteExtensionDocuments removes the states, the run history and the config of an extension from the storage
+func (inst *Instance) deleteExtensionDocuments(extensionName string) error {
+	statesPath, err := inst.getStatePath(extensionName)
+	if err != nil {
+		return err
+	}
+	err = inst.statesStorage.DeleteStates(statesPath)
+	if err != nil {
+		return err
+	}
+	inst.removeStatesFile(statesPath)
+	err = inst.runsStorage.DeleteRuns(statesPath)
+	if err != nil {
+		return err
+	}
+	if configRemover != nil {
+		return configRemover(inst, extensionName)
+	}
+	return nil
+}
+
+//MigrateStates copies the states of the registered extensions from a storage to another, it returns the migrated extensions.
+func (inst *Instance) MigrateStates(from StatesStorage, to StatesStorage) ([]string, error) {
+	log.Debug("Entering in... MigrateStates")
+	extensions, err := inst.ListExtensions("", false)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(extensions.Extensions))
+	for name := range extensions.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	migrated := make([]string, 0)
+	for _, name := range names {
+		statesPath, err := inst.getStatePath(name)
+		if err != nil {
+			return migrated, err
+		}
+		statesData, err := from.ReadStates(statesPath)
+		if os.IsNotExist(err) {
+			//Extension without states
+			continue
+		}
+		if err != nil {
+			return migrated, err
+		}
+		err = to.WriteStates(statesPath, statesData)
+		if err != nil {
+			return migrated, err
+		}
+		log.Info("States of " + name + " migrated")
+		migrated = append(migrated, name)
+	}
+	return migrated, nil
+}
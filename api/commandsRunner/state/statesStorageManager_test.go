@@ -0,0 +1,127 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+//memoryStatesStorage stores the states in memory
+type memoryStatesStorage struct {
+	mux      sync.Mutex
+	states   map[string][]byte
+	versions map[string]int
+}
+
+func newMemoryStatesStorage() *memoryStatesStorage {
+	return &memoryStatesStorage{
+		states:   make(map[string][]byte),
+		versions: make(map[string]int),
+	}
+}
+
+func (ms *memoryStatesStorage) ReadStates(path string) ([]byte, error) {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	data, ok := ms.states[path]
+	if !ok {
+		return nil, &os.PathError{Op: "read", Path: path, Err: os.ErrNotExist}
+	}
+	return data, nil
+}
+
+func (ms *memoryStatesStorage) WriteStates(path string, data []byte) error {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	ms.states[path] = data
+	ms.versions[path]++
+	return nil
+}
+
+func (ms *memoryStatesStorage) DeleteStates(path string) error {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	delete(ms.states, path)
+	return nil
+}
+
+func (ms *memoryStatesStorage) LockStates(path string) (func(), error) {
+	return func() {}, nil
+}
+
+func (ms *memoryStatesStorage) StatesVersion(path string) (string, error) {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+	if _, ok := ms.states[path]; !ok {
+		return "", &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+	return strconv.Itoa(ms.versions[path]), nil
+}
+
+func TestStatesStorage(t *testing.T) {
+	t.Log("Entering................. TestStatesStorage")
+	storage := newMemoryStatesStorage()
+	SetStatesStorage(storage)
+	defer SetStatesStorage(FileStatesStorage{})
+	sm := newStateManager("states-storage")
+	sm.StatesPath = "/nonexistent/states-storage/states-file.yml"
+	etag, err := sm.GetETag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != "" {
+		t.Errorf("Expected no ETag got %s", etag)
+	}
+	states := States{
+		StateArray: []State{
+			{Name: "state1", Script: "echo state1"},
+			{Name: "state2", Script: "echo state2"},
+		},
+	}
+	err = sm.SetStates(states, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := storage.states[sm.StatesPath]; !ok {
+		t.Fatal("States not written in the storage")
+	}
+	if _, err := os.Stat(sm.StatesPath); !os.IsNotExist(err) {
+		t.Error("States written in a file")
+	}
+	err = sm.SetState("state2", StateSKIP, "", "", -1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := sm.GetState("state2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StateSKIP {
+		t.Errorf("Expected status %s got %s", StateSKIP, state.Status)
+	}
+	newETag, err := sm.GetETag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newETag == "" || newETag == etag {
+		t.Errorf("Expected a new ETag got %s", newETag)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
@@ -43,11 +44,12 @@ Method: GET
 */
 func getTemplateEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in getTemplateEndpoint")
+	inst := GetInstance(req)
 	langs := i18nUtils.GetLangs(req)
 	extensionName, m, err := global.GetExtensionNameFromRequest(req)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
 	log.Debug("ExtensionName:" + extensionName)
@@ -58,12 +60,12 @@ func getTemplateEndpoint(w http.ResponseWriter, req *http.Request) {
 		uiMetaDataName = uiMetaDataNameFound[0]
 	}
 	//Retrieve the property name
-	uiconfig, err := GenerateUIMetaDataTemplate(extensionName, uiMetaDataName, langs)
+	uiconfig, err := inst.GenerateUIMetaDataTemplate(extensionName, uiMetaDataName, langs)
 	if err == nil {
 		//		log.Debug(string(uiconfig))
 		w.Write([]byte(uiconfig))
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
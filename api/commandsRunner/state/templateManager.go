@@ -31,7 +31,7 @@ import (
 
 type TraversePropertiesCallBack func(property map[string]interface{}, first bool, mandatory bool, parentProperty map[string]interface{}, path string, input interface{}) (err error)
 
-func GenerateUIMetaDataTemplate(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+func (inst *Instance) GenerateUIMetaDataTemplate(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
 	log.Debug("Entering in... GenerateUIMetaDataTemplate")
 	log.Debugf("extensionName=%s", extensionName)
 	log.Debugf("uiMetadataName=%s", uiMetadataName)
@@ -39,16 +39,16 @@ func GenerateUIMetaDataTemplate(extensionName string, uiMetadataName string, lan
 		uiMetadataName = global.DefaultUIMetaDataName
 	}
 	log.Debugf("uiMetadataName=%s", uiMetadataName)
-	raw, e := getUIMetadataTemplate(extensionName, uiMetadataName, langs)
+	raw, e := inst.getUIMetadataTemplate(extensionName, uiMetadataName, langs)
 	if e != nil {
 		return nil, e
 	}
 	return raw, nil
 }
 
-func getUIMetadataTemplate(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+func (inst *Instance) getUIMetadataTemplate(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
 	log.Debug("Entering in... getUIMetadataTemplate")
-	cfg, err := getUIMetadataParseConfig(extensionName, uiMetadataName, langs)
+	cfg, err := inst.getUIMetadataParseConfig(extensionName, uiMetadataName, langs)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +58,11 @@ func getUIMetadataTemplate(extensionName string, uiMetadataName string, langs []
 	if err != nil {
 		return nil, err
 	}
+	//The conditions are evaluated against the default values
+	defaultValues, err := getUIMetadataDefaultValues(groups)
+	if err != nil {
+		return nil, err
+	}
 	for _, group := range groups {
 		groupMap, ok := group.(map[string]interface{})
 		if !ok {
@@ -72,7 +77,7 @@ func getUIMetadataTemplate(extensionName string, uiMetadataName string, langs []
 			if val, ok := groupMap["mandatory"]; ok {
 				mandatory = val.(bool)
 			}
-			err = traverseProperties(propertiesList, true, mandatory, nil, printPropertyCallBack(), path, outTemplate)
+			err = traverseProperties(propertiesList, true, mandatory, nil, defaultValues, printPropertyCallBack(), path, outTemplate)
 			if err != nil {
 				return outTemplate.Bytes(), err
 			}
@@ -87,7 +92,7 @@ func getUIMetadataTemplate(extensionName string, uiMetadataName string, langs []
 	return indentOutTemplate.Bytes(), nil
 }
 
-func traverseProperties(properties []interface{}, first bool, mandatory bool, parentProperty map[string]interface{}, traversePropertiesCallBack TraversePropertiesCallBack, path string, input interface{}) error {
+func traverseProperties(properties []interface{}, first bool, mandatory bool, parentProperty map[string]interface{}, values map[string]interface{}, traversePropertiesCallBack TraversePropertiesCallBack, path string, input interface{}) error {
 	for _, property := range properties {
 		log.Debugf("property=%v", property)
 		p, ok := property.(map[string]interface{})
@@ -99,13 +104,12 @@ func traverseProperties(properties []interface{}, first bool, mandatory bool, pa
 			return errors.New("Property name missing at path " + path)
 		}
 		log.Debug("path=" + path)
-		newMandatory := mandatory
-		if val, ok := p["mandatory"]; ok {
-			if !val.(bool) && mandatory {
-				newMandatory = false
-			}
+		//A hidden property and its sub-properties are not mandatory
+		newMandatory, err := IsPropertyMandatory(p, mandatory, values)
+		if err != nil {
+			return err
 		}
-		err := traversePropertiesCallBack(p, first, newMandatory, parentProperty, path, input)
+		err = traversePropertiesCallBack(p, first, newMandatory, parentProperty, path, input)
 		first = false
 		if err != nil {
 			return err
@@ -128,7 +132,7 @@ func traverseProperties(properties []interface{}, first bool, mandatory bool, pa
 					}
 				}
 			}
-			err := traverseProperties(newProperties, first, newMandatory, p, traversePropertiesCallBack, newPath, input)
+			err := traverseProperties(newProperties, first, newMandatory, p, values, traversePropertiesCallBack, newPath, input)
 			if err != nil {
 				return err
 			}
@@ -186,6 +190,13 @@ func printPropertyCallBack() TraversePropertiesCallBack {
 			default:
 			}
 		}
+		//Add conditions to comment
+		if val, ok := property[VisibleIfKey]; ok {
+			commentLine = commentLine + fmt.Sprintf(" - %s: %s", VisibleIfKey, val)
+		}
+		if val, ok := property[RequiredIfKey]; ok {
+			commentLine = commentLine + fmt.Sprintf(" - %s: %s", RequiredIfKey, val)
+		}
 		// write comment line of not empty
 		if commentLine != "" {
 			commentLine = strings.Replace(commentLine, "\n", "\n# ", -1) + fmt.Sprint("\n")
@@ -244,6 +255,59 @@ func printPropertyCallBack() TraversePropertiesCallBack {
 	})
 }
 
+//getUIMetadataDefaultValues collects the default values of the properties, nested properties are collected in a sub-map
+func getUIMetadataDefaultValues(groups []interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, group := range groups {
+		groupMap, ok := group.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("Expect a map[string]interface{} under groups")
+		}
+		if properties, ok := groupMap["properties"]; ok {
+			propertiesList, ok := properties.([]interface{})
+			if !ok {
+				return nil, errors.New("Expect a []interface{} under properties")
+			}
+			err := addDefaultValues(values, propertiesList)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+func addDefaultValues(values map[string]interface{}, properties []interface{}) error {
+	for _, property := range properties {
+		p, ok := property.(map[string]interface{})
+		if !ok {
+			return errors.New("Expect a map[string]interface{} under properties")
+		}
+		name, ok := p["name"].(string)
+		if !ok {
+			return errors.New("Property name missing")
+		}
+		if val, ok := p["properties"]; ok {
+			if propertyType, ok := p["type"]; ok && propertyType == "array" {
+				continue
+			}
+			subProperties, ok := val.([]interface{})
+			if !ok {
+				return errors.New("Expect an []interface{} under " + name)
+			}
+			subValues := make(map[string]interface{})
+			err := addDefaultValues(subValues, subProperties)
+			if err != nil {
+				return err
+			}
+			values[name] = subValues
+		} else if val, ok := p["default"]; ok {
+			values[name] = val
+		}
+	}
+	return nil
+}
+
 func leftPad(s string, nb int, char string) string {
 	b := bytes.NewBufferString("")
 	for i := 0; i < nb; i++ {
@@ -63,7 +63,7 @@ func TestTraverseProperties(t *testing.T) {
 	fmt.Printf("%v", properties)
 	var path string
 	out := bytes.NewBufferString("")
-	err := traverseProperties(properties, false, true, nil, printPropertyCallBack(), path, out)
+	err := traverseProperties(properties, false, true, nil, nil, printPropertyCallBack(), path, out)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -93,6 +93,47 @@ param4: # Eg: sample_value 4
 	t.Logf("\n%s", out.String())
 }
 
+func TestTraversePropertiesConditions(t *testing.T) {
+	var properties []interface{}
+	properties = make([]interface{}, 0)
+	p1 := make(map[string]interface{}, 0)
+	p1["name"] = "proxy_enabled"
+	p1["default"] = false
+	properties = append(properties, p1)
+	p2 := make(map[string]interface{}, 0)
+	p2["name"] = "proxy_host"
+	p2["visible_if"] = "proxy_enabled == true"
+	properties = append(properties, p2)
+	p3 := make(map[string]interface{}, 0)
+	p3["name"] = "no_proxy"
+	p3["required_if"] = "proxy_enabled != true"
+	properties = append(properties, p3)
+	p4 := make(map[string]interface{}, 0)
+	p4["name"] = "proxy_exceptions"
+	p4["mandatory"] = false
+	p4["required_if"] = "proxy_enabled != true"
+	properties = append(properties, p4)
+	values := map[string]interface{}{"proxy_enabled": false}
+	var path string
+	out := bytes.NewBufferString("")
+	err := traverseProperties(properties, false, true, nil, values, printPropertyCallBack(), path, out)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	expectedOut := `# No description
+proxy_enabled: false
+# # No description - visible_if: proxy_enabled == true
+# proxy_host: # No default/sample_value provided or unknown default/sample_value type
+# No description - required_if: proxy_enabled != true
+no_proxy: # No default/sample_value provided or unknown default/sample_value type
+# No description - required_if: proxy_enabled != true
+proxy_exceptions: # No default/sample_value provided or unknown default/sample_value type
+`
+	if out.String() != expectedOut {
+		t.Errorf("expecting: \n%s \ngot \n%s", expectedOut, out.String())
+	}
+}
+
 func TestGetUIMetadataTemplate(t *testing.T) {
 	// log.SetLevel(log.DebugLevel)
 	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
@@ -104,7 +145,7 @@ func TestGetUIMetadataTemplate(t *testing.T) {
 		t.Fatal(err)
 	}
 	SetExtensionsPath(extensionsPath)
-	data, err := getUIMetadataTemplate("ext-template", "test-ui", []string{global.DefaultLanguage})
+	data, err := defaultInstance.getUIMetadataTemplate("ext-template", "test-ui", []string{global.DefaultLanguage})
 	if err != nil {
 		t.Logf("\n%s", data)
 		t.Error(err.Error())
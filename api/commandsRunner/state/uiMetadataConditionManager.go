@@ -0,0 +1,286 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//VisibleIfKey is the ui metadata property attribute hidding a property when its condition is false
+const VisibleIfKey = "visible_if"
+
+//RequiredIfKey is the ui metadata property attribute making a property mandatory when its condition is true
+const RequiredIfKey = "required_if"
+
+/*
+EvaluateCondition evaluates a visible_if/required_if condition.
+A condition is a list of comparisons "property == value", "property != value", "property" or "!property"
+combined with "&&", "||" and parenthesis.
+The property is searched in the values in the provided order, a dotted name goes down in nested maps.
+*/
+func EvaluateCondition(condition string, values ...map[string]interface{}) (bool, error) {
+	log.Debug("Entering in... EvaluateCondition")
+	log.Debugf("condition=%s", condition)
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	if len(tokens) == 0 {
+		return false, errors.New("Empty condition")
+	}
+	e := &conditionEvaluator{tokens: tokens, values: values}
+	result, err := e.evaluateOr()
+	if err != nil {
+		return false, errors.New("Invalid condition '" + condition + "': " + err.Error())
+	}
+	if e.pos != len(e.tokens) {
+		return false, errors.New("Invalid condition '" + condition + "': unexpected " + e.tokens[e.pos])
+	}
+	return result, nil
+}
+
+//IsPropertyVisible returns false if the visible_if condition of the property is false
+func IsPropertyVisible(property map[string]interface{}, values ...map[string]interface{}) (bool, error) {
+	condition, ok := property[VisibleIfKey].(string)
+	if !ok {
+		return true, nil
+	}
+	return EvaluateCondition(condition, values...)
+}
+
+/*
+IsPropertyRequired returns if a property is mandatory.
+The required_if condition takes precedence on the mandatory attribute
+*/
+func IsPropertyRequired(property map[string]interface{}, mandatory bool, values ...map[string]interface{}) (bool, error) {
+	condition, ok := property[RequiredIfKey].(string)
+	if !ok {
+		return mandatory, nil
+	}
+	return EvaluateCondition(condition, values...)
+}
+
+/*
+IsPropertyMandatory returns if a property must have a value, the template and the config validation use this rule.
+A hidden property is not mandatory, otherwise the required_if condition takes precedence on the mandatory attribute,
+a property without mandatory attribute inherits the mandatory flag of its parent.
+*/
+func IsPropertyMandatory(property map[string]interface{}, parentMandatory bool, values ...map[string]interface{}) (bool, error) {
+	visible, err := IsPropertyVisible(property, values...)
+	if err != nil || !visible {
+		return false, err
+	}
+	mandatory := parentMandatory
+	if val, ok := property["mandatory"].(bool); ok && !val {
+		mandatory = false
+	}
+	return IsPropertyRequired(property, mandatory, values...)
+}
+
+type conditionEvaluator struct {
+	tokens []string
+	pos    int
+	values []map[string]interface{}
+}
+
+func (e *conditionEvaluator) next() string {
+	if e.pos >= len(e.tokens) {
+		return ""
+	}
+	return e.tokens[e.pos]
+}
+
+func (e *conditionEvaluator) evaluateOr() (bool, error) {
+	result, err := e.evaluateAnd()
+	if err != nil {
+		return false, err
+	}
+	for e.next() == "||" {
+		e.pos++
+		right, err := e.evaluateAnd()
+		if err != nil {
+			return false, err
+		}
+		result = result || right
+	}
+	return result, nil
+}
+
+func (e *conditionEvaluator) evaluateAnd() (bool, error) {
+	result, err := e.evaluateComparison()
+	if err != nil {
+		return false, err
+	}
+	for e.next() == "&&" {
+		e.pos++
+		right, err := e.evaluateComparison()
+		if err != nil {
+			return false, err
+		}
+		result = result && right
+	}
+	return result, nil
+}
+
+func (e *conditionEvaluator) evaluateComparison() (bool, error) {
+	token := e.next()
+	switch token {
+	case "":
+		return false, errors.New("unexpected end of condition")
+	case "!":
+		e.pos++
+		result, err := e.evaluateComparison()
+		return !result, err
+	case "(":
+		e.pos++
+		result, err := e.evaluateOr()
+		if err != nil {
+			return false, err
+		}
+		if e.next() != ")" {
+			return false, errors.New("missing )")
+		}
+		e.pos++
+		return result, nil
+	case ")", "&&", "||", "==", "!=":
+		return false, errors.New("unexpected " + token)
+	}
+	e.pos++
+	value := e.lookup(token)
+	operator := e.next()
+	if operator != "==" && operator != "!=" {
+		return isConditionValueTrue(value), nil
+	}
+	e.pos++
+	literal := e.next()
+	if literal == "" {
+		return false, errors.New("missing value after " + operator)
+	}
+	e.pos++
+	equal := conditionValueToString(value) == unquoteConditionLiteral(literal)
+	if operator == "==" {
+		return equal, nil
+	}
+	return !equal, nil
+}
+
+func (e *conditionEvaluator) lookup(name string) interface{} {
+	for _, values := range e.values {
+		if value, ok := lookupConditionValue(values, name); ok {
+			return value
+		}
+	}
+	return nil
+}
+
+func lookupConditionValue(values map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := values[name]; ok {
+		return value, true
+	}
+	path := strings.SplitN(name, ".", 2)
+	if len(path) != 2 {
+		return nil, false
+	}
+	switch child := values[path[0]].(type) {
+	case map[string]interface{}:
+		return lookupConditionValue(child, path[1])
+	case map[interface{}]interface{}:
+		childMap := make(map[string]interface{})
+		for key, value := range child {
+			childMap[fmt.Sprint(key)] = value
+		}
+		return lookupConditionValue(childMap, path[1])
+	}
+	return nil, false
+}
+
+func conditionValueToString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func isConditionValueTrue(value interface{}) bool {
+	switch conditionValueToString(value) {
+	case "", "false", "0":
+		return false
+	}
+	return true
+}
+
+func unquoteConditionLiteral(literal string) string {
+	if len(literal) >= 2 &&
+		(literal[0] == '"' || literal[0] == '\'') &&
+		literal[len(literal)-1] == literal[0] {
+		return literal[1 : len(literal)-1]
+	}
+	return literal
+}
+
+func tokenizeCondition(condition string) ([]string, error) {
+	tokens := make([]string, 0)
+	runes := []rune(condition)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		case r == '=' || r == '!' || r == '&' || r == '|':
+			if i+1 < len(runes) {
+				operator := string(runes[i : i+2])
+				if operator == "==" || operator == "!=" || operator == "&&" || operator == "||" {
+					tokens = append(tokens, operator)
+					i += 2
+					continue
+				}
+			}
+			if r != '!' {
+				return nil, errors.New("Invalid operator in condition '" + condition + "'")
+			}
+			tokens = append(tokens, "!")
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.New("Unterminated string in condition '" + condition + "'")
+			}
+			tokens = append(tokens, string(runes[i:end+1]))
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("()=!&|\"'", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,124 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"testing"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	values := map[string]interface{}{
+		"proxy_enabled": true,
+		"proxy_port":    3128,
+		"proxy_type":    "http",
+		"empty":         "",
+		"backup": map[string]interface{}{
+			"enabled": false,
+		},
+	}
+	conditions := map[string]bool{
+		"proxy_enabled == true":                true,
+		"proxy_enabled":                        true,
+		"!proxy_enabled":                       false,
+		"proxy_enabled != true":                false,
+		"proxy_port == 3128":                   true,
+		"proxy_type == 'http'":                 true,
+		"proxy_type == \"https\"":              false,
+		"empty":                                false,
+		"not_found":                            false,
+		"not_found == ''":                      true,
+		"backup.enabled == false":              true,
+		"proxy_enabled && proxy_type == https": false,
+		"proxy_enabled && (proxy_type == https || empty == '')": true,
+		"!(proxy_enabled && backup.enabled)":                    true,
+	}
+	for condition, expected := range conditions {
+		result, err := EvaluateCondition(condition, values)
+		if err != nil {
+			t.Errorf("%s: %s", condition, err.Error())
+			continue
+		}
+		if result != expected {
+			t.Errorf("%s: expected %t but got %t", condition, expected, result)
+		}
+	}
+}
+
+func TestEvaluateConditionScopes(t *testing.T) {
+	local := map[string]interface{}{"enabled": false}
+	root := map[string]interface{}{"enabled": true, "proxy_enabled": true}
+	result, err := EvaluateCondition("enabled", local, root)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result {
+		t.Error("Expected the local value to take precedence")
+	}
+	result, err = EvaluateCondition("proxy_enabled", local, root)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !result {
+		t.Error("Expected the root value to be found")
+	}
+}
+
+func TestIsPropertyMandatory(t *testing.T) {
+	values := map[string]interface{}{"proxy_enabled": true}
+	tests := []struct {
+		property        map[string]interface{}
+		parentMandatory bool
+		expected        bool
+	}{
+		{map[string]interface{}{"name": "p"}, true, true},
+		{map[string]interface{}{"name": "p"}, false, false},
+		{map[string]interface{}{"name": "p", "mandatory": false}, true, false},
+		{map[string]interface{}{"name": "p", "mandatory": true}, false, false},
+		{map[string]interface{}{"name": "p", "mandatory": false, "required_if": "proxy_enabled"}, true, true},
+		{map[string]interface{}{"name": "p", "mandatory": false, "required_if": "proxy_enabled"}, false, true},
+		{map[string]interface{}{"name": "p", "required_if": "!proxy_enabled"}, true, false},
+		{map[string]interface{}{"name": "p", "required_if": "proxy_enabled", "visible_if": "!proxy_enabled"}, true, false},
+	}
+	for _, test := range tests {
+		mandatory, err := IsPropertyMandatory(test.property, test.parentMandatory, values)
+		if err != nil {
+			t.Errorf("%v: %s", test.property, err.Error())
+			continue
+		}
+		if mandatory != test.expected {
+			t.Errorf("%v with parent mandatory %t: expected %t but got %t", test.property, test.parentMandatory, test.expected, mandatory)
+		}
+	}
+}
+
+func TestEvaluateConditionError(t *testing.T) {
+	conditions := []string{
+		"",
+		"proxy_enabled ==",
+		"(proxy_enabled",
+		"proxy_enabled = true",
+		"proxy_type == 'http",
+		"proxy_enabled true",
+	}
+	for _, condition := range conditions {
+		_, err := EvaluateCondition(condition, map[string]interface{}{})
+		if err == nil {
+			t.Errorf("An error should be raised for '%s'", condition)
+		}
+	}
+}
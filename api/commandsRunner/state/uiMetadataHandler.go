@@ -18,10 +18,12 @@
 package state
 
 import (
+	"errors"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
@@ -37,17 +39,18 @@ func HandleUIMetadata(w http.ResponseWriter, req *http.Request) {
 }
 
 /*
-Retrieve all Status
-URL: /cr/v1/uiconfig/
+Retrieve the ui metadata or its JSON Schema if format=jsonschema
+URL: /cr/v1/uimetadata?extension-name=<extension_name>&ui-metadata-name=<ui_metadata_name>&format=<json|jsonschema>
 Method: GET
 */
 func getUIMetadataEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in getUIMetadataEndpoint")
+	inst := GetInstance(req)
 	langs := i18nUtils.GetLangs(req)
 	extensionName, m, err := global.GetExtensionNameFromRequest(req)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 		return
 	}
 	log.Debug("ExtensionName:" + extensionName)
@@ -57,13 +60,31 @@ func getUIMetadataEndpoint(w http.ResponseWriter, req *http.Request) {
 		log.Debugf("uiMetaDataName:%s", uiMetaDataNameFound)
 		uiMetaDataName = uiMetaDataNameFound[0]
 	}
+	format := "json"
+	formatFound, okFormat := m["format"]
+	if okFormat {
+		log.Debugf("format:%s", formatFound)
+		format = formatFound[0]
+	}
 	//Retrieve the property name
-	uiconfig, err := GetUIMetaDataConfig(extensionName, uiMetaDataName, langs)
+	var uiconfig []byte
+	switch format {
+	case "json":
+		uiconfig, err = inst.GetUIMetaDataConfig(extensionName, uiMetaDataName, langs)
+	case "jsonschema":
+		w.Header().Set("Content-Type", "application/schema+json")
+		uiconfig, err = inst.GetUIMetaDataJSONSchema(extensionName, uiMetaDataName, langs)
+	default:
+		err = errors.New("Unsupported format: " + format)
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
 	if err == nil {
 		//		log.Debug(string(uiconfig))
 		w.Write([]byte(uiconfig))
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
@@ -20,6 +20,7 @@ package state
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
@@ -84,3 +85,37 @@ func TestGetUIConfigEndpointSuccess(t *testing.T) {
 	t.Log(rr.Body)
 	global.RemoveTemp("TestGetUIConfigEndpointSuccess")
 }
+
+func TestGetUIConfigEndpointJSONSchema(t *testing.T) {
+	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
+	extensionPath, err := global.CopyToTemp("TestGetUIConfigEndpointJSONSchema", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestGetUIConfigEndpointJSONSchema")
+	req, err := http.NewRequest("GET", "/cr/v1/uimetadata?extension-name=ext-template&ui-metadata-name=test-ui&format=jsonschema", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(HandleUIMetadata)
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v, %v want %v",
+			status, rr.Body, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), JSONSchemaDraft) {
+		t.Errorf("Expected a JSON Schema but got %s", rr.Body.String())
+	}
+	req, err = http.NewRequest("GET", "/cr/v1/uimetadata?extension-name=ext-template&ui-metadata-name=test-ui&format=xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v, %v want %v",
+			status, rr.Body, http.StatusBadRequest)
+	}
+}
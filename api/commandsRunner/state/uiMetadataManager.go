@@ -27,7 +27,7 @@ import (
 	"github.com/olebedev/config"
 )
 
-func GetUIMetaDataConfig(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+func (inst *Instance) GetUIMetaDataConfig(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
 	log.Debug("Entering in... GetUIMetaDataConfig")
 	log.Debugf("extensionName=%s", extensionName)
 	log.Debugf("uiMetadataName=%s", uiMetadataName)
@@ -35,16 +35,16 @@ func GetUIMetaDataConfig(extensionName string, uiMetadataName string, langs []st
 		uiMetadataName = global.DefaultUIMetaDataName
 	}
 	log.Debugf("uiMetadataName=%s", uiMetadataName)
-	raw, e := getUIMetadataConfig(extensionName, uiMetadataName, langs)
+	raw, e := inst.getUIMetadataConfig(extensionName, uiMetadataName, langs)
 	if e != nil {
 		return nil, e
 	}
 	return raw, nil
 }
 
-func getUIMetadataConfig(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+func (inst *Instance) getUIMetadataConfig(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
 	log.Debug("Entering in... getUIMetadataConfig")
-	cfg, err := getUIMetadataParseConfig(extensionName, uiMetadataName, langs)
+	cfg, err := inst.getUIMetadataParseConfig(extensionName, uiMetadataName, langs)
 	if err == nil {
 		uiConfigFilefg, err := config.ParseYaml("ui_metadata:")
 		if err != nil {
@@ -63,9 +63,9 @@ func getUIMetadataConfig(extensionName string, uiMetadataName string, langs []st
 	return nil, errors.New("No ui configuration available for " + extensionName + " and " + uiMetadataName)
 }
 
-func getUIMetadataParseConfig(extensionName string, uiMetadataName string, langs []string) (cfg *config.Config, err error) {
+func (inst *Instance) getUIMetadataParseConfig(extensionName string, uiMetadataName string, langs []string) (cfg *config.Config, err error) {
 	log.Debug("Entering in... getUIMetadataParseConfig")
-	cfg, err = getUIMetadataParseConfigs(extensionName, langs)
+	cfg, err = inst.getUIMetadataParseConfigs(extensionName, langs)
 	if err != nil {
 		return nil, err
 	}
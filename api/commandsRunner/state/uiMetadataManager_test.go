@@ -55,7 +55,7 @@ func TestGetUIMetadataParseConfigsExtentionTest(t *testing.T) {
 		t.Fatal(err)
 	}
 	SetExtensionsPath(extensionPath)
-	cfg, err := getUIMetadataParseConfigs("ext-template", []string{global.DefaultLanguage})
+	cfg, err := defaultInstance.getUIMetadataParseConfigs("ext-template", []string{global.DefaultLanguage})
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -82,3 +82,70 @@ func TestGetUIConfigError(t *testing.T) {
 		t.Error("An error should be raised as this file doesn't exists")
 	}
 }
+
+func TestGetUIMetaDataJSONSchema(t *testing.T) {
+	SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
+	extensionPath, err := global.CopyToTemp("TestGetUIMetaDataJSONSchema", "../../test/resource/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetExtensionsPath(extensionPath)
+	defer global.RemoveTemp("TestGetUIMetaDataJSONSchema")
+	schema, err := GetUIMetaDataJSONSchema("ext-template", "test-ui", []string{global.DefaultLanguage})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Log(string(schema))
+	cfg, err := config.ParseJson(string(schema))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	draft, err := cfg.String("$schema")
+	if err != nil || draft != JSONSchemaDraft {
+		t.Errorf("Expected $schema %s but got %s", JSONSchemaDraft, draft)
+	}
+	pattern, err := cfg.String("properties.config.properties.servicebroker_port.pattern")
+	if err != nil || pattern == "" {
+		t.Error("Expected a pattern for servicebroker_port")
+	}
+	required, err := cfg.List("properties.config.required")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(required) != 5 {
+		t.Errorf("Expected 5 required properties but found %d", len(required))
+	}
+	arrayType, err := cfg.String("properties.config.properties.deployments_backup.properties.deployments.type")
+	if err != nil || arrayType != "array" {
+		t.Errorf("Expected deployments to be an array but got %s", arrayType)
+	}
+	itemType, err := cfg.String("properties.config.properties.deployments_backup.properties.deployments.items.properties.enabled.type")
+	if err != nil || itemType != "boolean" {
+		t.Errorf("Expected enabled to be a boolean but got %s", itemType)
+	}
+}
+
+func TestGetPropertyJSONSchemaPattern(t *testing.T) {
+	for _, propertyType := range []string{"text", "number", "integer"} {
+		property := map[string]interface{}{
+			"name":             "port",
+			"type":             propertyType,
+			"validation-regex": "^[0-9]+$",
+		}
+		schema, err := getPropertyJSONSchema(property, "port")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_, hasPattern := schema["pattern"]
+		if hasPattern != (propertyType == "text") {
+			t.Errorf("Expected pattern %t for type %s but got %t", propertyType == "text", propertyType, hasPattern)
+		}
+	}
+}
+
+func TestGetUIMetaDataJSONSchemaError(t *testing.T) {
+	_, err := GetUIMetaDataJSONSchema("does-not-exist", "test-ui", []string{global.DefaultLanguage})
+	if err == nil {
+		t.Error("An error should be raised as this extension doesn't exists")
+	}
+}
@@ -0,0 +1,226 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package state
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//JSONSchemaDraft is the JSON Schema dialect generated from the ui metadata
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+//GetUIMetaDataJSONSchema generates a JSON Schema describing the config file of a ui metadata
+func (inst *Instance) GetUIMetaDataJSONSchema(extensionName string, uiMetadataName string, langs []string) ([]byte, error) {
+	log.Debug("Entering in... GetUIMetaDataJSONSchema")
+	log.Debugf("extensionName=%s", extensionName)
+	if uiMetadataName == "" {
+		uiMetadataName = global.DefaultUIMetaDataName
+	}
+	log.Debugf("uiMetadataName=%s", uiMetadataName)
+	cfg, err := inst.getUIMetadataParseConfig(extensionName, uiMetadataName, langs)
+	if err != nil {
+		return nil, errors.New("No ui configuration available for " + extensionName + " and " + uiMetadataName)
+	}
+	groups, err := cfg.List("groups")
+	if err != nil {
+		return nil, err
+	}
+	properties := map[string]interface{}{
+		"configuration_name": map[string]interface{}{
+			"type":  "string",
+			"const": uiMetadataName,
+		},
+	}
+	required := make([]string, 0)
+	for _, group := range groups {
+		groupMap, ok := group.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("Expect a map[string]interface{} under groups")
+		}
+		groupProperties, ok := groupMap["properties"]
+		if !ok {
+			continue
+		}
+		propertiesList, ok := groupProperties.([]interface{})
+		if !ok {
+			return nil, errors.New("Expect a []interface{} under properties")
+		}
+		mandatory := true
+		if val, ok := groupMap["mandatory"]; ok {
+			mandatory, ok = val.(bool)
+			if !ok {
+				return nil, errors.New("Expect a bool under mandatory of the groups")
+			}
+		}
+		groupRequired, err := addJSONSchemaProperties(properties, propertiesList, mandatory, "")
+		if err != nil {
+			return nil, err
+		}
+		required = append(required, groupRequired...)
+	}
+	configSchema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		configSchema["required"] = required
+	}
+	schema := map[string]interface{}{
+		"$schema":    JSONSchemaDraft,
+		"title":      extensionName + " " + uiMetadataName,
+		"type":       "object",
+		"properties": map[string]interface{}{global.ConfigRootKey: configSchema},
+		"required":   []string{global.ConfigRootKey},
+	}
+	if label, err := cfg.String("label"); err == nil {
+		schema["title"] = label
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+//addJSONSchemaProperties adds the schema of each property in schemaProperties and returns the names of the required ones.
+func addJSONSchemaProperties(schemaProperties map[string]interface{}, properties []interface{}, mandatory bool, path string) ([]string, error) {
+	required := make([]string, 0)
+	for _, property := range properties {
+		p, ok := property.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("Expect a map[string]interface{} at path " + path)
+		}
+		name, ok := p["name"].(string)
+		if !ok {
+			return nil, errors.New("Property name missing at path " + path)
+		}
+		propertyPath := name
+		if path != "" {
+			propertyPath = path + "." + name
+		}
+		propertySchema, err := getPropertyJSONSchema(p, propertyPath)
+		if err != nil {
+			return nil, err
+		}
+		schemaProperties[name] = propertySchema
+		propertyMandatory := mandatory
+		if val, ok := p["mandatory"].(bool); ok && !val {
+			propertyMandatory = false
+		}
+		if propertyMandatory {
+			required = append(required, name)
+		}
+	}
+	return required, nil
+}
+
+//getPropertyJSONSchema converts a ui metadata property into its JSON Schema
+func getPropertyJSONSchema(property map[string]interface{}, path string) (map[string]interface{}, error) {
+	schema := make(map[string]interface{})
+	if val, ok := property["label"].(string); ok {
+		schema["title"] = val
+	}
+	if val, ok := property["description"].(string); ok {
+		schema["description"] = val
+	}
+	if val, ok := property["default"]; ok {
+		schema["default"] = val
+	}
+	if val, ok := property["sample_value"]; ok {
+		schema["examples"] = []interface{}{val}
+	}
+	propertyType, _ := property["type"].(string)
+	//Nested properties are either an object or an array of objects
+	if val, ok := property["properties"]; ok {
+		nestedProperties, ok := val.([]interface{})
+		if !ok {
+			return nil, errors.New("Expect an []interface{} at path: " + path)
+		}
+		objectProperties := make(map[string]interface{})
+		required, err := addJSONSchemaProperties(objectProperties, nestedProperties, true, path)
+		if err != nil {
+			return nil, err
+		}
+		objectSchema := map[string]interface{}{
+			"type":       "object",
+			"properties": objectProperties,
+		}
+		if len(required) > 0 {
+			objectSchema["required"] = required
+		}
+		if propertyType == "array" {
+			schema["type"] = "array"
+			schema["items"] = objectSchema
+		} else {
+			for key, value := range objectSchema {
+				schema[key] = value
+			}
+		}
+		return schema, nil
+	}
+	switch propertyType {
+	case "", "text", "textarea", "password", "radio", "select":
+		schema["type"] = "string"
+		if propertyType == "password" {
+			schema["writeOnly"] = true
+		}
+	case "checkbox", "boolean":
+		schema["type"] = "boolean"
+	case "number":
+		schema["type"] = "number"
+	case "integer":
+		schema["type"] = "integer"
+	case "array":
+		schema["type"] = "array"
+	default:
+		log.Debugf("Unknown type %s at path %s, no type constraint generated", propertyType, path)
+	}
+	//Both spellings of the validation regex are used in the manifests, a pattern only applies to strings
+	if schemaType, ok := schema["type"]; !ok || schemaType == "string" {
+		if val, ok := property["validation_regex"].(string); ok {
+			schema["pattern"] = val
+		} else if val, ok := property["validation-regex"].(string); ok {
+			schema["pattern"] = val
+		}
+	}
+	if val, ok := property["items"]; ok {
+		items, ok := val.([]interface{})
+		if !ok {
+			return nil, errors.New("Expect an []interface{} under items at path: " + path)
+		}
+		enum := make([]interface{}, 0)
+		for _, item := range items {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("Expect a map[string]interface{} under items at path: " + path)
+			}
+			if value, ok := itemMap["value"]; ok {
+				enum = append(enum, value)
+			} else if label, ok := itemMap["label"]; ok {
+				enum = append(enum, label)
+			}
+		}
+		if propertyType == "array" {
+			schema["items"] = map[string]interface{}{"enum": enum}
+		} else {
+			schema["enum"] = enum
+		}
+	}
+	return schema, nil
+}
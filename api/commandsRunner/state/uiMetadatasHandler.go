@@ -22,6 +22,7 @@ import (
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 	"github.com/IBM/commands-runner/api/i18n/i18nUtils"
@@ -43,12 +44,13 @@ Method: GET
 */
 func getUIMetadatasEndpoint(w http.ResponseWriter, req *http.Request) {
 	log.Debug("Entering in getUIMetadatasEndpoint")
+	inst := GetInstance(req)
 	langs := i18nUtils.GetLangs(req)
 	extensionName, m, err := global.GetExtensionNameFromRequest(req)
 	// Don't test because if extension_name not present we will return all configuration names for all extensions.
 	// if err != nil {
 	// 	logger.AddCallerField().Error(err.Error())
-	// 	http.Error(w, err.Error(), http.StatusNotFound)
+	// 	apiError.HTTPError(w, req, err, http.StatusNotFound)
 	// 	return
 	// }
 	log.Debug("ExtensionName:" + extensionName)
@@ -60,16 +62,16 @@ func getUIMetadatasEndpoint(w http.ResponseWriter, req *http.Request) {
 		namesOnly, err = strconv.ParseBool(namesOnlyFound[0])
 		if err != nil {
 			logger.AddCallerField().Error(err.Error())
-			http.Error(w, err.Error(), http.StatusNotFound)
+			apiError.HTTPError(w, req, err, http.StatusNotFound)
 			return
 		}
 	}
 	//Retrieve the property name
-	config, err := GetUIMetaDataConfigs(extensionName, namesOnly, langs)
+	config, err := inst.GetUIMetaDataConfigs(extensionName, namesOnly, langs)
 	if err == nil {
 		w.Write(config)
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
@@ -31,15 +31,15 @@ import (
 	"github.com/olebedev/config"
 )
 
-func GetUIMetaDataConfigs(extensionName string, namesOnly bool, langs []string) ([]byte, error) {
+func (inst *Instance) GetUIMetaDataConfigs(extensionName string, namesOnly bool, langs []string) ([]byte, error) {
 	log.Debug("Entering in... GetUIMetaDataConfig")
 	log.Debugf("extensionName=%s", extensionName)
 	var raw []byte
 	var e error
 	if extensionName == "" {
-		raw, e = getUIMetadataAllConfigs(namesOnly, langs)
+		raw, e = inst.getUIMetadataAllConfigs(namesOnly, langs)
 	} else {
-		raw, e = getUIMetadataConfigs(extensionName, namesOnly, langs)
+		raw, e = inst.getUIMetadataConfigs(extensionName, namesOnly, langs)
 	}
 	if e != nil {
 		return nil, e
@@ -47,13 +47,13 @@ func GetUIMetaDataConfigs(extensionName string, namesOnly bool, langs []string)
 	return raw, nil
 }
 
-func getUIMetadataAllConfigs(namesOnly bool, langs []string) ([]byte, error) {
+func (inst *Instance) getUIMetadataAllConfigs(namesOnly bool, langs []string) ([]byte, error) {
 	log.Debug("Entering in... getUIMetadataAllConfigs")
 	uiConfigFileCfg, errUIConfigFileCfg := config.ParseYaml("ui_metadata:")
 	if errUIConfigFileCfg != nil {
 		return nil, errUIConfigFileCfg
 	}
-	cfg, err := getUIMetadataAllConfigsCFg(namesOnly, langs)
+	cfg, err := inst.getUIMetadataAllConfigsCFg(namesOnly, langs)
 	if err == nil {
 		errUIConfigFileCfg = config.Set(uiConfigFileCfg.Root, "ui_metadata", cfg.Root)
 		if errUIConfigFileCfg != nil {
@@ -68,13 +68,13 @@ func getUIMetadataAllConfigs(namesOnly bool, langs []string) ([]byte, error) {
 	return nil, err
 }
 
-func getUIMetadataConfigs(extensionName string, namesOnly bool, langs []string) ([]byte, error) {
+func (inst *Instance) getUIMetadataConfigs(extensionName string, namesOnly bool, langs []string) ([]byte, error) {
 	log.Debug("Entering in... getUIMetadataConfigs")
 	uiConfigFileCfg, errUIConfigFileCfg := config.ParseYaml("ui_metadata:")
 	if errUIConfigFileCfg != nil {
 		return nil, errUIConfigFileCfg
 	}
-	cfg, err := getUIMetadataConfigsCfg(extensionName, namesOnly, langs)
+	cfg, err := inst.getUIMetadataConfigsCfg(extensionName, namesOnly, langs)
 	if err == nil && cfg != nil {
 		err = uiConfigFileCfg.Set("ui_metadata", cfg.Root)
 		if err != nil {
@@ -92,19 +92,19 @@ func getUIMetadataConfigs(extensionName string, namesOnly bool, langs []string)
 	return nil, err
 }
 
-func getUIMetadataAllConfigsCFg(namesOnly bool, langs []string) (*config.Config, error) {
+func (inst *Instance) getUIMetadataAllConfigsCFg(namesOnly bool, langs []string) (*config.Config, error) {
 	log.Debug("Entering in... getUIMetadataAllConfigsCFg")
 	uiConfigFileCfg, errUIConfigFileCfg := config.ParseYaml("dummy:")
 	if errUIConfigFileCfg != nil {
 		return nil, errUIConfigFileCfg
 	}
-	extensions, err := ListExtensions("", false)
+	extensions, err := inst.ListExtensions("", false)
 	if err != nil {
 		return nil, err
 	}
 	for key := range extensions.Extensions {
 		log.Debug("extension-name:" + key)
-		cfg, err := getUIMetadataConfigsCfg(key, namesOnly, langs)
+		cfg, err := inst.getUIMetadataConfigsCfg(key, namesOnly, langs)
 		log.Debug(cfg)
 		if err != nil {
 			if cfg == nil {
@@ -131,9 +131,9 @@ func getUIMetadataAllConfigsCFg(namesOnly bool, langs []string) (*config.Config,
 	return uiConfigFileCfg.Get("ui_metadata")
 }
 
-func getUIMetadataConfigsCfg(extensionName string, namesOnly bool, langs []string) (*config.Config, error) {
+func (inst *Instance) getUIMetadataConfigsCfg(extensionName string, namesOnly bool, langs []string) (*config.Config, error) {
 	log.Debug("Entering in... getUIMetadataConfigsCfg")
-	cfg, err := getUIMetadataParseConfigs(extensionName, langs)
+	cfg, err := inst.getUIMetadataParseConfigs(extensionName, langs)
 	log.Debug("")
 	if err == nil {
 		log.Debug("No ERROR")
@@ -150,12 +150,12 @@ func getUIMetadataConfigsCfg(extensionName string, namesOnly bool, langs []strin
 	return cfg, err
 }
 
-func getUIMetadataParseConfigs(extensionName string, langs []string) (cfg *config.Config, err error) {
+func (inst *Instance) getUIMetadataParseConfigs(extensionName string, langs []string) (cfg *config.Config, err error) {
 	log.Debug("Entering in... getUIMetadataParseConfigs")
-	rootPath := GetExtensionsPathCustom()
-	embeddedExtension, _ := IsEmbeddedExtension(extensionName)
+	rootPath := inst.GetExtensionsPathCustom()
+	embeddedExtension, _ := inst.IsEmbeddedExtension(extensionName)
 	if embeddedExtension {
-		rootPath = GetExtensionsPathEmbedded()
+		rootPath = inst.GetExtensionsPathEmbedded()
 	}
 
 	cfg, _, err = getUIMetadataTranslatedAndWrite(filepath.Join(rootPath, extensionName), langs)
@@ -403,8 +403,8 @@ func getUIMetadataNameOnly(cfg *config.Config) (*config.Config, error) {
 	}
 	var names []interface{}
 	for key, elem := range configs {
-		var name map[string]string
-		name = make(map[string]string, 0)
+		var name map[string]interface{}
+		name = make(map[string]interface{}, 0)
 		name["id"] = key
 		log.Info(elem)
 		if label, ok := (elem.(map[string]interface{}))["label"]; ok {
@@ -412,6 +412,14 @@ func getUIMetadataNameOnly(cfg *config.Config) (*config.Config, error) {
 		} else {
 			name["label"] = key
 		}
+		//Expose the conditional properties so the UI can fetch the values they depend on
+		conditions, err := getUIMetadataConditions(elem.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		if len(conditions) > 0 {
+			name["conditions"] = conditions
+		}
 		names = append(names, name)
 	}
 	errUIConfigFileCfg = uiConfigFileCfg.Set("ui_metadata", names)
@@ -420,3 +428,52 @@ func getUIMetadataNameOnly(cfg *config.Config) (*config.Config, error) {
 	}
 	return uiConfigFileCfg.Get("ui_metadata")
 }
+
+//getUIMetadataConditions returns the list of properties having a visible_if or required_if condition
+func getUIMetadataConditions(uiMetadata map[string]interface{}) ([]interface{}, error) {
+	log.Debug("Entering in... getUIMetadataConditions")
+	conditions := make([]interface{}, 0)
+	groups, ok := uiMetadata["groups"].([]interface{})
+	if !ok {
+		return conditions, nil
+	}
+	for _, group := range groups {
+		groupMap, ok := group.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("Expect a map[string]interface{} under groups")
+		}
+		if properties, ok := groupMap["properties"]; ok {
+			propertiesList, ok := properties.([]interface{})
+			if !ok {
+				return nil, errors.New("Expect a []interface{} under properties")
+			}
+			err := traverseProperties(propertiesList, true, true, nil, nil, conditionsCallBack(), "", &conditions)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return conditions, nil
+}
+
+func conditionsCallBack() TraversePropertiesCallBack {
+	return TraversePropertiesCallBack(func(property map[string]interface{}, first bool, mandatory bool, parentProperty map[string]interface{}, path string, input interface{}) (err error) {
+		conditions := input.(*[]interface{})
+		condition := make(map[string]interface{})
+		for _, key := range []string{VisibleIfKey, RequiredIfKey} {
+			if val, ok := property[key]; ok {
+				condition[key] = val
+			}
+		}
+		if len(condition) == 0 {
+			return nil
+		}
+		name := property["name"].(string)
+		if path != "" {
+			name = path + "." + name
+		}
+		condition["name"] = name
+		*conditions = append(*conditions, condition)
+		return nil
+	})
+}
@@ -25,6 +25,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/logger"
 )
 
@@ -56,7 +57,7 @@ func getStatusesEndpoint(w http.ResponseWriter, req *http.Request) {
 		json.NewEncoder(w).Encode(statuses)
 	} else {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
 
@@ -87,6 +88,6 @@ func setStatusesEndpoint(w http.ResponseWriter, req *http.Request) {
 	err := SetStatus(statusName, status)
 	if err != nil {
 		logger.AddCallerField().Error(err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
 	}
 }
@@ -20,7 +20,9 @@ package status
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -118,3 +120,30 @@ func TestGetStatusesEndpointWrongMethod(t *testing.T) {
 	}
 
 }
+
+func TestStatusesConcurrentAccess(t *testing.T) {
+	t.Log("Entering................. TestStatusesConcurrentAccess")
+	defer func() {
+		statusesMux.Lock()
+		defer statusesMux.Unlock()
+		for i := 0; i < 10; i++ {
+			delete(statuses, "test_status_"+strconv.Itoa(i))
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SetStatus("test_status_"+strconv.Itoa(i), strconv.Itoa(j))
+				GetStatuses()
+			}
+		}(i)
+	}
+	wg.Wait()
+	s, ok := GetStatus("test_status_0")
+	if !ok || s.Status != "99" {
+		t.Errorf("Unexpected status %+v", s)
+	}
+}
@@ -18,6 +18,8 @@
 package status
 
 import (
+	"sync"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -41,26 +43,40 @@ const CMStatus = "cr_status"
 
 var statuses Statuses
 
+//statusesMux protects statuses which is written from multiple goroutines
+var statusesMux = &sync.RWMutex{}
+
 //Initialize the properties map
 func init() {
 	statuses = make(Statuses)
 	setInceptionStatus("Initialization")
 }
 
-//Retrieve all statuses
+//Retrieve all statuses, the returned map is a copy
 func GetStatuses() (*Statuses, error) {
-	s, _ := getInceptionStatus()
-	statuses[s.Name] = *s
-	s, _ = getLogLevel()
-	statuses[s.Name] = *s
-	return &statuses, nil
+	s, _ := getLogLevel()
+	SetStatus(s.Name, s.Status)
+	statusesMux.RLock()
+	defer statusesMux.RUnlock()
+	statusesCopy := make(Statuses, len(statuses))
+	for name, status := range statuses {
+		statusesCopy[name] = status
+	}
+	return &statusesCopy, nil
+}
+
+//GetStatus returns a status
+func GetStatus(name string) (*Status, bool) {
+	statusesMux.RLock()
+	defer statusesMux.RUnlock()
+	s, ok := statuses[name]
+	return &s, ok
 }
 
 //Retrieve the inception status
 func getInceptionStatus() (*Status, error) {
-	var s Status
-	s = statuses[CMStatus]
-	return &s, nil
+	s, _ := GetStatus(CMStatus)
+	return s, nil
 }
 
 //Set the inception status
@@ -74,6 +90,8 @@ func SetStatus(name string, status string) error {
 	var s Status
 	s.Name = name
 	s.Status = status
+	statusesMux.Lock()
+	defer statusesMux.Unlock()
 	statuses[s.Name] = s
 	return nil
 }
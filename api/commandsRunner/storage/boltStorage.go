@@ -0,0 +1,255 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+var statesBucket = []byte("states")
+var configBucket = []byte("config")
+var runsBucket = []byte("runs")
+
+//BoltStorage stores the states and the config documents in an embedded bolt database, keyed by their file path.
+//Each document is stored with its version, a sequence incremented at each write, and is updated in a transaction.
+//The run history of an extension is a bucket of runs keyed by a sequence, the latest runs are read first without reading the others.
+//The database is locked exclusively by the process which opens it, even to read: the other processes wait up to
+//global.FileLockTimeout to open it then fail. While the server runs, the states and the config are accessed through its api.
+//A document not yet in the database is read from its file, ie: the states file delivered with an extension.
+type BoltStorage struct {
+	db    *bolt.DB
+	files FileStorage
+}
+
+//OpenBolt opens the bolt database, it is created if it doesn't exist.
+func OpenBolt(path string) (*BoltStorage, error) {
+	log.Debug("Entering in... OpenBolt")
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: global.FileLockTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, errors.New("Unable to open the database " + path + ", it is opened by another process (ie: the server), use the api or stop that process")
+	}
+	if err != nil {
+		return nil, errors.New("Unable to open the database " + path + " " + err.Error())
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{statesBucket, configBucket, runsBucket} {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStorage{db: db}, nil
+}
+
+//Close closes the database
+func (bs *BoltStorage) Close() error {
+	return bs.db.Close()
+}
+
+//get returns a copy of a document, nil if it is not in the database.
+func (bs *BoltStorage) get(bucket []byte, path string) ([]byte, error) {
+	var data []byte
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucket).Get([]byte(path))
+		if value != nil {
+			data = append(make([]byte, 0, len(value)-8), value[8:]...)
+		}
+		return nil
+	})
+	return data, err
+}
+
+//version returns the version of a document, false if it is not in the database.
+func (bs *BoltStorage) version(bucket []byte, path string) (uint64, bool, error) {
+	var version uint64
+	found := false
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucket).Get([]byte(path))
+		if value != nil {
+			version = binary.BigEndian.Uint64(value[:8])
+			found = true
+		}
+		return nil
+	})
+	return version, found, err
+}
+
+//put writes a document with a new version
+func (bs *BoltStorage) put(bucket []byte, path string, data []byte) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		version, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		value := make([]byte, 8, 8+len(data))
+		binary.BigEndian.PutUint64(value, version)
+		return b.Put([]byte(path), append(value, data...))
+	})
+}
+
+//delete removes a document
+func (bs *BoltStorage) delete(bucket []byte, path string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(path))
+	})
+}
+
+//ReadStates reads the states from the database or from the states file if they are not in the database yet
+func (bs *BoltStorage) ReadStates(path string) ([]byte, error) {
+	data, err := bs.get(statesBucket, path)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return bs.files.ReadStates(path)
+	}
+	return data, nil
+}
+
+//WriteStates writes the states in the database
+func (bs *BoltStorage) WriteStates(path string, data []byte) error {
+	return bs.put(statesBucket, path, data)
+}
+
+//DeleteStates removes the states from the database
+func (bs *BoltStorage) DeleteStates(path string) error {
+	return bs.delete(statesBucket, path)
+}
+
+//LockStates does nothing, the database is locked by the process and the writes are serialized by bolt
+func (bs *BoltStorage) LockStates(path string) (func(), error) {
+	return func() {}, nil
+}
+
+//StatesVersion returns the version of the states in the database or the version of the states file if they are not in the database yet
+func (bs *BoltStorage) StatesVersion(path string) (string, error) {
+	version, found, err := bs.version(statesBucket, path)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		fileVersion, err := bs.files.StatesVersion(path)
+		if err != nil {
+			return "", err
+		}
+		return "file-" + fileVersion, nil
+	}
+	return strconv.FormatUint(version, 10), nil
+}
+
+//ReadConfig reads the config from the database or from the config file if it is not in the database yet
+func (bs *BoltStorage) ReadConfig(path string) ([]byte, error) {
+	data, err := bs.get(configBucket, path)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return bs.files.ReadConfig(path)
+	}
+	return data, nil
+}
+
+//WriteConfig writes the config in the database
+func (bs *BoltStorage) WriteConfig(path string, data []byte) error {
+	return bs.put(configBucket, path, data)
+}
+
+//DeleteConfig removes the config from the database
+func (bs *BoltStorage) DeleteConfig(path string) error {
+	return bs.delete(configBucket, path)
+}
+
+//LockConfig does nothing, the database is locked by the process and the writes are serialized by bolt
+func (bs *BoltStorage) LockConfig(path string) (func(), error) {
+	return func() {}, nil
+}
+
+//AddRun appends a run in the run history bucket of the extension
+func (bs *BoltStorage) AddRun(path string, run state.Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(runsBucket).CreateBucketIfNotExists([]byte(path))
+		if err != nil {
+			return err
+		}
+		sequence, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, sequence)
+		return b.Put(key, data)
+	})
+}
+
+//ListRuns reads the run history bucket of the extension from the most recent run and stops at limit runs
+func (bs *BoltStorage) ListRuns(path string, status string, limit int) ([]state.Run, error) {
+	runs := make([]state.Run, 0)
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(runsBucket).Bucket([]byte(path))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for key, value := c.Last(); key != nil && (limit <= 0 || len(runs) < limit); key, value = c.Prev() {
+			var run state.Run
+			err := json.Unmarshal(value, &run)
+			if err != nil {
+				return err
+			}
+			if status == "" || run.Status == status {
+				runs = append(runs, run)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+//DeleteRuns removes the run history bucket of the extension
+func (bs *BoltStorage) DeleteRuns(path string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(runsBucket).DeleteBucket([]byte(path))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
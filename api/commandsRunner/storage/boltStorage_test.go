@@ -0,0 +1,229 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/properties"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+func openTestBolt(t *testing.T) (*BoltStorage, string) {
+	dir, err := ioutil.TempDir("", "bolt-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := OpenBolt(filepath.Join(dir, BoltFileName))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return bs, dir
+}
+
+func TestBoltStorageStates(t *testing.T) {
+	t.Log("Entering................. TestBoltStorageStates")
+	bs, dir := openTestBolt(t)
+	defer os.RemoveAll(dir)
+	defer bs.Close()
+	statesPath := filepath.Join(dir, "ext1", global.StatesFileName)
+	_, err := bs.ReadStates(statesPath)
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error got %v", err)
+	}
+	_, err = bs.StatesVersion(statesPath)
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error got %v", err)
+	}
+	//A document not in the database is read from its file
+	err = os.MkdirAll(filepath.Dir(statesPath), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(statesPath, []byte("states: []\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bs.ReadStates(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "states: []\n" {
+		t.Errorf("Expected the states file content got %s", string(data))
+	}
+	fileVersion, err := bs.StatesVersion(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bs.WriteStates(statesPath, []byte("states:\n- name: state1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = bs.ReadStates(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "states:\n- name: state1\n" {
+		t.Errorf("Expected the written states got %s", string(data))
+	}
+	version1, err := bs.StatesVersion(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bs.WriteStates(statesPath, []byte("states: []\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	version2, err := bs.StatesVersion(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileVersion == version1 || version1 == version2 {
+		t.Errorf("Expected a new version at each write got %s, %s, %s", fileVersion, version1, version2)
+	}
+	//The config is stored apart from the states
+	_, err = bs.ReadConfig(statesPath + ".config")
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error got %v", err)
+	}
+	err = bs.DeleteStates(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = bs.ReadStates(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "states: []\n" {
+		t.Errorf("Expected the states file content after delete got %s", string(data))
+	}
+}
+
+func TestBoltStorageRuns(t *testing.T) {
+	t.Log("Entering................. TestBoltStorageRuns")
+	bs, dir := openTestBolt(t)
+	defer os.RemoveAll(dir)
+	defer bs.Close()
+	statesPath := filepath.Join(dir, "ext1", global.StatesFileName)
+	runs, err := bs.ListRuns(statesPath, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("Expected no run got %d", len(runs))
+	}
+	for i, status := range []string{state.StateSUCCEEDED, state.StateFAILED, state.StateSUCCEEDED} {
+		err = bs.AddRun(statesPath, state.Run{ID: "run" + strconv.Itoa(i), ExecutionID: i, Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err = bs.ListRuns(statesPath, state.StateSUCCEEDED, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].ID != "run2" {
+		t.Errorf("Expected the most recent succeeded run got %v", runs)
+	}
+	runs, err = bs.ListRuns(statesPath, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[0].ID != "run2" || runs[2].ID != "run0" {
+		t.Errorf("Expected all runs most recent first got %v", runs)
+	}
+	err = bs.DeleteRuns(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err = bs.ListRuns(statesPath, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("Expected no run after the deletion got %d", len(runs))
+	}
+	//Deleting twice is not an error
+	err = bs.DeleteRuns(statesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBoltStorageLocked(t *testing.T) {
+	t.Log("Entering................. TestBoltStorageLocked")
+	bs, dir := openTestBolt(t)
+	defer os.RemoveAll(dir)
+	defer bs.Close()
+	timeout := global.FileLockTimeout
+	global.FileLockTimeout = 100 * time.Millisecond
+	defer func() { global.FileLockTimeout = timeout }()
+	_, err := Open(BoltBackend, filepath.Join(dir, BoltFileName))
+	if err == nil {
+		t.Error("Expected an error as the database is already opened")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Log("Entering................. TestMigrate")
+	extensionPath, err := global.CopyToTemp("TestMigrate", "../../test/data/extensions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer global.RemoveTemp("TestMigrate")
+	state.SetExtensionsPath(extensionPath)
+	state.SetExtensionsEmbeddedFile("../../test/resource/extensions/test-extensions.yml")
+	bs, dir := openTestBolt(t)
+	defer os.RemoveAll(dir)
+	defer bs.Close()
+	migrated, err := Migrate(state.DefaultInstance(), FileStorage{}, bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) == 0 {
+		t.Fatal("Expected migrated extensions")
+	}
+	for _, name := range migrated {
+		statesPath := filepath.Join(state.GetRootExtensionPath(state.GetExtensionsPath(), name), global.StatesFileName)
+		configPath := properties.GetConfigFilePath(state.DefaultInstance(), name)
+		paths := map[string][]byte{
+			statesPath: statesBucket,
+			configPath: configBucket,
+		}
+		for path, bucket := range paths {
+			fileData, err := ioutil.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			data, err := bs.get(bucket, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(fileData) {
+				t.Errorf("%s of %s not migrated", string(bucket), name)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package storage
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/properties"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+//FileBackend stores the states and the config in the files of the extensions directory (default)
+const FileBackend = "file"
+
+//BoltBackend stores the states and the config in an embedded bolt database
+const BoltBackend = "bolt"
+
+//BoltFileName default file name of the bolt database in the config directory
+const BoltFileName = "commands-runner.db"
+
+//Storage stores the states, the run history and the config of the extensions
+type Storage interface {
+	state.StatesStorage
+	state.RunsStorage
+	properties.ConfigStorage
+	//Close releases the storage
+	Close() error
+}
+
+//FileStorage stores the states, the run history and the config in their files
+type FileStorage struct {
+	state.FileStatesStorage
+	properties.FileConfigStorage
+}
+
+//Close does nothing, the files are closed after each access
+func (FileStorage) Close() error {
+	return nil
+}
+
+//Open opens a storage backend, path is the database of the bolt backend
+func Open(backend string, path string) (Storage, error) {
+	log.Debug("Entering in... storage.Open")
+	switch backend {
+	case "", FileBackend:
+		return FileStorage{}, nil
+	case BoltBackend:
+		return OpenBolt(path)
+	}
+	return nil, errors.New("Unknown storage backend: " + backend + " (" + FileBackend + "," + BoltBackend + ")")
+}
+
+//SetStorage sets the storage of the states, of the run history and of the config of an instance
+func SetStorage(inst *state.Instance, storage Storage) {
+	inst.SetStatesStorage(storage)
+	inst.SetRunsStorage(storage)
+	inst.SetConfigStorage(storage)
+}
+
+//Migrate copies the states, the run history and the config of the extensions registered in an instance from a storage to another.
+//It returns the extensions having states, runs or config migrated.
+func Migrate(inst *state.Instance, from Storage, to Storage) ([]string, error) {
+	log.Debug("Entering in... storage.Migrate")
+	migratedStates, err := inst.MigrateStates(from, to)
+	if err != nil {
+		return nil, err
+	}
+	migratedRuns, err := inst.MigrateRuns(from, to)
+	if err != nil {
+		return nil, err
+	}
+	migratedConfig, err := properties.MigrateConfig(inst, from, to)
+	if err != nil {
+		return nil, err
+	}
+	migrated := append(make([]string, 0), migratedStates...)
+	for _, name := range append(migratedRuns, migratedConfig...) {
+		found := false
+		for _, migratedName := range migrated {
+			if migratedName == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			migrated = append(migrated, name)
+		}
+	}
+	return migrated, nil
+}
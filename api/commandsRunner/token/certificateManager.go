@@ -0,0 +1,88 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package token
+
+import (
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+)
+
+//CertificateRole maps a client certificate subject to a role
+type CertificateRole struct {
+	//Subject the certificate subject (ie: "CN=ci,O=acme") or only its common name (ie: "ci")
+	Subject string `yaml:"subject" json:"subject"`
+	Role    string `yaml:"role" json:"role"`
+	//Extensions if not empty the certificate can only be used for these extensions
+	Extensions []string `yaml:"extensions,omitempty" json:"extensions,omitempty"`
+}
+
+var certificateRoles = make([]CertificateRole, 0)
+
+//SetCertificateRoles sets the mapping between the client certificate subjects and the roles
+func SetCertificateRoles(roles []CertificateRole) error {
+	for _, r := range roles {
+		if r.Subject == "" {
+			return errors.New("Client certificate subject missing")
+		}
+		if !IsValidRole(r.Role) {
+			return errors.New("Invalid role: " + r.Role + " for client certificate " + r.Subject)
+		}
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	certificateRoles = roles
+	return nil
+}
+
+//LoadCertificateRoles reads the client_cert_roles attribute of the commands-runner.yml content
+func LoadCertificateRoles(raw []byte) error {
+	log.Debug("Entering in... LoadCertificateRoles")
+	var cfg struct {
+		ClientCertRoles []CertificateRole `yaml:"client_cert_roles"`
+	}
+	err := yaml.Unmarshal(raw, &cfg)
+	if err != nil {
+		return err
+	}
+	return SetCertificateRoles(cfg.ClientCertRoles)
+}
+
+//AuthenticateCertificate returns the role mapped to a verified client certificate
+func AuthenticateCertificate(cert *x509.Certificate) (*Token, error) {
+	log.Debug("Entering in... AuthenticateCertificate")
+	if cert == nil {
+		return nil, errors.New("No client certificate")
+	}
+	subject := cert.Subject.String()
+	mux.Lock()
+	defer mux.Unlock()
+	for _, r := range certificateRoles {
+		if strings.EqualFold(r.Subject, subject) || r.Subject == cert.Subject.CommonName {
+			return &Token{
+				Name:       "cert:" + subject,
+				Role:       r.Role,
+				Extensions: r.Extensions,
+			}, nil
+		}
+	}
+	return nil, errors.New("No role mapped to the client certificate " + subject)
+}
@@ -0,0 +1,72 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package token
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticateCertificate(t *testing.T) {
+	t.Log("Entering... TestAuthenticateCertificate")
+	raw := []byte(`port: "30101"
+client_cert_roles:
+- subject: ci
+  role: operator
+  extensions:
+  - ext1
+- subject: CN=admin,O=acme
+  role: admin
+`)
+	err := LoadCertificateRoles(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetCertificateRoles(nil)
+	ci := &x509.Certificate{Subject: pkix.Name{CommonName: "ci", Organization: []string{"other"}}}
+	token, err := AuthenticateCertificate(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Role != RoleOperator {
+		t.Error("Expected operator, got " + token.Role)
+	}
+	req := httptest.NewRequest("PUT", "/cr/v1/engine?action=start&extension-name=ext2", nil)
+	if token.Authorize(req) == nil {
+		t.Error("Expected the certificate to be restricted to ext1")
+	}
+	admin := &x509.Certificate{Subject: pkix.Name{CommonName: "admin", Organization: []string{"acme"}}}
+	token, err = AuthenticateCertificate(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Role != RoleAdmin {
+		t.Error("Expected admin, got " + token.Role)
+	}
+	unknown := &x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}}
+	_, err = AuthenticateCertificate(unknown)
+	if err == nil {
+		t.Error("Expected an error for an unmapped certificate")
+	}
+	err = SetCertificateRoles([]CertificateRole{{Subject: "ci", Role: "root"}})
+	if err == nil {
+		t.Error("Expected an error for an invalid role")
+	}
+}
@@ -0,0 +1,103 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package token
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+)
+
+//PeerRole maps a local user connected through the unix socket to a role
+type PeerRole struct {
+	//User the user name or the uid of the peer process
+	User string `yaml:"user" json:"user"`
+	Role string `yaml:"role" json:"role"`
+	//Extensions if not empty the user can only manage these extensions
+	Extensions []string `yaml:"extensions,omitempty" json:"extensions,omitempty"`
+}
+
+var peerRoles = make([]PeerRole, 0)
+
+//SetPeerRoles sets the mapping between the unix socket peer users and the roles
+func SetPeerRoles(roles []PeerRole) error {
+	for _, r := range roles {
+		if r.User == "" {
+			return errors.New("Unix socket peer user missing")
+		}
+		if !IsValidRole(r.Role) {
+			return errors.New("Invalid role: " + r.Role + " for unix socket peer user " + r.User)
+		}
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	peerRoles = roles
+	return nil
+}
+
+//LoadPeerRoles reads the unix_socket_peer_roles attribute of the commands-runner.yml content
+func LoadPeerRoles(raw []byte) error {
+	log.Debug("Entering in... LoadPeerRoles")
+	var cfg struct {
+		UnixSocketPeerRoles []PeerRole `yaml:"unix_socket_peer_roles"`
+	}
+	err := yaml.Unmarshal(raw, &cfg)
+	if err != nil {
+		return err
+	}
+	return SetPeerRoles(cfg.UnixSocketPeerRoles)
+}
+
+//AuthenticatePeer returns the role of a process connected through the unix socket, uid is -1 if the peer credentials are not available.
+//If no peer role is defined the access is only controlled by the socket permissions and the peer gets the admin role.
+func AuthenticatePeer(uid int) (*Token, error) {
+	log.Debug("Entering in... AuthenticatePeer")
+	mux.Lock()
+	defer mux.Unlock()
+	uidStr := strconv.Itoa(uid)
+	if len(peerRoles) == 0 {
+		return &Token{
+			Name: "unix:" + uidStr,
+			Role: RoleAdmin,
+		}, nil
+	}
+	if uid < 0 {
+		return nil, errors.New("Unix socket peer credentials not available")
+	}
+	userName := ""
+	if u, err := user.LookupId(uidStr); err == nil {
+		userName = u.Username
+	}
+	for _, r := range peerRoles {
+		if r.User == uidStr || (userName != "" && r.User == userName) {
+			name := uidStr
+			if userName != "" {
+				name = userName
+			}
+			return &Token{
+				Name:       "unix:" + name,
+				Role:       r.Role,
+				Extensions: r.Extensions,
+			}, nil
+		}
+	}
+	return nil, errors.New("No role mapped to the unix socket peer uid " + uidStr)
+}
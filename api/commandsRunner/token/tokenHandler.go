@@ -0,0 +1,177 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package token
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+)
+
+//HandleToken handles token rest api requests
+func (s *Store) HandleToken(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleToken")
+	switch req.Method {
+	case "POST":
+		m, _ := url.ParseQuery(req.URL.RawQuery)
+		if actionFound, okAction := m["action"]; okAction && actionFound[0] == "rotate" {
+			s.rotateTokenEndpoint(w, req)
+		} else {
+			s.createTokenEndpoint(w, req)
+		}
+	case "DELETE":
+		s.revokeTokenEndpoint(w, req)
+	default:
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+	}
+}
+
+//HandleTokens handles tokens rest api requests
+func (s *Store) HandleTokens(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleTokens")
+	switch req.Method {
+	case "GET":
+		s.listTokensEndpoint(w, req)
+	default:
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+	}
+}
+
+/*
+Create a token, the response contains the token value which can not be retrieved afterward.
+expires-in is a duration (ie: 720h) after which the token is rejected.
+URL: /cr/v1/token?name=<name>&role=<viewer|operator|admin>[&extensions=<ext1,ext2>][&expires-in=<duration>]
+Method: POST
+*/
+func (s *Store) createTokenEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... createTokenEndpoint")
+	m, _ := url.ParseQuery(req.URL.RawQuery)
+	name := ""
+	nameFound, okName := m["name"]
+	if okName {
+		name = nameFound[0]
+	}
+	role := ""
+	roleFound, okRole := m["role"]
+	if okRole {
+		role = roleFound[0]
+	}
+	var extensions []string
+	extensionsFound, okExtensions := m["extensions"]
+	if okExtensions && extensionsFound[0] != "" {
+		extensions = strings.Split(extensionsFound[0], ",")
+	}
+	expiresIn, err := getDurationParam(m, "expires-in", 0)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	t, err := s.CreateToken(name, role, extensions, expiresIn)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+/*
+Rotate a token, the response contains the new token value, the previous value stays valid during the overlap (default 1h).
+URL: /cr/v1/token?action=rotate&name=<name>[&overlap=<duration>][&expires-in=<duration>]
+Method: POST
+*/
+func (s *Store) rotateTokenEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... rotateTokenEndpoint")
+	m, _ := url.ParseQuery(req.URL.RawQuery)
+	name := ""
+	nameFound, okName := m["name"]
+	if okName {
+		name = nameFound[0]
+	}
+	overlap, err := getDurationParam(m, "overlap", DefaultRotationOverlap)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	expiresIn, err := getDurationParam(m, "expires-in", 0)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	t, err := s.RotateToken(name, overlap, expiresIn)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+func getDurationParam(m url.Values, name string, defaultValue time.Duration) (time.Duration, error) {
+	valueFound, okValue := m[name]
+	if !okValue || valueFound[0] == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(valueFound[0])
+}
+
+/*
+Revoke a token
+URL: /cr/v1/token?name=<name>
+Method: DELETE
+*/
+func (s *Store) revokeTokenEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... revokeTokenEndpoint")
+	m, _ := url.ParseQuery(req.URL.RawQuery)
+	name := ""
+	nameFound, okName := m["name"]
+	if okName {
+		name = nameFound[0]
+	}
+	err := s.RevokeToken(name)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
+	}
+}
+
+/*
+List the tokens, the token values are not returned
+URL: /cr/v1/tokens
+Method: GET
+*/
+func (s *Store) listTokensEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... listTokensEndpoint")
+	tokens, err := s.ListTokens()
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(tokens)
+}
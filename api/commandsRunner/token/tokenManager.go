@@ -0,0 +1,535 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+//Roles, each role includes the rights of the previous ones
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+//LegacyTokenName name given to the token read from the cr-token file
+const LegacyTokenName = "default"
+
+//Authorization header schemes
+const (
+	BearerScheme      = "Bearer "
+	LegacyTokenScheme = "Token:"
+)
+
+//DefaultRotationOverlap how long the previous token stays valid after a rotation
+const DefaultRotationOverlap = time.Hour
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+//Token a named API token, only the hash of the token value is stored.
+type Token struct {
+	Name string `yaml:"name" json:"name"`
+	//Token the token value, only returned at the creation or rotation
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+	Hash  string `yaml:"hash,omitempty" json:"-"`
+	Role  string `yaml:"role" json:"role"`
+	//Extensions if not empty the token can only be used for these extensions
+	Extensions []string `yaml:"extensions,omitempty" json:"extensions,omitempty"`
+	//ExpiresAt RFC3339 date after which the token is rejected, empty means no expiry
+	ExpiresAt string `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+	//PreviousHash hash of the token value before the last rotation, valid until PreviousExpiresAt
+	PreviousHash      string `yaml:"previous_hash,omitempty" json:"-"`
+	PreviousExpiresAt string `yaml:"previous_expires_at,omitempty" json:"previous_expires_at,omitempty"`
+}
+
+//Tokens the token store
+type Tokens struct {
+	Tokens []Token `yaml:"tokens" json:"tokens"`
+}
+
+//mux protects the certificate and peer roles of the process
+var mux = &sync.Mutex{}
+
+//tokensCache the token caches of a config directory, reloaded when the files change
+type tokensCache struct {
+	tokens     *Tokens
+	tokensInfo os.FileInfo
+	legacyHash string
+	legacyInfo os.FileInfo
+}
+
+//Store the token store of a config directory, each runner has its own
+type Store struct {
+	configDir string
+	mux       sync.Mutex
+	cache     tokensCache
+}
+
+//NewStore creates the token store of the config directory
+func NewStore(configDir string) *Store {
+	return &Store{
+		configDir: configDir,
+	}
+}
+
+//ConfigDir returns the config directory of the store
+func (s *Store) ConfigDir() string {
+	return s.configDir
+}
+
+func getTokensPath(configDir string) string {
+	return filepath.Join(configDir, global.TokensFileName)
+}
+
+func getLegacyTokenPath(configDir string) string {
+	return filepath.Join(configDir, global.TokenFileName)
+}
+
+//isSameFile returns true if the file didn't change since it was cached
+func isSameFile(info os.FileInfo, cachedInfo os.FileInfo) bool {
+	return cachedInfo != nil && info.ModTime().Equal(cachedInfo.ModTime()) && info.Size() == cachedInfo.Size()
+}
+
+//hashToken returns the hex sha256 of a token value
+func hashToken(value string) string {
+	h := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(h[:])
+}
+
+//matchHash compares in constant time a value against a stored hash
+func matchHash(value string, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(value)), []byte(hash)) == 1
+}
+
+//isExpired returns true if the RFC3339 date is set and in the past
+func isExpired(date string, now time.Time) bool {
+	if date == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		log.Warning("Invalid token date " + date + ": " + err.Error())
+		return true
+	}
+	return now.After(t)
+}
+
+func (s *Store) readTokens() (*Tokens, error) {
+	log.Debug("Entering in... readTokens")
+	cache := &s.cache
+	info, err := os.Stat(getTokensPath(s.configDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			cache.tokens = nil
+			return &Tokens{Tokens: make([]Token, 0)}, nil
+		}
+		return nil, err
+	}
+	if cache.tokens != nil && isSameFile(info, cache.tokensInfo) {
+		return cache.tokens, nil
+	}
+	data, err := ioutil.ReadFile(getTokensPath(s.configDir))
+	if err != nil {
+		return nil, err
+	}
+	tokens := &Tokens{Tokens: make([]Token, 0)}
+	err = yaml.Unmarshal(data, tokens)
+	if err != nil {
+		return nil, err
+	}
+	//Hash the tokens stored in plain text
+	migrate := false
+	for i := range tokens.Tokens {
+		if tokens.Tokens[i].Token != "" {
+			tokens.Tokens[i].Hash = hashToken(tokens.Tokens[i].Token)
+			tokens.Tokens[i].Token = ""
+			migrate = true
+		}
+	}
+	if migrate {
+		log.Info("Hashing the tokens stored in plain text in " + getTokensPath(s.configDir))
+		return tokens, s.writeTokens(tokens)
+	}
+	cache.tokens = tokens
+	cache.tokensInfo = info
+	return tokens, nil
+}
+
+func (s *Store) writeTokens(tokens *Tokens) error {
+	log.Debug("Entering in... writeTokens")
+	cache := &s.cache
+	data, err := yaml.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(getTokensPath(s.configDir), data, 0600)
+	if err != nil {
+		cache.tokens = nil
+		return err
+	}
+	info, err := os.Stat(getTokensPath(s.configDir))
+	if err != nil {
+		cache.tokens = nil
+		return err
+	}
+	cache.tokens = tokens
+	cache.tokensInfo = info
+	return nil
+}
+
+//readLegacyTokenHash returns the hash of the token stored in the cr-token file
+func (s *Store) readLegacyTokenHash() (string, error) {
+	cache := &s.cache
+	info, err := os.Stat(getLegacyTokenPath(s.configDir))
+	if err != nil {
+		return "", err
+	}
+	if cache.legacyHash != "" && isSameFile(info, cache.legacyInfo) {
+		return cache.legacyHash, nil
+	}
+	data, err := ioutil.ReadFile(getLegacyTokenPath(s.configDir))
+	if err != nil {
+		return "", err
+	}
+	cache.legacyHash = hashToken(strings.TrimSuffix(string(data), "\n"))
+	cache.legacyInfo = info
+	return cache.legacyHash, nil
+}
+
+//IsValidRole returns true if the role exists
+func IsValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+//generateToken generates a random token value
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//GetTokenFromRequest extracts the token from the Authorization header, "Bearer <token>" and the legacy "Token:<token>" are supported.
+func GetTokenFromRequest(req *http.Request) (string, error) {
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return "", errors.New("Token not provided")
+	}
+	var value string
+	switch {
+	case len(auth) > len(BearerScheme) && strings.EqualFold(auth[:len(BearerScheme)], BearerScheme):
+		value = auth[len(BearerScheme):]
+	case strings.HasPrefix(auth, LegacyTokenScheme):
+		value = auth[len(LegacyTokenScheme):]
+	default:
+		return "", errors.New("Unsupported authorization scheme")
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("Token not provided")
+	}
+	return value, nil
+}
+
+//CreateToken creates a new token with the given role in the store, the returned token contains the token value.
+//If expiresIn is not 0 the token expires after that duration.
+func (s *Store) CreateToken(name string, role string, extensions []string, expiresIn time.Duration) (*Token, error) {
+	log.Debug("Entering in... CreateToken")
+	if name == "" {
+		return nil, apiError.BadRequest("Token name missing")
+	}
+	if name == LegacyTokenName {
+		return nil, errors.New("Token name " + LegacyTokenName + " is reserved")
+	}
+	if !IsValidRole(role) {
+		return nil, apiError.BadRequest("Invalid role: " + role + ", expected " + RoleViewer + ", " + RoleOperator + " or " + RoleAdmin)
+	}
+	if expiresIn < 0 {
+		return nil, errors.New("The expiry duration must be positive")
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	tokens, err := s.readTokens()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens.Tokens {
+		if t.Name == name {
+			return nil, apiError.Conflict("Token " + name + " already exists")
+		}
+	}
+	value, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	t := Token{
+		Name:       name,
+		Hash:       hashToken(value),
+		Role:       role,
+		Extensions: extensions,
+	}
+	if expiresIn != 0 {
+		t.ExpiresAt = time.Now().UTC().Add(expiresIn).Format(time.RFC3339)
+	}
+	newTokens := &Tokens{Tokens: append(append(make([]Token, 0), tokens.Tokens...), t)}
+	err = s.writeTokens(newTokens)
+	if err != nil {
+		return nil, err
+	}
+	t.Token = value
+	return &t, nil
+}
+
+/*
+RotateToken issues a new value for the token, the previous value stays valid during the overlap window.
+If expiresIn is not 0 the new value expires after that duration otherwise the current expiry is kept.
+*/
+func (s *Store) RotateToken(name string, overlap time.Duration, expiresIn time.Duration) (*Token, error) {
+	log.Debug("Entering in... RotateToken")
+	if name == LegacyTokenName {
+		return nil, errors.New("The token " + LegacyTokenName + " can not be rotated, replace the file " + global.TokenFileName)
+	}
+	if overlap < 0 || expiresIn < 0 {
+		return nil, errors.New("The overlap and expiry durations must be positive")
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	tokens, err := s.readTokens()
+	if err != nil {
+		return nil, err
+	}
+	newTokens := &Tokens{Tokens: append(make([]Token, 0), tokens.Tokens...)}
+	for i, t := range newTokens.Tokens {
+		if t.Name != name {
+			continue
+		}
+		value, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		t.PreviousHash = t.Hash
+		t.PreviousExpiresAt = now.Add(overlap).Format(time.RFC3339)
+		t.Hash = hashToken(value)
+		if expiresIn != 0 {
+			t.ExpiresAt = now.Add(expiresIn).Format(time.RFC3339)
+		}
+		newTokens.Tokens[i] = t
+		err = s.writeTokens(newTokens)
+		if err != nil {
+			return nil, err
+		}
+		t.Token = value
+		return &t, nil
+	}
+	return nil, apiError.NotFound(apiError.CodeNotFound, "Token "+name+" not found")
+}
+
+//ListTokens returns the tokens without their value
+func (s *Store) ListTokens() ([]Token, error) {
+	log.Debug("Entering in... ListTokens")
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	tokens, err := s.readTokens()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Token, 0)
+	if _, err := os.Stat(getLegacyTokenPath(s.configDir)); err == nil {
+		list = append(list, Token{Name: LegacyTokenName, Role: RoleAdmin})
+	}
+	list = append(list, tokens.Tokens...)
+	return list, nil
+}
+
+//RevokeToken removes a token from the store
+func (s *Store) RevokeToken(name string) error {
+	log.Debug("Entering in... RevokeToken")
+	if name == LegacyTokenName {
+		return errors.New("The token " + LegacyTokenName + " can not be revoked, remove the file " + global.TokenFileName)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	tokens, err := s.readTokens()
+	if err != nil {
+		return err
+	}
+	for i, t := range tokens.Tokens {
+		if t.Name == name {
+			newTokens := &Tokens{Tokens: append(append(make([]Token, 0), tokens.Tokens[:i]...), tokens.Tokens[i+1:]...)}
+			return s.writeTokens(newTokens)
+		}
+	}
+	return apiError.NotFound(apiError.CodeNotFound, "Token "+name+" not found")
+}
+
+//Authenticate searches the token matching the received value in the store, the cr-token file is considered as an admin token.
+func (s *Store) Authenticate(value string) (*Token, error) {
+	log.Debug("Entering in... Authenticate")
+	if value == "" {
+		return nil, errors.New("Invalid token")
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	tokens, err := s.readTokens()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	//All tokens are checked to not leak which token matched through the response time
+	var found *Token
+	for i := range tokens.Tokens {
+		t := tokens.Tokens[i]
+		current := matchHash(value, t.Hash) && !isExpired(t.ExpiresAt, now)
+		previous := matchHash(value, t.PreviousHash) && !isExpired(t.PreviousExpiresAt, now)
+		if (current || previous) && found == nil {
+			found = &t
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	legacyHash, err := s.readLegacyTokenHash()
+	if err == nil && matchHash(value, legacyHash) {
+		return &Token{Name: LegacyTokenName, Role: RoleAdmin}, nil
+	}
+	return nil, errors.New("Invalid token")
+}
+
+//GetRequiredRole returns the role needed to execute the request
+func GetRequiredRole(req *http.Request) string {
+	path := strings.TrimSuffix(global.ToV1Path(req.URL.Path), "/")
+	if strings.HasPrefix(path, "/cr/v1/token") || path == "/cr/v1/audit" || path == "/cr/v1/webhooks" {
+		return RoleAdmin
+	}
+	m, _ := url.ParseQuery(req.URL.RawQuery)
+	//The config validation and generation run the extension scripts on the server
+	if path == "/cr/v1/config" {
+		if actionFound, ok := m["action"]; ok && (actionFound[0] == "validate" || actionFound[0] == "generate") {
+			return RoleOperator
+		}
+		if req.Method == http.MethodPut {
+			return RoleOperator
+		}
+	}
+	if req.Method == http.MethodGet {
+		return RoleViewer
+	}
+	switch path {
+	case "/cr/v1/engine", "/cr/v1/state":
+		return RoleOperator
+	case "/cr/v1/states":
+		if actionFound, ok := m["action"]; ok && actionFound[0] == "set-statuses" {
+			return RoleOperator
+		}
+	}
+	return RoleAdmin
+}
+
+//extensionScopedPaths the endpoints returning or updating only the data of the extension-name parameter
+var extensionScopedPaths = []string{
+	"/cr/v1/state",
+	"/cr/v1/states",
+	"/cr/v1/engine",
+	"/cr/v1/events",
+	"/cr/v1/extension",
+	"/cr/v1/uimetadata",
+	"/cr/v1/uimetadatas",
+	"/cr/v1/config",
+	"/cr/v1/template",
+	"/cr/v1/runs",
+}
+
+//serverPaths the endpoints not related to an extension allowed to the tokens scoped to extensions
+var serverPaths = []string{
+	"/cr/v1/status",
+	"/cr/v1/cr/",
+}
+
+//isExtensionScopedPath returns true if the path is an endpoint of extensionScopedPaths or under it
+func isExtensionScopedPath(path string) bool {
+	for _, scopedPath := range extensionScopedPaths {
+		if path == scopedPath || strings.HasPrefix(path, scopedPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Authorize checks if the token has the role and the extension scope to execute the request.
+A token scoped to extensions is denied by default: it is allowed only on the endpoints scoped by the extension-name
+parameter with one of its extensions and on the server endpoints, the lists of all extensions are denied.
+*/
+func (t *Token) Authorize(req *http.Request) error {
+	requiredRole := GetRequiredRole(req)
+	if roleLevels[t.Role] < roleLevels[requiredRole] {
+		return errors.New("Token " + t.Name + " with role " + t.Role + " is not allowed, role " + requiredRole + " required")
+	}
+	if len(t.Extensions) == 0 {
+		return nil
+	}
+	path := global.ToV1Path(req.URL.Path)
+	for _, serverPath := range serverPaths {
+		if path == serverPath || (strings.HasSuffix(serverPath, "/") && strings.HasPrefix(path, serverPath)) {
+			return nil
+		}
+	}
+	if !isExtensionScopedPath(path) {
+		return errors.New("Token " + t.Name + " is scoped to extensions and is not allowed on " + req.URL.Path)
+	}
+	extensionName, _, err := global.GetExtensionNameFromRequest(req)
+	if err != nil {
+		return errors.New("Token " + t.Name + " is scoped to extensions, extension-name is required")
+	}
+	for _, e := range t.Extensions {
+		if e == extensionName {
+			return nil
+		}
+	}
+	return errors.New("Token " + t.Name + " is not allowed on extension " + extensionName)
+}
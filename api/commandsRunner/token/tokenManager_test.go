@@ -0,0 +1,246 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package token
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+func TestTokenRoles(t *testing.T) {
+	t.Log("Entering... TestTokenRoles")
+	dir, err := ioutil.TempDir("", "TestTokenRoles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewStore(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, global.TokenFileName), []byte("legacy\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := store.Authenticate("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.Role != RoleAdmin {
+		t.Error("Expected legacy token to be admin, got " + legacy.Role)
+	}
+	_, err = store.CreateToken("reader", "unknown", nil, 0)
+	if err == nil {
+		t.Error("Expected an error for an invalid role")
+	}
+	viewer, err := store.CreateToken("reader", RoleViewer, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CreateToken("reader", RoleViewer, nil, 0)
+	if err == nil {
+		t.Error("Expected an error for a duplicate name")
+	}
+	operator, err := store.CreateToken("ops", RoleOperator, []string{"ext1"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		token  *Token
+		method string
+		url    string
+		allow  bool
+	}{
+		{viewer, "GET", "/cr/v1/states?extension-name=ext1", true},
+		{viewer, "PUT", "/cr/v1/engine?action=start&extension-name=ext1", false},
+		{operator, "PUT", "/cr/v1/engine?action=start&extension-name=ext1", true},
+		{operator, "PUT", "/cr/v1/engine?action=start&extension-name=ext2", false},
+		{operator, "PUT", "/cr/v1/states?action=set-statuses&extension-name=ext1", true},
+		{operator, "PUT", "/cr/v1/states?extension-name=ext1", false},
+		{operator, "POST", "/cr/v1/extension?extension-name=ext1", false},
+		{operator, "GET", "/cr/v1/tokens", false},
+		{operator, "GET", "/cr/v1/webhooks", false},
+		{legacy, "GET", "/cr/v1/webhooks", true},
+		{legacy, "DELETE", "/cr/v1/extension?extension-name=ext1", true},
+		{legacy, "PUT", "/cr/v1/cr/log/level?level=debug", true},
+		{operator, "PUT", "/cr/v2/engine?action=start&extension-name=ext1", true},
+		{operator, "GET", "/cr/v2/tokens", false},
+		{operator, "GET", "/cr/v1/states?extension-name=ext1", true},
+		{operator, "GET", "/cr/v1/states", false},
+		{operator, "GET", "/cr/v1/state/state1?extension-name=ext2", false},
+		{operator, "GET", "/cr/v1/extensions", false},
+		{operator, "GET", "/cr/v1/extensions?extension-name=ext1", false},
+		{operator, "GET", "/cr/v1/events", false},
+		{operator, "GET", "/metrics", false},
+		{operator, "GET", "/cr/v1/status", true},
+		{operator, "GET", "/cr/v2/config?extension-name=ext1", true},
+		{viewer, "GET", "/cr/v1/config?extension-name=ext1", true},
+		{viewer, "GET", "/cr/v1/config?action=validate&extension-name=ext1", false},
+		{operator, "GET", "/cr/v1/config?action=validate&extension-name=ext1", true},
+		{viewer, "GET", "/cr/v2/config?action=validate&extension-name=ext1", false},
+		{viewer, "PUT", "/cr/v1/config?extension-name=ext1", false},
+		{operator, "PUT", "/cr/v1/config?extension-name=ext1", true},
+		{operator, "PUT", "/cr/v1/config?action=generate&extension-name=ext1", true},
+		{operator, "POST", "/cr/v1/config?extension-name=ext1", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.url, nil)
+		err := test.token.Authorize(req)
+		if test.allow && err != nil {
+			t.Errorf("%s %s %s: %s", test.token.Name, test.method, test.url, err.Error())
+		}
+		if !test.allow && err == nil {
+			t.Errorf("%s %s %s: expected to be forbidden", test.token.Name, test.method, test.url)
+		}
+	}
+	found, err := store.Authenticate(operator.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "ops" {
+		t.Error("Expected token ops, got " + found.Name)
+	}
+	tokens, err := store.ListTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 3 {
+		t.Errorf("Expected 3 tokens, got %d", len(tokens))
+	}
+	for _, token := range tokens {
+		if token.Token != "" {
+			t.Error("Token value returned for " + token.Name)
+		}
+	}
+	err = store.RevokeToken("ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Authenticate(operator.Token)
+	if err == nil {
+		t.Error("Expected revoked token to be rejected")
+	}
+	err = store.RevokeToken("ops")
+	if err == nil {
+		t.Error("Expected an error when revoking an unknown token")
+	}
+}
+
+func TestTokenHashExpiryRotation(t *testing.T) {
+	t.Log("Entering... TestTokenHashExpiryRotation")
+	dir, err := ioutil.TempDir("", "TestTokenHashExpiryRotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewStore(dir)
+	created, err := store.CreateToken("ci", RoleOperator, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, global.TokensFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), created.Token) {
+		t.Error("Token value stored in plain text")
+	}
+	rotated, err := store.RotateToken("ci", time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Token == created.Token {
+		t.Error("Rotation returned the same token value")
+	}
+	for _, value := range []string{created.Token, rotated.Token} {
+		_, err = store.Authenticate(value)
+		if err != nil {
+			t.Error("Expected token to be valid during the overlap: " + err.Error())
+		}
+	}
+	//Rotate without overlap, the previous value is rejected
+	rotatedAgain, err := store.RotateToken("ci", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	_, err = store.Authenticate(rotated.Token)
+	if err == nil {
+		t.Error("Expected the previous value to be rejected once the overlap expired")
+	}
+	_, err = store.Authenticate(rotatedAgain.Token)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	expiring, err := store.CreateToken("short", RoleViewer, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiring.ExpiresAt == "" {
+		t.Error("Expiry date not set")
+	}
+	time.Sleep(2100 * time.Millisecond)
+	_, err = store.Authenticate(expiring.Token)
+	if err == nil {
+		t.Error("Expected an expired token to be rejected")
+	}
+	//Tokens stored in plain text are hashed at the first read
+	err = ioutil.WriteFile(filepath.Join(dir, global.TokensFileName), []byte("tokens:\n- name: old\n  token: plain\n  role: viewer\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Authenticate("plain")
+	if err != nil {
+		t.Error(err.Error())
+	}
+	data, err = ioutil.ReadFile(filepath.Join(dir, global.TokensFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "plain") {
+		t.Error("Token not hashed: " + string(data))
+	}
+}
+
+func TestGetTokenFromRequest(t *testing.T) {
+	t.Log("Entering... TestGetTokenFromRequest")
+	tests := map[string]string{
+		"Bearer abc": "abc",
+		"bearer abc": "abc",
+		"Token:abc":  "abc",
+		"":           "",
+		"Token":      "",
+		"Bearer ":    "",
+		"Basic abc":  "",
+	}
+	for header, expected := range tests {
+		req := httptest.NewRequest("GET", "/cr/v1/states", nil)
+		req.Header.Set("Authorization", header)
+		value, err := GetTokenFromRequest(req)
+		if expected == "" && err == nil {
+			t.Errorf("Expected an error for %q", header)
+		}
+		if expected != "" && (err != nil || value != expected) {
+			t.Errorf("Expected %q for %q, got %q %v", expected, header, value, err)
+		}
+	}
+}
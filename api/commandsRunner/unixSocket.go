@@ -0,0 +1,126 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package commandsRunner
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//peerNetwork the network of the addresses of the connections accepted on the unix socket
+const peerNetwork = "unix-peer"
+
+//peerCredentials the credentials of the process connected to the unix socket, uid is -1 if not available
+type peerCredentials struct {
+	uid int
+	gid int
+	pid int
+}
+
+//peers the credentials of the open unix socket connections by remote address
+var peers = make(map[string]peerCredentials)
+var peersMux sync.Mutex
+var peerCounter uint64
+
+//peerAddr a unique address per unix socket connection, the requests get it as RemoteAddr
+type peerAddr string
+
+func (a peerAddr) Network() string {
+	return peerNetwork
+}
+
+func (a peerAddr) String() string {
+	return string(a)
+}
+
+//peerConn a connection accepted on the unix socket
+type peerConn struct {
+	net.Conn
+	addr peerAddr
+}
+
+func (c *peerConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *peerConn) Close() error {
+	peersMux.Lock()
+	delete(peers, string(c.addr))
+	peersMux.Unlock()
+	return c.Conn.Close()
+}
+
+//unixListener records the peer credentials of each accepted connection
+type unixListener struct {
+	net.Listener
+}
+
+func (l *unixListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := getPeerCredentials(conn)
+	if err != nil {
+		log.Debug(err.Error())
+		creds = peerCredentials{uid: -1, gid: -1, pid: -1}
+	}
+	addr := peerAddr(peerNetwork + ":" + strconv.FormatUint(atomic.AddUint64(&peerCounter, 1), 10))
+	peersMux.Lock()
+	peers[string(addr)] = creds
+	peersMux.Unlock()
+	return &peerConn{Conn: conn, addr: addr}, nil
+}
+
+//getPeer returns the credentials of the peer if the request came through the unix socket
+func getPeer(remoteAddr string) (peerCredentials, bool) {
+	peersMux.Lock()
+	defer peersMux.Unlock()
+	creds, ok := peers[remoteAddr]
+	return creds, ok
+}
+
+//listenUnixSocket listens on the unix socket path and sets its permissions, a stale socket file is removed
+func listenUnixSocket(path string, mode os.FileMode) (net.Listener, error) {
+	log.Debug("Entering in... listenUnixSocket")
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New("Unable to listen on " + path + ", the file exists and is not a socket")
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(path, mode)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &unixListener{Listener: listener}, nil
+}
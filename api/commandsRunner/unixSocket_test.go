@@ -0,0 +1,102 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package commandsRunner
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/token"
+)
+
+func TestUnixSocket(t *testing.T) {
+	t.Log("Entering... TestUnixSocket")
+	dir, err := ioutil.TempDir("", "TestUnixSocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer token.SetPeerRoles(nil)
+	socketPath := filepath.Join(dir, "cr.sock")
+	//A stale socket file is replaced
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	listener, err := listenUnixSocket(socketPath, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket permissions 0600, got %v", info.Mode().Perm())
+	}
+	handler := validateToken(token.NewStore(dir), func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	defer server.Close()
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	call := func(method string) int {
+		req, _ := http.NewRequest(method, "http://unix/cr/v1/states?extension-name=ext1", nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	//No peer role, the socket permissions control the access
+	if code := call("PUT"); code != http.StatusOK {
+		t.Errorf("Expected %d without peer roles, got %d", http.StatusOK, code)
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+	//The current user is mapped to the viewer role
+	err = token.SetPeerRoles([]token.PeerRole{{User: strconv.Itoa(os.Getuid()), Role: token.RoleViewer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := call("GET"); code != http.StatusOK {
+		t.Errorf("Expected %d for a viewer GET, got %d", http.StatusOK, code)
+	}
+	if code := call("PUT"); code != http.StatusForbidden {
+		t.Errorf("Expected %d for a viewer PUT, got %d", http.StatusForbidden, code)
+	}
+}
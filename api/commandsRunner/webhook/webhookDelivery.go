@@ -0,0 +1,258 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+//Headers sent with the payloads
+const (
+	EventHeader     = "X-CR-Event"
+	DeliveryHeader  = "X-CR-Delivery"
+	SignatureHeader = "X-CR-Signature"
+)
+
+//WebhookMaxAttempts number of delivery attempts of a payload
+var WebhookMaxAttempts = 4
+
+//WebhookRetryDelay delay before the first retry, doubled at each retry
+var WebhookRetryDelay = 2 * time.Second
+
+//WebhookTimeout timeout of a delivery attempt
+var WebhookTimeout = 10 * time.Second
+
+//WebhookQueueSize number of payloads waiting for delivery per webhook, the events are dropped when the queue is full
+var WebhookQueueSize = 1000
+
+//EventQueueSize number of events waiting to be dispatched to the webhooks, the events are dropped when the queue is full
+var EventQueueSize = 10000
+
+//DeliveryLogSize number of deliveries kept in the delivery log
+var DeliveryLogSize = 1000
+
+//Payload the json document posted to the webhooks
+type Payload struct {
+	Webhook string      `json:"webhook"`
+	Event   state.Event `json:"event"`
+}
+
+//Delivery the result of the delivery of an event to a webhook
+type Delivery struct {
+	ID            int64  `json:"id"`
+	Webhook       string `json:"webhook"`
+	EventID       int64  `json:"event_id"`
+	EventType     string `json:"event_type"`
+	ExtensionName string `json:"extension_name"`
+	Timestamp     string `json:"timestamp"`
+	Attempts      int    `json:"attempts"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	Delivered     bool   `json:"delivered"`
+	Error         string `json:"error,omitempty"`
+}
+
+var httpClient = &http.Client{}
+
+//Start registers the webhooks as event listener of the instance and starts the dispatcher
+func (m *Manager) Start() {
+	m.startOnce.Do(func() {
+		m.events = make(chan state.Event, EventQueueSize)
+		go m.dispatcher()
+		m.inst.AddEventListener(m.queueEvent)
+	})
+}
+
+//queueEvent queues the event for the dispatcher without blocking the publisher
+func (m *Manager) queueEvent(event state.Event) {
+	select {
+	case m.events <- event:
+	default:
+		log.Warning("Webhook event queue full, event " + strconv.FormatInt(event.ID, 10) + " dropped")
+	}
+}
+
+//dispatcher dispatches the queued events
+func (m *Manager) dispatcher() {
+	for event := range m.events {
+		m.dispatch(event)
+	}
+}
+
+//dispatch queues the event for the matching webhooks
+func (m *Manager) dispatch(event state.Event) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	webhooks, err := m.getAllWebhooks()
+	if err != nil {
+		log.Error("Unable to read the webhooks: " + err.Error())
+		return
+	}
+	for _, w := range webhooks {
+		if !w.Match(event) {
+			continue
+		}
+		queue, ok := m.queues[w.Name]
+		if !ok {
+			queue = make(chan Payload, WebhookQueueSize)
+			m.queues[w.Name] = queue
+			go m.deliverQueue(queue)
+		}
+		select {
+		case queue <- Payload{Webhook: w.Name, Event: event}:
+		default:
+			m.addDelivery(Delivery{
+				Webhook:       w.Name,
+				EventID:       event.ID,
+				EventType:     event.Type,
+				ExtensionName: event.ExtensionName,
+				Error:         "Delivery queue full, event dropped",
+			})
+		}
+	}
+}
+
+//deliverQueue delivers in order the payloads of a webhook
+func (m *Manager) deliverQueue(queue chan Payload) {
+	for payload := range queue {
+		w, err := m.getWebhook(payload.Webhook)
+		if err != nil {
+			log.Warning(err.Error())
+			continue
+		}
+		m.addDelivery(m.deliver(w, payload))
+	}
+}
+
+//getWebhook returns the webhook including its secret
+func (m *Manager) getWebhook(name string) (*Webhook, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	webhooks, err := m.getAllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range webhooks {
+		if w.Name == name {
+			return &w, nil
+		}
+	}
+	return nil, errors.New("Webhook " + name + " not found, event dropped")
+}
+
+//Sign returns the hex HMAC-SHA256 of the payload with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//deliver posts the payload to the webhook, retrying with an exponential backoff
+func (m *Manager) deliver(w *Webhook, payload Payload) Delivery {
+	log.Debug("Entering in... deliver")
+	delivery := Delivery{
+		ID:            atomic.AddInt64(&m.deliveryID, 1),
+		Webhook:       w.Name,
+		EventID:       payload.Event.ID,
+		EventType:     payload.Event.Type,
+		ExtensionName: payload.Event.ExtensionName,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	delay := WebhookRetryDelay
+	for delivery.Attempts < WebhookMaxAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		delivery.Attempts++
+		delivery.StatusCode, err = post(w, delivery.ID, payload.Event.Type, body)
+		if err == nil {
+			delivery.Delivered = true
+			delivery.Error = ""
+			return delivery
+		}
+		delivery.Error = err.Error()
+		log.Warning("Delivery " + strconv.FormatInt(delivery.ID, 10) + " to webhook " + w.Name + " failed (attempt " + strconv.Itoa(delivery.Attempts) + "): " + err.Error())
+	}
+	return delivery
+}
+
+func post(w *Webhook, id int64, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(id, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, body))
+	client := *httpClient
+	client.Timeout = WebhookTimeout
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, errors.New("Unexpected status code " + strconv.Itoa(res.StatusCode))
+	}
+	return res.StatusCode, nil
+}
+
+//addDelivery appends the delivery to the delivery log
+func (m *Manager) addDelivery(delivery Delivery) {
+	if delivery.ID == 0 {
+		delivery.ID = atomic.AddInt64(&m.deliveryID, 1)
+	}
+	delivery.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	m.deliveriesMux.Lock()
+	defer m.deliveriesMux.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	if len(m.deliveries) > DeliveryLogSize {
+		m.deliveries = append(make([]Delivery, 0), m.deliveries[len(m.deliveries)-DeliveryLogSize:]...)
+	}
+}
+
+//GetDeliveries returns the delivery log of a webhook, all webhooks if name is empty
+func (m *Manager) GetDeliveries(name string) []Delivery {
+	m.deliveriesMux.Lock()
+	defer m.deliveriesMux.Unlock()
+	result := make([]Delivery, 0)
+	for _, d := range m.deliveries {
+		if name == "" || d.Webhook == name {
+			result = append(result, d)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/logger"
+)
+
+//HandleWebhooks handles webhooks rest api requests
+func (m *Manager) HandleWebhooks(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in HandleWebhooks")
+	switch req.Method {
+	case "GET":
+		query, _ := url.ParseQuery(req.URL.RawQuery)
+		if actionFound, okAction := query["action"]; okAction && actionFound[0] == "deliveries" {
+			m.listDeliveriesEndpoint(w, req)
+		} else {
+			m.listWebhooksEndpoint(w, req)
+		}
+	case "POST":
+		m.createWebhookEndpoint(w, req)
+	case "DELETE":
+		m.deleteWebhookEndpoint(w, req)
+	default:
+		http.Error(w, "Unsupported method:"+req.Method, http.StatusMethodNotAllowed)
+	}
+}
+
+/*
+Register a webhook, the body is a json webhook (ie: {"name":"chat","url":"https://...","extension_name":"ext","events":["state_status:FAILED","run_end"]}).
+The response contains the secret used to sign the payloads, generated if not provided, which can not be retrieved afterward.
+URL: /cr/v1/webhooks
+Method: POST
+*/
+func (m *Manager) createWebhookEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... createWebhookEndpoint")
+	var webhook Webhook
+	err := json.NewDecoder(req.Body).Decode(&webhook)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	created, err := m.CreateWebhook(webhook)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(created)
+}
+
+/*
+Delete a webhook registered through the api
+URL: /cr/v1/webhooks?name=<name>
+Method: DELETE
+*/
+func (m *Manager) deleteWebhookEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... deleteWebhookEndpoint")
+	query, _ := url.ParseQuery(req.URL.RawQuery)
+	name := ""
+	nameFound, okName := query["name"]
+	if okName {
+		name = nameFound[0]
+	}
+	err := m.DeleteWebhook(name)
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusNotFound)
+	}
+}
+
+/*
+List the webhooks, the secrets are not returned
+URL: /cr/v1/webhooks
+Method: GET
+*/
+func (m *Manager) listWebhooksEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... listWebhooksEndpoint")
+	webhooks, err := m.ListWebhooks()
+	if err != nil {
+		logger.AddCallerField().Error(err.Error())
+		apiError.HTTPError(w, req, err, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+/*
+List the deliveries of a webhook, all webhooks if no name is provided
+URL: /cr/v1/webhooks?action=deliveries[&name=<name>]
+Method: GET
+*/
+func (m *Manager) listDeliveriesEndpoint(w http.ResponseWriter, req *http.Request) {
+	log.Debug("Entering in... listDeliveriesEndpoint")
+	query, _ := url.ParseQuery(req.URL.RawQuery)
+	name := ""
+	nameFound, okName := query["name"]
+	if okName {
+		name = nameFound[0]
+	}
+	json.NewEncoder(w).Encode(m.GetDeliveries(name))
+}
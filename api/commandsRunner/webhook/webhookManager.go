@@ -0,0 +1,339 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-yaml/yaml"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+//Webhook an endpoint receiving the run and state events
+type Webhook struct {
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+	//ExtensionName if empty the webhook receives the events of all extensions
+	ExtensionName string `yaml:"extension_name,omitempty" json:"extension_name,omitempty"`
+	//Events the event filters "<type>[:<status>]" (ie: run_start, state_status:FAILED, run_end:SUCCEEDED), empty means all run and state events
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+	//Secret the shared secret used to sign the payloads, only returned at creation
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+	//ReadOnly true for the webhooks defined in the server config, they can not be deleted through the api
+	ReadOnly bool `yaml:"-" json:"read_only,omitempty"`
+}
+
+//Webhooks the webhook store
+type Webhooks struct {
+	Webhooks []Webhook `yaml:"webhooks" json:"webhooks"`
+}
+
+var eventTypes = map[string]bool{
+	state.EventRunStart:    true,
+	state.EventRunEnd:      true,
+	state.EventStateStatus: true,
+	state.EventLog:         true,
+}
+
+/*
+Manager the webhooks of a state instance: the webhooks defined in the server config, the webhooks registered
+in the config directory of the instance, their delivery queues and their delivery log. Each runner has its own.
+*/
+type Manager struct {
+	//deliveryID the id of the last delivery
+	deliveryID int64
+	inst       *state.Instance
+	mux        sync.Mutex
+	//configWebhooks the webhooks defined in the server config
+	configWebhooks []Webhook
+	//webhooks the registered webhooks, read once from the file and replaced at each write
+	webhooks *Webhooks
+	//queues the delivery queue of each webhook
+	queues map[string]chan Payload
+	//events the events waiting to be dispatched
+	events        chan state.Event
+	startOnce     sync.Once
+	deliveriesMux sync.Mutex
+	deliveries    []Delivery
+}
+
+//NewManager creates the webhook manager of the instance
+func NewManager(inst *state.Instance) *Manager {
+	return &Manager{
+		inst:           inst,
+		configWebhooks: make([]Webhook, 0),
+		queues:         make(map[string]chan Payload),
+		deliveries:     make([]Delivery, 0),
+	}
+}
+
+func (m *Manager) getWebhooksPath() string {
+	return filepath.Join(m.inst.GetConfigDir(), global.WebhooksFileName)
+}
+
+//validateWebhook checks the webhook attributes
+func validateWebhook(w Webhook) error {
+	if w.Name == "" {
+		return apiError.BadRequest("Webhook name missing")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return apiError.BadRequest("Invalid url for webhook " + w.Name + ": " + err.Error())
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apiError.BadRequest("Invalid url for webhook " + w.Name + ": " + w.URL + ", expected an http or https url")
+	}
+	for _, filter := range w.Events {
+		eventType := strings.SplitN(filter, ":", 2)[0]
+		if !eventTypes[eventType] {
+			return apiError.BadRequest("Invalid event filter " + filter + " for webhook " + w.Name + ", expected <" + state.EventRunStart + "|" + state.EventRunEnd + "|" + state.EventStateStatus + "|" + state.EventLog + ">[:<status>]")
+		}
+	}
+	return nil
+}
+
+//Match returns true if the event must be sent to the webhook
+func (w *Webhook) Match(event state.Event) bool {
+	if w.ExtensionName != "" && w.ExtensionName != event.ExtensionName && w.ExtensionName != event.ExecutedByExtensionName {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return event.Type != state.EventLog
+	}
+	for _, filter := range w.Events {
+		filterType := strings.SplitN(filter, ":", 2)
+		if filterType[0] != event.Type {
+			continue
+		}
+		if len(filterType) == 1 || strings.EqualFold(filterType[1], event.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+//validateConfigWebhooks checks the webhooks defined in the server config, their secret is mandatory as it signs the payloads
+func validateConfigWebhooks(webhooks []Webhook) error {
+	for i := range webhooks {
+		err := validateWebhook(webhooks[i])
+		if err != nil {
+			return err
+		}
+		if webhooks[i].Secret == "" {
+			return apiError.BadRequest("Secret missing for webhook " + webhooks[i].Name + " defined in the server config")
+		}
+	}
+	return nil
+}
+
+//SetConfigWebhooks sets the webhooks defined in the server config
+func (m *Manager) SetConfigWebhooks(webhooks []Webhook) error {
+	err := validateConfigWebhooks(webhooks)
+	if err != nil {
+		return err
+	}
+	configWebhooks := make([]Webhook, len(webhooks))
+	for i := range webhooks {
+		configWebhooks[i] = webhooks[i]
+		configWebhooks[i].ReadOnly = true
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.configWebhooks = configWebhooks
+	return nil
+}
+
+//LoadWebhooks reads and checks the webhooks attribute of the commands-runner.yml content
+func LoadWebhooks(raw []byte) ([]Webhook, error) {
+	log.Debug("Entering in... LoadWebhooks")
+	var cfg Webhooks
+	err := yaml.Unmarshal(raw, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	err = validateConfigWebhooks(cfg.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Webhooks, nil
+}
+
+//readWebhooks returns the webhooks registered in the config directory of the instance, the file is only read if they are not in memory yet
+func (m *Manager) readWebhooks() (*Webhooks, error) {
+	if m.webhooks != nil {
+		return m.webhooks, nil
+	}
+	log.Debug("Entering in... readWebhooks")
+	data, err := ioutil.ReadFile(m.getWebhooksPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			m.webhooks = &Webhooks{Webhooks: make([]Webhook, 0)}
+			return m.webhooks, nil
+		}
+		return nil, err
+	}
+	webhooks := &Webhooks{Webhooks: make([]Webhook, 0)}
+	err = yaml.Unmarshal(data, webhooks)
+	if err != nil {
+		return nil, err
+	}
+	m.webhooks = webhooks
+	return webhooks, nil
+}
+
+func (m *Manager) writeWebhooks(webhooks *Webhooks) error {
+	log.Debug("Entering in... writeWebhooks")
+	data, err := yaml.Marshal(webhooks)
+	if err != nil {
+		return err
+	}
+	err = global.WriteFileAtomic(m.getWebhooksPath(), data, 0600)
+	if err != nil {
+		m.webhooks = nil
+		return err
+	}
+	m.webhooks = webhooks
+	return nil
+}
+
+//lockWebhooks takes the lock of the webhooks file of the instance, the registered webhooks are read again from the file
+//as another process may have updated it. It returns the function releasing the lock.
+func (m *Manager) lockWebhooks() (func(), error) {
+	unlockFile, err := global.LockFile(m.getWebhooksPath())
+	if err != nil {
+		return nil, err
+	}
+	m.webhooks = nil
+	return unlockFile, nil
+}
+
+//getAllWebhooks returns the webhooks of the server config followed by the ones registered in the instance
+func (m *Manager) getAllWebhooks() ([]Webhook, error) {
+	webhooks, err := m.readWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	return append(append(make([]Webhook, 0), m.configWebhooks...), webhooks.Webhooks...), nil
+}
+
+//generateSecret generates a random secret
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//CreateWebhook registers a webhook in the config directory of the instance, a secret is generated if not provided. The returned webhook contains the secret.
+func (m *Manager) CreateWebhook(w Webhook) (*Webhook, error) {
+	log.Debug("Entering in... CreateWebhook")
+	w.ReadOnly = false
+	err := validateWebhook(w)
+	if err != nil {
+		return nil, err
+	}
+	if w.Secret == "" {
+		w.Secret, err = generateSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	unlockFile, err := m.lockWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFile()
+	all, err := m.getAllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range all {
+		if existing.Name == w.Name {
+			return nil, apiError.Conflict("Webhook " + w.Name + " already exists")
+		}
+	}
+	webhooks, err := m.readWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	newWebhooks := &Webhooks{Webhooks: append(append(make([]Webhook, 0), webhooks.Webhooks...), w)}
+	err = m.writeWebhooks(newWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+//DeleteWebhook removes a registered webhook
+func (m *Manager) DeleteWebhook(name string) error {
+	log.Debug("Entering in... DeleteWebhook")
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, w := range m.configWebhooks {
+		if w.Name == name {
+			return errors.New("Webhook " + name + " is defined in the server config and can not be deleted")
+		}
+	}
+	unlockFile, err := m.lockWebhooks()
+	if err != nil {
+		return err
+	}
+	defer unlockFile()
+	webhooks, err := m.readWebhooks()
+	if err != nil {
+		return err
+	}
+	for i, w := range webhooks.Webhooks {
+		if w.Name == name {
+			newWebhooks := &Webhooks{Webhooks: append(append(make([]Webhook, 0), webhooks.Webhooks[:i]...), webhooks.Webhooks[i+1:]...)}
+			return m.writeWebhooks(newWebhooks)
+		}
+	}
+	return apiError.NotFound(apiError.CodeNotFound, "Webhook "+name+" not found")
+}
+
+//ListWebhooks returns the webhooks without their secret
+func (m *Manager) ListWebhooks() ([]Webhook, error) {
+	log.Debug("Entering in... ListWebhooks")
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	webhooks, err := m.getAllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
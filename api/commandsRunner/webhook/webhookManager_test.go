@@ -0,0 +1,187 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+func TestWebhooks(t *testing.T) {
+	t.Log("Entering... TestWebhooks")
+	dir, err := ioutil.TempDir("", "TestWebhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	inst := state.NewInstance()
+	inst.SetConfigDir(dir)
+	m := NewManager(inst)
+	_, err = LoadWebhooks([]byte("webhooks:\n- name: config\n  url: https://example.com/hook\n  events:\n  - run_end\n"))
+	if err == nil {
+		t.Error("Expected an error for a config webhook without secret")
+	}
+	configWebhooks, err := LoadWebhooks([]byte("webhooks:\n- name: config\n  url: https://example.com/hook\n  secret: config-secret\n  events:\n  - run_end\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.SetConfigWebhooks(configWebhooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.CreateWebhook(Webhook{Name: "invalid", URL: "ftp://example.com"})
+	if err == nil {
+		t.Error("Expected an error for an invalid url")
+	}
+	_, err = m.CreateWebhook(Webhook{Name: "invalid", URL: "https://example.com", Events: []string{"unknown"}})
+	if err == nil {
+		t.Error("Expected an error for an invalid event filter")
+	}
+	_, err = m.CreateWebhook(Webhook{Name: "config", URL: "https://example.com"})
+	if err == nil {
+		t.Error("Expected an error for an existing webhook")
+	}
+	created, err := m.CreateWebhook(Webhook{Name: "chat", URL: "https://example.com/chat", ExtensionName: "ext1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Secret == "" {
+		t.Error("Expected a generated secret")
+	}
+	webhooks, err := m.ListWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 2 || webhooks[0].Name != "config" || !webhooks[0].ReadOnly || webhooks[1].Name != "chat" || webhooks[1].Secret != "" {
+		t.Errorf("Unexpected webhooks %+v", webhooks)
+	}
+	err = m.DeleteWebhook("config")
+	if err == nil {
+		t.Error("Expected an error when deleting a config webhook")
+	}
+	err = m.DeleteWebhook("chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks, err = m.ListWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 1 {
+		t.Errorf("Expected 1 webhook, got %d", len(webhooks))
+	}
+}
+
+func TestWebhookMatch(t *testing.T) {
+	t.Log("Entering... TestWebhookMatch")
+	all := Webhook{Name: "all"}
+	filtered := Webhook{Name: "filtered", ExtensionName: "ext1", Events: []string{"run_start", "state_status:failed"}}
+	tests := []struct {
+		w     Webhook
+		event state.Event
+		match bool
+	}{
+		{all, state.Event{Type: state.EventRunStart, ExtensionName: "ext2"}, true},
+		{all, state.Event{Type: state.EventLog, ExtensionName: "ext2"}, false},
+		{filtered, state.Event{Type: state.EventRunStart, ExtensionName: "ext1"}, true},
+		{filtered, state.Event{Type: state.EventRunStart, ExtensionName: "ext2"}, false},
+		{filtered, state.Event{Type: state.EventRunStart, ExtensionName: "ext2", ExecutedByExtensionName: "ext1"}, true},
+		{filtered, state.Event{Type: state.EventStateStatus, ExtensionName: "ext1", Status: state.StateFAILED}, true},
+		{filtered, state.Event{Type: state.EventStateStatus, ExtensionName: "ext1", Status: state.StateSUCCEEDED}, false},
+		{filtered, state.Event{Type: state.EventRunEnd, ExtensionName: "ext1"}, false},
+	}
+	for _, test := range tests {
+		if test.w.Match(test.event) != test.match {
+			t.Errorf("Expected match %t for webhook %s and event %+v", test.match, test.w.Name, test.event)
+		}
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	t.Log("Entering... TestWebhookDelivery")
+	dir, err := ioutil.TempDir("", "TestWebhookDelivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	inst := state.NewInstance()
+	inst.SetConfigDir(dir)
+	m := NewManager(inst)
+	retryDelay := WebhookRetryDelay
+	WebhookRetryDelay = 10 * time.Millisecond
+	defer func() { WebhookRetryDelay = retryDelay }()
+	var calls int
+	var callsMux sync.Mutex
+	received := make(chan Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		callsMux.Lock()
+		calls++
+		call := calls
+		callsMux.Unlock()
+		//Fail the first attempt to test the retry
+		if call == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get(SignatureHeader) != "sha256="+Sign("secret", body) {
+			t.Error("Invalid signature " + req.Header.Get(SignatureHeader))
+		}
+		if req.Header.Get(EventHeader) != state.EventRunEnd {
+			t.Error("Unexpected event header " + req.Header.Get(EventHeader))
+		}
+		var payload Payload
+		json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer server.Close()
+	_, err = m.CreateWebhook(Webhook{Name: "delivery", URL: server.URL, ExtensionName: "ext-delivery", Events: []string{"run_end:SUCCEEDED"}, Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start()
+	inst.PublishEvent(state.Event{Type: state.EventRunStart, ExtensionName: "ext-delivery", Status: state.StateRUNNING})
+	inst.PublishEvent(state.Event{Type: state.EventRunEnd, ExtensionName: "ext-delivery", Status: state.StateSUCCEEDED})
+	select {
+	case payload := <-received:
+		if payload.Webhook != "delivery" || payload.Event.Type != state.EventRunEnd || payload.Event.ExtensionName != "ext-delivery" {
+			t.Errorf("Unexpected payload %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Payload not received")
+	}
+	var deliveries []Delivery
+	for i := 0; i < 50; i++ {
+		deliveries = m.GetDeliveries("delivery")
+		if len(deliveries) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(deliveries) != 1 || !deliveries[0].Delivered || deliveries[0].Attempts != 2 || deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("Unexpected deliveries %+v", deliveries)
+	}
+}
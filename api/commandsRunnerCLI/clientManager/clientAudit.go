@@ -0,0 +1,84 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package clientManager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/audit"
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+)
+
+//GetAudit returns the audit records matching the filter
+func (crc *CommandsRunnerClient) GetAudit(caller string, method string, endpoint string, extensionName string, status string, from string, to string, limit string) (string, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"caller":         caller,
+		"method":         method,
+		"endpoint":       endpoint,
+		"extension-name": extensionName,
+		"status":         status,
+		"from":           from,
+		"to":             to,
+		"limit":          limit,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	url := "audit"
+	if len(query) > 0 {
+		url += "?" + query.Encode()
+	}
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to get the audit records: " + data + ", please check log for more information")
+	}
+	//Generate the text format otherwize return the json
+	if crc.OutputFormat == "text" {
+		var records []audit.Record
+		jsonErr := json.Unmarshal([]byte(data), &records)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		out := ""
+		for _, record := range records {
+			out += fmt.Sprintf("%s %-20s %-15s %-6s %s", record.Timestamp, record.Caller, record.RemoteAddress, record.Method, record.Endpoint)
+			params := make([]string, 0)
+			for key, values := range record.Parameters {
+				params = append(params, key+"="+strings.Join(values, ","))
+			}
+			sort.Strings(params)
+			if len(params) > 0 {
+				out += " " + strings.Join(params, "&")
+			}
+			out += fmt.Sprintf(" => %d\n", record.Status)
+		}
+		return out, nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
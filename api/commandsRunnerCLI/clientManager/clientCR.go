@@ -31,7 +31,7 @@ import (
 //GetLogLevel of PCM
 func (crc *CommandsRunnerClient) GetCRLogLevel() (string, error) {
 	url := "cr/log/level"
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return data, err
 	}
@@ -56,7 +56,7 @@ func (crc *CommandsRunnerClient) GetCRLogLevel() (string, error) {
 
 func (crc *CommandsRunnerClient) SetCRLogLevel(level string) (string, error) {
 	url := "cr/log/level?level=" + level
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return data, err
 	}
@@ -69,7 +69,7 @@ func (crc *CommandsRunnerClient) SetCRLogLevel(level string) (string, error) {
 //GetCRLogMaxBackups of CR
 func (crc *CommandsRunnerClient) GetCRLogMaxBackups() (string, error) {
 	url := "cr/log/max-backups"
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return data, err
 	}
@@ -94,7 +94,7 @@ func (crc *CommandsRunnerClient) GetCRLogMaxBackups() (string, error) {
 
 func (crc *CommandsRunnerClient) SetCRLogMaxBackups(maxBackups string) (string, error) {
 	url := "cr/log/max-backups?max-backups=" + maxBackups
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return data, err
 	}
@@ -107,7 +107,7 @@ func (crc *CommandsRunnerClient) SetCRLogMaxBackups(maxBackups string) (string,
 //GetCRSettings of CR
 func (crc *CommandsRunnerClient) GetCRSettings() (string, error) {
 	url := "cr/settings"
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return data, err
 	}
@@ -132,7 +132,7 @@ func (crc *CommandsRunnerClient) GetCRSettings() (string, error) {
 //GetAbout of CR
 func (crc *CommandsRunnerClient) GetCRAbout() (string, error) {
 	url := "cr/about"
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return data, err
 	}
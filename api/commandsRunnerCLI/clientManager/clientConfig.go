@@ -29,13 +29,13 @@ import (
 )
 
 //GetConfig returns the config
-func (crc *CommandsRunnerClient) GetConfig(extensionName string) (string, error) {
-	url := "config"
+func (crc *CommandsRunnerClient) GetConfig(extensionName string, merged bool) (string, error) {
+	url := "config?merged=" + strconv.FormatBool(merged)
 	if extensionName != "" {
-		url += "?extension-name=" + extensionName
+		url += "&extension-name=" + extensionName
 	}
 	//Call the rest API
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, header, err := crc.restCallWithHeaders(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -56,6 +56,8 @@ func (crc *CommandsRunnerClient) GetConfig(extensionName string) (string, error)
 			fmt.Println(jsonErr.Error())
 			return "", jsonErr
 		}
+		//The ETag goes to stderr to keep the output parsable
+		fmt.Fprintf(os.Stderr, "ETag: %s\n", header.Get(global.ETagHeader))
 		out := ""
 		for k, v := range ps {
 			out += fmt.Sprintf("=>\n")
@@ -73,7 +75,7 @@ func (crc *CommandsRunnerClient) GetProperty(extensionName string, propertyName
 		url += "?extension-name=" + extensionName
 	}
 	//Call the rest API
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -113,8 +115,8 @@ func (crc *CommandsRunnerClient) GetProperty(extensionName string, propertyName
 	return crc.convertJSONOrYAML(data)
 }
 
-//SetConfig saves config
-func (crc *CommandsRunnerClient) SetConfig(extensionName string, configPath string) (string, error) {
+//SetConfig saves config, if ifMatch is not empty the config is saved only if its ETag didn't change
+func (crc *CommandsRunnerClient) SetConfig(extensionName string, configPath string, ifMatch string) (string, error) {
 	if configPath == "" {
 		errConfigPath := errors.New("config file missing")
 		return "", errConfigPath
@@ -128,14 +130,30 @@ func (crc *CommandsRunnerClient) SetConfig(extensionName string, configPath stri
 	if errFile != nil {
 		return "", errFile
 	}
-	data, errCode, err := crc.RestCall(http.MethodPost, global.BaseURL, url, file, nil)
+	data, errCode, err := crc.RestCall(http.MethodPost, global.BaseURLV2, url, file, ifMatchHeaders(ifMatch))
 	if err != nil {
 		return "", err
 	}
 	if errCode != http.StatusOK {
 		return "", errors.New("Unable to save the configuration: " + data + ", please check log for more information")
 	}
-	return "", nil
+	if data == "" {
+		return "", nil
+	}
+	//Convert to text otherwize return the json
+	if crc.OutputFormat == "text" {
+		cfg, jsonErr := config.ParseJson(data)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		invalidatedStates, _ := cfg.List("invalidated_states")
+		out := ""
+		for _, invalidatedState := range invalidatedStates {
+			out += fmt.Sprintf("State invalidated: %v\n", invalidatedState)
+		}
+		return out, nil
+	}
+	return crc.convertJSONOrYAML(data)
 }
 
 //ValidateConfig saves config
@@ -146,7 +164,7 @@ func (crc *CommandsRunnerClient) ValidateConfig(extensionName string) (string, e
 		url += "&extension-name=" + extensionName
 	}
 	//Call the rest API
-	data, errCode, errResp := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, errResp := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if errResp != nil {
 		return "", errResp
 	}
@@ -194,7 +212,7 @@ func (crc *CommandsRunnerClient) GenerateConfig(extensionName string) (string, e
 		url += "?extension-name=" + extensionName
 	}
 	//Call the rest API
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -39,7 +39,7 @@ func (crc *CommandsRunnerClient) Curl(method string, url string, dataPath string
 		}
 		file = fileOS
 	}
-	data, errCode, err := crc.RestCall(method, global.BaseURL, url, file, nil)
+	data, errCode, err := crc.RestCall(method, global.BaseURLV2, url, file, nil)
 	if err != nil {
 		return data, err
 	}
@@ -41,7 +41,7 @@ func (crc *CommandsRunnerClient) ResetEngine(extensionName string) (string, erro
 		url += "&extension-name=" + extensionName
 	}
 	//Call rest api
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -63,7 +63,7 @@ func (crc *CommandsRunnerClient) IsRunningEngine(extensionName string) (string,
 		url += "?extension-name=" + extensionName
 	}
 	//Call rest api
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -77,7 +77,8 @@ func (crc *CommandsRunnerClient) IsRunningEngine(extensionName string) (string,
 }
 
 //StartEngine returns the states
-func (crc *CommandsRunnerClient) StartEngine(extensionName string, fromState string, toState string) (string, error) {
+//the overlay is the config overlay to use for this run, if empty the server default is used.
+func (crc *CommandsRunnerClient) StartEngine(extensionName string, fromState string, toState string, overlay string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
@@ -92,8 +93,11 @@ func (crc *CommandsRunnerClient) StartEngine(extensionName string, fromState str
 	if toState != "" {
 		url += "&to-state=" + toState
 	}
+	if overlay != "" {
+		url += "&overlay=" + overlay
+	}
 	//Call rest api
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -112,7 +116,7 @@ func (crc *CommandsRunnerClient) SetMockEngine(mock bool) (string, error) {
 	//Build url
 	url := "engine?action=mock&mock=" + strconv.FormatBool(mock)
 	//Call rest api
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -126,7 +130,7 @@ func (crc *CommandsRunnerClient) GetMockEngine() (string, error) {
 	//Build url
 	url := "engine?action=mock"
 	//Call rest api
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
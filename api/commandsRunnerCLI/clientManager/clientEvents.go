@@ -0,0 +1,98 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package clientManager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+//subscribeEvents opens the server-sent events stream of an extension,
+//the returned channel is closed when the stream ends and the returned function closes the stream.
+func (crc *CommandsRunnerClient) subscribeEvents(extensionName string) (<-chan state.Event, func(), error) {
+	if extensionName == "" {
+		extensionName = crc.DefaultExtensionName
+	}
+	url := crc.requestURL + global.BaseURLV2 + "events?extension-name=" + extensionName
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if crc.Token != "" {
+		req.Header.Set("Authorization", "Token:"+crc.Token)
+	}
+	//The stream stays open as long as the run, so no timeout
+	client := crc.client
+	client.Timeout = 0
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return nil, nil, errors.New("Unable to subscribe to the events of " + extensionName + ": " + strings.TrimSpace(string(body)))
+	}
+	events := make(chan state.Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(res.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var data strings.Builder
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				//End of event
+				if data.Len() == 0 {
+					continue
+				}
+				var event state.Event
+				err := json.Unmarshal([]byte(data.String()), &event)
+				data.Reset()
+				if err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-done:
+					return
+				}
+			case strings.HasPrefix(line, "data:"):
+				if data.Len() > 0 {
+					data.WriteString("\n")
+				}
+				data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			}
+		}
+	}()
+	closeEvents := func() {
+		close(done)
+		res.Body.Close()
+	}
+	return events, closeEvents, nil
+}
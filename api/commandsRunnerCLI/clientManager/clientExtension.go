@@ -80,8 +80,14 @@ func (crc *CommandsRunnerClient) registerExtension(pathToZip, extensionName stri
 
 	headers["Content-Type"] = writer.FormDataContentType()
 
-	respBody, httpCode, err := crc.RestCall(http.MethodPost, global.BaseURL, url, body, headers)
-	if httpCode != http.StatusConflict && httpCode != http.StatusCreated && httpCode != http.StatusOK {
+	respBody, httpCode, err := crc.RestCall(http.MethodPost, global.BaseURLV2, url, body, headers)
+	if httpCode == http.StatusConflict {
+		return httpCode, nil
+	}
+	if httpCode != http.StatusCreated && httpCode != http.StatusOK {
+		if err != nil {
+			return httpCode, err
+		}
 		if respBody != "" {
 			return httpCode, errors.New(respBody)
 		}
@@ -103,7 +109,7 @@ func (crc *CommandsRunnerClient) registerExtension(pathToZip, extensionName stri
 	// 		return http.StatusInternalServerError, errFile
 	// 	}
 	// }
-	// body, httpCode, err := crc.RestCall(http.MethodPost, global.BaseURL, url, file, headers)
+	// body, httpCode, err := crc.RestCall(http.MethodPost, global.BaseURLV2, url, file, headers)
 	// if httpCode != http.StatusConflict && httpCode != http.StatusCreated && httpCode != http.StatusOK {
 	// 	if body != "" {
 	// 		return httpCode, errors.New(body)
@@ -120,7 +126,7 @@ func (crc *CommandsRunnerClient) unregisterExtension(extensionName string) error
 	}
 	url := "extension?extension-name=" + extensionName
 
-	response, errCode, err := crc.RestCall(http.MethodDelete, global.BaseURL, url, nil, nil)
+	response, errCode, err := crc.RestCall(http.MethodDelete, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return errors.New(err.Error())
 	}
@@ -22,23 +22,38 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
 )
 
-func (crc *CommandsRunnerClient) getExtensions(extensionsToList string, catalog bool) (string, error) {
-	url := "/extensions?filter=" + extensionsToList + "&catalog=" + strconv.FormatBool(catalog)
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+func (crc *CommandsRunnerClient) getExtensions(extensionsToList string, catalog bool, filters map[string]string) (string, error) {
+	query := url.Values{}
+	query.Set("filter", extensionsToList)
+	query.Set("catalog", strconv.FormatBool(catalog))
+	for key, value := range filters {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	url := "/extensions?" + query.Encode()
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
 	if errCode != http.StatusOK {
 		return "", errors.New("Unable to get extensions: " + data + ", please check log for more information")
 	}
 	return data, err
 }
 
-func (crc *CommandsRunnerClient) GetExtensions(extensionToList string, catalog bool) (string, error) {
-	data, err := crc.getExtensions(extensionToList, catalog)
+//GetExtensions returns the extensions matching the filters.
+//The filters are the extensions endpoint query parameters (name, offset, limit and fields), empty values are ignored.
+func (crc *CommandsRunnerClient) GetExtensions(extensionToList string, catalog bool, filters map[string]string) (string, error) {
+	data, err := crc.getExtensions(extensionToList, catalog, filters)
 	if err != nil {
 		return "", err
 	}
@@ -49,10 +64,15 @@ func (crc *CommandsRunnerClient) GetExtensions(extensionToList string, catalog b
 		if jsonErr != nil {
 			return "", jsonErr
 		}
-		for key, v := range extensions.Extensions {
+		keys := make([]string, 0)
+		for key := range extensions.Extensions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
 			out += fmt.Sprintf("=>\n")
 			out += fmt.Sprintf("name : %s\n", key)
-			out += fmt.Sprintf("type : %s\n", v.Type)
+			out += fmt.Sprintf("type : %s\n", extensions.Extensions[key].Type)
 		}
 		return out, nil
 	}
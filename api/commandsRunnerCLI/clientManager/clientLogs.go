@@ -23,7 +23,6 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
@@ -37,7 +36,7 @@ func (crc *CommandsRunnerClient) getLogsByChars(extensionName string, stateName
 	url := "state/" + stateName + "/log?extension-name=" + extensionName + "&first-char=" + strconv.FormatInt(firstChar, 10)
 	url += "&length=" + strconv.FormatInt(nbChar, 10)
 	//Call the rest API
-	data, _, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, _, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	return data, err
 }
 
@@ -58,62 +57,6 @@ func (crc *CommandsRunnerClient) getLogs(extensionName string, position int64, s
 	return currentPostion, nil
 }
 
-//Display ongoing logs
-// if quiet = true then log are not displayed but the method will wait until the deploy is done.
-func (crc *CommandsRunnerClient) follow(extensionName string, position int64, stateName string, quiet bool) error {
-	currentPostion := position
-	maxRetry := 12
-	var errLog error
-	//Loop until
-	for {
-		if maxRetry < 0 {
-			errLog = errors.New("Unable to retrieve logs more than 1 min")
-		}
-		time.Sleep(5 * time.Second)
-		var newPos int64
-		var err error
-		maxRetry = maxRetry - 1
-		if !quiet {
-			newPos, err = crc.getLogs(extensionName, currentPostion, stateName)
-			if err != nil {
-				fmt.Println("\nFailed to get logs (trying again):" + err.Error())
-				continue
-			}
-		}
-		if newPos == currentPostion {
-			fmt.Print(".")
-		}
-		if extensionName == "cr" {
-			currentPostion = newPos
-			continue
-		}
-		//Get last status
-		status, err := crc.getStateStatus(extensionName, stateName)
-		if err != nil {
-			fmt.Println("\nFailed to get Status (trying again):" + err.Error())
-			continue
-		}
-		maxRetry = 10
-		currentPostion = newPos
-		if status == state.StateSUCCEEDED {
-			break
-		}
-		if status == state.StateFAILED {
-			errLog = errors.New("\nDeployment of " + extensionName + " Failed, state: " + stateName)
-			break
-		}
-	}
-	//left over
-	time.Sleep(5 * time.Second)
-	if !quiet {
-		_, err := crc.getLogs(extensionName, currentPostion, stateName)
-		if err != nil {
-			fmt.Println("\nFailed to get logs left over:" + err.Error())
-		}
-	}
-	return errLog
-}
-
 func isStatePartOfTheCurrentRun(currentState state.State, states state.States) bool {
 	return currentState.ExecutedByExtensionName != "" &&
 		currentState.ExecutedByExtensionName == states.ExecutedByExtensionName &&
@@ -121,44 +64,8 @@ func isStatePartOfTheCurrentRun(currentState state.State, states state.States) b
 		currentState.ExecutionID == states.ExecutionID
 }
 
-func (crc *CommandsRunnerClient) searchNextStatePartOfTheCurrentRun(extensionName string, states state.States, startStateIndex int, endStateIndex int) (*state.State, int, error) {
-	var nextState *state.State
-	statesAux := states
-	nextStateIndex := -1
-	// fmt.Println("startStateIndex:" + strconv.Itoa(startStateIndex))
-	for {
-		for index, stateAux := range statesAux.StateArray[startStateIndex:endStateIndex] {
-			// fmt.Println("Index:" + strconv.Itoa(index))
-			if isStatePartOfTheCurrentRun(stateAux, statesAux) {
-				nextState = &stateAux
-				nextStateIndex = index + startStateIndex
-				// fmt.Println("nextStateIndex:" + strconv.Itoa(nextStateIndex))
-				break
-			}
-		}
-		if nextState != nil {
-			break
-		}
-		// fmt.Println("states.EndTime:" + statesAux.EndTime)
-		if statesAux.EndTime != "" {
-			return nil, -1, nil
-		}
-		time.Sleep(5 * time.Second)
-		//Retrieve list of states and unmarshal
-		data, err := crc.getRestStates(extensionName, "", false, false)
-		if err != nil {
-			return nil, -1, err
-		}
-		errUnMarshal := json.Unmarshal([]byte(data), &statesAux)
-		if errUnMarshal != nil {
-			return nil, -1, errUnMarshal
-		}
-	}
-	return nextState, nextStateIndex, nil
-}
-
 //GetLogs returns the logs of a given state, if state not provided will retrieve logs from the first state.
-// if follow = true the method will loop for new data in the log
+// if follow = true the method will stream the new log lines until the run ends
 // if quiet = true then log are not displayed but the method will wait until the deploy is done.
 func (crc *CommandsRunnerClient) GetLogs(extensionName string, stateName string, follow bool, quiet bool) error {
 	if extensionName == "" {
@@ -168,61 +75,15 @@ func (crc *CommandsRunnerClient) GetLogs(extensionName string, stateName string,
 		_, err := crc.getLogs(extensionName, 0, stateName)
 		return err
 	}
-	//Retrieve list of states and unmarshal
-	data, err := crc.getRestStates(extensionName, "", false, false)
+	if follow {
+		return crc.followEvents(extensionName, stateName, quiet)
+	}
+	states, startStateIndex, endStateIndex, err := crc.getStatesRange(extensionName, stateName)
 	if err != nil {
 		return err
 	}
-	var states state.States
-	errUnMarshal := json.Unmarshal([]byte(data), &states)
-	if errUnMarshal != nil {
-		return errUnMarshal
-	}
-	//Search the start and end index state to process
-	var startStateIndex int
-	var endStateIndex int
-	if stateName != "" {
-		for index, state := range states.StateArray {
-			if state.Name == stateName {
-				startStateIndex = index
-				endStateIndex = index + 1
-				break
-			}
-		}
-	} else {
-		startStateIndex = 0
-		endStateIndex = len(states.StateArray)
-	}
-	//Wait that the first states in the range ran or is running
-	if follow {
-		for {
-			statesStarted := false
-			for _, stateAux := range states.StateArray[startStateIndex:endStateIndex] {
-				if !isStatePartOfTheCurrentRun(stateAux, states) {
-					continue
-				}
-				statesStarted = true
-				break
-			}
-			if statesStarted {
-				break
-			}
-			time.Sleep(5 * time.Second)
-			//Retrieve list of states and unmarshal
-			data, err := crc.getRestStates(extensionName, "", false, false)
-			if err != nil {
-				return err
-			}
-			errUnMarshal := json.Unmarshal([]byte(data), &states)
-			if errUnMarshal != nil {
-				return errUnMarshal
-			}
-		}
-	}
-	var pos int64
-	var currentIndex int
 	//display the existing logs for all states
-	for index, currentState := range states.StateArray[startStateIndex:endStateIndex] {
+	for _, currentState := range states.StateArray[startStateIndex:endStateIndex] {
 		//Check if this state is not part of the current execution.
 		if !isStatePartOfTheCurrentRun(currentState, states) {
 			continue
@@ -236,13 +97,12 @@ func (crc *CommandsRunnerClient) GetLogs(extensionName string, stateName string,
 		if status == state.StateSUCCEEDED ||
 			status == state.StateRUNNING ||
 			status == state.StateFAILED {
-			currentIndex = index
-			pos, err = crc.getLogs(extensionName, 0, currentState.Name)
+			_, err = crc.getLogs(extensionName, 0, currentState.Name)
 			if err != nil {
 				return err
 			}
 		}
-		//if running or failed nothing else to display (-f will be managed bellow)
+		//if running or failed nothing else to display
 		if status == state.StateRUNNING {
 			break
 		}
@@ -250,36 +110,143 @@ func (crc *CommandsRunnerClient) GetLogs(extensionName string, stateName string,
 			return errors.New("\nDeployment of " + extensionName + " failed, state: " + currentState.Name)
 		}
 	}
-	currentIndex += startStateIndex
-	//if -f set follow up
-	if follow {
-		// manage the remaning logs
-		//		previousEndTime := time.Now()
-		for {
-			var newState *state.State
-			// fmt.Println("Before currentIndex:" + strconv.Itoa(currentIndex))
-			newState, currentIndex, err = crc.searchNextStatePartOfTheCurrentRun(extensionName, states, currentIndex, endStateIndex)
-			// fmt.Println("After currentIndex:" + strconv.Itoa(currentIndex))
+	if !quiet {
+		fmt.Println("")
+	}
+	return nil
+}
+
+//getStatesRange returns the states and the index range of the requested state, all states if the state name is empty
+func (crc *CommandsRunnerClient) getStatesRange(extensionName string, stateName string) (state.States, int, int, error) {
+	var states state.States
+	//Retrieve list of states and unmarshal
+	data, _, err := crc.getRestStates(extensionName, "", false, false, nil)
+	if err != nil {
+		return states, 0, 0, err
+	}
+	errUnMarshal := json.Unmarshal([]byte(data), &states)
+	if errUnMarshal != nil {
+		return states, 0, 0, errUnMarshal
+	}
+	//Search the start and end index state to process
+	if stateName != "" {
+		for index, state := range states.StateArray {
+			if state.Name == stateName {
+				return states, index, index + 1, nil
+			}
+		}
+		return states, 0, 0, nil
+	}
+	return states, 0, len(states.StateArray), nil
+}
+
+//followEvents displays the logs of the current run and then the new log lines received on the event stream until the run ends.
+// if quiet = true then log are not displayed but the method will wait until the deploy is done.
+func (crc *CommandsRunnerClient) followEvents(extensionName string, stateName string, quiet bool) error {
+	//Subscribe first to not miss the events sent while the existing logs are displayed
+	events, closeEvents, err := crc.subscribeEvents(extensionName)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+	states, startStateIndex, endStateIndex, err := crc.getStatesRange(extensionName, stateName)
+	if err != nil {
+		return err
+	}
+	//positions of the logs already displayed per state
+	positions := make(map[string]int64)
+	runStarted := false
+	runningState := ""
+	for _, currentState := range states.StateArray[startStateIndex:endStateIndex] {
+		if !isStatePartOfTheCurrentRun(currentState, states) {
+			continue
+		}
+		runStarted = true
+		if currentState.Status != state.StateSUCCEEDED &&
+			currentState.Status != state.StateRUNNING &&
+			currentState.Status != state.StateFAILED {
+			continue
+		}
+		if !quiet {
+			pos, err := crc.getLogs(extensionName, 0, currentState.Name)
 			if err != nil {
 				return err
 			}
-			if currentIndex == -1 {
-				break
+			positions[extensionName+"/"+currentState.Name] = pos
+		}
+		if currentState.Status == state.StateRUNNING {
+			runningState = currentState.Name
+			break
+		}
+		if currentState.Status == state.StateFAILED {
+			return errors.New("\nDeployment of " + extensionName + " failed, state: " + currentState.Name)
+		}
+		if currentState.Name == stateName {
+			return nil
+		}
+	}
+	if runStarted && states.Status != state.StateRUNNING {
+		if !quiet {
+			fmt.Println("")
+		}
+		return nil
+	}
+	for event := range events {
+		switch event.Type {
+		case state.EventLog:
+			if quiet {
+				continue
+			}
+			//The lines of the inserted extensions belong to the running state
+			if stateName != "" && event.ExtensionName == extensionName && event.StateName != stateName {
+				continue
+			}
+			if stateName != "" && event.ExtensionName != extensionName && runningState != stateName {
+				continue
+			}
+			key := event.ExtensionName + "/" + event.StateName
+			end := event.Position + int64(len(event.Line))
+			pos := positions[key]
+			if end <= pos {
+				continue
+			}
+			line := event.Line
+			if event.Position < pos {
+				line = line[pos-event.Position:]
+			}
+			fmt.Print(line)
+			positions[key] = end
+		case state.EventStateStatus:
+			if event.ExtensionName != extensionName {
+				continue
 			}
-			if newState.Status == state.StateSUCCEEDED ||
-				newState.Status == state.StateRUNNING ||
-				newState.Status == state.StateFAILED {
-				err := crc.follow(extensionName, pos, newState.Name, quiet)
-				if err != nil {
-					return err
+			switch event.Status {
+			case state.StateRUNNING:
+				runningState = event.StateName
+			case state.StateFAILED:
+				if stateName == "" || stateName == event.StateName {
+					return errors.New("\nDeployment of " + extensionName + " failed, state: " + event.StateName)
+				}
+			case state.StateSUCCEEDED:
+				if stateName == event.StateName {
+					if !quiet {
+						fmt.Println("")
+					}
+					return nil
 				}
 			}
-			currentIndex = currentIndex + 1
-			pos = 0
+		case state.EventRunEnd:
+			if event.ExtensionName != extensionName {
+				continue
+			}
+			if event.Status == state.StateFAILED {
+				return errors.New("\nDeployment of " + extensionName + " failed")
+			}
+			if !quiet {
+				fmt.Println("")
+			}
+			return nil
 		}
 	}
-	if !quiet {
-		fmt.Println("")
-	}
-	return nil
+	return errors.New("\nThe event stream of " + extensionName + " has been closed by the server")
 }
@@ -0,0 +1,72 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package clientManager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/state"
+)
+
+//GetRuns returns the run history of an extension, the most recent run first
+func (crc *CommandsRunnerClient) GetRuns(extensionName string, status string, limit string) (string, error) {
+	if extensionName == "" {
+		extensionName = crc.DefaultExtensionName
+	}
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"extension-name": extensionName,
+		"status":         status,
+		"limit":          limit,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	url := "runs?" + query.Encode()
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to get the runs: " + data + ", please check log for more information")
+	}
+	//Generate the text format otherwize return the json
+	if crc.OutputFormat == "text" {
+		var runs state.Runs
+		jsonErr := json.Unmarshal([]byte(data), &runs)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		out := ""
+		for _, run := range runs.Runs {
+			out += fmt.Sprintf("%s %s %s %-9s %s..%s", run.StartTime, run.EndTime, run.ID, run.Status, run.FromState, run.ToState)
+			if run.Reason != "" {
+				out += " " + run.Reason
+			}
+			out += "\n"
+		}
+		return out, nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
@@ -32,7 +32,7 @@ import (
 )
 
 //SetAPISetup set the API configuration
-func SetClientSetup(urlIn string, outputFormat string, timeout string, caCertPath string, insecureSSL string, token string, defaultExtensionName string) error {
+func SetClientSetup(urlIn string, outputFormat string, timeout string, caCertPath string, clientCertPath string, clientKeyPath string, insecureSSL string, token string, defaultExtensionName string) error {
 	var clientManager CommandsRunnerClient
 	//Read existing
 	data, errFile := ioutil.ReadFile(configFilePath)
@@ -80,6 +80,19 @@ func SetClientSetup(urlIn string, outputFormat string, timeout string, caCertPat
 			finalCACertPath = caCertPath
 		}
 	}
+	var finalClientCertPath, finalClientKeyPath string
+	if clientCertPath != "" {
+		finalClientCertPath, err = filepath.Abs(clientCertPath)
+		if err != nil {
+			finalClientCertPath = clientCertPath
+		}
+	}
+	if clientKeyPath != "" {
+		finalClientKeyPath, err = filepath.Abs(clientKeyPath)
+		if err != nil {
+			finalClientKeyPath = clientKeyPath
+		}
+	}
 	var insecureSSLBool bool
 	if insecureSSL != "" {
 		insecureSSLBool, err = strconv.ParseBool(insecureSSL)
@@ -100,6 +113,12 @@ func SetClientSetup(urlIn string, outputFormat string, timeout string, caCertPat
 	if caCertPath != "" {
 		clientManager.CACertPath = finalCACertPath
 	}
+	if clientCertPath != "" {
+		clientManager.ClientCertPath = finalClientCertPath
+	}
+	if clientKeyPath != "" {
+		clientManager.ClientKeyPath = finalClientKeyPath
+	}
 	if insecureSSL != "" {
 		clientManager.InsecureSSL = insecureSSLBool
 	}
@@ -126,7 +145,7 @@ func SetClientSetup(urlIn string, outputFormat string, timeout string, caCertPat
 		return err
 	}
 	insecureSSL = strconv.FormatBool(u.Scheme != "https")
-	client, err := NewClient("", "", "", "", insecureSSL, "", "")
+	client, err := NewClient("", "", "", "", "", "", insecureSSL, "", "")
 	_, errStatus := client.GetCMStatus()
 	if errStatus != nil {
 		return errors.New("wrong url, certificate or token or API server not ready yet:" + errStatus.Error())
@@ -160,6 +179,8 @@ func GetClientSetup(outputFormat string) (string, error) {
 		out += fmt.Sprintf("Format:  %s\n", clientManager.OutputFormat)
 		out += fmt.Sprintf("Timeout: %d\n", clientManager.Timeout)
 		out += fmt.Sprintf("CACertPath: %s\n", clientManager.CACertPath)
+		out += fmt.Sprintf("ClientCertPath: %s\n", clientManager.ClientCertPath)
+		out += fmt.Sprintf("ClientKeyPath: %s\n", clientManager.ClientKeyPath)
 		out += fmt.Sprintf("InsecureSSL: %t\n", clientManager.InsecureSSL)
 		out += fmt.Sprintf("Token: %s\n", clientManager.Token)
 		out += fmt.Sprintf("DefaultExtensionName: %s\n", clientManager.DefaultExtensionName)
@@ -34,7 +34,10 @@ func (crc *CommandsRunnerClient) getState(extensionName string, stateName string
 		url += "?extension-name=" + extensionName
 	}
 	//Call the rest api
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
 	if errCode != http.StatusOK {
 		return "", errors.New("Unable to get states: " + data + ", please check log for more information")
 	}
@@ -109,7 +112,7 @@ func (crc *CommandsRunnerClient) SetState(extensionName string, stateName string
 		url += "&extension-name=" + extensionName
 	}
 	//Call the rest api
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return err
 	}
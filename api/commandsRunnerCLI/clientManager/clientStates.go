@@ -23,43 +23,80 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 	"github.com/IBM/commands-runner/api/commandsRunner/state"
 )
 
-func (crc *CommandsRunnerClient) getRestStates(extensionName string, status string, extensionOnly bool, recursive bool) (string, error) {
+func (crc *CommandsRunnerClient) getRestStates(extensionName string, status string, extensionOnly bool, recursive bool, filters map[string]string) (string, string, error) {
 	//build url
-	url := "states"
-	url += "?extensions-only=" + strconv.FormatBool(extensionOnly)
-	url += "&recursive=" + strconv.FormatBool(recursive)
+	query := url.Values{}
+	query.Set("extensions-only", strconv.FormatBool(extensionOnly))
+	query.Set("recursive", strconv.FormatBool(recursive))
 	if extensionName != "" {
-		url += "&extension-name=" + extensionName
+		query.Set("extension-name", extensionName)
 	}
 	if status != "" {
-		url += "&status=" + status
+		query.Set("status", status)
+	}
+	for key, value := range filters {
+		if value != "" {
+			query.Set(key, value)
+		}
 	}
+	url := "states?" + query.Encode()
 	//Call rest api
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, header, err := crc.restCallWithHeaders(http.MethodGet, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", "", err
+	}
 	if errCode != http.StatusOK {
-		return "", errors.New("Unable to get states: " + data + ", please check log for more information")
+		return "", "", errors.New("Unable to get states: " + data + ", please check log for more information")
 	}
-	return data, err
+	return data, header.Get(global.ETagHeader), err
 }
 
 //GetStates returns the states having a specific status
 func (crc *CommandsRunnerClient) GetStates(extensionName string, status string, extensionOnly bool, recursive bool) (string, error) {
+	return crc.FindStates(extensionName, status, extensionOnly, recursive, nil)
+}
+
+//FindStates returns the states having a specific status and matching the filters.
+//The filters are the states endpoint query parameters (name, label, is-extension, started-after, started-before,
+//ended-after, ended-before, sort, offset, limit and fields), empty values are ignored.
+func (crc *CommandsRunnerClient) FindStates(extensionName string, status string, extensionOnly bool, recursive bool, filters map[string]string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
-	data, err := crc.getRestStates(extensionName, status, extensionOnly, recursive)
+	data, etag, err := crc.getRestStates(extensionName, status, extensionOnly, recursive, filters)
 	if err != nil {
 		return "", err
 	}
 
 	//Convert to text otherwize return the json
+	if crc.OutputFormat == "text" && filters["fields"] != "" {
+		var states struct {
+			StateArray []map[string]interface{} `json:"states"`
+		}
+		jsonErr := json.Unmarshal([]byte(data), &states)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		//The ETag goes to stderr to keep the output parsable
+		fmt.Fprintf(os.Stderr, "ETag: %s\n", etag)
+		out := ""
+		for _, state := range states.StateArray {
+			out += fmt.Sprintf("=>\n")
+			for _, field := range strings.Split(filters["fields"], ",") {
+				out += fmt.Sprintf("%-25s: %v\n", field, state[field])
+			}
+		}
+		return out, nil
+	}
 	if crc.OutputFormat == "text" {
 		var states state.States
 		jsonErr := json.Unmarshal([]byte(data), &states)
@@ -67,6 +104,7 @@ func (crc *CommandsRunnerClient) GetStates(extensionName string, status string,
 			fmt.Println(jsonErr.Error())
 			return "", jsonErr
 		}
+		fmt.Fprintf(os.Stderr, "ETag: %s\n", etag)
 		out := ""
 		for _, state := range states.StateArray {
 			out += fmt.Sprintf("=>\n")
@@ -92,8 +130,8 @@ func (crc *CommandsRunnerClient) GetStates(extensionName string, status string,
 	return crc.convertJSONOrYAML(data)
 }
 
-//Set a state file
-func (crc *CommandsRunnerClient) SetStates(extensionName string, statesPath string, overwrite bool) (string, error) {
+//Set a state file, if ifMatch is not empty the states are saved only if their ETag didn't change
+func (crc *CommandsRunnerClient) SetStates(extensionName string, statesPath string, overwrite bool, ifMatch string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
@@ -112,7 +150,7 @@ func (crc *CommandsRunnerClient) SetStates(extensionName string, statesPath stri
 	}
 	file = fileOS
 	//Call the rest API
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, file, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, file, ifMatchHeaders(ifMatch))
 	if err != nil {
 		return "", err
 	}
@@ -122,8 +160,8 @@ func (crc *CommandsRunnerClient) SetStates(extensionName string, statesPath stri
 	return "", nil
 }
 
-//Set a state file
-func (crc *CommandsRunnerClient) SetStatesStatuses(extensionName string, newStatus string, fromState string, fromInclude bool, toState string, toInclude bool) (string, error) {
+//Set the status of a range of states, if ifMatch is not empty the statuses are set only if the states ETag didn't change
+func (crc *CommandsRunnerClient) SetStatesStatuses(extensionName string, newStatus string, fromState string, fromInclude bool, toState string, toInclude bool, ifMatch string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
@@ -142,7 +180,7 @@ func (crc *CommandsRunnerClient) SetStatesStatuses(extensionName string, newStat
 		url += "&to-state-name=" + toState + "&to-include=" + strconv.FormatBool(toInclude)
 	}
 	//Call the rest API
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, ifMatchHeaders(ifMatch))
 	if err != nil {
 		return "", err
 	}
@@ -156,7 +194,7 @@ func (crc *CommandsRunnerClient) SetStatesStatuses(extensionName string, newStat
 	return data, nil
 }
 
-func (crc *CommandsRunnerClient) InsertStateStates(extensionName string, pos int, stateName string, before bool, statePath string, insertExtensionName string, overwrite bool) (string, error) {
+func (crc *CommandsRunnerClient) InsertStateStates(extensionName string, pos int, stateName string, before bool, statePath string, insertExtensionName string, overwrite bool, ifMatch string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
@@ -185,7 +223,7 @@ func (crc *CommandsRunnerClient) InsertStateStates(extensionName string, pos int
 	} else {
 		file = nil
 	}
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, file, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, file, ifMatchHeaders(ifMatch))
 	if err != nil {
 		return "", err
 	}
@@ -199,7 +237,7 @@ func (crc *CommandsRunnerClient) InsertStateStates(extensionName string, pos int
 	return data, nil
 }
 
-func (crc *CommandsRunnerClient) DeleteStateStates(extensionName string, pos int, stateName string) (string, error) {
+func (crc *CommandsRunnerClient) DeleteStateStates(extensionName string, pos int, stateName string, ifMatch string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
@@ -211,7 +249,7 @@ func (crc *CommandsRunnerClient) DeleteStateStates(extensionName string, pos int
 		url += "&state-name=" + stateName
 	}
 	//Call the rest API
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, ifMatchHeaders(ifMatch))
 	if err != nil {
 		return "", err
 	}
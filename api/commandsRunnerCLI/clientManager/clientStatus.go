@@ -31,7 +31,7 @@ import (
 //GetCMStatus returns the configManager status
 func (crc *CommandsRunnerClient) GetCMStatus() (string, error) {
 	url := "status"
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -59,7 +59,7 @@ func (crc *CommandsRunnerClient) GetCMStatus() (string, error) {
 //SetCMStatus set a status
 func (crc *CommandsRunnerClient) SetCMStatus(name string, status string) (string, error) {
 	url := "status?name=" + url.QueryEscape(name) + "&status=" + url.QueryEscape(status)
-	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodPut, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -30,7 +30,7 @@ func (crc *CommandsRunnerClient) GetTemplate(extensionName string, uiConfigName
 		extensionName = crc.DefaultExtensionName
 	}
 	url := "template?extension-name=" + extensionName + "&ui-metadata-name=" + uiConfigName
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -20,9 +20,18 @@ package clientManager
 import (
 	"crypto/sha512"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/token"
 )
 
 //NewToken creates Token base on time
@@ -38,3 +47,91 @@ func NewTokenFile(filePath string) error {
 	token := NewToken()
 	return ioutil.WriteFile(filePath, []byte(token), 0644)
 }
+
+//CreateAPIToken creates a named token with a role on the server, the token value is only returned at creation.
+func (crc *CommandsRunnerClient) CreateAPIToken(name string, role string, extensions string, expiresIn string) (string, error) {
+	url := "token?name=" + url.QueryEscape(name) + "&role=" + url.QueryEscape(role)
+	if extensions != "" {
+		url += "&extensions=" + extensions
+	}
+	if expiresIn != "" {
+		url += "&expires-in=" + expiresIn
+	}
+	data, errCode, err := crc.RestCall(http.MethodPost, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to create token: " + data + ", please check log for more information")
+	}
+	return crc.convertTokenValue(data)
+}
+
+//RotateAPIToken issues a new value for a token, the previous value stays valid during the overlap.
+func (crc *CommandsRunnerClient) RotateAPIToken(name string, overlap string, expiresIn string) (string, error) {
+	url := "token?action=rotate&name=" + url.QueryEscape(name)
+	if overlap != "" {
+		url += "&overlap=" + overlap
+	}
+	if expiresIn != "" {
+		url += "&expires-in=" + expiresIn
+	}
+	data, errCode, err := crc.RestCall(http.MethodPost, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to rotate token: " + data + ", please check log for more information")
+	}
+	return crc.convertTokenValue(data)
+}
+
+//convertTokenValue returns the token value in text format otherwize the json or yaml
+func (crc *CommandsRunnerClient) convertTokenValue(data string) (string, error) {
+	if crc.OutputFormat == "text" {
+		var t token.Token
+		jsonErr := json.Unmarshal([]byte(data), &t)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		return t.Token + "\n", nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
+
+//ListAPITokens lists the tokens defined on the server
+func (crc *CommandsRunnerClient) ListAPITokens() (string, error) {
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, "tokens", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to list tokens: " + data + ", please check log for more information")
+	}
+	if crc.OutputFormat == "text" {
+		var tokens []token.Token
+		jsonErr := json.Unmarshal([]byte(data), &tokens)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		out := fmt.Sprintf("%-20s %-10s %-25s %s\n", "NAME", "ROLE", "EXPIRES AT", "EXTENSIONS")
+		for _, t := range tokens {
+			out += fmt.Sprintf("%-20s %-10s %-25s %s\n", t.Name, t.Role, t.ExpiresAt, strings.Join(t.Extensions, ","))
+		}
+		return out, nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
+
+//RevokeAPIToken revokes a token on the server
+func (crc *CommandsRunnerClient) RevokeAPIToken(name string) (string, error) {
+	url := "token?name=" + url.QueryEscape(name)
+	data, errCode, err := crc.RestCall(http.MethodDelete, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to revoke token: " + data + ", please check log for more information")
+	}
+	return "", nil
+}
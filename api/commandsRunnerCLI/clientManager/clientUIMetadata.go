@@ -26,18 +26,25 @@ import (
 )
 
 //GetUIConfig reeturns the uiconfig
-func (crc *CommandsRunnerClient) GetUIMetadata(extensionName string, uiConfigName string) (string, error) {
+//if format is "jsonschema" the JSON Schema of the config is returned as is.
+func (crc *CommandsRunnerClient) GetUIMetadata(extensionName string, uiConfigName string, format string) (string, error) {
 	if extensionName == "" {
 		extensionName = crc.DefaultExtensionName
 	}
 	url := "uimetadata?extension-name=" + extensionName + "&ui-metadata-name=" + uiConfigName
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	if format != "" {
+		url += "&format=" + format
+	}
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
 	if errCode != http.StatusOK {
 		return "", errors.New("Unable to get the ui metadata: " + data + ", please check logs for more details")
 	}
+	if format == "jsonschema" {
+		return data, nil
+	}
 	return crc.convertJSONOrYAML(data)
 }
 
@@ -46,7 +53,7 @@ func (crc *CommandsRunnerClient) GetUIMetadatas(extensionName string, namesOnly
 	if extensionName != "" {
 		url += "&extension-name=" + extensionName
 	}
-	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURL, url, nil, nil)
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, url, nil, nil)
 	if err != nil {
 		return "", err
 	}
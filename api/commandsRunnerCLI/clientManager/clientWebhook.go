@@ -0,0 +1,130 @@
+/*
+################################################################################
+# Copyright 2019 IBM Corp. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+################################################################################
+*/
+package clientManager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/IBM/commands-runner/api/commandsRunner/global"
+	"github.com/IBM/commands-runner/api/commandsRunner/webhook"
+)
+
+//CreateWebhook registers a webhook on the server, events is a comma separated list of event filters.
+//The secret is generated by the server if not provided and only returned at creation.
+func (crc *CommandsRunnerClient) CreateWebhook(name string, webhookURL string, extensionName string, events string, secret string) (string, error) {
+	w := webhook.Webhook{
+		Name:          name,
+		URL:           webhookURL,
+		ExtensionName: extensionName,
+		Secret:        secret,
+	}
+	if events != "" {
+		w.Events = strings.Split(events, ",")
+	}
+	body, err := json.Marshal(w)
+	if err != nil {
+		return "", err
+	}
+	data, errCode, err := crc.RestCall(http.MethodPost, global.BaseURLV2, "webhooks", bytes.NewBuffer(body), nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to create webhook: " + data + ", please check log for more information")
+	}
+	if crc.OutputFormat == "text" {
+		var created webhook.Webhook
+		jsonErr := json.Unmarshal([]byte(data), &created)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		return created.Secret + "\n", nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
+
+//ListWebhooks lists the webhooks defined on the server
+func (crc *CommandsRunnerClient) ListWebhooks() (string, error) {
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, "webhooks", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to list webhooks: " + data + ", please check log for more information")
+	}
+	if crc.OutputFormat == "text" {
+		var webhooks []webhook.Webhook
+		jsonErr := json.Unmarshal([]byte(data), &webhooks)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		out := fmt.Sprintf("%-20s %-20s %-40s %-9s %s\n", "NAME", "EXTENSION", "URL", "READ ONLY", "EVENTS")
+		for _, w := range webhooks {
+			out += fmt.Sprintf("%-20s %-20s %-40s %-9t %s\n", w.Name, w.ExtensionName, w.URL, w.ReadOnly, strings.Join(w.Events, ","))
+		}
+		return out, nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
+
+//DeleteWebhook deletes a webhook registered on the server
+func (crc *CommandsRunnerClient) DeleteWebhook(name string) (string, error) {
+	url := "webhooks?name=" + url.QueryEscape(name)
+	data, errCode, err := crc.RestCall(http.MethodDelete, global.BaseURLV2, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to delete webhook: " + data + ", please check log for more information")
+	}
+	return "", nil
+}
+
+//GetWebhookDeliveries returns the delivery log of a webhook, all webhooks if name is empty
+func (crc *CommandsRunnerClient) GetWebhookDeliveries(name string) (string, error) {
+	uri := "webhooks?action=deliveries"
+	if name != "" {
+		uri += "&name=" + url.QueryEscape(name)
+	}
+	data, errCode, err := crc.RestCall(http.MethodGet, global.BaseURLV2, uri, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if errCode != http.StatusOK {
+		return "", errors.New("Unable to get the webhook deliveries: " + data + ", please check log for more information")
+	}
+	if crc.OutputFormat == "text" {
+		var deliveries []webhook.Delivery
+		jsonErr := json.Unmarshal([]byte(data), &deliveries)
+		if jsonErr != nil {
+			return "", jsonErr
+		}
+		out := fmt.Sprintf("%-8s %-25s %-20s %-20s %-12s %-8s %-9s %s\n", "ID", "TIMESTAMP", "WEBHOOK", "EXTENSION", "EVENT", "ATTEMPTS", "DELIVERED", "ERROR")
+		for _, d := range deliveries {
+			out += fmt.Sprintf("%-8d %-25s %-20s %-20s %-12s %-8d %-9t %s\n", d.ID, d.Timestamp, d.Webhook, d.ExtensionName, d.EventType, d.Attempts, d.Delivered, d.Error)
+		}
+		return out, nil
+	}
+	return crc.convertJSONOrYAML(data)
+}
@@ -18,19 +18,25 @@
 package clientManager
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/IBM/commands-runner/api/commandsRunner/apiError"
 	"github.com/IBM/commands-runner/api/commandsRunner/global"
 )
 
@@ -54,12 +60,17 @@ type CommandsRunnerClient struct {
 	OutputFormat         string `json:"output_format"`
 	Timeout              int    `json:"timeout"`
 	CACertPath           string `json:"ca_cert_path"`
+	ClientCertPath       string `json:"client_cert_path,omitempty"`
+	ClientKeyPath        string `json:"client_key_path,omitempty"`
 	Token                string `json:"token"`
 	InsecureSSL          bool
 	rootCertPEM          []byte
 	protocol             string
+	requestURL           string
 	client               http.Client
 	DefaultExtensionName string `json:"default_extension_name"`
+	//v1Only set when the server doesn't serve the v2 api, the next calls use the v1 api
+	v1Only bool
 }
 
 func init() {
@@ -70,7 +81,7 @@ func init() {
 }
 
 //NewClient creates a new client
-func NewClient(urlIn string, outputFormat string, timeout string, caCertPath string, insecureSSL string, token string, defaultExtensionName string) (*CommandsRunnerClient, error) {
+func NewClient(urlIn string, outputFormat string, timeout string, caCertPath string, clientCertPath string, clientKeyPath string, insecureSSL string, token string, defaultExtensionName string) (*CommandsRunnerClient, error) {
 	var c *CommandsRunnerClient
 	//Set the default values
 	cd := &CommandsRunnerClient{
@@ -108,6 +119,12 @@ func NewClient(urlIn string, outputFormat string, timeout string, caCertPath str
 	if token != "" {
 		c.Token = token
 	}
+	if clientCertPath != "" {
+		c.ClientCertPath = clientCertPath
+	}
+	if clientKeyPath != "" {
+		c.ClientKeyPath = clientKeyPath
+	}
 	if defaultExtensionName != "" {
 		c.DefaultExtensionName = defaultExtensionName
 	}
@@ -117,6 +134,7 @@ func NewClient(urlIn string, outputFormat string, timeout string, caCertPath str
 		return nil, err
 	}
 	c.protocol = u.Scheme
+	c.requestURL = c.URL
 	insecureSSLBool := c.InsecureSSL
 	if insecureSSL != "" {
 		insecureSSLBool, err = strconv.ParseBool(insecureSSL)
@@ -156,12 +174,38 @@ func NewClient(urlIn string, outputFormat string, timeout string, caCertPath str
 				InsecureSkipVerify: true,
 			}
 		}
+		//Add the client certificate for the mutual TLS authentication
+		if c.ClientCertPath != "" || c.ClientKeyPath != "" {
+			if c.ClientCertPath == "" || c.ClientKeyPath == "" {
+				return nil, errors.New("Both client certificate and client key must be provided")
+			}
+			clientCert, err := tls.LoadX509KeyPair(c.ClientCertPath, c.ClientKeyPath)
+			if err != nil {
+				log.Print(err.Error())
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{clientCert}
+		}
 		transport := &http.Transport{TLSClientConfig: tlsConfig}
 		//Create http client with a specific timeout and transport
 		c.client = http.Client{
 			Timeout:   time.Second * time.Duration(c.Timeout), // Maximum of 2 secs
 			Transport: transport,
 		}
+	} else if c.protocol == "unix" {
+		//The requests are sent over the unix socket unix:///path, the host of the request url is not used
+		socketPath := u.Path
+		c.requestURL = "http://unix"
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}
+		c.client = http.Client{
+			Timeout:   time.Second * time.Duration(c.Timeout),
+			Transport: transport,
+		}
 	} else {
 		//Create http client with a specific timeout and transport
 		c.client = http.Client{
@@ -174,13 +218,56 @@ func NewClient(urlIn string, outputFormat string, timeout string, caCertPath str
 
 //Do a restcall to a given uri
 func (crc *CommandsRunnerClient) RestCall(method string, baseUrl string, uri string, bodyReader io.Reader, headers map[string]string) (string, int, error) {
+	data, code, _, err := crc.restCallWithHeaders(method, baseUrl, uri, bodyReader, headers)
+	return data, code, err
+}
+
+//Do a restcall to a given uri and return the response headers.
+//A v2 call is retried on the v1 api if the server doesn't serve the v2 api.
+func (crc *CommandsRunnerClient) restCallWithHeaders(method string, baseUrl string, uri string, bodyReader io.Reader, headers map[string]string) (string, int, http.Header, error) {
+	if baseUrl != global.BaseURLV2 {
+		return crc.doRestCall(method, baseUrl, uri, bodyReader, headers)
+	}
+	if crc.v1Only {
+		return crc.doRestCall(method, global.BaseURL, uri, bodyReader, headers)
+	}
+	//keep the body to resend it on the v1 api
+	var body []byte
+	if bodyReader != nil {
+		var err error
+		body, err = ioutil.ReadAll(bodyReader)
+		if err != nil {
+			return "", http.StatusInternalServerError, nil, err
+		}
+	}
+	data, code, header, err := crc.doRestCall(method, baseUrl, uri, newBodyReader(body, bodyReader), headers)
+	//A server without v2 api answers 404 without json error
+	if code == http.StatusNotFound {
+		if _, ok := err.(*apiError.Error); !ok {
+			crc.v1Only = true
+			return crc.doRestCall(method, global.BaseURL, uri, newBodyReader(body, bodyReader), headers)
+		}
+	}
+	return data, code, header, err
+}
+
+//newBodyReader returns a reader on the body or nil if there is no body
+func newBodyReader(body []byte, bodyReader io.Reader) io.Reader {
+	if bodyReader == nil {
+		return nil
+	}
+	return bytes.NewReader(body)
+}
+
+//doRestCall executes the restcall on the base url
+func (crc *CommandsRunnerClient) doRestCall(method string, baseUrl string, uri string, bodyReader io.Reader, headers map[string]string) (string, int, http.Header, error) {
 
 	//add the base url to the uri
-	url := crc.URL + baseUrl + uri
+	url := crc.requestURL + baseUrl + uri
 
 	req, err := http.NewRequest(method, url, bodyReader)
 	if err != nil {
-		return "", http.StatusInternalServerError, err
+		return "", http.StatusInternalServerError, nil, err
 	}
 	//Prepare the requested
 	for key, value := range headers {
@@ -194,13 +281,13 @@ func (crc *CommandsRunnerClient) RestCall(method string, baseUrl string, uri str
 	//if not set get socket remaning open.
 	req.Close = true
 	if err != nil {
-		return "", http.StatusInternalServerError, err
+		return "", http.StatusInternalServerError, nil, err
 	}
 	//Execute the request
 	res, getErr := crc.client.Do(req)
 	if getErr != nil {
 		fmt.Printf(getErr.Error())
-		return "", http.StatusInternalServerError, getErr
+		return "", http.StatusInternalServerError, nil, getErr
 	}
 	//Close request boddy.
 	if req.Body != nil {
@@ -213,8 +300,34 @@ func (crc *CommandsRunnerClient) RestCall(method string, baseUrl string, uri str
 
 	if readErr != nil {
 		fmt.Printf(readErr.Error())
-		return "", res.StatusCode, readErr
+		return "", res.StatusCode, res.Header, readErr
+	}
+
+	//The v2 api returns the errors as json, surface them as *apiError.Error
+	if apiErr := parseAPIError(res, body); apiErr != nil {
+		return string(body), res.StatusCode, res.Header, apiErr
+	}
+
+	return string(body), res.StatusCode, res.Header, err
+}
+
+//ifMatchHeaders returns the If-Match header of an update, nil if the etag is empty
+func ifMatchHeaders(ifMatch string) map[string]string {
+	if ifMatch == "" {
+		return nil
 	}
+	return map[string]string{global.IfMatchHeader: ifMatch}
+}
 
-	return string(body), res.StatusCode, err
+//parseAPIError returns the json error of the response or nil if the response is not a json error
+func parseAPIError(res *http.Response, body []byte) *apiError.Error {
+	if res.StatusCode < http.StatusBadRequest || !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	var apiErr apiError.Error
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Code == "" {
+		return nil
+	}
+	apiErr.Status = res.StatusCode
+	return &apiErr
 }
@@ -20,8 +20,10 @@ package commandsRunnerCLI
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	cli "gopkg.in/urfave/cli.v1"
 
@@ -41,6 +43,8 @@ var URL string
 var OutputFormat string
 var Timeout string
 var CACertPath string
+var ClientCertPath string
+var ClientKeyPath string
 var InsecureSSL string
 var Token string
 var DefaultExtensionName string
@@ -52,8 +56,14 @@ func Client() *cli.App {
 	var configPath string
 	var searchStatus string
 	var fromState, toState string
+	var configOverlay string
 	var extensionName string
 	var tokenOutputFilePath string
+	var tokenName string
+	var tokenRole string
+	var tokenExtensions string
+	var tokenExpiresIn string
+	var tokenOverlap string
 	var extensionsToList string
 	var extensionZipPath string
 	var statesPath string
@@ -61,6 +71,7 @@ func Client() *cli.App {
 	var insertExtensionName string
 	var statePosition int
 	var stateName string
+	var ifMatch string
 
 	var curlMethod string
 	var curlDataPath string
@@ -72,11 +83,20 @@ func Client() *cli.App {
 	var status string
 
 	var uiMetadataName string
+	var uiMetadataFormat string
 
 	var propertyName string
 
+	var auditCaller, auditMethod, auditEndpoint, auditStatus, auditFrom, auditTo, auditLimit string
+
+	var runsStatus, runsLimit string
+
+	var webhookName, webhookURL, webhookEvents, webhookSecret string
+
+	var filterName, filterLabel, filterIsExtension, filterStartedAfter, filterStartedBefore, filterEndedAfter, filterEndedBefore, filterSort, filterOffset, filterLimit, filterFields string
+
 	getStatus := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -91,7 +111,7 @@ func Client() *cli.App {
 	}
 
 	setStatus := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -116,7 +136,7 @@ func Client() *cli.App {
 	}
 
 	setClientSetup := func(c *cli.Context) error {
-		errClient := clientManager.SetClientSetup(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		errClient := clientManager.SetClientSetup(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -134,6 +154,24 @@ func Client() *cli.App {
 	}
 
 	createToken := func(c *cli.Context) error {
+		//A named token is created on the server
+		if tokenName != "" {
+			client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+			if errClient != nil {
+				fmt.Println(errClient.Error())
+				return errClient
+			}
+			data, err := client.CreateAPIToken(tokenName, tokenRole, tokenExtensions, tokenExpiresIn)
+			if err != nil {
+				fmt.Println(err.Error())
+				return err
+			}
+			if tokenOutputFilePath != "" {
+				return ioutil.WriteFile(tokenOutputFilePath, []byte(strings.TrimSuffix(data, "\n")), 0600)
+			}
+			fmt.Print(data)
+			return nil
+		}
 		if tokenOutputFilePath != "" {
 			return clientManager.NewTokenFile(tokenOutputFilePath)
 		}
@@ -142,8 +180,143 @@ func Client() *cli.App {
 		return nil
 	}
 
+	listTokens := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.ListAPITokens()
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	rotateToken := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.RotateAPIToken(tokenName, tokenOverlap, tokenExpiresIn)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	revokeToken := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.RevokeAPIToken(tokenName)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	createWebhook := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.CreateWebhook(webhookName, webhookURL, extensionName, webhookEvents, webhookSecret)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	listWebhooks := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.ListWebhooks()
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	deleteWebhook := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.DeleteWebhook(webhookName)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	getWebhookDeliveries := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.GetWebhookDeliveries(webhookName)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	getAudit := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.GetAudit(auditCaller, auditMethod, auditEndpoint, extensionName, auditStatus, auditFrom, auditTo, auditLimit)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
+	getRuns := func(c *cli.Context) error {
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
+		if errClient != nil {
+			fmt.Println(errClient.Error())
+			return errClient
+		}
+		data, err := client.GetRuns(extensionName, runsStatus, runsLimit)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+		fmt.Print(data)
+		return nil
+	}
+
 	getLogs := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -157,7 +330,7 @@ func Client() *cli.App {
 	}
 
 	getConfig := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -165,7 +338,7 @@ func Client() *cli.App {
 		data := ""
 		var err error
 		if propertyName == "" {
-			data, err = client.GetConfig(extensionName)
+			data, err = client.GetConfig(extensionName, c.Bool("merged"))
 		} else {
 			data, err = client.GetProperty(extensionName, propertyName)
 		}
@@ -178,12 +351,12 @@ func Client() *cli.App {
 	}
 
 	setConfig := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.SetConfig(extensionName, configPath)
+		data, err := client.SetConfig(extensionName, configPath, ifMatch)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -193,7 +366,7 @@ func Client() *cli.App {
 	}
 
 	generateConfig := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -208,7 +381,7 @@ func Client() *cli.App {
 	}
 
 	validateConfig := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -237,12 +410,12 @@ func Client() *cli.App {
 	}
 
 	deploy := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.StartEngine(extensionName, fromState, toState)
+		data, err := client.StartEngine(extensionName, fromState, toState, configOverlay)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -259,7 +432,7 @@ func Client() *cli.App {
 	}
 
 	isRunning := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -274,7 +447,7 @@ func Client() *cli.App {
 	}
 
 	reset := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -288,7 +461,7 @@ func Client() *cli.App {
 	}
 
 	setMock := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -302,7 +475,7 @@ func Client() *cli.App {
 	}
 
 	getMock := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -317,7 +490,7 @@ func Client() *cli.App {
 	}
 
 	getUIMetaData := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -326,7 +499,7 @@ func Client() *cli.App {
 		if c.Bool("all") {
 			data, errClient = client.GetUIMetadatas(extensionName, c.Bool("names-only"))
 		} else {
-			data, errClient = client.GetUIMetadata(extensionName, uiMetadataName)
+			data, errClient = client.GetUIMetadata(extensionName, uiMetadataName, uiMetadataFormat)
 		}
 		if errClient != nil {
 			fmt.Println(errClient.Error())
@@ -337,7 +510,7 @@ func Client() *cli.App {
 	}
 
 	getTemplate := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -352,7 +525,7 @@ func Client() *cli.App {
 	}
 
 	setState := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -366,7 +539,7 @@ func Client() *cli.App {
 	}
 
 	getState := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -381,7 +554,7 @@ func Client() *cli.App {
 	}
 
 	getStates := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -396,12 +569,12 @@ func Client() *cli.App {
 	}
 
 	setStates := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.SetStates(extensionName, statesPath, true)
+		data, err := client.SetStates(extensionName, statesPath, true, ifMatch)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -411,12 +584,12 @@ func Client() *cli.App {
 	}
 
 	setStatesStatuses := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.SetStatesStatuses(extensionName, newStatus, fromState, c.Bool("from-included"), toState, c.Bool("to-included"))
+		data, err := client.SetStatesStatuses(extensionName, newStatus, fromState, c.Bool("from-included"), toState, c.Bool("to-included"), ifMatch)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -426,12 +599,12 @@ func Client() *cli.App {
 	}
 
 	mergeStates := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.SetStates(extensionName, statesPath, false)
+		data, err := client.SetStates(extensionName, statesPath, false, ifMatch)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -441,12 +614,12 @@ func Client() *cli.App {
 	}
 
 	insertStateStates := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.InsertStateStates(extensionName, statePosition, stateName, c.Bool("before"), statePath, insertExtensionName, c.Bool("overwrite"))
+		data, err := client.InsertStateStates(extensionName, statePosition, stateName, c.Bool("before"), statePath, insertExtensionName, c.Bool("overwrite"), ifMatch)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -456,12 +629,12 @@ func Client() *cli.App {
 	}
 
 	deleteStateStates := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.DeleteStateStates(extensionName, statePosition, stateName)
+		data, err := client.DeleteStateStates(extensionName, statePosition, stateName, ifMatch)
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -471,12 +644,24 @@ func Client() *cli.App {
 	}
 
 	findStates := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.GetStates(extensionName, searchStatus, c.Bool("extension"), c.Bool("recursive"))
+		data, err := client.FindStates(extensionName, searchStatus, c.Bool("extension"), c.Bool("recursive"), map[string]string{
+			"name":           filterName,
+			"label":          filterLabel,
+			"is-extension":   filterIsExtension,
+			"started-after":  filterStartedAfter,
+			"started-before": filterStartedBefore,
+			"ended-after":    filterEndedAfter,
+			"ended-before":   filterEndedBefore,
+			"sort":           filterSort,
+			"offset":         filterOffset,
+			"limit":          filterLimit,
+			"fields":         filterFields,
+		})
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -486,7 +671,7 @@ func Client() *cli.App {
 	}
 
 	register := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -501,7 +686,7 @@ func Client() *cli.App {
 	}
 
 	unregister := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -516,12 +701,17 @@ func Client() *cli.App {
 	}
 
 	getExtensions := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
 		}
-		data, err := client.GetExtensions(extensionsToList, c.Bool("catalog"))
+		data, err := client.GetExtensions(extensionsToList, c.Bool("catalog"), map[string]string{
+			"name":   filterName,
+			"offset": filterOffset,
+			"limit":  filterLimit,
+			"fields": filterFields,
+		})
 		if err != nil {
 			fmt.Println(err.Error())
 			return err
@@ -531,7 +721,7 @@ func Client() *cli.App {
 	}
 
 	curl := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -545,7 +735,7 @@ func Client() *cli.App {
 	}
 
 	getCRLogLevel := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -556,7 +746,7 @@ func Client() *cli.App {
 	}
 
 	getCRLogMaxBackups := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -567,7 +757,7 @@ func Client() *cli.App {
 	}
 
 	getCRAbout := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -578,7 +768,7 @@ func Client() *cli.App {
 	}
 
 	setCRLogLevel := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -589,7 +779,7 @@ func Client() *cli.App {
 	}
 
 	setCRLogMaxBackups := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -600,7 +790,7 @@ func Client() *cli.App {
 	}
 
 	getCRSettings := func(c *cli.Context) error {
-		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, InsecureSSL, Token, DefaultExtensionName)
+		client, errClient := clientManager.NewClient(URL, OutputFormat, Timeout, CACertPath, ClientCertPath, ClientKeyPath, InsecureSSL, Token, DefaultExtensionName)
 		if errClient != nil {
 			fmt.Println(errClient.Error())
 			return errClient
@@ -617,7 +807,7 @@ func Client() *cli.App {
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:        "url, u",
-			Usage:       "API Url",
+			Usage:       "API Url, ie: http://localhost:30101 or unix:///var/run/cr.sock",
 			Destination: &URL,
 		},
 		cli.StringFlag{
@@ -635,6 +825,16 @@ func Client() *cli.App {
 			Usage:       "CA Cert path",
 			Destination: &CACertPath,
 		},
+		cli.StringFlag{
+			Name:        "client-cert",
+			Usage:       "Client certificate path for mutual TLS authentication",
+			Destination: &ClientCertPath,
+		},
+		cli.StringFlag{
+			Name:        "client-key",
+			Usage:       "Client key path for mutual TLS authentication",
+			Destination: &ClientKeyPath,
+		},
 		cli.StringFlag{
 			Name:        "insecure, s",
 			Usage:       "true/false, false turn off SSL verification",
@@ -681,16 +881,77 @@ func Client() *cli.App {
 				{
 					Name:    "create",
 					Aliases: []string{"c"},
-					Usage:   "Create token",
+					Usage:   "Create token, if a name is provided the token is created on the server with the given role",
 					Flags: []cli.Flag{
 						cli.StringFlag{
 							Name:        "outFilePath, o",
 							Usage:       "Output file",
 							Destination: &tokenOutputFilePath,
 						},
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "Token name",
+							Destination: &tokenName,
+						},
+						cli.StringFlag{
+							Name:        "role, r",
+							Usage:       "Token role: viewer, operator or admin",
+							Value:       "viewer",
+							Destination: &tokenRole,
+						},
+						cli.StringFlag{
+							Name:        "extensions",
+							Usage:       "Comma separated list of extensions the token is restricted to",
+							Destination: &tokenExtensions,
+						},
+						cli.StringFlag{
+							Name:        "expires-in",
+							Usage:       "Duration after which the token expires, ie: 720h",
+							Destination: &tokenExpiresIn,
+						},
 					},
 					Action: createToken,
 				},
+				{
+					Name:  "rotate",
+					Usage: "Issue a new value for a token, the previous value stays valid during the overlap",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "Token name",
+							Destination: &tokenName,
+						},
+						cli.StringFlag{
+							Name:        "overlap",
+							Usage:       "Duration during which the previous value stays valid, default 1h",
+							Destination: &tokenOverlap,
+						},
+						cli.StringFlag{
+							Name:        "expires-in",
+							Usage:       "Duration after which the new value expires, ie: 720h",
+							Destination: &tokenExpiresIn,
+						},
+					},
+					Action: rotateToken,
+				},
+				{
+					Name:    "list",
+					Aliases: []string{"l"},
+					Usage:   "List tokens",
+					Action:  listTokens,
+				},
+				{
+					Name:  "revoke",
+					Usage: "Revoke token",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "Token name",
+							Destination: &tokenName,
+						},
+					},
+					Action: revokeToken,
+				},
 			},
 		},
 		/*            Extensions                  */
@@ -707,6 +968,26 @@ func Client() *cli.App {
 					Name:  "catalog, c",
 					Usage: "Display unregistered IBM extensions",
 				},
+				cli.StringFlag{
+					Name:        "name, n",
+					Usage:       "Extension name glob pattern",
+					Destination: &filterName,
+				},
+				cli.StringFlag{
+					Name:        "offset",
+					Usage:       "Number of extensions to skip, the extensions are ordered by name",
+					Destination: &filterOffset,
+				},
+				cli.StringFlag{
+					Name:        "limit",
+					Usage:       "Maximum number of extensions to return",
+					Destination: &filterLimit,
+				},
+				cli.StringFlag{
+					Name:        "fields",
+					Usage:       "Comma separated list of extension attributes to return",
+					Destination: &filterFields,
+				},
 			},
 			Action: getExtensions,
 		},
@@ -745,6 +1026,11 @@ func Client() *cli.App {
 							Name:  "names-only, n",
 							Usage: "Get only the configuration names",
 						},
+						cli.StringFlag{
+							Name:        "format",
+							Usage:       "ui metadata format (json, jsonschema)",
+							Destination: &uiMetadataFormat,
+						},
 					},
 					Action: getUIMetaData,
 				},
@@ -791,6 +1077,11 @@ func Client() *cli.App {
 							Usage:       "Configuration file",
 							Destination: &configPath,
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: setConfig,
 				},
@@ -936,6 +1227,10 @@ func Client() *cli.App {
 					Usage:       "Property name",
 					Destination: &propertyName,
 				},
+				cli.BoolFlag{
+					Name:  "merged, m",
+					Usage: "Merge the config overlay of the current run or the server default, the result must not be saved",
+				},
 			},
 			Action: getConfig,
 			Subcommands: []cli.Command{
@@ -949,6 +1244,11 @@ func Client() *cli.App {
 							Usage:       "Configuration file",
 							Destination: &configPath,
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: setConfig,
 				},
@@ -995,6 +1295,11 @@ func Client() *cli.App {
 							Usage:       "Finish at the provided state included",
 							Destination: &toState,
 						},
+						cli.StringFlag{
+							Name:        "overlay, o",
+							Usage:       "Config overlay to merge for this run, ie: 'prod' for config.prod.yml",
+							Destination: &configOverlay,
+						},
 					},
 					Action: deploy,
 				},
@@ -1053,6 +1358,147 @@ func Client() *cli.App {
 			},
 			Action: getLogs,
 		},
+		/*            AUDIT                  */
+		{
+			Name:  "audit",
+			Usage: "List the audit records of the mutating api calls",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "caller",
+					Usage:       "Token name or certificate subject",
+					Destination: &auditCaller,
+				},
+				cli.StringFlag{
+					Name:        "method, m",
+					Usage:       "HTTP method",
+					Destination: &auditMethod,
+				},
+				cli.StringFlag{
+					Name:        "endpoint",
+					Usage:       "Endpoint prefix, ie: /cr/v1/states",
+					Destination: &auditEndpoint,
+				},
+				cli.StringFlag{
+					Name:        "extension, e",
+					Usage:       "Extension name",
+					Destination: &extensionName,
+				},
+				cli.StringFlag{
+					Name:        "status, s",
+					Usage:       "Response status code",
+					Destination: &auditStatus,
+				},
+				cli.StringFlag{
+					Name:        "from",
+					Usage:       "RFC3339 date, ie: 2019-01-02T15:04:05Z",
+					Destination: &auditFrom,
+				},
+				cli.StringFlag{
+					Name:        "to",
+					Usage:       "RFC3339 date, ie: 2019-01-02T15:04:05Z",
+					Destination: &auditTo,
+				},
+				cli.StringFlag{
+					Name:        "limit, l",
+					Usage:       "Maximum number of records, the most recent are returned",
+					Destination: &auditLimit,
+				},
+			},
+			Action: getAudit,
+		},
+		/*            RUNS                  */
+		{
+			Name:  "runs",
+			Usage: "List the run history of an extension, the most recent run first",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "extension, e",
+					Usage:       "Extension name",
+					Destination: &extensionName,
+				},
+				cli.StringFlag{
+					Name:        "status, s",
+					Usage:       "Run status (SUCCEEDED, FAILED)",
+					Destination: &runsStatus,
+				},
+				cli.StringFlag{
+					Name:        "limit, l",
+					Usage:       "Maximum number of runs, the most recent are returned",
+					Destination: &runsLimit,
+				},
+			},
+			Action: getRuns,
+		},
+		/*            WEBHOOK                  */
+		{
+			Name:  "webhook",
+			Usage: "Webhook management",
+			Subcommands: []cli.Command{
+				{
+					Name:    "create",
+					Aliases: []string{"c"},
+					Usage:   "Register a webhook receiving the run and state events, the secret used to sign the payloads is displayed",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "Webhook name",
+							Destination: &webhookName,
+						},
+						cli.StringFlag{
+							Name:        "url, u",
+							Usage:       "Webhook url",
+							Destination: &webhookURL,
+						},
+						cli.StringFlag{
+							Name:        "extension, e",
+							Usage:       "Extension name, leave empty for all extensions",
+							Destination: &extensionName,
+						},
+						cli.StringFlag{
+							Name:        "events",
+							Usage:       "Comma separated list of event filters <run_start|run_end|state_status|log>[:<status>], ie: run_end:SUCCEEDED,state_status:FAILED",
+							Destination: &webhookEvents,
+						},
+						cli.StringFlag{
+							Name:        "secret",
+							Usage:       "Shared secret used to sign the payloads, generated if not provided",
+							Destination: &webhookSecret,
+						},
+					},
+					Action: createWebhook,
+				},
+				{
+					Name:    "list",
+					Aliases: []string{"l"},
+					Usage:   "List webhooks",
+					Action:  listWebhooks,
+				},
+				{
+					Name:  "delete",
+					Usage: "Delete webhook",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "Webhook name",
+							Destination: &webhookName,
+						},
+					},
+					Action: deleteWebhook,
+				},
+				{
+					Name:  "deliveries",
+					Usage: "List the deliveries of a webhook",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "Webhook name, leave empty for all webhooks",
+							Destination: &webhookName,
+						},
+					},
+					Action: getWebhookDeliveries,
+				},
+			},
+		},
 		/*            CM STATUS                  */
 		{
 			Name:   "status",
@@ -1141,6 +1587,11 @@ func Client() *cli.App {
 					Name:  "names-only, n",
 					Usage: "Get only the configuration names",
 				},
+				cli.StringFlag{
+					Name:        "format",
+					Usage:       "ui metadata format (json, jsonschema)",
+					Destination: &uiMetadataFormat,
+				},
 			},
 			Action: getUIMetaData,
 		},
@@ -1178,7 +1629,7 @@ func Client() *cli.App {
 				{
 					Name:    "find",
 					Aliases: []string{"f"},
-					Usage:   "Find the states with a given status",
+					Usage:   "Find the states matching the filters",
 					Flags: []cli.Flag{
 						cli.StringFlag{
 							Name:        "status, s",
@@ -1193,6 +1644,61 @@ func Client() *cli.App {
 							Name:  "recursive, r",
 							Usage: "Search recursively in the extension states",
 						},
+						cli.StringFlag{
+							Name:        "name, n",
+							Usage:       "State name glob pattern",
+							Destination: &filterName,
+						},
+						cli.StringFlag{
+							Name:        "label, l",
+							Usage:       "State label glob pattern",
+							Destination: &filterLabel,
+						},
+						cli.StringFlag{
+							Name:        "is-extension",
+							Usage:       "true to find the extension states only, false to find the other states only",
+							Destination: &filterIsExtension,
+						},
+						cli.StringFlag{
+							Name:        "started-after",
+							Usage:       "Find the states started after this date (RFC3339)",
+							Destination: &filterStartedAfter,
+						},
+						cli.StringFlag{
+							Name:        "started-before",
+							Usage:       "Find the states started before this date (RFC3339)",
+							Destination: &filterStartedBefore,
+						},
+						cli.StringFlag{
+							Name:        "ended-after",
+							Usage:       "Find the states ended after this date (RFC3339)",
+							Destination: &filterEndedAfter,
+						},
+						cli.StringFlag{
+							Name:        "ended-before",
+							Usage:       "Find the states ended before this date (RFC3339)",
+							Destination: &filterEndedBefore,
+						},
+						cli.StringFlag{
+							Name:        "sort",
+							Usage:       "Sort by position, name or duration, prefix with - for a descending order",
+							Destination: &filterSort,
+						},
+						cli.StringFlag{
+							Name:        "offset",
+							Usage:       "Number of states to skip",
+							Destination: &filterOffset,
+						},
+						cli.StringFlag{
+							Name:        "limit",
+							Usage:       "Maximum number of states to return",
+							Destination: &filterLimit,
+						},
+						cli.StringFlag{
+							Name:        "fields",
+							Usage:       "Comma separated list of state attributes to return",
+							Destination: &filterFields,
+						},
 					},
 					Action: findStates,
 				},
@@ -1229,6 +1735,11 @@ func Client() *cli.App {
 							Name:  "overwrite, o",
 							Usage: "overwrite the state if already exists",
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: insertStateStates,
 				},
@@ -1247,6 +1758,11 @@ func Client() *cli.App {
 							Usage:       "Referencing state name, can be use instead of position",
 							Destination: &stateName,
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: deleteStateStates,
 				},
@@ -1261,6 +1777,11 @@ func Client() *cli.App {
 							Usage:       "States file path to set",
 							Destination: &statesPath,
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: setStates,
 				},
@@ -1292,6 +1813,11 @@ func Client() *cli.App {
 							Name:  "to-included, ti",
 							Usage: "Include the state range end",
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: setStatesStatuses,
 				},
@@ -1306,6 +1832,11 @@ func Client() *cli.App {
 							Usage:       "States file path to merge",
 							Destination: &statesPath,
 						},
+						cli.StringFlag{
+							Name:        "if-match",
+							Usage:       "Only update if the ETag returned by the last read is still current",
+							Destination: &ifMatch,
+						},
 					},
 					Action: mergeStates,
 				},
@@ -69,7 +69,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _enYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xca\x34\xb4\xc8\xd3\x2b\x49\x2d\x2e\xd1\xcb\x48\xcd\xc9\xc9\x2f\xcf\x2f\xca\x49\xb1\x52\xf0\x00\xb1\x15\xc0\x1c\x2e\x2e\x40\x00\x00\x00\xff\xff\xcf\xa5\xc2\xcd\x23\x00\x00\x00")
+var _enYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x65\x90\xd1\x4a\xc5\x30\x0c\x86\xef\xf7\x14\x79\x00\x19\x78\x27\xbb\x54\x14\xbd\x50\x04\xf1\xba\x74\x6b\xc6\x02\x3d\x8d\xa6\xd9\x39\xe2\xd3\x9b\x76\x9d\x0c\xbd\x4b\xbe\xff\x6b\x9b\x94\xae\x6f\x52\xaf\x98\xb5\x5f\x30\x46\xbe\xb0\xc4\x30\xc0\x63\xa9\xa1\x36\x1d\x8a\xb0\xf4\xa3\x0f\x4e\xf0\x73\x35\x73\x80\x5b\x1f\xa0\x35\x2d\x5e\x93\x5f\x75\x61\xa1\x6f\xb4\xe3\xef\x87\xae\x09\x33\xcb\x48\x21\x60\x1a\xe0\x61\x2f\x5b\x94\x58\xdd\xcc\x6b\xb2\x83\x2f\xac\x50\xcb\x16\xe1\x97\x62\xca\xc4\xc9\x1d\xa4\xfb\x1d\x42\xfa\xa3\x67\xf5\x8a\x47\xf5\xad\x80\x7f\xda\x09\x6d\xb8\x50\x3d\x5f\x76\x2e\x23\x3f\x57\x56\xd5\xc6\x9a\x3c\x71\x9a\x23\x4d\xb6\xf5\x5d\xab\x5a\xf0\x21\x68\x59\x20\x2d\xe3\xcd\x9e\x62\xb9\xe6\xf5\x00\x61\x83\x57\xa0\x0b\xda\x77\x65\x5e\x65\x42\xb8\xf8\x0c\x27\x0e\x34\xd3\xef\x13\x94\x14\x25\xf9\xe8\x32\xca\x19\xc5\x55\x3a\xc0\x53\xc3\xb0\x61\xa8\x78\xdf\xd4\x10\x4d\xe8\xec\xdf\xcf\xf6\x8a\x1f\x23\xda\xb6\x1b\x84\x03\xec\xba\x1f\x89\x38\x23\xeb\xe1\x01\x00\x00")
 
 func enYmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -84,12 +84,12 @@ func enYml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "en.yml", size: 35, mode: os.FileMode(420), modTime: time.Unix(1548420182, 0)}
+	info := bindataFileInfo{name: "en.yml", size: 481, mode: os.FileMode(420), modTime: time.Unix(1792419812, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _frYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xca\x34\xb4\xc8\xd3\x2b\x49\x2d\x2e\xd1\xcb\x48\xcd\xc9\xc9\x2f\xcf\x2f\xca\x49\xb1\x52\x70\xca\xcf\xcb\xca\x2f\x2d\x52\x28\xc9\x2f\x2d\x51\xc8\x49\x55\xc8\xcd\xcf\x4b\x49\xe5\x02\x04\x00\x00\xff\xff\x2c\xbd\x85\xc6\x2c\x00\x00\x00")
+var _frYml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6d\x51\x41\x4e\xc4\x30\x0c\xbc\xf3\x0a\x3f\x00\x55\xe2\x86\x7a\x03\xc4\x81\x03\x1c\xe0\x01\x55\x36\x71\xb5\x41\x59\x7b\x71\x9c\x82\xf8\x01\x3f\xa1\x7c\x23\x1f\xc3\xe9\x6e\x57\x2b\xc4\x2d\x1e\x8f\xed\x99\x49\xbc\xba\xa6\x4e\x31\x6b\xb7\xc5\x94\xf8\x9d\x25\x85\x1e\x6e\x99\x5e\xb9\x08\x28\x17\x85\x84\xb0\x63\x0a\x78\x81\x22\x2c\xdd\xc6\x85\x41\xf0\xad\xd8\x4c\x0f\xcf\xf6\xa8\x3f\x8a\x10\x69\x72\x29\x9e\x48\x85\x5c\xd1\x2d\x4b\xfc\x44\x5b\xf7\xc4\x04\xad\x46\xd2\x38\xc6\x3a\x1f\x49\x23\xcb\x26\x86\x80\xd4\xc3\x8d\xf7\xf5\x3b\x83\xe0\x58\xf2\xa9\x4f\xac\xc3\xc8\x85\x6c\xc3\x03\xa9\x70\x99\xdc\x26\xad\x17\xf0\x43\x91\x72\x64\x1a\xce\x68\xf7\x2b\x68\x7a\xfe\x0e\x64\x75\x8a\xe7\xe4\xfa\x65\xc8\x3f\xc4\x1d\x9a\xf2\xb0\x30\x5d\x8b\xa4\x39\x78\xac\x73\x03\x11\xe8\x60\xc5\x9c\x99\xce\x75\xc2\x33\x8d\x29\x7a\xcb\xe3\x6e\x79\xe9\x11\xdf\x0b\x5a\x2b\x44\x6d\x32\x47\x17\x13\x2e\x67\xfd\x16\x3d\xd8\xae\xe4\x60\x2f\x75\x3e\x51\x2e\x1b\x22\x98\xb3\x45\xef\x11\x1c\xd8\xd1\x3a\x5b\xf8\x61\x49\x6d\xbd\x66\x8a\x51\xc8\xa5\x21\xa3\x4c\x28\xc3\x82\x9a\x77\x11\xb4\x2f\x3b\x74\x11\x42\x81\xa5\x5f\x64\xf5\x6f\x55\xf4\x38\xd8\xd7\x4c\x26\xa5\x19\xee\xe1\xe5\x00\xda\x54\x88\x79\xcf\x14\x5b\x0c\xbf\xcf\xac\x49\x8c\x14\x02\x00\x00")
 
 func frYmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -104,7 +104,7 @@ func frYml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "fr.yml", size: 44, mode: os.FileMode(420), modTime: time.Unix(1548420189, 0)}
+	info := bindataFileInfo{name: "fr.yml", size: 532, mode: os.FileMode(420), modTime: time.Unix(1792419812, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
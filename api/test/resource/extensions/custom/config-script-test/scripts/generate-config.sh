@@ -0,0 +1,8 @@
+#!/bin/sh
+# Generates the config, the current config is received on stdin
+cat > /dev/null
+cat <<EOT
+config:
+  env_name: generated
+  subnet: "192.168.100.0/24"
+EOT
@@ -0,0 +1,8 @@
+#!/bin/sh
+# Validates the config received on stdin, env_name is mandatory
+input=$(cat)
+if echo "$input" | grep -q '"env_name"'; then
+  echo "$input"
+else
+  echo '{"config":{"env_name":{"value":"","message_type":"error","message":"env_name is mandatory"}}}'
+fi
@@ -27,8 +27,8 @@ type MyCommandsRunnerClient struct {
 	CMC *clientManager.CommandsRunnerClient
 }
 
-func NewClient(urlIn string, outputFormat string, timeout string, caCertPath string, insecureSSL string, token string, defaultExtensionName string) (*MyCommandsRunnerClient, error) {
-	client, errClient := clientManager.NewClient(urlIn, outputFormat, timeout, caCertPath, insecureSSL, token, defaultExtensionName)
+func NewClient(urlIn string, outputFormat string, timeout string, caCertPath string, clientCertPath string, clientKeyPath string, insecureSSL string, token string, defaultExtensionName string) (*MyCommandsRunnerClient, error) {
+	client, errClient := clientManager.NewClient(urlIn, outputFormat, timeout, caCertPath, clientCertPath, clientKeyPath, insecureSSL, token, defaultExtensionName)
 	if errClient != nil {
 		return nil, errClient
 	}